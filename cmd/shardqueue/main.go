@@ -1,12 +1,10 @@
 package main
 
 import (
+	"context"
 	"log"
-	"os"
-	"os/signal"
 	"strconv"
 	"sync/atomic"
-	"syscall"
 	"time"
 
 	"github.com/joripage/go_util/pkg/shardqueue"
@@ -22,28 +20,28 @@ func main() {
 		ID int32
 	}
 
-	sq := shardqueue.NewShardQueue(numShard, queueSize)
-	sq.Start(func(msg interface{}) error {
-		if v, ok := msg.(testStruct); ok {
-			if processCount == totalMsg-1 {
-				log.Println("process id", v.ID, "processCount", processCount, "in", time.Since(begin))
-			}
-			atomic.AddInt32(&processCount, 1)
+	sq := shardqueue.NewShardQueue[string, testStruct](numShard, queueSize)
+	err := sq.Start(context.Background(), func(ctx context.Context, msg testStruct) error {
+		if processCount == totalMsg-1 {
+			log.Println("process id", msg.ID, "processCount", processCount, "in", time.Since(begin))
 		}
+		atomic.AddInt32(&processCount, 1)
 		return nil
 	})
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	test := testStruct{}
 	for i := range totalMsg {
 		test.ID = i
-		sq.Shard(strconv.Itoa(int(test.ID)), test)
+		if err := sq.Shard(strconv.Itoa(int(test.ID)), test); err != nil {
+			log.Println("shard error", err)
+		}
 	}
 
+	// waits for the queued messages to be processed
 	sq.Stop()
 
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
-	<-sigs
-
-	log.Println("done")
+	log.Println("done, processed", atomic.LoadInt32(&processCount), "in", time.Since(begin))
 }
@@ -34,54 +34,46 @@ func main() {
 
 	// --- Pattern 1: Cancel multiple tasks at once ---
 	fmt.Println("Pattern 1: Cancel multiple tasks individually")
-	_ = tm.StartTask(context.Background(), "task1", processAllOrders)
-	_ = tm.StartTask(context.Background(), "task2", processAllOrders)
+	task1, _ := tm.StartTask(context.Background(), "task1", processAllOrders)
+	task2, _ := tm.StartTask(context.Background(), "task2", processAllOrders)
 	time.Sleep(1500 * time.Millisecond)
-	tm.StopTask("task1")
-	tm.StopTask("task2")
+	task1.Stop()
+	task2.Stop()
 	time.Sleep(2000 * time.Millisecond)
 
 	// --- Pattern 2: Cancel tasks via shared parent context ---
 	fmt.Println("\nPattern 2: Cancel all tasks via shared parent context")
 	parentCtx, cancelAll := context.WithCancel(context.Background())
-	_ = tm.StartTask(parentCtx, "task3", processAllOrders)
-	_ = tm.StartTask(parentCtx, "task4", processAllOrders)
+	_, _ = tm.StartTask(parentCtx, "task3", processAllOrders)
+	_, _ = tm.StartTask(parentCtx, "task4", processAllOrders)
 	time.Sleep(1500 * time.Millisecond)
 	cancelAll() // stops task3 and task4
 	time.Sleep(2000 * time.Millisecond)
 
 	// --- Pattern 3: Cancel tasks by tag ---
 	fmt.Println("\nPattern 3: Cancel tasks by tag")
-	taskTags := map[string]string{}
-	startTagged := func(id, tag string) {
-		taskTags[id] = tag
-		_ = tm.StartTask(context.Background(), id, processAllOrders)
-	}
-	startTagged("sync1", "sync")
-	startTagged("sync2", "sync")
-	startTagged("report1", "report")
+	_, _ = tm.StartTask(context.Background(), "sync1", processAllOrders, taskmanager.WithTags("sync"))
+	_, _ = tm.StartTask(context.Background(), "sync2", processAllOrders, taskmanager.WithTags("sync"))
+	_, _ = tm.StartTask(context.Background(), "report1", processAllOrders, taskmanager.WithTags("report"))
 	time.Sleep(1500 * time.Millisecond)
 	// stop all tasks with tag "sync"
-	for id, tag := range taskTags {
-		if tag == "sync" {
-			tm.StopTask(id)
-		}
-	}
+	tm.StopTasksByTag("sync")
 	time.Sleep(2000 * time.Millisecond)
 
 	// --- Pattern 4: Timeout for automatic cancellation ---
 	fmt.Println("\nPattern 4: Timeout for automatic cancellation")
-	ctxTimeout, cancel := context.WithTimeout(context.Background(), 1500*time.Millisecond)
-	defer cancel()
-	_ = tm.StartTask(ctxTimeout, "task_with_timeout", processAllOrders)
+	_, _ = tm.StartTask(context.Background(), "task_with_timeout", processAllOrders, taskmanager.WithTimeout(1500*time.Millisecond))
 	time.Sleep(3 * time.Second) // wait to see timeout
 
 	// --- Pattern 5: Graceful shutdown of all tasks ---
 	fmt.Println("\nPattern 5: Graceful shutdown of all tasks")
-	_ = tm.StartTask(context.Background(), "task5", processAllOrders)
-	_ = tm.StartTask(context.Background(), "task6", processAllOrders)
+	_, _ = tm.StartTask(context.Background(), "task5", processAllOrders)
+	_, _ = tm.StartTask(context.Background(), "task6", processAllOrders)
 	time.Sleep(1500 * time.Millisecond)
 	fmt.Println("Shutting down...")
-	tm.GracefulShutdown(true, 3*time.Second)
-	time.Sleep(500 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	if err := tm.GracefulShutdownContext(ctx); err != nil {
+		fmt.Println(err)
+	}
 }
@@ -0,0 +1,106 @@
+package shardqueue
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+)
+
+type batchFunc[V any] func(ctx context.Context, msgs []V) error
+
+// StartBatch is like Start but calls fn with batches of up to size messages
+// of a shard, e.g. for bulk writes. A batch is processed once it is full or
+// linger after its first message, whichever comes first. Retries apply to
+// the whole batch, and a failed batch is reported message by message to the
+// error handler and the dead letter function.
+func (sq *ShardQueue[K, V]) StartBatch(ctx context.Context, size int, linger time.Duration, fn batchFunc[V]) error {
+	if size <= 0 {
+		return ErrInvalidBatchSize
+	}
+	return sq.start(ctx, func(id int, buf buffer[envelope[K, V]]) {
+		sq.batchWorker(ctx, id, buf, size, linger, fn)
+	})
+}
+
+func (sq *ShardQueue[K, V]) batchWorker(ctx context.Context, id int, buf buffer[envelope[K, V]], size int, linger time.Duration, fn batchFunc[V]) {
+	batch := make([]envelope[K, V], 0, size)
+	for {
+		if ctx.Err() != nil {
+			log.Printf("Shard %d stopped: %v, %d batched message(s) dropped", id, ctx.Err(), len(batch))
+			return
+		}
+
+		// wait for the first message of the batch
+		e, res := buf.pop(ctx.Done(), nil)
+		switch res {
+		case popInterrupted:
+			continue
+		case popClosed:
+			log.Printf("Shard %d done", id)
+			return
+		}
+		batch = append(batch, e)
+
+		timer := time.NewTimer(linger)
+		closed := false
+	collect:
+		for len(batch) < size {
+			e, res := buf.pop(ctx.Done(), timer.C)
+			switch res {
+			case popInterrupted:
+				break collect
+			case popClosed:
+				closed = true
+				break collect
+			}
+			batch = append(batch, e)
+		}
+		timer.Stop()
+		if ctx.Err() != nil {
+			continue
+		}
+
+		sq.processBatch(ctx, id, batch, fn)
+		batch = batch[:0]
+		if closed {
+			log.Printf("Shard %d done", id)
+			return
+		}
+	}
+}
+
+func (sq *ShardQueue[K, V]) processBatch(ctx context.Context, shard int, batch []envelope[K, V], fn batchFunc[V]) {
+	batch = slices.DeleteFunc(batch, func(e envelope[K, V]) bool {
+		if sq.expired(shard, e) {
+			sq.handled(e)
+			e.future.resolve(ErrExpired)
+			return true
+		}
+		return false
+	})
+	if len(batch) == 0 {
+		return
+	}
+	msgs := make([]V, len(batch))
+	for i, e := range batch {
+		msgs[i] = e.msg
+	}
+
+	ctx, span := sq.startBatchSpan(ctx, shard, batch)
+	start := time.Now()
+	err, attempts := sq.attempt(ctx, shard, func() error { return fn(ctx, msgs) })
+	duration := time.Since(start)
+	endSpan(span, outcomeOf(ctx, err), err, attempts)
+	for _, e := range batch {
+		sq.metrics[shard].observe(duration, err)
+		sq.result(shard, e, err, duration)
+		if err != nil {
+			sq.fail(shard, e, err, attempts)
+		}
+		if err == nil || ctx.Err() == nil {
+			sq.handled(e)
+		}
+		e.future.resolve(err)
+	}
+}
@@ -0,0 +1,85 @@
+package shardqueue
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardQueue_StartBatch(t *testing.T) {
+	sq := NewShardQueue[string, int](2, 10)
+	var mu sync.Mutex
+	var batches [][]int
+	err := sq.StartBatch(context.Background(), 3, time.Minute, func(ctx context.Context, msgs []int) error {
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, slices.Clone(msgs))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for msg := range 7 {
+		_ = sq.Shard("key", msg)
+	}
+	// flushes the last partial batch
+	sq.Stop()
+
+	want := [][]int{{0, 1, 2}, {3, 4, 5}, {6}}
+	if !slices.EqualFunc(batches, want, slices.Equal) {
+		t.Errorf("Expected the batches %v, got %v", want, batches)
+	}
+	if processed := sq.Stats()[0].Processed + sq.Stats()[1].Processed; processed != 7 {
+		t.Errorf("Expected 7 processed messages, got %d", processed)
+	}
+}
+
+func TestShardQueue_StartBatchLinger(t *testing.T) {
+	sq := NewShardQueue[string, int](1, 10)
+	batches := make(chan []int, 1)
+	_ = sq.StartBatch(context.Background(), 10, 20*time.Millisecond, func(ctx context.Context, msgs []int) error {
+		batches <- slices.Clone(msgs)
+		return nil
+	})
+	defer sq.Stop()
+
+	_ = sq.Shard("key", 1)
+	_ = sq.Shard("key", 2)
+	select {
+	case got := <-batches:
+		if !slices.Equal(got, []int{1, 2}) {
+			t.Errorf("Expected the lingering messages in a batch, got %v", got)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected a partial batch once the linger elapsed")
+	}
+}
+
+func TestShardQueue_StartBatchFailure(t *testing.T) {
+	dead := make(chan DeadLetter, 2)
+	sq := NewShardQueue[string, int](1, 10,
+		WithErrorHandler(func(int, any, error) {}),
+		WithDeadLetter(DeadLetterChan(dead)),
+	)
+	_ = sq.StartBatch(context.Background(), 2, time.Minute, func(ctx context.Context, msgs []int) error {
+		return errors.New("bulk insert failed")
+	})
+	_ = sq.Shard("key", 1)
+	_ = sq.Shard("key", 2)
+	sq.Stop()
+
+	close(dead)
+	var msgs []any
+	for dl := range dead {
+		msgs = append(msgs, dl.Msg)
+	}
+	if !slices.Equal(msgs, []any{1, 2}) {
+		t.Errorf("Expected every message of the failed batch dead-lettered, got %v", msgs)
+	}
+	if err := sq.StartBatch(context.Background(), 0, time.Second, nil); !errors.Is(err, ErrInvalidBatchSize) {
+		t.Errorf("Expected ErrInvalidBatchSize, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package shardqueue
+
+import "time"
+
+// buffer holds the queued messages of a shard, a channel by default or a
+// ring buffer, see WithRingBuffer.
+type buffer[E any] interface {
+	// tryPush adds e unless the buffer is full.
+	tryPush(e E) bool
+	// push adds e, waiting for room until stop or done is closed.
+	push(e E, stop, done <-chan struct{}) bool
+	// tryPop takes the oldest element, if any.
+	tryPop() (E, bool)
+	// pop takes the oldest element, waiting for one until the buffer is
+	// closed and drained, or until done is closed or timeout fires.
+	pop(done <-chan struct{}, timeout <-chan time.Time) (E, popResult)
+	// close tells pop that no more elements are coming.
+	close()
+	len() int
+}
+
+type popResult int
+
+const (
+	popped popResult = iota
+	popClosed
+	popInterrupted
+)
+
+// chanBuffer is a buffer backed by a channel.
+type chanBuffer[E any] chan E
+
+func (c chanBuffer[E]) tryPush(e E) bool {
+	select {
+	case c <- e:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c chanBuffer[E]) push(e E, stop, done <-chan struct{}) bool {
+	select {
+	case c <- e:
+		return true
+	case <-stop:
+		return false
+	case <-done:
+		return false
+	}
+}
+
+func (c chanBuffer[E]) tryPop() (E, bool) {
+	select {
+	case e, ok := <-c:
+		return e, ok
+	default:
+		var zero E
+		return zero, false
+	}
+}
+
+func (c chanBuffer[E]) pop(done <-chan struct{}, timeout <-chan time.Time) (E, popResult) {
+	var zero E
+	select {
+	case e, ok := <-c:
+		if !ok {
+			return zero, popClosed
+		}
+		return e, popped
+	case <-done:
+		return zero, popInterrupted
+	case <-timeout:
+		return zero, popInterrupted
+	}
+}
+
+func (c chanBuffer[E]) close() {
+	close(c)
+}
+
+func (c chanBuffer[E]) len() int {
+	return len(c)
+}
@@ -0,0 +1,38 @@
+package shardqueue
+
+import "time"
+
+// DeadLetter is a message the process function failed for, once its
+// attempts are exhausted or for a permanent error, see WithDeadLetter.
+type DeadLetter struct {
+	Shard int
+	Key   any
+	Msg   any
+	// Err is the error of the last attempt.
+	Err      error
+	Attempts int
+	// EnqueuedAt is when the message was queued and FailedAt when its last
+	// attempt failed.
+	EnqueuedAt time.Time
+	FailedAt   time.Time
+}
+
+// DeadLetterFunc receives the messages that could not be processed. It is
+// called from the worker of the shard, which waits for it to return.
+type DeadLetterFunc func(DeadLetter)
+
+// WithDeadLetter hands the messages the process function failed for to fn,
+// e.g. to store them for a later replay, instead of dropping them.
+func WithDeadLetter(fn DeadLetterFunc) Option {
+	return func(o *options) {
+		o.deadLetter = fn
+	}
+}
+
+// DeadLetterChan returns a DeadLetterFunc sending the dead letters to ch. The
+// worker of the shard blocks while ch is full.
+func DeadLetterChan(ch chan<- DeadLetter) DeadLetterFunc {
+	return func(dl DeadLetter) {
+		ch <- dl
+	}
+}
@@ -0,0 +1,122 @@
+package shardqueue
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithDeadLetter_Exhausted(t *testing.T) {
+	dead := make(chan DeadLetter, 1)
+	sq := NewShardQueue[string, int](2, 1,
+		WithRetry(3, time.Millisecond),
+		WithErrorHandler(func(int, any, error) {}),
+		WithDeadLetter(DeadLetterChan(dead)),
+	)
+	var calls atomic.Int32
+	boom := errors.New("boom")
+	_ = sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		calls.Add(1)
+		return boom
+	})
+	defer sq.Stop()
+
+	before := time.Now()
+	_ = sq.Shard("key", 42)
+	select {
+	case dl := <-dead:
+		if dl.Key != "key" || dl.Msg != 42 || !errors.Is(dl.Err, boom) {
+			t.Errorf("Expected the failed message with its key and error, got %+v", dl)
+		}
+		if dl.Attempts != 3 || calls.Load() != 3 {
+			t.Errorf("Expected 3 attempts, got %d (%d calls)", dl.Attempts, calls.Load())
+		}
+		if dl.Shard != sq.WhichShard("key") {
+			t.Errorf("Expected the shard of the key, got %d", dl.Shard)
+		}
+		if dl.EnqueuedAt.Before(before) || dl.FailedAt.Before(dl.EnqueuedAt.Add(3*time.Millisecond)) {
+			t.Errorf("Expected the enqueue time and the time of the last attempt, got %v and %v", dl.EnqueuedAt, dl.FailedAt)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected the message to be dead-lettered")
+	}
+}
+
+func TestWithDeadLetter_Permanent(t *testing.T) {
+	dead := make(chan DeadLetter, 1)
+	sq := NewShardQueue[string, int](1, 1,
+		WithRetry(3, time.Millisecond),
+		WithErrorHandler(func(int, any, error) {}),
+		WithDeadLetter(DeadLetterChan(dead)),
+	)
+	_ = sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		return Permanent(errors.New("invalid"))
+	})
+	defer sq.Stop()
+
+	_ = sq.Shard("key", 1)
+	select {
+	case dl := <-dead:
+		if dl.Attempts != 1 || !IsPermanent(dl.Err) {
+			t.Errorf("Expected a single attempt for a permanent error, got %d: %v", dl.Attempts, dl.Err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected the message to be dead-lettered")
+	}
+}
+
+func TestWithRetry_Succeeds(t *testing.T) {
+	sq := NewShardQueue[string, int](1, 1,
+		WithRetry(3, time.Millisecond),
+		WithDeadLetter(func(dl DeadLetter) { t.Errorf("Unexpected dead letter %+v", dl) }),
+	)
+	var calls atomic.Int32
+	_ = sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		if calls.Add(1) < 2 {
+			return errors.New("flaky")
+		}
+		return nil
+	})
+
+	_ = sq.Shard("key", 1)
+	sq.Stop()
+	if n := calls.Load(); n != 2 {
+		t.Errorf("Expected a retry after the first failure, got %d calls", n)
+	}
+	if stats := sq.Stats()[0]; stats.Failed != 0 || stats.Processed != 1 {
+		t.Errorf("Expected the message counted once as processed, got %+v", stats)
+	}
+}
+
+func TestWithDeadLetter_Canceled(t *testing.T) {
+	dead := make(chan DeadLetter, 1)
+	var reported atomic.Int32
+	sq := NewShardQueue[string, int](1, 1,
+		WithRetry(3, time.Millisecond),
+		WithErrorHandler(func(int, any, error) { reported.Add(1) }),
+		WithDeadLetter(DeadLetterChan(dead)),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	_ = sq.Start(ctx, func(ctx context.Context, msg int) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	_ = sq.Shard("key", 1)
+	<-started
+	cancel()
+	sq.Stop()
+
+	select {
+	case dl := <-dead:
+		t.Errorf("Expected an interrupted message not to be dead-lettered, got %+v", dl)
+	default:
+	}
+	if n := reported.Load(); n != 0 {
+		t.Errorf("Expected an interrupted message not to be reported, got %d reports", n)
+	}
+}
@@ -0,0 +1,37 @@
+package shardqueue
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrQueueFull        = errors.New("shard queue is full")
+	ErrEnqueueTimeout   = errors.New("timed out waiting for room in the shard queue")
+	ErrNotStarted       = errors.New("shard queue not started")
+	ErrAlreadyStarted   = errors.New("shard queue already started")
+	ErrStopped          = errors.New("shard queue stopped")
+	ErrInvalidBatchSize = errors.New("batch size must be positive")
+	ErrNoPriorities     = errors.New("shard queue has no priorities")
+	ErrInvalidPriority  = errors.New("invalid priority")
+	ErrExpired          = errors.New("message expired")
+	ErrDropped          = errors.New("message dropped")
+	ErrNoKeyExtractor   = errors.New("shard queue has no key extractor")
+	ErrOptionType       = errors.New("shard queue option of another key or message type")
+)
+
+// EnqueueError is returned by ShardCtx and the other Ctx variants when the
+// message could not be queued in time.
+type EnqueueError struct {
+	Shard int
+	// Err is ErrEnqueueTimeout or the cause of the context.
+	Err error
+}
+
+func (e *EnqueueError) Error() string {
+	return fmt.Sprintf("enqueue to shard %d: %v", e.Shard, e.Err)
+}
+
+func (e *EnqueueError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,122 @@
+package shardqueue
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// flushGen counts the messages of a shard queued between two Flush calls and
+// not done with yet. It is sealed by the Flush call ending it, after which
+// its count only decreases.
+type flushGen struct {
+	pending atomic.Int64
+	sealed  atomic.Bool
+	prev    atomic.Pointer[flushGen] // nil once it and the older ones are done
+	flushed *flushSignal
+}
+
+// done accounts for a message done with, and wakes up the Flush calls once
+// a sealed generation is done.
+func (g *flushGen) done() {
+	if g.pending.Add(-1) == 0 && g.sealed.Load() {
+		g.flushed.broadcast()
+	}
+}
+
+// drained reports whether the messages of g and the older generations are
+// all done.
+func (g *flushGen) drained() bool {
+	for ; g != nil; g = g.prev.Load() {
+		if g.pending.Load() > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// flushSignal wakes up the Flush calls waiting for the generations.
+type flushSignal struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func (s *flushSignal) wait() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ch
+}
+
+func (s *flushSignal) broadcast() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.ch)
+	s.ch = make(chan struct{})
+}
+
+// flusher holds the current generation of a shard.
+type flusher struct {
+	mu  sync.RWMutex
+	gen *flushGen
+}
+
+func newFlushers(numShard int) ([]flusher, *flushSignal) {
+	signal := &flushSignal{ch: make(chan struct{})}
+	flushers := make([]flusher, numShard)
+	for i := range flushers {
+		flushers[i].gen = &flushGen{flushed: signal}
+	}
+	return flushers, signal
+}
+
+// track counts e in the current generation of the shard.
+func (sq *ShardQueue[K, V]) track(shard int, e *envelope[K, V]) {
+	f := &sq.flushers[shard]
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	e.gen = f.gen
+	e.gen.pending.Add(1)
+}
+
+// Flush waits until every message queued before the call was handled:
+// processed, dead-lettered, expired or dropped, without stopping the queue,
+// e.g. to commit the offsets of a batch of messages once it was processed.
+// It returns the error of ctx if it is done first, which it will be if the
+// workers were stopped by the context of Start. It returns ErrNotStarted
+// before Start.
+func (sq *ShardQueue[K, V]) Flush(ctx context.Context) error {
+	sq.mu.RLock()
+	state := sq.state
+	sq.mu.RUnlock()
+	if state == stateNew {
+		return ErrNotStarted
+	}
+
+	gens := make([]*flushGen, len(sq.flushers))
+	for i := range sq.flushers {
+		f := &sq.flushers[i]
+		f.mu.Lock()
+		gens[i] = f.gen
+		f.gen = &flushGen{flushed: sq.flushed}
+		f.gen.prev.Store(gens[i])
+		f.mu.Unlock()
+		gens[i].sealed.Store(true)
+	}
+
+	for {
+		wait := sq.flushed.wait()
+		if !slices.ContainsFunc(gens, func(g *flushGen) bool { return !g.drained() }) {
+			break
+		}
+		select {
+		case <-wait:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	for _, g := range gens {
+		g.prev.Store(nil)
+	}
+	return nil
+}
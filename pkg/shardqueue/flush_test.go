@@ -0,0 +1,136 @@
+package shardqueue
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShardQueue_Flush(t *testing.T) {
+	sq := NewShardQueue[string, int](1, 10, WithWorkersPerShard(2))
+	if err := sq.Flush(context.Background()); !errors.Is(err, ErrNotStarted) {
+		t.Errorf("Expected ErrNotStarted, got %v", err)
+	}
+
+	release := make(chan struct{})
+	var processed atomic.Int32
+	sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		if msg == 0 {
+			<-release
+		}
+		processed.Add(1)
+		return nil
+	})
+	defer sq.Stop()
+
+	// the message of a blocks its worker, the ones of b are processed meanwhile
+	sq.Shard("a", 0)
+	for i := 1; i <= 5; i++ {
+		sq.Shard("b", i)
+	}
+
+	flushed := make(chan error, 1)
+	go func() {
+		flushed <- sq.Flush(context.Background())
+	}()
+	select {
+	case err := <-flushed:
+		t.Fatalf("Expected Flush to wait for the message in flight, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case err := <-flushed:
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected Flush to return once the messages were processed")
+	}
+	if n := processed.Load(); n != 6 {
+		t.Errorf("Expected the 6 messages processed, got %d", n)
+	}
+
+	// nothing left to wait for
+	if err := sq.Flush(context.Background()); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestShardQueue_FlushContext(t *testing.T) {
+	sq, release, _ := fullQueue(t)
+	defer func() {
+		close(release)
+		sq.Stop()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := sq.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestShardQueue_FlushSpilled(t *testing.T) {
+	sq, release, processed := fullQueue(t, WithSpill(t.TempDir(), JSONCodec[string, int]{}))
+	for i := 2; i <= 5; i++ {
+		_ = sq.Shard("key", i)
+	}
+
+	flushed := make(chan error, 1)
+	go func() {
+		flushed <- sq.Flush(context.Background())
+	}()
+	close(release)
+	select {
+	case err := <-flushed:
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected Flush to return once the spilled messages were processed")
+	}
+	if got := processed(); !slices.Equal(got, []int{0, 1, 2, 3, 4, 5}) {
+		t.Errorf("Expected the spilled messages processed before Flush returned, got %v", got)
+	}
+	sq.Stop()
+}
+
+func TestShardQueue_FlushRejected(t *testing.T) {
+	sq, release, processed := fullQueue(t, WithOverflowPolicy(OverflowReject), WithOnDrop(func(any, int, OverflowPolicy) {}))
+	defer sq.Stop()
+
+	for msg := 2; msg <= 3; msg++ {
+		if err := sq.Shard("key", msg); !errors.Is(err, ErrQueueFull) {
+			t.Errorf("Expected ErrQueueFull, got %v", err)
+		}
+	}
+
+	flushed := make(chan error, 1)
+	go func() {
+		flushed <- sq.Flush(context.Background())
+	}()
+	select {
+	case err := <-flushed:
+		t.Errorf("Expected Flush to wait for the queued messages despite the rejected ones, got %v", err)
+		flushed <- nil
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case err := <-flushed:
+		if err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected Flush to return once the messages were processed")
+	}
+	if got := processed(); !slices.Equal(got, []int{0, 1}) {
+		t.Errorf("Expected the queued messages processed, got %v", got)
+	}
+}
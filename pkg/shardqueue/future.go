@@ -0,0 +1,69 @@
+package shardqueue
+
+import "context"
+
+// Future is the outcome of a message queued by ShardAsync.
+type Future struct {
+	done chan struct{}
+	err  error
+}
+
+func newFuture() *Future {
+	return &Future{done: make(chan struct{})}
+}
+
+// Done is closed once the message was processed or dropped.
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Err returns the error of the message once Done is closed, nil before: the
+// last error of the process function, ErrExpired, ErrDropped, or the error
+// of the context of Start if it interrupted the processing.
+func (f *Future) Err() error {
+	select {
+	case <-f.done:
+		return f.err
+	default:
+		return nil
+	}
+}
+
+// Wait waits for the message to be processed or dropped and returns its
+// error, or the error of ctx if it is done first. A message still queued
+// when the workers were stopped by the context of Start is never processed.
+func (f *Future) Wait(ctx context.Context) error {
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// resolve completes the future, if any, with err.
+func (f *Future) resolve(err error) {
+	if f == nil {
+		return
+	}
+	f.err = err
+	close(f.done)
+}
+
+// ShardAsync is like Shard but also returns a Future resolved once the
+// message was processed or dropped, e.g. to wait for the response to a
+// request processed by the queue.
+func (sq *ShardQueue[K, V]) ShardAsync(routingKey K, msg V) (*Future, error) {
+	return sq.ShardAsyncCtx(context.Background(), routingKey, msg)
+}
+
+// ShardAsyncCtx is like ShardAsync but the message is processed in the trace
+// of ctx, see WithTracerProvider, and it gives up waiting for room once ctx
+// is done, returning an *EnqueueError wrapping its cause.
+func (sq *ShardQueue[K, V]) ShardAsyncCtx(ctx context.Context, routingKey K, msg V) (*Future, error) {
+	f := newFuture()
+	if err := sq.shard(ctx, routingKey, msg, sq.ttl, PriorityNormal, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,94 @@
+package shardqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestShardQueue_ShardAsync(t *testing.T) {
+	sq := NewShardQueue[string, int](4, 10)
+	if _, err := sq.ShardAsync("key", 1); !errors.Is(err, ErrNotStarted) {
+		t.Errorf("Expected ErrNotStarted, got %v", err)
+	}
+
+	boom := errors.New("boom")
+	sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		if msg < 0 {
+			return boom
+		}
+		return nil
+	})
+	defer sq.Stop()
+
+	ok, err := sq.ShardAsync("a", 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	failed, _ := sq.ShardAsync("b", -1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := ok.Wait(ctx); err != nil {
+		t.Errorf("Expected the message processed, got %v", err)
+	}
+	if err := failed.Wait(ctx); !errors.Is(err, boom) {
+		t.Errorf("Expected the error of the process function, got %v", err)
+	}
+	select {
+	case <-failed.Done():
+	default:
+		t.Error("Expected Done to be closed")
+	}
+	if err := failed.Err(); !errors.Is(err, boom) {
+		t.Errorf("Expected Err to return the error, got %v", err)
+	}
+}
+
+func TestShardQueue_ShardAsyncDropped(t *testing.T) {
+	sq, release, _ := fullQueue(t, WithOverflowPolicy(OverflowDropNewest), WithOnDrop(func(msg any, shard int, reason OverflowPolicy) {}))
+
+	f, err := sq.ShardAsync("key", 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := f.Wait(ctx); !errors.Is(err, ErrDropped) {
+		t.Errorf("Expected ErrDropped, got %v", err)
+	}
+	close(release)
+	sq.Stop()
+}
+
+func TestShardQueue_ShardAsyncExpired(t *testing.T) {
+	sq, release, _ := fullQueue(t, WithTTL(time.Millisecond), WithSpill(t.TempDir(), JSONCodec[string, int]{}), WithOnExpired(func(shard int, msg any, waited time.Duration) {}))
+
+	f, err := sq.ShardAsync("key", 2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := f.Err(); err != nil {
+		t.Errorf("Expected no error before the message was handled, got %v", err)
+	}
+
+	// the future survives the spill of the message
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := f.Wait(ctx); !errors.Is(err, ErrExpired) {
+		t.Errorf("Expected ErrExpired, got %v", err)
+	}
+	sq.Stop()
+}
+
+func TestFuture_WaitContext(t *testing.T) {
+	f := newFuture()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := f.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package shardqueue
+
+import (
+	"fmt"
+	"hash/maphash"
+	"reflect"
+)
+
+// Hasher hashes a routing key to pick its shard.
+type Hasher[K comparable] func(key K) uint64
+
+// WithHasher hashes the routing keys with fn instead of the default hasher,
+// e.g. xxhash.Sum64String. The default hasher only maps string and integer
+// keys to the same shards across processes, set a stable fn for the other
+// key types if they must. The key type of fn must be the one of the queue,
+// New returns ErrOptionType otherwise.
+func WithHasher[K comparable](fn Hasher[K]) Option {
+	return func(o *options) {
+		if fn != nil {
+			o.hasher = fn
+		}
+	}
+}
+
+const (
+	fnvOffset32 = 2166136261
+	fnvPrime32  = 16777619
+)
+
+// FNV is a Hasher of string keys using the 32-bit FNV-1a hash, the default
+// one of string keys. It is stable across processes.
+func FNV(key string) uint64 {
+	h := uint32(fnvOffset32)
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= fnvPrime32
+	}
+	return uint64(h)
+}
+
+// fnvUint64 is FNV over the 8 big-endian bytes of n.
+func fnvUint64(n uint64) uint64 {
+	h := uint32(fnvOffset32)
+	for shift := 56; shift >= 0; shift -= 8 {
+		h ^= uint32(byte(n >> shift))
+		h *= fnvPrime32
+	}
+	return uint64(h)
+}
+
+type integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+func fnvInteger[T integer](key T) uint64 {
+	return fnvUint64(uint64(key))
+}
+
+// hasherOf returns the hasher set by WithHasher, or the default one.
+func hasherOf[K comparable](hasher any) (Hasher[K], error) {
+	switch fn := hasher.(type) {
+	case nil:
+		return defaultHasher[K](), nil
+	case Hasher[K]:
+		return fn, nil
+	default:
+		var key K
+		return nil, fmt.Errorf("%w: hasher %T doesn't hash %T keys", ErrOptionType, hasher, key)
+	}
+}
+
+// defaultHasher hashes string and integer keys with FNV over their bytes,
+// the integers widened to 64 bits, so a key maps to the same shard in every
+// process. Other keys are hashed with maphash and a random seed, so a key
+// maps to the same shard for the lifetime of the queue only.
+func defaultHasher[K comparable]() Hasher[K] {
+	var fn any
+	switch any(*new(K)).(type) {
+	case string:
+		fn = Hasher[string](FNV)
+	case int:
+		fn = Hasher[int](fnvInteger[int])
+	case int8:
+		fn = Hasher[int8](fnvInteger[int8])
+	case int16:
+		fn = Hasher[int16](fnvInteger[int16])
+	case int32:
+		fn = Hasher[int32](fnvInteger[int32])
+	case int64:
+		fn = Hasher[int64](fnvInteger[int64])
+	case uint:
+		fn = Hasher[uint](fnvInteger[uint])
+	case uint8:
+		fn = Hasher[uint8](fnvInteger[uint8])
+	case uint16:
+		fn = Hasher[uint16](fnvInteger[uint16])
+	case uint32:
+		fn = Hasher[uint32](fnvInteger[uint32])
+	case uint64:
+		fn = Hasher[uint64](fnvInteger[uint64])
+	case uintptr:
+		fn = Hasher[uintptr](fnvInteger[uintptr])
+	}
+	if fn != nil {
+		return fn.(Hasher[K])
+	}
+
+	// named types, e.g. type AccountID string
+	switch reflect.TypeFor[K]().Kind() {
+	case reflect.String:
+		return func(key K) uint64 {
+			return FNV(reflect.ValueOf(key).String())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return func(key K) uint64 {
+			return fnvUint64(uint64(reflect.ValueOf(key).Int()))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(key K) uint64 {
+			return fnvUint64(reflect.ValueOf(key).Uint())
+		}
+	}
+
+	seed := maphash.MakeSeed()
+	return func(key K) uint64 {
+		return maphash.Comparable(seed, key)
+	}
+}
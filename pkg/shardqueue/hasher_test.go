@@ -0,0 +1,104 @@
+package shardqueue
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWithHasher(t *testing.T) {
+	first := NewShardQueue[string, int](16, 1, WithHasher(FNV))
+	second := NewShardQueue[string, int](16, 1, WithHasher(FNV))
+	for _, key := range []string{"a", "b", "order-42", ""} {
+		if first.WhichShard(key) != second.WhichShard(key) {
+			t.Errorf("Expected key %q on the same shard of both queues", key)
+		}
+	}
+	// FNV-1a of "a" is 0xe40c292c
+	if shard := first.WhichShard("a"); shard != 0xe40c292c%16 {
+		t.Errorf("Expected the FNV-1a shard of \"a\", got %d", shard)
+	}
+
+	byID := NewShardQueue[int, int](4, 1, WithHasher(func(id int) uint64 { return uint64(id) }))
+	if shard := byID.WhichShard(6); shard != 2 {
+		t.Errorf("Expected the custom hasher to pick shard 2, got %d", shard)
+	}
+}
+
+func TestWithHasher_KeyTypeMismatch(t *testing.T) {
+	if _, err := New[int, int](4, 1, WithHasher(FNV)); !errors.Is(err, ErrOptionType) {
+		t.Errorf("Expected ErrOptionType for a hasher of another key type, got %v", err)
+	}
+	if _, err := New[string, int](4, 1, WithHasher(FNV)); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected NewShardQueue to panic")
+		}
+	}()
+	NewShardQueue[int, int](4, 1, WithHasher(FNV))
+}
+
+type accountID string
+
+type orderID int32
+
+func TestDefaultHasher_Stable(t *testing.T) {
+	// FNV-1a of the 8 big-endian bytes of 1
+	const one = 0x9ae16fd2
+
+	byString := NewShardQueue[string, int](16, 1)
+	if shard := byString.WhichShard("a"); shard != 0xe40c292c%16 {
+		t.Errorf("Expected the FNV-1a shard of \"a\", got %d", shard)
+	}
+	if shard := NewShardQueue[accountID, int](16, 1).WhichShard("a"); shard != 0xe40c292c%16 {
+		t.Errorf("Expected the FNV-1a shard of a named string, got %d", shard)
+	}
+	if shard := NewShardQueue[int, int](16, 1).WhichShard(1); shard != one%16 {
+		t.Errorf("Expected the FNV-1a shard of 1, got %d", shard)
+	}
+	if shard := NewShardQueue[uint8, int](16, 1).WhichShard(1); shard != one%16 {
+		t.Errorf("Expected integers hashed as 64 bits, got %d", shard)
+	}
+	if shard := NewShardQueue[orderID, int](16, 1).WhichShard(1); shard != one%16 {
+		t.Errorf("Expected the FNV-1a shard of a named integer, got %d", shard)
+	}
+
+	first := NewShardQueue[int64, int](16, 1)
+	second := NewShardQueue[int64, int](16, 1)
+	for _, key := range []int64{0, -1, 42, 1 << 40} {
+		if first.WhichShard(key) != second.WhichShard(key) {
+			t.Errorf("Expected key %d on the same shard of both queues", key)
+		}
+	}
+}
+
+func TestDefaultHasher_PinnedShards(t *testing.T) {
+	// FNV-1a modulo 64, computed independently; a change of these shards
+	// reroutes the keys of every running deployment
+	byString := NewShardQueue[string, int](64, 1)
+	byAccount := NewShardQueue[accountID, int](64, 1)
+	for key, want := range map[string]int{"order-42": 52, "acme": 47, "": 5} {
+		if shard := byString.WhichShard(key); shard != want {
+			t.Errorf("Expected %q on shard %d, got %d", key, want, shard)
+		}
+		if shard := byAccount.WhichShard(accountID(key)); shard != want {
+			t.Errorf("Expected the named string %q on shard %d, got %d", key, want, shard)
+		}
+	}
+
+	byInt := NewShardQueue[int, int](64, 1)
+	byOrder := NewShardQueue[orderID, int](64, 1)
+	for key, want := range map[int]int{0: 37, 42: 55, -1: 29} {
+		if shard := byInt.WhichShard(key); shard != want {
+			t.Errorf("Expected %d on shard %d, got %d", key, want, shard)
+		}
+		if shard := byOrder.WhichShard(orderID(key)); shard != want {
+			t.Errorf("Expected the named integer %d on shard %d, got %d", key, want, shard)
+		}
+	}
+	if shard := NewShardQueue[uint64, int](64, 1).WhichShard(1 << 40); shard != 44 {
+		t.Errorf("Expected 1<<40 on shard 44, got %d", shard)
+	}
+}
@@ -0,0 +1,48 @@
+package shardqueue
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithKeyExtractor derives the routing key of the messages queued by Enqueue
+// with fn, so producers don't pass it along with the message. The key and
+// message types of fn must be the ones of the queue, New returns
+// ErrOptionType otherwise.
+func WithKeyExtractor[K comparable, V any](fn func(msg V) K) Option {
+	return func(o *options) {
+		if fn != nil {
+			o.keyExtractor = fn
+		}
+	}
+}
+
+// keyExtractorOf returns the function set by WithKeyExtractor, if any.
+func keyExtractorOf[K comparable, V any](keyExtractor any) (func(msg V) K, error) {
+	switch fn := keyExtractor.(type) {
+	case nil:
+		return nil, nil
+	case func(msg V) K:
+		return fn, nil
+	default:
+		var key K
+		var msg V
+		return nil, fmt.Errorf("%w: key extractor %T doesn't map %T messages to %T keys", ErrOptionType, keyExtractor, msg, key)
+	}
+}
+
+// Enqueue is like Shard with the routing key derived from msg by the
+// function set by WithKeyExtractor. It returns ErrNoKeyExtractor without it.
+func (sq *ShardQueue[K, V]) Enqueue(msg V) error {
+	return sq.EnqueueCtx(context.Background(), msg)
+}
+
+// EnqueueCtx is like Enqueue but the message is processed in the trace of
+// ctx, see WithTracerProvider, and it gives up waiting for room once ctx is
+// done, returning an *EnqueueError wrapping its cause.
+func (sq *ShardQueue[K, V]) EnqueueCtx(ctx context.Context, msg V) error {
+	if sq.keyOf == nil {
+		return ErrNoKeyExtractor
+	}
+	return sq.shard(ctx, sq.keyOf(msg), msg, sq.ttl, PriorityNormal, nil)
+}
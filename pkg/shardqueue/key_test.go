@@ -0,0 +1,61 @@
+package shardqueue
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestWithKeyExtractor(t *testing.T) {
+	sq := NewShardQueue[string, payment](4, 10, WithKeyExtractor(func(p payment) string {
+		return p.Account
+	}))
+
+	var mu sync.Mutex
+	got := map[string][]int{}
+	sq.Start(context.Background(), func(ctx context.Context, p payment) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got[p.Account] = append(got[p.Account], p.Seq)
+		return nil
+	})
+
+	for seq := range 5 {
+		for _, account := range []string{"a", "b", "c"} {
+			if err := sq.Enqueue(payment{Account: account, Seq: seq}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+	}
+	sq.Stop()
+
+	for _, account := range []string{"a", "b", "c"} {
+		if !slices.Equal(got[account], []int{0, 1, 2, 3, 4}) {
+			t.Errorf("Expected the messages of %s in order, got %v", account, got[account])
+		}
+	}
+	if stats := sq.Stats(); stats[sq.WhichShard("a")].Enqueued == 0 {
+		t.Error("Expected the messages of a in the shard of its key")
+	}
+}
+
+func TestEnqueue_NoKeyExtractor(t *testing.T) {
+	sq := NewShardQueue[string, int](1, 1)
+	sq.Start(context.Background(), func(ctx context.Context, msg int) error { return nil })
+	defer sq.Stop()
+
+	if err := sq.Enqueue(1); !errors.Is(err, ErrNoKeyExtractor) {
+		t.Errorf("Expected ErrNoKeyExtractor, got %v", err)
+	}
+}
+
+func TestWithKeyExtractor_TypeMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected NewShardQueue to panic")
+		}
+	}()
+	NewShardQueue[string, int](1, 1, WithKeyExtractor(func(msg int) int { return msg }))
+}
@@ -0,0 +1,46 @@
+package shardqueue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MessageInfo describes the message a Middleware wraps the processing of.
+type MessageInfo[K comparable] struct {
+	Shard      int
+	Key        K
+	EnqueuedAt time.Time
+}
+
+// Middleware wraps the process function for a message, e.g. to log,
+// measure, trace or validate every message. It is applied once per message,
+// and the function it returns is called once per attempt, see WithRetry.
+type Middleware[K comparable, V any] func(info MessageInfo[K], next func(ctx context.Context, msg V) error) func(ctx context.Context, msg V) error
+
+type middlewares[K comparable, V any] struct {
+	mu  sync.RWMutex
+	mws []Middleware[K, V]
+}
+
+// Use adds middlewares wrapping the process function of Start for every
+// message processed from then on. The first middleware added is the
+// outermost. They don't wrap the batches of StartBatch.
+func (sq *ShardQueue[K, V]) Use(mw ...Middleware[K, V]) {
+	sq.middlewares.mu.Lock()
+	defer sq.middlewares.mu.Unlock()
+	for _, m := range mw {
+		if m != nil {
+			sq.middlewares.mws = append(sq.middlewares.mws, m)
+		}
+	}
+}
+
+func (m *middlewares[K, V]) wrap(info MessageInfo[K], fn processFunc[V]) processFunc[V] {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := len(m.mws) - 1; i >= 0; i-- {
+		fn = m.mws[i](info, fn)
+	}
+	return fn
+}
@@ -0,0 +1,106 @@
+package shardqueue
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardQueue_Use(t *testing.T) {
+	sq := NewShardQueue[string, int](4, 10)
+
+	var mu sync.Mutex
+	var calls []string
+	var infos []MessageInfo[string]
+	record := func(name string) Middleware[string, int] {
+		return func(info MessageInfo[string], next func(ctx context.Context, msg int) error) func(ctx context.Context, msg int) error {
+			return func(ctx context.Context, msg int) error {
+				mu.Lock()
+				calls = append(calls, name)
+				if name == "outer" {
+					infos = append(infos, info)
+				}
+				mu.Unlock()
+				return next(ctx, msg)
+			}
+		}
+	}
+	sq.Use(record("outer"), nil, record("inner"))
+
+	done := make(chan struct{})
+	sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		mu.Lock()
+		calls = append(calls, "process")
+		mu.Unlock()
+		close(done)
+		return nil
+	})
+	defer sq.Stop()
+
+	before := time.Now()
+	sq.Shard("key", 1)
+	select {
+	case <-done:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected the message to be processed")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(calls, []string{"outer", "inner", "process"}) {
+		t.Errorf("Expected the first middleware outermost, got %v", calls)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("Expected 1 message info, got %d", len(infos))
+	}
+	info := infos[0]
+	if info.Shard != sq.WhichShard("key") || info.Key != "key" || info.EnqueuedAt.Before(before) {
+		t.Errorf("Expected the shard, key and enqueue time of the message, got %+v", info)
+	}
+}
+
+func TestShardQueue_UseRetry(t *testing.T) {
+	var failures []error
+	sq := NewShardQueue[string, int](1, 10,
+		WithRetry(3, time.Millisecond),
+		WithErrorHandler(func(shard int, msg any, err error) {
+			failures = append(failures, err)
+		}),
+	)
+
+	wrapped, attempts, processed := 0, 0, 0
+	errInvalid := errors.New("invalid")
+	sq.Use(func(info MessageInfo[string], next func(ctx context.Context, msg int) error) func(ctx context.Context, msg int) error {
+		wrapped++
+		return func(ctx context.Context, msg int) error {
+			attempts++
+			if msg < 0 {
+				return Permanent(errInvalid)
+			}
+			return next(ctx, msg)
+		}
+	})
+	sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		if processed++; processed < 3 {
+			return errors.New("unavailable")
+		}
+		return nil
+	})
+
+	sq.Shard("key", 1)
+	sq.Shard("key", -1)
+	sq.Stop()
+
+	if wrapped != 2 || attempts != 4 {
+		t.Errorf("Expected the middleware applied once per message and called per attempt, got %d and %d", wrapped, attempts)
+	}
+	if processed != 3 {
+		t.Errorf("Expected the invalid message not processed, got %d calls", processed)
+	}
+	if len(failures) != 1 || !errors.Is(failures[0], errInvalid) {
+		t.Errorf("Expected the invalid message reported, got %v", failures)
+	}
+}
@@ -0,0 +1,74 @@
+package shardqueue
+
+import (
+	"log"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Option configures a ShardQueue, see NewShardQueue.
+type Option func(*options)
+
+type options struct {
+	errorHandler func(shard int, msg any, err error)
+	deadLetter   DeadLetterFunc
+	maxAttempts  int
+	backoff      time.Duration
+
+	workersPerShard int
+	hasher          any // Hasher of the key type of the queue
+	virtualNodes    int
+	priorities      bool
+	shardRate       float64
+	shardBurst      int
+	limiter         Limiter
+	ttl             time.Duration
+	onExpired       func(shard int, msg any, waited time.Duration)
+	overflow        OverflowPolicy
+	onDrop          func(msg any, shard int, reason OverflowPolicy)
+	ringBuffer      bool
+	spillDir        string
+	codec           any // Codec of the key and message types of the queue
+	walDir          string
+	onHandled       func(msg any)
+	tracer          trace.Tracer
+	results         ResultFunc
+	keyExtractor    any // func(msg V) K of the queue, see WithKeyExtractor
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		errorHandler: logError,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithErrorHandler calls fn with the shard and the message for which the
+// process function failed, after its last attempt, e.g. to count the
+// failures, instead of logging the error.
+func WithErrorHandler(fn func(shard int, msg any, err error)) Option {
+	return func(o *options) {
+		if fn != nil {
+			o.errorHandler = fn
+		}
+	}
+}
+
+// WithOnHandled calls fn with every message the queue is done with:
+// processed, failed after its last attempt, expired or dropped by the
+// overflow policy, e.g. to commit its offset upstream. The messages whose
+// processing was interrupted by the cancellation of the context of Start are
+// not reported.
+func WithOnHandled(fn func(msg any)) Option {
+	return func(o *options) {
+		o.onHandled = fn
+	}
+}
+
+func logError(shard int, _ any, err error) {
+	log.Printf("Shard %d process error: %v", shard, err)
+}
@@ -0,0 +1,111 @@
+package shardqueue
+
+import (
+	"context"
+	"log"
+)
+
+// OverflowPolicy is what Shard does when the shard of a message is full, see
+// WithOverflowPolicy.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for room in the shard.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the new message.
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest message of the shard to make room
+	// for the new one.
+	OverflowDropOldest
+	// OverflowReject returns ErrQueueFull, like TryShard.
+	OverflowReject
+)
+
+var overflowNames = [...]string{
+	OverflowBlock:      "block",
+	OverflowDropNewest: "drop_newest",
+	OverflowDropOldest: "drop_oldest",
+	OverflowReject:     "reject",
+}
+
+func (p OverflowPolicy) String() string {
+	if p < 0 || int(p) >= len(overflowNames) {
+		return "unknown"
+	}
+	return overflowNames[p]
+}
+
+// WithOverflowPolicy sets what Shard, ShardWithPriority and ShardWithTTL, and
+// their Ctx variants, do when the shard of a message is full, OverflowBlock
+// by default. The dropped and rejected messages are counted in Stats and
+// reported to WithOnDrop. TryShard and ShardCtx are not affected.
+func WithOverflowPolicy(policy OverflowPolicy) Option {
+	return func(o *options) {
+		o.overflow = policy
+	}
+}
+
+// WithOnDrop calls fn with the messages dropped or rejected because their
+// shard was full, and the policy that dropped them, instead of logging them.
+func WithOnDrop(fn func(msg any, shard int, reason OverflowPolicy)) Option {
+	return func(o *options) {
+		o.onDrop = fn
+	}
+}
+
+// send queues the message in the inbox of the shard according to the
+// overflow policy, blocking until ctx is done at most.
+func (sq *ShardQueue[K, V]) send(ctx context.Context, shard int, inbox buffer[envelope[K, V]], e envelope[K, V]) error {
+	if sq.spills != nil && sq.trySpill(shard, e) {
+		return nil
+	}
+	for {
+		select {
+		case <-sq.stopping:
+			return ErrStopped
+		default:
+		}
+		if inbox.tryPush(e) {
+			sq.metrics[shard].enqueued.Add(1)
+			return nil
+		}
+
+		switch sq.overflow {
+		case OverflowDropNewest:
+			sq.drop(shard, e, OverflowDropNewest)
+			return nil
+		case OverflowReject:
+			sq.drop(shard, e, OverflowReject)
+			return ErrQueueFull
+		case OverflowDropOldest:
+			// the worker may take the oldest message first, then try again
+			if old, ok := inbox.tryPop(); ok {
+				sq.drop(shard, old, OverflowDropOldest)
+			}
+		default:
+			if !inbox.push(e, sq.stopping, ctx.Done()) {
+				if ctx.Err() != nil {
+					return &EnqueueError{Shard: shard, Err: context.Cause(ctx)}
+				}
+				return ErrStopped
+			}
+			sq.metrics[shard].enqueued.Add(1)
+			return nil
+		}
+	}
+}
+
+func (sq *ShardQueue[K, V]) drop(shard int, e envelope[K, V], reason OverflowPolicy) {
+	// a rejected message is acked by the caller, which gets ErrQueueFull,
+	// see acked
+	if reason != OverflowReject {
+		sq.handled(e)
+	}
+	e.future.resolve(ErrDropped)
+	sq.metrics[shard].dropped.Add(1)
+	if sq.onDrop != nil {
+		sq.onDrop(e.msg, shard, reason)
+		return
+	}
+	log.Printf("Shard %d full, message dropped (%s)", shard, reason)
+}
@@ -0,0 +1,77 @@
+package shardqueue
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// fullQueue returns a started queue of one shard holding one message, whose
+// worker is blocked until release is closed, and the processed messages.
+func fullQueue(t *testing.T, opts ...Option) (sq *ShardQueue[string, int], release chan struct{}, processed func() []int) {
+	t.Helper()
+	sq = NewShardQueue[string, int](1, 1, opts...)
+	started, release := make(chan struct{}), make(chan struct{})
+	var mu sync.Mutex
+	var got []int
+	_ = sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		if msg == 0 {
+			close(started)
+			<-release
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, msg)
+		return nil
+	})
+	_ = sq.Shard("key", 0)
+	<-started
+	_ = sq.Shard("key", 1)
+	return sq, release, func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(got)
+	}
+}
+
+func TestWithOverflowPolicy(t *testing.T) {
+	tests := []struct {
+		policy    OverflowPolicy
+		err       error
+		processed []int
+		dropped   any
+	}{
+		{OverflowDropNewest, nil, []int{0, 1}, 2},
+		{OverflowDropOldest, nil, []int{0, 2}, 1},
+		{OverflowReject, ErrQueueFull, []int{0, 1}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy.String(), func(t *testing.T) {
+			var dropped []any
+			sq, release, processed := fullQueue(t, WithOverflowPolicy(tt.policy), WithOnDrop(func(msg any, shard int, reason OverflowPolicy) {
+				if reason != tt.policy {
+					t.Errorf("Expected the drop reason %s, got %s", tt.policy, reason)
+				}
+				dropped = append(dropped, msg)
+			}))
+
+			if err := sq.Shard("key", 2); !errors.Is(err, tt.err) {
+				t.Errorf("Expected the error %v, got %v", tt.err, err)
+			}
+			close(release)
+			sq.Stop()
+
+			if got := processed(); !slices.Equal(got, tt.processed) {
+				t.Errorf("Expected the messages %v to be processed, got %v", tt.processed, got)
+			}
+			if !slices.Equal(dropped, []any{tt.dropped}) {
+				t.Errorf("Expected message %v to be dropped, got %v", tt.dropped, dropped)
+			}
+			if n := sq.Stats()[0].Dropped; n != 1 {
+				t.Errorf("Expected 1 dropped message, got %d", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package shardqueue
+
+import (
+	"context"
+)
+
+// Priority orders the messages of a shard, see ShardWithPriority.
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+
+	numPriorities = 3
+)
+
+// WithPriorities lets ShardWithPriority queue messages with a priority. Each
+// shard then holds up to queueSize messages per priority, and its worker
+// takes the higher priority messages first. It costs an extra hand-off per
+// message.
+func WithPriorities() Option {
+	return func(o *options) {
+		o.priorities = true
+	}
+}
+
+// ShardWithPriority is like Shard but queues msg with the given priority.
+// The messages of a key with the same priority are processed in order. It
+// returns ErrNoPriorities unless the queue was created WithPriorities.
+func (sq *ShardQueue[K, V]) ShardWithPriority(routingKey K, msg V, priority Priority) error {
+	return sq.ShardWithPriorityCtx(context.Background(), routingKey, msg, priority)
+}
+
+// ShardWithPriorityCtx is like ShardWithPriority but the message is
+// processed in the trace of ctx, see WithTracerProvider, and it gives up
+// waiting for room once ctx is done, returning an *EnqueueError wrapping its
+// cause.
+func (sq *ShardQueue[K, V]) ShardWithPriorityCtx(ctx context.Context, routingKey K, msg V, priority Priority) error {
+	if !sq.priorities {
+		return ErrNoPriorities
+	}
+	if priority < PriorityLow || priority > PriorityHigh {
+		return ErrInvalidPriority
+	}
+	return sq.shard(ctx, routingKey, msg, sq.ttl, priority, nil)
+}
+
+// inbox returns the buffer the messages of the shard with the priority are
+// sent to.
+func (sq *ShardQueue[K, V]) inbox(shard int, priority Priority) buffer[envelope[K, V]] {
+	if sq.priorities {
+		return chanBuffer[envelope[K, V]](sq.levels[shard][priority])
+	}
+	return sq.queue[shard]
+}
+
+func (sq *ShardQueue[K, V]) closeInboxes() {
+	for i := 0; i < sq.numShard; i++ {
+		if !sq.priorities {
+			sq.queue[i].close()
+			continue
+		}
+		for _, level := range sq.levels[i] {
+			close(level)
+		}
+	}
+}
+
+// depth returns the number of messages waiting in the shard.
+func (sq *ShardQueue[K, V]) depth(shard int) int {
+	if sq.queue[shard] == nil {
+		return 0
+	}
+	n := sq.queue[shard].len()
+	if sq.levels != nil {
+		for _, level := range sq.levels[shard] {
+			n += len(level)
+		}
+	}
+	return n
+}
+
+// forward hands the messages of the priority levels of a shard to its
+// worker, highest priority first, and closes out once they are all drained.
+func (sq *ShardQueue[K, V]) forward(ctx context.Context, levels [numPriorities]chan envelope[K, V], out chanBuffer[envelope[K, V]]) {
+	low, normal, high := levels[PriorityLow], levels[PriorityNormal], levels[PriorityHigh]
+	for low != nil || normal != nil || high != nil {
+		var e envelope[K, V]
+		var ok bool
+		var from *chan envelope[K, V]
+
+		// take the highest priority message waiting, if any
+		for _, level := range []*chan envelope[K, V]{&high, &normal, &low} {
+			if *level == nil {
+				continue
+			}
+			select {
+			case e, ok = <-*level:
+				from = level
+			default:
+			}
+			if from != nil {
+				break
+			}
+		}
+		if from == nil {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok = <-high:
+				from = &high
+			case e, ok = <-normal:
+				from = &normal
+			case e, ok = <-low:
+				from = &low
+			}
+		}
+		if !ok {
+			*from = nil
+			continue
+		}
+
+		select {
+		case out <- e:
+		case <-ctx.Done():
+			return
+		}
+	}
+	close(out)
+}
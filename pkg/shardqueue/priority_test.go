@@ -0,0 +1,57 @@
+package shardqueue
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestShardWithPriority(t *testing.T) {
+	sq := NewShardQueue[string, string](1, 10, WithPriorities())
+	started, release := make(chan struct{}), make(chan struct{})
+	var got []string
+	_ = sq.Start(context.Background(), func(ctx context.Context, msg string) error {
+		if msg == "first" {
+			close(started)
+			<-release
+		}
+		got = append(got, msg)
+		return nil
+	})
+
+	_ = sq.Shard("key", "first")
+	<-started
+	for _, msg := range []string{"low0", "low1", "low2"} {
+		_ = sq.ShardWithPriority("key", msg, PriorityLow)
+	}
+	// the first low message is already handed to the busy worker
+	for sq.Stats()[0].Depth != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	_ = sq.ShardWithPriority("key", "high0", PriorityHigh)
+	_ = sq.Shard("key", "normal0")
+	_ = sq.ShardWithPriority("key", "high1", PriorityHigh)
+	close(release)
+	sq.Stop()
+
+	want := []string{"first", "low0", "high0", "high1", "normal0", "low1", "low2"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected the messages in the order %v, got %v", want, got)
+	}
+}
+
+func TestShardWithPriority_Errors(t *testing.T) {
+	sq := NewShardQueue[string, int](1, 1)
+	if err := sq.ShardWithPriority("key", 1, PriorityHigh); !errors.Is(err, ErrNoPriorities) {
+		t.Errorf("Expected ErrNoPriorities, got %v", err)
+	}
+	sq = NewShardQueue[string, int](1, 1, WithPriorities())
+	if err := sq.ShardWithPriority("key", 1, Priority(7)); !errors.Is(err, ErrInvalidPriority) {
+		t.Errorf("Expected ErrInvalidPriority, got %v", err)
+	}
+	if depth := sq.Stats()[0].Depth; depth != 0 {
+		t.Errorf("Expected an empty shard before Start, got %d", depth)
+	}
+}
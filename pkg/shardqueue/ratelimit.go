@@ -0,0 +1,123 @@
+package shardqueue
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limiter throttles the calls to the process function, see WithLimiter.
+// TokenBucket implements it, and so does golang.org/x/time/rate.Limiter.
+type Limiter interface {
+	// Wait blocks until a call is allowed or ctx is done.
+	Wait(ctx context.Context) error
+}
+
+// WithRateLimit allows each shard up to rps calls to the process function
+// per second, with bursts of up to burst calls. Retries and batches count as
+// one call each. SetRateLimit changes the limit at runtime.
+func WithRateLimit(rps float64, burst int) Option {
+	return func(o *options) {
+		o.shardRate = rps
+		o.shardBurst = burst
+	}
+}
+
+// WithLimiter makes every call to the process function, of any shard, wait
+// for l too, e.g. a TokenBucket shared with other queues to protect a
+// downstream service.
+func WithLimiter(l Limiter) Option {
+	return func(o *options) {
+		o.limiter = l
+	}
+}
+
+// SetRateLimit changes the per-shard rate limit, see WithRateLimit. A
+// non-positive rps removes it.
+func (sq *ShardQueue[K, V]) SetRateLimit(rps float64, burst int) {
+	for _, l := range sq.limiters {
+		l.SetLimit(rps, burst)
+	}
+}
+
+// throttled returns call waiting for the limiters of the shard first.
+func (sq *ShardQueue[K, V]) throttled(ctx context.Context, shard int, call func() error) func() error {
+	return func() error {
+		if err := sq.limiters[shard].Wait(ctx); err != nil {
+			return err
+		}
+		if sq.limiter != nil {
+			if err := sq.limiter.Wait(ctx); err != nil {
+				return err
+			}
+		}
+		return call()
+	}
+}
+
+// TokenBucket is a Limiter allowing rate calls per second on average, with
+// bursts of up to burst calls. A non-positive rate doesn't limit the calls.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+var _ Limiter = (*TokenBucket)(nil)
+
+// NewTokenBucket returns a full TokenBucket. A burst below 1 is raised to 1.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	b := &TokenBucket{}
+	b.SetLimit(rate, burst)
+	b.tokens = b.burst
+	return b
+}
+
+// SetLimit changes the rate and burst of the bucket, also for the calls
+// already waiting.
+func (b *TokenBucket) SetLimit(rate float64, burst int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refill(time.Now())
+	b.rate = rate
+	b.burst = float64(max(burst, 1))
+	b.tokens = math.Min(b.tokens, b.burst)
+}
+
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		if b.rate <= 0 {
+			b.mu.Unlock()
+			return ctx.Err()
+		}
+		now := time.Now()
+		b.refill(now)
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		// wait at most a second at once to pick up a changed rate
+		timer := time.NewTimer(min(wait, time.Second))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (b *TokenBucket) refill(now time.Time) {
+	if !b.last.IsZero() && b.rate > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+}
@@ -0,0 +1,87 @@
+package shardqueue
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	b := NewTokenBucket(100, 2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for range 6 {
+		if err := b.Wait(ctx); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	// a burst of 2, then 4 calls 10ms apart
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("Expected the calls to be throttled, took %v", elapsed)
+	}
+
+	b.SetLimit(0, 0)
+	start = time.Now()
+	for range 100 {
+		_ = b.Wait(ctx)
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Errorf("Expected no limit once the rate is removed, took %v", elapsed)
+	}
+
+	b = NewTokenBucket(0.001, 1)
+	if err := b.Wait(ctx); err != nil {
+		t.Fatalf("Expected the burst to allow a call, got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := b.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the context error while waiting, got %v", err)
+	}
+}
+
+type countingLimiter struct {
+	waits atomic.Int32
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.waits.Add(1)
+	return nil
+}
+
+func TestWithRateLimit(t *testing.T) {
+	global := &countingLimiter{}
+	sq := NewShardQueue[int, int](2, 10, WithRateLimit(50, 1), WithLimiter(global))
+	_ = sq.Start(context.Background(), func(ctx context.Context, msg int) error { return nil })
+
+	start := time.Now()
+	for range 4 {
+		_ = sq.Shard(0, 0)
+	}
+	sq.Stop()
+	// the first call uses the burst, the next three wait 20ms each
+	if elapsed := time.Since(start); elapsed < 55*time.Millisecond {
+		t.Errorf("Expected the shard to be throttled, took %v", elapsed)
+	}
+	if n := global.waits.Load(); n != 4 {
+		t.Errorf("Expected every call to wait for the global limiter, got %d", n)
+	}
+}
+
+func TestShardQueue_SetRateLimit(t *testing.T) {
+	sq := NewShardQueue[int, int](1, 10, WithRateLimit(1, 1))
+	sq.SetRateLimit(0, 0)
+	_ = sq.Start(context.Background(), func(ctx context.Context, msg int) error { return nil })
+
+	start := time.Now()
+	for range 5 {
+		_ = sq.Shard(0, 0)
+	}
+	sq.Stop()
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the removed limit not to throttle, took %v", elapsed)
+	}
+}
@@ -0,0 +1,46 @@
+package shardqueue
+
+import "time"
+
+// Result is the outcome of the processing of a message, see WithResults.
+type Result struct {
+	Shard int
+	Key   any
+	Msg   any
+	// Err is the error of the last attempt, nil if the message was
+	// processed.
+	Err error
+	// Duration is the time spent processing the message, retries included.
+	// The messages of a batch share the duration of the batch.
+	Duration time.Duration
+}
+
+// ResultFunc receives the outcome of every message processed. It is called
+// from the worker of the shard, which waits for it to return.
+type ResultFunc func(Result)
+
+// WithResults hands fn the outcome of every message the process function was
+// called for, e.g. to count the successes or record the outcome per key,
+// without wrapping the process function. Expired and dropped messages are
+// not processed, see WithOnExpired and WithOnDrop.
+func WithResults(fn ResultFunc) Option {
+	return func(o *options) {
+		o.results = fn
+	}
+}
+
+// ResultChan returns a ResultFunc sending the results to ch. The worker of
+// the shard blocks while ch is full.
+func ResultChan(ch chan<- Result) ResultFunc {
+	return func(r Result) {
+		ch <- r
+	}
+}
+
+// result reports the outcome of a message to WithResults, if set.
+func (sq *ShardQueue[K, V]) result(shard int, e envelope[K, V], err error, d time.Duration) {
+	if sq.results == nil {
+		return
+	}
+	sq.results(Result{Shard: shard, Key: e.key, Msg: e.msg, Err: err, Duration: d})
+}
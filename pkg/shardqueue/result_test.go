@@ -0,0 +1,64 @@
+package shardqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithResults(t *testing.T) {
+	results := make(chan Result, 2)
+	sq := NewShardQueue[string, int](4, 10,
+		WithErrorHandler(func(int, any, error) {}),
+		WithResults(ResultChan(results)),
+	)
+	boom := errors.New("boom")
+	sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		time.Sleep(time.Millisecond)
+		if msg < 0 {
+			return boom
+		}
+		return nil
+	})
+	defer sq.Stop()
+
+	sq.Shard("a", 1)
+	sq.Shard("a", -1)
+	for _, want := range []struct {
+		msg int
+		err error
+	}{{1, nil}, {-1, boom}} {
+		select {
+		case r := <-results:
+			if r.Key != "a" || r.Msg != want.msg || !errors.Is(r.Err, want.err) {
+				t.Errorf("Expected the result of %d, got %+v", want.msg, r)
+			}
+			if r.Shard != sq.WhichShard("a") || r.Duration < time.Millisecond {
+				t.Errorf("Expected the shard and the processing time, got %+v", r)
+			}
+		case <-time.After(500 * time.Millisecond):
+			t.Fatalf("Expected the result of %d", want.msg)
+		}
+	}
+}
+
+func TestWithResults_Batch(t *testing.T) {
+	results := make(chan Result, 2)
+	sq := NewShardQueue[string, int](1, 10, WithResults(ResultChan(results)))
+	sq.StartBatch(context.Background(), 2, time.Second, func(ctx context.Context, msgs []int) error {
+		return nil
+	})
+	sq.Shard("a", 1)
+	sq.Shard("b", 2)
+	sq.Stop()
+
+	if len(results) != 2 {
+		t.Fatalf("Expected a result per message of the batch, got %d", len(results))
+	}
+	for _, want := range []int{1, 2} {
+		if r := <-results; r.Msg != want || r.Err != nil {
+			t.Errorf("Expected the result of %d, got %+v", want, r)
+		}
+	}
+}
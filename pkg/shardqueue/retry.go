@@ -0,0 +1,125 @@
+package shardqueue
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// maxRetryBackoff caps the exponential growth of the retry delay.
+const maxRetryBackoff = time.Minute
+
+// WithRetry calls the process function up to maxAttempts times in total
+// while it fails for a message. The delay between attempts starts at backoff
+// and doubles after every attempt. Permanent errors and cancellations are
+// not retried. The shard processes no other message meanwhile, which keeps
+// the messages of a key in order.
+func WithRetry(maxAttempts int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.maxAttempts = maxAttempts
+		o.backoff = backoff
+	}
+}
+
+// PermanentError marks a failure that must not be retried, see Permanent.
+type PermanentError struct {
+	Err error
+}
+
+// Permanent wraps err so that WithRetry doesn't retry it and the message is
+// dead-lettered at once. Permanent(nil) is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// IsPermanent reports whether err was wrapped by Permanent.
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// process calls fn with the message until it succeeds or the attempts are
+// exhausted, then reports a failure unless the queue was canceled meanwhile.
+func (sq *ShardQueue[K, V]) process(ctx context.Context, shard int, e envelope[K, V], fn processFunc[V]) {
+	ctx, span := sq.startSpan(ctx, shard, e)
+	if sq.expired(shard, e) {
+		endSpan(span, outcomeExpired, nil, 0)
+		sq.handled(e)
+		e.future.resolve(ErrExpired)
+		return
+	}
+	fn = sq.middlewares.wrap(MessageInfo[K]{Shard: shard, Key: e.key, EnqueuedAt: e.enqueuedAt}, fn)
+	start := time.Now()
+	err, attempts := sq.attempt(ctx, shard, func() error { return fn(ctx, e.msg) })
+	duration := time.Since(start)
+	sq.metrics[shard].observe(duration, err)
+	endSpan(span, outcomeOf(ctx, err), err, attempts)
+	sq.result(shard, e, err, duration)
+	if err != nil && ctx.Err() != nil {
+		// an interrupted message didn't fail, the next run replays it, see
+		// WithWAL
+		e.future.resolve(err)
+		return
+	}
+	if err != nil {
+		sq.fail(shard, e, err, attempts)
+	}
+	sq.handled(e)
+	e.future.resolve(err)
+}
+
+// attempt calls call until it succeeds or the attempts are exhausted, and
+// returns the last error and the number of calls. Every call waits for the
+// rate limiters of the shard.
+func (sq *ShardQueue[K, V]) attempt(ctx context.Context, shard int, call func() error) (error, int) {
+	call = sq.throttled(ctx, shard, call)
+	err := call()
+	attempts := 1
+	delay := sq.backoff
+	for ; attempts < sq.maxAttempts && shouldRetry(err) && ctx.Err() == nil; attempts++ {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+		timer.Stop()
+		if ctx.Err() != nil {
+			break
+		}
+		delay = min(2*delay, maxRetryBackoff)
+		err = call()
+	}
+	return err, attempts
+}
+
+// fail reports a message the process function failed for to the error
+// handler and the dead letter function.
+func (sq *ShardQueue[K, V]) fail(shard int, e envelope[K, V], err error, attempts int) {
+	sq.errorHandler(shard, e.msg, err)
+	if sq.deadLetter != nil {
+		sq.deadLetter(DeadLetter{
+			Shard:      shard,
+			Key:        e.key,
+			Msg:        e.msg,
+			Err:        err,
+			Attempts:   attempts,
+			EnqueuedAt: e.enqueuedAt,
+			FailedAt:   time.Now(),
+		})
+	}
+}
+
+func shouldRetry(err error) bool {
+	return err != nil && !IsPermanent(err) && !errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,77 @@
+package shardqueue
+
+import (
+	"hash/fnv"
+	"slices"
+	"strconv"
+)
+
+// WithConsistentHashing places the shards on a consistent hash ring with
+// virtualNodes points each instead of taking the key hash modulo the number
+// of shards. When the number of shards changes, only about 1/n of the keys
+// move to another shard. More virtual nodes spread the keys more evenly, 100
+// to 200 is a good start.
+func WithConsistentHashing(virtualNodes int) Option {
+	return func(o *options) {
+		o.virtualNodes = virtualNodes
+	}
+}
+
+// ring is a consistent hash ring. The points depend only on the shard and
+// virtual node indexes, so rings of different sizes agree on the shared
+// shards.
+type ring struct {
+	points []uint64 // sorted
+	shards []int    // shard of the point at the same index
+}
+
+func newRing(numShard, virtualNodes int) *ring {
+	type point struct {
+		hash  uint64
+		shard int
+	}
+	points := make([]point, 0, numShard*virtualNodes)
+	for shard := range numShard {
+		for node := range virtualNodes {
+			h := fnv.New64a()
+			h.Write([]byte(strconv.Itoa(shard) + "-" + strconv.Itoa(node)))
+			points = append(points, point{hash: mix(h.Sum64()), shard: shard})
+		}
+	}
+	slices.SortFunc(points, func(a, b point) int {
+		if a.hash < b.hash {
+			return -1
+		}
+		if a.hash > b.hash {
+			return 1
+		}
+		return a.shard - b.shard
+	})
+
+	r := &ring{points: make([]uint64, len(points)), shards: make([]int, len(points))}
+	for i, p := range points {
+		r.points[i], r.shards[i] = p.hash, p.shard
+	}
+	return r
+}
+
+// shard returns the shard of the first point at or after the key hash,
+// wrapping around the ring.
+func (r *ring) shard(hash uint64) int {
+	i, _ := slices.BinarySearch(r.points, mix(hash))
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.shards[i]
+}
+
+// mix spreads the bits of h over the ring, so that narrower hashes like
+// 32-bit FNV still cover all of it. It is the splitmix64 finalizer.
+func mix(h uint64) uint64 {
+	h ^= h >> 30
+	h *= 0xbf58476d1ce4e5b9
+	h ^= h >> 27
+	h *= 0x94d049bb133111eb
+	h ^= h >> 31
+	return h
+}
@@ -0,0 +1,35 @@
+package shardqueue
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestWithConsistentHashing(t *testing.T) {
+	eight := NewShardQueue[string, int](8, 1, WithHasher(FNV), WithConsistentHashing(160))
+	nine := NewShardQueue[string, int](9, 1, WithHasher(FNV), WithConsistentHashing(160))
+
+	const keys = 10000
+	moved := 0
+	perShard := make([]int, 8)
+	for i := range keys {
+		key := "key-" + strconv.Itoa(i)
+		shard := eight.WhichShard(key)
+		perShard[shard]++
+		if again := nine.WhichShard(key); again != shard {
+			moved++
+			if again != 8 {
+				t.Fatalf("Expected key %s to move to the new shard only, got %d instead of %d", key, again, shard)
+			}
+		}
+	}
+	// about 1/9 of the keys move to the new shard
+	if moved < keys/18 || moved > keys/6 {
+		t.Errorf("Expected about %d moved keys, got %d", keys/9, moved)
+	}
+	for shard, n := range perShard {
+		if n < keys/8/2 || n > keys/8*2 {
+			t.Errorf("Expected about %d keys per shard, shard %d has %d", keys/8, shard, n)
+		}
+	}
+}
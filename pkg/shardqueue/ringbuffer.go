@@ -0,0 +1,201 @@
+package shardqueue
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WithRingBuffer backs the shards with bounded lock-free ring buffers instead
+// of channels, which cuts the contention between the producers of a busy
+// shard. The capacity of a shard is queueSize rounded up to a power of two.
+// The priority levels of WithPriorities are still channels.
+func WithRingBuffer() Option {
+	return func(o *options) {
+		o.ringBuffer = true
+	}
+}
+
+const cacheLine = 64
+
+// ringBuffer is a bounded multi-producer multi-consumer queue where every
+// slot carries a sequence number telling whether it is free for the push or
+// the pop of a given position. Only pushes and pops that find the buffer
+// full or empty wait, on the space and ready channels.
+type ringBuffer[E any] struct {
+	_   [cacheLine]byte
+	enq atomic.Uint64 // next position to push to
+	_   [cacheLine - 8]byte
+	deq atomic.Uint64 // next position to pop from
+	_   [cacheLine - 8]byte
+
+	mask  uint64
+	slots []ringSlot[E]
+
+	pushWaiters atomic.Int32
+	popWaiters  atomic.Int32
+	space       chan struct{} // signaled by pop when pushes wait
+	ready       chan struct{} // signaled by push when pops wait
+	closed      atomic.Bool
+	done        chan struct{} // closed by close
+}
+
+type ringSlot[E any] struct {
+	seq atomic.Uint64
+	val E
+}
+
+func newRingBuffer[E any](size int) *ringBuffer[E] {
+	n := 2
+	for n < size {
+		n <<= 1
+	}
+	r := &ringBuffer[E]{
+		mask:  uint64(n - 1),
+		slots: make([]ringSlot[E], n),
+		space: make(chan struct{}, 1),
+		ready: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+	for i := range r.slots {
+		r.slots[i].seq.Store(uint64(i))
+	}
+	return r
+}
+
+func (r *ringBuffer[E]) tryPush(e E) bool {
+	pos := r.enq.Load()
+	for {
+		slot := &r.slots[pos&r.mask]
+		switch seq := slot.seq.Load(); {
+		case seq == pos:
+			if !r.enq.CompareAndSwap(pos, pos+1) {
+				pos = r.enq.Load()
+				continue
+			}
+			slot.val = e
+			slot.seq.Store(pos + 1)
+			if r.popWaiters.Load() > 0 {
+				signal(r.ready)
+			}
+			return true
+		case seq < pos:
+			// the slot still holds the element of the previous lap
+			return false
+		default:
+			pos = r.enq.Load()
+		}
+	}
+}
+
+func (r *ringBuffer[E]) push(e E, stop, done <-chan struct{}) bool {
+	for {
+		if r.tryPush(e) {
+			return true
+		}
+		// announce the wait before checking again so that a pop either
+		// sees it or frees the slot checked
+		r.pushWaiters.Add(1)
+		if r.tryPush(e) {
+			r.pushWaiters.Add(-1)
+			return true
+		}
+		select {
+		case <-r.space:
+			r.pushWaiters.Add(-1)
+			if r.tryPush(e) {
+				// pass the wake-up on, more slots may be free
+				if r.pushWaiters.Load() > 0 {
+					signal(r.space)
+				}
+				return true
+			}
+		case <-stop:
+			r.pushWaiters.Add(-1)
+			return false
+		case <-done:
+			r.pushWaiters.Add(-1)
+			return false
+		}
+	}
+}
+
+func (r *ringBuffer[E]) tryPop() (E, bool) {
+	pos := r.deq.Load()
+	for {
+		slot := &r.slots[pos&r.mask]
+		switch seq := slot.seq.Load(); {
+		case seq == pos+1:
+			if !r.deq.CompareAndSwap(pos, pos+1) {
+				pos = r.deq.Load()
+				continue
+			}
+			e := slot.val
+			var zero E
+			slot.val = zero
+			slot.seq.Store(pos + r.mask + 1)
+			if r.pushWaiters.Load() > 0 {
+				signal(r.space)
+			}
+			return e, true
+		case seq < pos+1:
+			// nothing pushed to the slot yet
+			var zero E
+			return zero, false
+		default:
+			pos = r.deq.Load()
+		}
+	}
+}
+
+func (r *ringBuffer[E]) pop(done <-chan struct{}, timeout <-chan time.Time) (E, popResult) {
+	for {
+		if e, ok := r.tryPop(); ok {
+			return e, popped
+		}
+		if r.closed.Load() {
+			// the pushes are over, take what they left
+			if e, ok := r.tryPop(); ok {
+				return e, popped
+			}
+			var zero E
+			return zero, popClosed
+		}
+
+		r.popWaiters.Add(1)
+		if e, ok := r.tryPop(); ok {
+			r.popWaiters.Add(-1)
+			return e, popped
+		}
+		select {
+		case <-r.ready:
+		case <-r.done:
+		case <-done:
+			r.popWaiters.Add(-1)
+			var zero E
+			return zero, popInterrupted
+		case <-timeout:
+			r.popWaiters.Add(-1)
+			var zero E
+			return zero, popInterrupted
+		}
+		r.popWaiters.Add(-1)
+	}
+}
+
+func (r *ringBuffer[E]) close() {
+	r.closed.Store(true)
+	close(r.done)
+}
+
+func (r *ringBuffer[E]) len() int {
+	n := int(r.enq.Load() - r.deq.Load())
+	return min(max(n, 0), len(r.slots))
+}
+
+// signal wakes up a waiter of ch, if it isn't already pending.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
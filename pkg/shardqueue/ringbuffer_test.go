@@ -0,0 +1,175 @@
+package shardqueue
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRingBuffer_Bounded(t *testing.T) {
+	r := newRingBuffer[int](3)
+
+	for i := range 4 {
+		if !r.tryPush(i) {
+			t.Fatalf("Expected room for %d in a buffer rounded up to 4", i)
+		}
+	}
+	if r.tryPush(4) {
+		t.Error("Expected a full buffer")
+	}
+	if r.len() != 4 {
+		t.Errorf("Expected 4 elements, got %d", r.len())
+	}
+	for i := range 4 {
+		if e, ok := r.tryPop(); !ok || e != i {
+			t.Fatalf("Expected %d, got %d, %v", i, e, ok)
+		}
+	}
+	if _, ok := r.tryPop(); ok {
+		t.Error("Expected an empty buffer")
+	}
+
+	r.close()
+	if _, res := r.pop(nil, nil); res != popClosed {
+		t.Errorf("Expected a closed buffer, got %v", res)
+	}
+}
+
+func TestRingBuffer_Concurrent(t *testing.T) {
+	r := newRingBuffer[[2]int](8)
+	const producers, perProducer = 4, 10000
+
+	var wg sync.WaitGroup
+	for p := range producers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range perProducer {
+				r.push([2]int{p, i}, nil, nil)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		r.close()
+	}()
+
+	got := make([][]int, producers)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			e, res := r.pop(nil, nil)
+			if res == popClosed {
+				return
+			}
+			got[e[0]] = append(got[e[0]], e[1])
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected every element to be popped")
+	}
+
+	for p := range producers {
+		if len(got[p]) != perProducer || !slices.IsSorted(got[p]) {
+			t.Errorf("Expected the %d elements of producer %d in order, got %d", perProducer, p, len(got[p]))
+		}
+	}
+}
+
+func TestRingBuffer_PushInterrupted(t *testing.T) {
+	r := newRingBuffer[int](2)
+	r.tryPush(0)
+	r.tryPush(1)
+
+	stop := make(chan struct{})
+	time.AfterFunc(20*time.Millisecond, func() { close(stop) })
+	if r.push(2, stop, nil) {
+		t.Error("Expected the push to a full buffer to give up once stopped")
+	}
+	if _, res := r.pop(nil, time.After(20*time.Millisecond)); res != popped {
+		t.Errorf("Expected an element, got %v", res)
+	}
+}
+
+func TestWithRingBuffer(t *testing.T) {
+	sq := NewShardQueue[string, order](4, 10, WithRingBuffer())
+
+	var mu sync.Mutex
+	got := map[string][]int{}
+	_ = sq.Start(context.Background(), func(ctx context.Context, msg order) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got[msg.account] = append(got[msg.account], msg.seq)
+		return nil
+	})
+
+	accounts := []string{"a", "b", "c", "d", "e"}
+	var wg sync.WaitGroup
+	for _, account := range accounts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for seq := range 200 {
+				_ = sq.Shard(account, order{account: account, seq: seq})
+			}
+		}()
+	}
+	wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := sq.StopContext(ctx); err != nil {
+		t.Fatalf("Expected the shards to drain, got %v", err)
+	}
+	for _, account := range accounts {
+		if !slices.IsSorted(got[account]) || len(got[account]) != 200 {
+			t.Errorf("Expected the 200 messages of %s in order, got %d", account, len(got[account]))
+		}
+	}
+}
+
+func TestWithRingBuffer_DropOldest(t *testing.T) {
+	sq, release, processed := fullQueue(t, WithRingBuffer(), WithOverflowPolicy(OverflowDropOldest),
+		WithOnDrop(func(msg any, shard int, reason OverflowPolicy) {}))
+
+	// the ring of one shard holds two messages
+	for i := 2; i <= 4; i++ {
+		_ = sq.Shard("key", i)
+	}
+	close(release)
+	sq.Stop()
+
+	if got := processed(); !slices.Equal(got, []int{0, 3, 4}) {
+		t.Errorf("Expected the oldest message dropped, got %v", got)
+	}
+}
+
+func benchmarkShardQueue(b *testing.B, opts ...Option) {
+	sq := NewShardQueue[int, int](8, 1024, opts...)
+	_ = sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		return nil
+	})
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		key := 0
+		for pb.Next() {
+			_ = sq.Shard(key, key)
+			key++
+		}
+	})
+	sq.Stop()
+}
+
+func BenchmarkShardQueue_Channel(b *testing.B) {
+	benchmarkShardQueue(b)
+}
+
+func BenchmarkShardQueue_RingBuffer(b *testing.B) {
+	benchmarkShardQueue(b, WithRingBuffer())
+}
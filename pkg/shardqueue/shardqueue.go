@@ -1,110 +1,395 @@
 package shardqueue
 
 import (
-	"encoding/binary"
-	"hash/fnv"
+	"context"
+	"hash/maphash"
 	"log"
-	"math"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ShardQueue dispatches messages of type V to a fixed number of shards by
+// their routing key of type K. Messages with the same key always go to the
+// same shard and are processed in order.
+type ShardQueue[K comparable, V any] struct {
+	numShard    int
+	queueSize   int
+	queue       []buffer[envelope[K, V]]
+	levels      [][numPriorities]chan envelope[K, V] // per shard, see WithPriorities
+	hash        Hasher[K]
+	keyOf       func(msg V) K // set by WithKeyExtractor
+	ring        *ring         // set by WithConsistentHashing
+	subSeed     maphash.Seed  // picks the worker of a key within its shard
+	wg          sync.WaitGroup
+	metrics     []shardMetrics
+	limiters    []*TokenBucket // per shard, see WithRateLimit
+	codec       Codec[K, V]    // set by WithSpill and WithWAL
+	spills      []*spill[K, V] // per shard, see WithSpill
+	wals        []*wal         // per shard, see WithWAL
+	staleWALs   []*wal         // of the shards of a previous run with more shards
+	replayers   sync.WaitGroup
+	flushers    []flusher // per shard, see Flush
+	flushed     *flushSignal
+	middlewares middlewares[K, V]
+	options
+
+	mu       sync.RWMutex
+	state    state
+	senders  sync.WaitGroup // Shard calls in flight, waited for before closing the shards
+	stopping chan struct{}  // closed by Stop to release the blocked Shard calls
+	// spillsDone is closed by Stop once the Shard calls have returned, for
+	// the replay goroutines to return once they replayed every message.
+	spillsDone chan struct{}
+}
+
+// state is the lifecycle of a queue: new, started, stopping then stopped.
+type state int
+
+const (
+	stateNew state = iota
+	stateStarted
+	stateStopping
+	stateStopped
 )
 
-type Shardqueue struct {
-	numShard  int
-	queueSize int
-	queue     []chan interface{}
+type processFunc[V any] func(ctx context.Context, msg V) error
+
+// envelope is a queued message with its routing key, enqueue time and
+// expiry.
+type envelope[K comparable, V any] struct {
+	key        K
+	msg        V
+	enqueuedAt time.Time
+	deadline   time.Time // zero if the message doesn't expire, see WithTTL
+	wal        *wal      // the log of the message, see WithWAL
+	seq        uint64
+	trace      trace.SpanContext // of the caller of ShardCtx, see WithTracerProvider
+	gen        *flushGen         // see Flush
+	future     *Future           // see ShardAsync
 }
 
-type processFunc func(i interface{}) error
+// NewShardQueue is like New but panics if an option doesn't match the key or
+// message type of the queue.
+func NewShardQueue[K comparable, V any](numShard, queueSize int, opts ...Option) *ShardQueue[K, V] {
+	sq, err := New[K, V](numShard, queueSize, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return sq
+}
 
-func NewShardQueue(numShard, queueSize int) *Shardqueue {
-	sq := &Shardqueue{
+// New returns a queue of numShard shards holding up to queueSize messages
+// each. It returns ErrOptionType if an option of another key or message
+// type is set, e.g. WithHasher.
+func New[K comparable, V any](numShard, queueSize int, opts ...Option) (*ShardQueue[K, V], error) {
+	sq := &ShardQueue[K, V]{
 		numShard:  numShard,
 		queueSize: queueSize,
-		queue:     make([]chan interface{}, numShard),
+		queue:     make([]buffer[envelope[K, V]], numShard),
+		metrics:   make([]shardMetrics, numShard),
+		subSeed:   maphash.MakeSeed(),
+		options:   newOptions(opts),
+		stopping:  make(chan struct{}),
+	}
+	sq.flushers, sq.flushed = newFlushers(numShard)
+	var err error
+	if sq.hash, err = hasherOf[K](sq.hasher); err != nil {
+		return nil, err
+	}
+	if sq.keyOf, err = keyExtractorOf[K, V](sq.keyExtractor); err != nil {
+		return nil, err
+	}
+	sq.limiters = make([]*TokenBucket, numShard)
+	for i := range sq.limiters {
+		sq.limiters[i] = NewTokenBucket(sq.shardRate, sq.shardBurst)
+	}
+	if sq.virtualNodes > 0 {
+		sq.ring = newRing(numShard, sq.virtualNodes)
+	}
+	if sq.spillDir != "" || sq.walDir != "" {
+		if sq.codec, err = codecOf[K, V](sq.options.codec); err != nil {
+			return nil, err
+		}
 	}
 
-	return sq
+	return sq, nil
 }
 
-func (sq *Shardqueue) Start(fn processFunc) {
-	for i := 0; i < sq.numShard; i++ {
-		sq.queue[i] = make(chan interface{}, sq.queueSize)
-		go sq.shardWorker(i, sq.queue[i], fn)
-	}
+// Start starts a worker per shard calling fn with ctx for every message. The
+// workers stop once ctx is canceled, leaving the messages still queued
+// unprocessed. It returns ErrAlreadyStarted if the queue was already
+// started and ErrStopped once it is stopped.
+func (sq *ShardQueue[K, V]) Start(ctx context.Context, fn processFunc[V]) error {
+	return sq.start(ctx, func(id int, buf buffer[envelope[K, V]]) {
+		sq.shardWorker(ctx, id, buf, fn)
+	})
 }
 
-func (sq *Shardqueue) Stop() {
+// start creates the shards and runs worker for each of them, or
+// workersPerShard of them behind a dispatcher.
+func (sq *ShardQueue[K, V]) start(ctx context.Context, worker func(id int, buf buffer[envelope[K, V]])) error {
+	sq.mu.Lock()
+	defer sq.mu.Unlock()
+
+	switch sq.state {
+	case stateStarted:
+		return ErrAlreadyStarted
+	case stateStopping, stateStopped:
+		return ErrStopped
+	}
+	var recovered [][]envelope[K, V]
+	if sq.walDir != "" {
+		var err error
+		if recovered, err = sq.openWALs(); err != nil {
+			return err
+		}
+	}
+	if sq.spillDir != "" && !sq.priorities {
+		spills, err := sq.newSpills()
+		if err != nil {
+			if sq.wals != nil {
+				sq.closeWALs()
+				sq.wals, sq.staleWALs = nil, nil
+			}
+			return err
+		}
+		sq.spills = spills
+	}
+	if sq.priorities {
+		sq.levels = make([][numPriorities]chan envelope[K, V], sq.numShard)
+	}
 	for i := 0; i < sq.numShard; i++ {
-		close(sq.queue[i])
+		if sq.priorities {
+			for p := range sq.levels[i] {
+				sq.levels[i][p] = make(chan envelope[K, V], sq.queueSize)
+			}
+			out := make(chanBuffer[envelope[K, V]])
+			sq.queue[i] = out
+			sq.wg.Add(1)
+			go func() {
+				defer sq.wg.Done()
+				sq.forward(ctx, sq.levels[i], out)
+			}()
+		} else if sq.ringBuffer {
+			sq.queue[i] = newRingBuffer[envelope[K, V]](sq.queueSize)
+		} else {
+			sq.queue[i] = make(chanBuffer[envelope[K, V]], sq.queueSize)
+		}
+		if recovered != nil && len(recovered[i]) > 0 {
+			sq.queue[i] = newRecoveredBuffer(sq.queue[i], recovered[i])
+		}
+		sq.wg.Add(1)
+		go func() {
+			defer sq.wg.Done()
+			if sq.workersPerShard > 1 {
+				sq.dispatch(ctx, i, sq.queue[i], worker)
+				return
+			}
+			worker(i, sq.queue[i])
+		}()
+	}
+	if sq.spills != nil {
+		sq.startReplays(ctx)
 	}
+	sq.state = stateStarted
+	return nil
 }
 
-func (sq *Shardqueue) Shard(routingKey interface{}, msg interface{}) {
-	shard := hashKeyToShard(convertKeyToBytes(routingKey), sq.numShard)
-	sq.queue[shard] <- msg
+// Stop closes the shards and waits for the workers to process the queued
+// messages and return. Shard calls then fail with ErrStopped. Stop can be
+// called more than once, the later calls only wait for the workers.
+func (sq *ShardQueue[K, V]) Stop() {
+	_ = sq.StopContext(context.Background())
 }
 
-func (sq *Shardqueue) shardWorker(id int, ch chan interface{}, fn processFunc) {
-	for msg := range ch {
-		if err := fn(msg); err != nil {
-			log.Printf("Shard %d process error: %v", id, err)
+// StopContext is like Stop but returns ctx.Err() if ctx is done before the
+// workers have returned. The workers keep draining the shards meanwhile.
+func (sq *ShardQueue[K, V]) StopContext(ctx context.Context) error {
+	sq.mu.Lock()
+	prev := sq.state
+	if prev == stateNew {
+		sq.state = stateStopped
+	} else if prev == stateStarted {
+		sq.state = stateStopping
+		close(sq.stopping)
+	}
+	sq.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		if prev == stateStarted {
+			sq.senders.Wait()
+			if sq.spills != nil {
+				close(sq.spillsDone)
+				sq.replayers.Wait()
+			}
+			sq.closeInboxes()
+		}
+		sq.wg.Wait()
+		if prev == stateStarted && sq.wals != nil {
+			sq.closeWALs()
 		}
+		if prev == stateStarted {
+			sq.mu.Lock()
+			sq.state = stateStopped
+			sq.mu.Unlock()
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
-	log.Printf("Shard %d done", id)
 }
 
-func hashKeyToShard(key []byte, numShard int) int {
-	h := fnv.New32a()
-	h.Write(key)
-	return int(h.Sum32()) % numShard
+// Shard queues msg in the shard of routingKey, blocking while the shard is
+// full unless an overflow policy says otherwise, see WithOverflowPolicy. It
+// returns ErrNotStarted before Start and ErrStopped once Stop was called.
+func (sq *ShardQueue[K, V]) Shard(routingKey K, msg V) error {
+	return sq.shard(context.Background(), routingKey, msg, sq.ttl, PriorityNormal, nil)
+}
+
+// shard queues msg with the trace of ctx according to the overflow policy,
+// blocking until ctx is done at most.
+func (sq *ShardQueue[K, V]) shard(ctx context.Context, routingKey K, msg V, ttl time.Duration, priority Priority, future *Future) error {
+	if err := sq.enter(); err != nil {
+		return err
+	}
+	defer sq.senders.Done()
+
+	shard := sq.WhichShard(routingKey)
+	e := sq.envelope(routingKey, msg, ttl)
+	e.future = future
+	sq.traceFrom(ctx, &e)
+	if err := sq.accept(shard, &e); err != nil {
+		return err
+	}
+	return sq.acked(e, sq.send(ctx, shard, sq.inbox(shard, priority), e))
 }
 
-func convertKeyToBytes(key interface{}) []byte {
-	switch v := key.(type) {
-	case []byte:
-		return v
+// TryShard is like Shard but returns ErrQueueFull at once instead of
+// blocking when the shard of the key is full.
+func (sq *ShardQueue[K, V]) TryShard(routingKey K, msg V) error {
+	return sq.TryShardCtx(context.Background(), routingKey, msg)
+}
+
+// TryShardCtx is like TryShard but the message is processed in the trace of
+// ctx, see WithTracerProvider.
+func (sq *ShardQueue[K, V]) TryShardCtx(ctx context.Context, routingKey K, msg V) error {
+	if err := sq.enter(); err != nil {
+		return err
+	}
+	defer sq.senders.Done()
+
+	select {
+	case <-sq.stopping:
+		return ErrStopped
+	default:
+	}
+	shard := sq.WhichShard(routingKey)
+	e := sq.envelope(routingKey, msg, sq.ttl)
+	sq.traceFrom(ctx, &e)
+	if err := sq.accept(shard, &e); err != nil {
+		return err
+	}
+	if sq.spills != nil && sq.trySpill(shard, e) {
+		return nil
+	}
+	if !sq.inbox(shard, PriorityNormal).tryPush(e) {
+		sq.ack(e)
+		return ErrQueueFull
+	}
+	sq.metrics[shard].enqueued.Add(1)
+	return nil
+}
 
-	case string:
-		return []byte(v)
+// ShardCtx is like Shard but gives up once ctx is done or, with a positive
+// timeout, once timeout has elapsed. It then returns an *EnqueueError
+// wrapping the cause of ctx or ErrEnqueueTimeout.
+func (sq *ShardQueue[K, V]) ShardCtx(ctx context.Context, routingKey K, msg V, timeout ...time.Duration) error {
+	if err := sq.enter(); err != nil {
+		return err
+	}
+	defer sq.senders.Done()
 
-	case int:
-		return intToBytes(int64(v))
-	case int32:
-		return intToBytes(int64(v))
-	case int64:
-		return intToBytes(v)
+	shard := sq.WhichShard(routingKey)
+	if len(timeout) > 0 && timeout[0] > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout[0], ErrEnqueueTimeout)
+		defer cancel()
+	}
 
-	case uint:
-		return uintToBytes(uint64(v))
-	case uint32:
-		return uintToBytes(uint64(v))
-	case uint64:
-		return uintToBytes(v)
+	e := sq.envelope(routingKey, msg, sq.ttl)
+	sq.traceFrom(ctx, &e)
+	if err := sq.accept(shard, &e); err != nil {
+		return err
+	}
+	if sq.spills != nil && sq.trySpill(shard, e) {
+		return nil
+	}
+	if !sq.inbox(shard, PriorityNormal).push(e, sq.stopping, ctx.Done()) {
+		sq.ack(e)
+		if ctx.Err() != nil {
+			return &EnqueueError{Shard: shard, Err: context.Cause(ctx)}
+		}
+		return ErrStopped
+	}
+	sq.metrics[shard].enqueued.Add(1)
+	return nil
+}
 
-	case float64:
-		return floatToBytes(v)
-	case float32:
-		return floatToBytes(float64(v))
+// enter registers a Shard call if the queue accepts messages. The caller
+// must call sq.senders.Done once it has returned.
+func (sq *ShardQueue[K, V]) enter() error {
+	sq.mu.RLock()
+	defer sq.mu.RUnlock()
 
+	switch sq.state {
+	case stateNew:
+		return ErrNotStarted
+	case stateStarted:
+		sq.senders.Add(1)
+		return nil
 	default:
-		return []byte("defaultRoutingKey")
+		return ErrStopped
 	}
 }
 
-func intToBytes(n int64) []byte {
-	buf := make([]byte, 8)
-	binary.BigEndian.PutUint64(buf, uint64(n))
-	return buf
+// accept appends e to the WAL of the shard, if any, and counts it for Flush.
+func (sq *ShardQueue[K, V]) accept(shard int, e *envelope[K, V]) error {
+	if err := sq.appendWAL(shard, e); err != nil {
+		return err
+	}
+	sq.track(shard, e)
+	return nil
 }
 
-func uintToBytes(n uint64) []byte {
-	buf := make([]byte, 8)
-	binary.BigEndian.PutUint64(buf, n)
-	return buf
+func (sq *ShardQueue[K, V]) shardWorker(ctx context.Context, id int, buf buffer[envelope[K, V]], fn processFunc[V]) {
+	for {
+		// a canceled context wins over the queued messages
+		if ctx.Err() != nil {
+			log.Printf("Shard %d stopped: %v", id, ctx.Err())
+			return
+		}
+		e, res := buf.pop(ctx.Done(), nil)
+		switch res {
+		case popClosed:
+			log.Printf("Shard %d done", id)
+			return
+		case popped:
+			sq.process(ctx, id, e, fn)
+		}
+	}
 }
 
-func floatToBytes(f float64) []byte {
-	bits := math.Float64bits(f)
-	buf := make([]byte, 8)
-	binary.BigEndian.PutUint64(buf, bits)
-	return buf
+// WhichShard returns the shard of the messages of key.
+func (sq *ShardQueue[K, V]) WhichShard(key K) int {
+	if sq.ring != nil {
+		return sq.ring.shard(sq.hash(key))
+	}
+	return int(sq.hash(key) % uint64(sq.numShard))
 }
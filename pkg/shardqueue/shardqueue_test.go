@@ -0,0 +1,323 @@
+package shardqueue
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type order struct {
+	account string
+	seq     int
+}
+
+func TestShardQueue_KeyOrder(t *testing.T) {
+	sq := NewShardQueue[string, order](4, 10)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	got := map[string][]int{}
+	sq.Start(context.Background(), func(ctx context.Context, msg order) error {
+		defer wg.Done()
+		mu.Lock()
+		defer mu.Unlock()
+		got[msg.account] = append(got[msg.account], msg.seq)
+		return nil
+	})
+
+	accounts := []string{"a", "b", "c", "d", "e"}
+	for seq := range 20 {
+		for _, account := range accounts {
+			wg.Add(1)
+			sq.Shard(account, order{account: account, seq: seq})
+		}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected every message to be processed")
+	}
+	sq.Stop()
+
+	for _, account := range accounts {
+		if !slices.IsSorted(got[account]) || len(got[account]) != 20 {
+			t.Errorf("Expected the 20 messages of %s in order, got %v", account, got[account])
+		}
+	}
+}
+
+func TestShardQueue_ContextCanceled(t *testing.T) {
+	sq := NewShardQueue[int, int](1, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	started := make(chan struct{})
+	results := make(chan error, 10)
+	sq.Start(ctx, func(ctx context.Context, msg int) error {
+		if msg == 0 {
+			close(started)
+		}
+		<-ctx.Done()
+		results <- ctx.Err()
+		return ctx.Err()
+	})
+	for msg := range 3 {
+		sq.Shard(0, msg)
+	}
+	<-started
+	cancel()
+
+	select {
+	case err := <-results:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected the handler to see the cancellation, got %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected the in-flight message to be aborted")
+	}
+	select {
+	case <-results:
+		t.Error("Expected the queued messages not to be processed once canceled")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestShardQueue_TryShard(t *testing.T) {
+	sq := NewShardQueue[int, int](1, 1)
+	started, release := make(chan struct{}), make(chan struct{})
+	sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		if msg == 0 {
+			close(started)
+		}
+		<-release
+		return nil
+	})
+	defer sq.Stop()
+	defer close(release)
+
+	if err := sq.TryShard(0, 0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	<-started
+	if err := sq.TryShard(0, 1); err != nil {
+		t.Fatalf("Expected room for a queued message, got %v", err)
+	}
+	if err := sq.TryShard(0, 2); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Expected ErrQueueFull, got %v", err)
+	}
+}
+
+func TestShardQueue_ShardCtx(t *testing.T) {
+	sq := NewShardQueue[int, int](1, 1)
+	release := make(chan struct{})
+	sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		<-release
+		return nil
+	})
+	defer sq.Stop()
+	defer close(release)
+
+	// one message in the handler, one queued
+	sq.Shard(0, 0)
+	for sq.TryShard(0, 1) != nil {
+		time.Sleep(time.Millisecond)
+	}
+
+	err := sq.ShardCtx(context.Background(), 0, 2, 20*time.Millisecond)
+	var enqueueErr *EnqueueError
+	if !errors.As(err, &enqueueErr) || !errors.Is(err, ErrEnqueueTimeout) {
+		t.Errorf("Expected an EnqueueError for the timeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := sq.ShardCtx(ctx, 0, 2); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the context error, got %v", err)
+	}
+	if err := sq.ShardWithTTLCtx(ctx, 0, 2, time.Minute); !errors.As(err, &enqueueErr) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected an EnqueueError for the context, got %v", err)
+	}
+	if _, err := sq.ShardAsyncCtx(ctx, 0, 2); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the context error, got %v", err)
+	}
+}
+
+func TestShardQueue_StopDrains(t *testing.T) {
+	sq := NewShardQueue[int, int](2, 100)
+	var processed atomic.Int32
+	sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		time.Sleep(time.Millisecond)
+		processed.Add(1)
+		return nil
+	})
+	for msg := range 50 {
+		sq.Shard(msg, msg)
+	}
+
+	sq.Stop()
+	if n := processed.Load(); n != 50 {
+		t.Errorf("Expected Stop to wait for the 50 messages, %d were processed", n)
+	}
+}
+
+func TestShardQueue_StopContext(t *testing.T) {
+	sq := NewShardQueue[int, int](1, 1)
+	release := make(chan struct{})
+	sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		<-release
+		return nil
+	})
+	sq.Shard(0, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := sq.StopContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the context error while a worker is busy, got %v", err)
+	}
+	close(release)
+}
+
+func TestShardQueue_Lifecycle(t *testing.T) {
+	sq := NewShardQueue[int, int](2, 1)
+	fn := func(ctx context.Context, msg int) error { return nil }
+
+	if err := sq.Shard(0, 0); !errors.Is(err, ErrNotStarted) {
+		t.Errorf("Expected ErrNotStarted before Start, got %v", err)
+	}
+	if err := sq.Start(context.Background(), fn); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := sq.Start(context.Background(), fn); !errors.Is(err, ErrAlreadyStarted) {
+		t.Errorf("Expected ErrAlreadyStarted, got %v", err)
+	}
+
+	sq.Stop()
+	sq.Stop()
+	if err := sq.Shard(0, 0); !errors.Is(err, ErrStopped) {
+		t.Errorf("Expected ErrStopped after Stop, got %v", err)
+	}
+	if err := sq.TryShard(0, 0); !errors.Is(err, ErrStopped) {
+		t.Errorf("Expected ErrStopped after Stop, got %v", err)
+	}
+	if err := sq.Start(context.Background(), fn); !errors.Is(err, ErrStopped) {
+		t.Errorf("Expected ErrStopped when starting a stopped queue, got %v", err)
+	}
+}
+
+func TestShardQueue_StopReleasesBlockedShard(t *testing.T) {
+	sq := NewShardQueue[int, int](1, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	_ = sq.Start(ctx, func(ctx context.Context, msg int) error { return nil })
+	// the worker returns and the shard fills up
+	cancel()
+	_ = sq.TryShard(0, 0)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for msg := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- sq.Shard(0, msg)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	sq.Stop()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil && !errors.Is(err, ErrStopped) {
+			t.Errorf("Expected ErrStopped for the blocked calls, got %v", err)
+		}
+	}
+}
+
+func TestShardQueue_WhichShard(t *testing.T) {
+	sq := NewShardQueue[int, struct{}](8, 1)
+	for key := range 100 {
+		shard := sq.WhichShard(key)
+		if shard < 0 || shard >= 8 {
+			t.Fatalf("Expected a shard within [0, 8), got %d", shard)
+		}
+		if again := sq.WhichShard(key); again != shard {
+			t.Errorf("Expected key %d to stay on shard %d, got %d", key, shard, again)
+		}
+	}
+}
+
+func TestWithErrorHandler(t *testing.T) {
+	type failure struct {
+		shard int
+		msg   any
+		err   error
+	}
+	failures := make(chan failure, 1)
+	sq := NewShardQueue[string, order](2, 1, WithErrorHandler(func(shard int, msg any, err error) {
+		failures <- failure{shard, msg, err}
+	}))
+	boom := errors.New("boom")
+	sq.Start(context.Background(), func(ctx context.Context, msg order) error {
+		return boom
+	})
+	defer sq.Stop()
+
+	sq.Shard("a", order{account: "a", seq: 1})
+	select {
+	case f := <-failures:
+		if f.shard != sq.WhichShard("a") {
+			t.Errorf("Expected the shard of the key, got %d", f.shard)
+		}
+		if f.msg != (order{account: "a", seq: 1}) || !errors.Is(f.err, boom) {
+			t.Errorf("Expected the failed message and its error, got %v and %v", f.msg, f.err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected the error handler to be called")
+	}
+}
+
+func TestWithOnHandled(t *testing.T) {
+	var mu sync.Mutex
+	var handled []any
+	sq := NewShardQueue[string, int](1, 10,
+		WithErrorHandler(func(shard int, msg any, err error) {}),
+		WithOnExpired(func(shard int, msg any, waited time.Duration) {}),
+		WithOnHandled(func(msg any) {
+			mu.Lock()
+			defer mu.Unlock()
+			handled = append(handled, msg)
+		}),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupted := make(chan struct{})
+	_ = sq.Start(ctx, func(ctx context.Context, msg int) error {
+		switch msg {
+		case 1:
+			return errors.New("boom")
+		case 3:
+			close(interrupted)
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	_ = sq.Shard("key", 0)
+	_ = sq.Shard("key", 1)
+	_ = sq.ShardWithTTL("key", 2, time.Nanosecond)
+	_ = sq.Shard("key", 3)
+	<-interrupted
+	cancel()
+	sq.Stop()
+
+	if !slices.Equal(handled, []any{0, 1, 2}) {
+		t.Errorf("Expected the processed, failed and expired messages reported, got %v", handled)
+	}
+}
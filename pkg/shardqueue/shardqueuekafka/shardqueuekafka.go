@@ -0,0 +1,237 @@
+// Package shardqueuekafka feeds the records of Kafka topics to a shard queue
+// with franz-go.
+//
+// Kafka orders the records of a partition, the shard queue those of a key,
+// so the records of a partition are processed concurrently by key. The
+// offset of a partition is only committed up to its oldest record not
+// processed yet, so a crash redelivers the records in flight but never skips
+// one.
+package shardqueuekafka
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/joripage/go_util/pkg/shardqueue"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const DefaultCommitTimeout = 10 * time.Second
+
+// Consumer consumes Kafka records in a consumer group and queues them in a
+// shard queue by key.
+type Consumer[K comparable] struct {
+	client *kgo.Client
+	queue  *shardqueue.ShardQueue[K, *kgo.Record]
+	key    func(r *kgo.Record) K
+
+	commitTimeout time.Duration
+
+	mu         sync.Mutex
+	partitions map[partition]*watermark
+}
+
+type Option func(*config)
+
+type config struct {
+	clientOpts    []kgo.Opt
+	queueOpts     []shardqueue.Option
+	commitTimeout time.Duration
+}
+
+// WithClientOptions configures the franz-go client, e.g. with
+// kgo.SeedBrokers, kgo.ConsumerGroup and kgo.ConsumeTopics. The consumer
+// sets how offsets are committed.
+func WithClientOptions(opts ...kgo.Opt) Option {
+	return func(c *config) {
+		c.clientOpts = append(c.clientOpts, opts...)
+	}
+}
+
+// WithQueueOptions configures the shard queue, except for
+// shardqueue.WithOnHandled which the consumer uses to commit the offsets.
+func WithQueueOptions(opts ...shardqueue.Option) Option {
+	return func(c *config) {
+		c.queueOpts = append(c.queueOpts, opts...)
+	}
+}
+
+// WithCommitTimeout bounds the commit of the offsets when Run returns and
+// when partitions are revoked.
+func WithCommitTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.commitTimeout = d
+	}
+}
+
+// StringKey routes a record by its Kafka key.
+func StringKey(r *kgo.Record) string {
+	return string(r.Key)
+}
+
+// New returns a Consumer queuing the records in numShard shards of queueSize
+// records, routed by the key returned by key, e.g. StringKey.
+func New[K comparable](numShard, queueSize int, key func(r *kgo.Record) K, opts ...Option) (*Consumer[K], error) {
+	cfg := config{commitTimeout: DefaultCommitTimeout}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	c := &Consumer[K]{
+		key:           key,
+		commitTimeout: cfg.commitTimeout,
+		partitions:    map[partition]*watermark{},
+	}
+	clientOpts := append(cfg.clientOpts,
+		kgo.AutoCommitMarks(),
+		kgo.OnPartitionsRevoked(c.revoked),
+		kgo.OnPartitionsLost(c.lost),
+	)
+	queueOpts := append(cfg.queueOpts, shardqueue.WithOnHandled(c.handled))
+	queue, err := shardqueue.New[K, *kgo.Record](numShard, queueSize, queueOpts...)
+	if err != nil {
+		return nil, err
+	}
+	c.queue = queue
+	client, err := kgo.NewClient(clientOpts...)
+	if err != nil {
+		return nil, err
+	}
+	c.client = client
+	return c, nil
+}
+
+// Queue returns the shard queue of the consumer, e.g. for its stats.
+func (c *Consumer[K]) Queue() *shardqueue.ShardQueue[K, *kgo.Record] {
+	return c.queue
+}
+
+// Run polls the records and processes them with fn until ctx is done. It
+// then stops polling, waits for the queued records to be processed, commits
+// the offsets and closes the client. The records fn fails for are committed
+// once they were reported to the error handler and the dead letter function
+// of the queue.
+func (c *Consumer[K]) Run(ctx context.Context, fn func(ctx context.Context, r *kgo.Record) error) error {
+	if err := c.queue.Start(context.WithoutCancel(ctx), fn); err != nil {
+		c.client.Close()
+		return err
+	}
+	defer c.close()
+
+	for {
+		fetches := c.client.PollFetches(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		fetches.EachError(func(topic string, p int32, err error) {
+			log.Printf("Kafka fetch %s/%d: %v", topic, p, err)
+		})
+		var err error
+		fetches.EachRecord(func(r *kgo.Record) {
+			if err != nil {
+				return
+			}
+			c.track(r)
+			err = c.queue.Shard(c.key(r), r)
+		})
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// close drains the queue, commits the offsets of the processed records and
+// closes the client.
+func (c *Consumer[K]) close() {
+	c.queue.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), c.commitTimeout)
+	defer cancel()
+	if err := c.client.CommitMarkedOffsets(ctx); err != nil {
+		log.Printf("Kafka commit: %v", err)
+	}
+	c.client.Close()
+}
+
+type partition struct {
+	topic     string
+	partition int32
+}
+
+// watermark tracks the records of a partition in flight, in offset order.
+type watermark struct {
+	pending []*kgo.Record
+	done    map[*kgo.Record]bool // of the pending records
+}
+
+func (c *Consumer[K]) track(r *kgo.Record) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p := partition{r.Topic, r.Partition}
+	w := c.partitions[p]
+	if w == nil {
+		w = &watermark{done: map[*kgo.Record]bool{}}
+		c.partitions[p] = w
+	}
+	w.pending = append(w.pending, r)
+	w.done[r] = false
+}
+
+// handled marks the offsets of the records of the partition processed in a
+// row, up to r if the older ones are processed, to be committed.
+func (c *Consumer[K]) handled(msg any) {
+	r := msg.(*kgo.Record)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w := c.partitions[partition{r.Topic, r.Partition}]
+	if w == nil {
+		// the partition was revoked meanwhile
+		return
+	}
+	if done, ok := w.done[r]; !ok || done {
+		// fetched before the partition was revoked and assigned again
+		return
+	}
+	w.done[r] = true
+	var last *kgo.Record
+	for len(w.pending) > 0 && w.done[w.pending[0]] {
+		last = w.pending[0]
+		delete(w.done, last)
+		w.pending[0] = nil
+		w.pending = w.pending[1:]
+	}
+	if last != nil {
+		c.client.MarkCommitRecords(last)
+	}
+}
+
+// revoked commits the offsets of the revoked partitions and forgets their
+// records in flight, which are redelivered to their new consumer.
+func (c *Consumer[K]) revoked(ctx context.Context, client *kgo.Client, revoked map[string][]int32) {
+	c.forget(revoked)
+	ctx, cancel := context.WithTimeout(ctx, c.commitTimeout)
+	defer cancel()
+	if err := client.CommitMarkedOffsets(ctx); err != nil {
+		log.Printf("Kafka commit on revoke: %v", err)
+	}
+}
+
+func (c *Consumer[K]) lost(_ context.Context, _ *kgo.Client, lost map[string][]int32) {
+	c.forget(lost)
+}
+
+// forget clears the watermarks of the partitions, so the records in flight
+// are ignored once handled, even if the partitions are assigned again.
+func (c *Consumer[K]) forget(partitions map[string][]int32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for topic, ps := range partitions {
+		for _, p := range ps {
+			delete(c.partitions, partition{topic, p})
+		}
+	}
+}
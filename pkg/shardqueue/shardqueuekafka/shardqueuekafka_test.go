@@ -0,0 +1,154 @@
+package shardqueuekafka
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func newCluster(t *testing.T) []string {
+	t.Helper()
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(3, "orders"))
+	if err != nil {
+		t.Fatalf("Unexpected error starting the cluster: %v", err)
+	}
+	t.Cleanup(cluster.Close)
+	return cluster.ListenAddrs()
+}
+
+func produce(t *testing.T, brokers []string, records ...*kgo.Record) {
+	t.Helper()
+	client, err := kgo.NewClient(kgo.SeedBrokers(brokers...), kgo.DefaultProduceTopic("orders"))
+	if err != nil {
+		t.Fatalf("Unexpected error creating the producer: %v", err)
+	}
+	defer client.Close()
+	if err := client.ProduceSync(context.Background(), records...).FirstErr(); err != nil {
+		t.Fatalf("Unexpected error producing: %v", err)
+	}
+}
+
+func newConsumer(t *testing.T, brokers []string) *Consumer[string] {
+	t.Helper()
+	c, err := New(4, 10, StringKey, WithClientOptions(
+		kgo.SeedBrokers(brokers...),
+		kgo.ConsumerGroup("group"),
+		kgo.ConsumeTopics("orders"),
+		kgo.FetchMaxWait(100*time.Millisecond),
+	))
+	if err != nil {
+		t.Fatalf("Unexpected error creating the consumer: %v", err)
+	}
+	return c
+}
+
+// consume runs a consumer until it processed n records, and returns them by
+// key.
+func consume(t *testing.T, brokers []string, n int) map[string][]int {
+	t.Helper()
+	c := newConsumer(t, brokers)
+
+	var mu sync.Mutex
+	got := map[string][]int{}
+	count := 0
+	enough := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Run(ctx, func(ctx context.Context, r *kgo.Record) error {
+			seq, _ := strconv.Atoi(string(r.Value))
+			mu.Lock()
+			defer mu.Unlock()
+			got[string(r.Key)] = append(got[string(r.Key)], seq)
+			if count++; count == n {
+				close(enough)
+			}
+			return nil
+		})
+	}()
+
+	select {
+	case <-enough:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Expected %d records, got %d", n, count)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Unexpected error from Run: %v", err)
+	}
+	return got
+}
+
+func TestConsumer(t *testing.T) {
+	brokers := newCluster(t)
+	var records []*kgo.Record
+	accounts := []string{"a", "b", "c", "d", "e"}
+	for seq := range 10 {
+		for _, account := range accounts {
+			records = append(records, &kgo.Record{Key: []byte(account), Value: []byte(strconv.Itoa(seq))})
+		}
+	}
+	produce(t, brokers, records...)
+
+	got := consume(t, brokers, len(records))
+	for _, account := range accounts {
+		if !slices.Equal(got[account], []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}) {
+			t.Errorf("Expected the records of %s in order, got %v", account, got[account])
+		}
+	}
+
+	// the next consumer of the group starts after the committed offsets
+	produce(t, brokers, &kgo.Record{Key: []byte("a"), Value: []byte("10")})
+	if got := consume(t, brokers, 1); fmt.Sprint(got) != "map[a:[10]]" {
+		t.Errorf("Expected only the new record, got %v", got)
+	}
+}
+
+func TestConsumer_Watermark(t *testing.T) {
+	c := &Consumer[string]{partitions: map[partition]*watermark{}}
+	records := make([]*kgo.Record, 3)
+	for i := range records {
+		records[i] = &kgo.Record{Topic: "orders", Offset: int64(i)}
+		c.track(records[i])
+	}
+
+	// the client is only used once the oldest record is processed
+	c.handled(records[2])
+	c.handled(records[1])
+	w := c.partitions[partition{"orders", 0}]
+	if len(w.pending) != 3 {
+		t.Errorf("Expected the 3 records in flight until the oldest is processed, got %d", len(w.pending))
+	}
+}
+
+func TestConsumer_RevokedThenReassigned(t *testing.T) {
+	c := &Consumer[string]{partitions: map[partition]*watermark{}}
+	stale := []*kgo.Record{{Topic: "orders", Offset: 0}, {Topic: "orders", Offset: 1}}
+	for _, r := range stale {
+		c.track(r)
+	}
+	c.forget(map[string][]int32{"orders": {0}})
+	if _, ok := c.partitions[partition{"orders", 0}]; ok {
+		t.Fatal("Expected the watermark of the revoked partition cleared")
+	}
+
+	// redelivered from the committed offset once assigned again
+	redelivered := &kgo.Record{Topic: "orders", Offset: 0}
+	c.track(redelivered)
+	c.handled(stale[1])
+	c.handled(stale[0])
+	w := c.partitions[partition{"orders", 0}]
+	if len(w.pending) != 1 || w.pending[0] != redelivered {
+		t.Errorf("Expected only the redelivered record in flight, got %v", w.pending)
+	}
+	if len(w.done) != 1 || w.done[redelivered] {
+		t.Errorf("Expected the stale records ignored, got %v", w.done)
+	}
+}
@@ -0,0 +1,276 @@
+// Package shardqueuenats feeds the messages of NATS subjects or JetStream
+// consumers to a shard queue.
+//
+// JetStream messages are acked once processed. A message the process
+// function failed for is nacked for JetStream to redeliver it, until its last
+// delivery allowed by the MaxDeliver of the consumer, then terminated and
+// dead-lettered. Core NATS messages are not redelivered, a failed one is
+// dead-lettered at once.
+package shardqueuenats
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joripage/go_util/pkg/shardqueue"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Source queues the messages of a subscription in a shard queue by key.
+type Source[K comparable] struct {
+	queue     *shardqueue.ShardQueue[K, jetstream.Msg]
+	key       func(m jetstream.Msg) K
+	subscribe func() (subscription, error)
+	config
+
+	failed sync.Map // messages failed for, not to be acked
+}
+
+type Option func(*config)
+
+type config struct {
+	queueOpts  []shardqueue.Option
+	queueGroup string
+	nakDelay   time.Duration
+	maxDeliver int
+	deadLetter func(m jetstream.Msg, err error)
+}
+
+// WithQueueOptions configures the shard queue, except for
+// shardqueue.WithErrorHandler and shardqueue.WithOnHandled which the source
+// uses to ack the messages.
+func WithQueueOptions(opts ...shardqueue.Option) Option {
+	return func(c *config) {
+		c.queueOpts = append(c.queueOpts, opts...)
+	}
+}
+
+// WithQueueGroup subscribes to the subject in a queue group, for the
+// messages to be spread over its members. Only for Subscribe.
+func WithQueueGroup(name string) Option {
+	return func(c *config) {
+		c.queueGroup = name
+	}
+}
+
+// WithNakDelay delays the redelivery of the failed JetStream messages.
+func WithNakDelay(d time.Duration) Option {
+	return func(c *config) {
+		c.nakDelay = d
+	}
+}
+
+// WithDeadLetter calls fn with the messages failed for on their last
+// delivery, or at once for permanent errors and core NATS messages, instead
+// of logging them.
+func WithDeadLetter(fn func(m jetstream.Msg, err error)) Option {
+	return func(c *config) {
+		c.deadLetter = fn
+	}
+}
+
+// HeaderKey routes a message by the value of a header.
+func HeaderKey(name string) func(m jetstream.Msg) string {
+	return func(m jetstream.Msg) string {
+		return m.Headers().Get(name)
+	}
+}
+
+// SubjectToken routes a message by the i-th token of its subject, counted
+// from the end if negative, e.g. SubjectToken(1) for orders.<account>.
+func SubjectToken(i int) func(m jetstream.Msg) string {
+	return func(m jetstream.Msg) string {
+		tokens := strings.Split(m.Subject(), ".")
+		j := i
+		if j < 0 {
+			j += len(tokens)
+		}
+		if j < 0 || j >= len(tokens) {
+			return ""
+		}
+		return tokens[j]
+	}
+}
+
+// Subscribe returns a Source of the messages of the core NATS subject,
+// queued in numShard shards of queueSize messages by the key returned by
+// key. The messages are wrapped in a jetstream.Msg whose acks do nothing.
+func Subscribe[K comparable](nc *nats.Conn, subject string, numShard, queueSize int, key func(m jetstream.Msg) K, opts ...Option) *Source[K] {
+	s := newSource(numShard, queueSize, key, opts)
+	s.subscribe = func() (subscription, error) {
+		sub, err := nc.QueueSubscribeSync(subject, s.queueGroup)
+		if err != nil {
+			return nil, err
+		}
+		return coreSubscription{sub}, nil
+	}
+	return s
+}
+
+// Consume returns a Source of the messages of the JetStream consumer, queued
+// in numShard shards of queueSize messages by the key returned by key.
+func Consume[K comparable](cons jetstream.Consumer, numShard, queueSize int, key func(m jetstream.Msg) K, opts ...Option) *Source[K] {
+	s := newSource(numShard, queueSize, key, opts)
+	if info := cons.CachedInfo(); info != nil {
+		s.maxDeliver = info.Config.MaxDeliver
+	}
+	s.subscribe = func() (subscription, error) {
+		it, err := cons.Messages()
+		if err != nil {
+			return nil, err
+		}
+		return jetStreamSubscription{it}, nil
+	}
+	return s
+}
+
+func newSource[K comparable](numShard, queueSize int, key func(m jetstream.Msg) K, opts []Option) *Source[K] {
+	s := &Source[K]{key: key}
+	for _, opt := range opts {
+		opt(&s.config)
+	}
+	queueOpts := append(s.queueOpts,
+		shardqueue.WithErrorHandler(s.fail),
+		shardqueue.WithOnHandled(s.handled),
+	)
+	s.queue = shardqueue.NewShardQueue[K, jetstream.Msg](numShard, queueSize, queueOpts...)
+	return s
+}
+
+// Queue returns the shard queue of the source, e.g. for its stats.
+func (s *Source[K]) Queue() *shardqueue.ShardQueue[K, jetstream.Msg] {
+	return s.queue
+}
+
+// Run subscribes and processes the messages with fn until ctx is done. It
+// then unsubscribes and waits for the queued messages to be processed and
+// acked, so the connection must stay open until it returns.
+func (s *Source[K]) Run(ctx context.Context, fn func(ctx context.Context, m jetstream.Msg) error) error {
+	sub, err := s.subscribe()
+	if err != nil {
+		return err
+	}
+	if err := s.queue.Start(context.WithoutCancel(ctx), fn); err != nil {
+		sub.stop()
+		return err
+	}
+	defer s.queue.Stop()
+	defer sub.stop()
+
+	for {
+		m, err := sub.next(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+			return err
+		}
+		if err != nil {
+			// e.g. missed heartbeats, the iterator recovers by itself
+			log.Printf("NATS receive: %v", err)
+			continue
+		}
+		if err := s.queue.Shard(s.key(m), m); err != nil {
+			return err
+		}
+	}
+}
+
+// fail nacks a JetStream message for it to be redelivered, or terminates and
+// dead-letters it on its last delivery or for a permanent error.
+func (s *Source[K]) fail(_ int, msg any, err error) {
+	m := msg.(jetstream.Msg)
+	s.failed.Store(m, struct{}{})
+
+	md, mdErr := m.Metadata()
+	if mdErr == nil && !shardqueue.IsPermanent(err) && (s.maxDeliver <= 0 || int(md.NumDelivered) < s.maxDeliver) {
+		if err := m.NakWithDelay(s.nakDelay); err != nil {
+			log.Printf("NATS nak %s: %v", m.Subject(), err)
+		}
+		return
+	}
+	if mdErr == nil {
+		if err := m.TermWithReason(err.Error()); err != nil {
+			log.Printf("NATS term %s: %v", m.Subject(), err)
+		}
+	}
+	if s.deadLetter != nil {
+		s.deadLetter(m, err)
+		return
+	}
+	log.Printf("NATS message on %s dead-lettered: %v", m.Subject(), err)
+}
+
+// handled acks a message done with, unless it was failed for.
+func (s *Source[K]) handled(msg any) {
+	m := msg.(jetstream.Msg)
+	if _, failed := s.failed.LoadAndDelete(m); failed {
+		return
+	}
+	if err := m.Ack(); err != nil {
+		log.Printf("NATS ack %s: %v", m.Subject(), err)
+	}
+}
+
+type subscription interface {
+	next(ctx context.Context) (jetstream.Msg, error)
+	stop()
+}
+
+type jetStreamSubscription struct {
+	it jetstream.MessagesContext
+}
+
+func (s jetStreamSubscription) next(ctx context.Context) (jetstream.Msg, error) {
+	return s.it.Next(jetstream.NextContext(ctx))
+}
+
+func (s jetStreamSubscription) stop() {
+	s.it.Stop()
+}
+
+type coreSubscription struct {
+	sub *nats.Subscription
+}
+
+func (s coreSubscription) next(ctx context.Context) (jetstream.Msg, error) {
+	m, err := s.sub.NextMsgWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &coreMsg{m}, nil
+}
+
+func (s coreSubscription) stop() {
+	if err := s.sub.Unsubscribe(); err != nil {
+		log.Printf("NATS unsubscribe %s: %v", s.sub.Subject, err)
+	}
+}
+
+// coreMsg is a core NATS message as a jetstream.Msg, without acks.
+type coreMsg struct {
+	msg *nats.Msg
+}
+
+var _ jetstream.Msg = (*coreMsg)(nil)
+
+func (m *coreMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	return nil, nats.ErrNotJSMessage
+}
+
+func (m *coreMsg) Data() []byte                     { return m.msg.Data }
+func (m *coreMsg) Headers() nats.Header             { return m.msg.Header }
+func (m *coreMsg) Subject() string                  { return m.msg.Subject }
+func (m *coreMsg) Reply() string                    { return m.msg.Reply }
+func (m *coreMsg) Ack() error                       { return nil }
+func (m *coreMsg) DoubleAck(context.Context) error  { return nil }
+func (m *coreMsg) Nak() error                       { return nil }
+func (m *coreMsg) NakWithDelay(time.Duration) error { return nil }
+func (m *coreMsg) InProgress() error                { return nil }
+func (m *coreMsg) Term() error                      { return nil }
+func (m *coreMsg) TermWithReason(string) error      { return nil }
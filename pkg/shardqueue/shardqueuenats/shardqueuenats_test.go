@@ -0,0 +1,185 @@
+package shardqueuenats
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joripage/go_util/pkg/shardqueue"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func newServer(t *testing.T) *nats.Conn {
+	t.Helper()
+	s, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating the server: %v", err)
+	}
+	go s.Start()
+	t.Cleanup(s.Shutdown)
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("Expected the server to start")
+	}
+	nc, err := nats.Connect(s.ClientURL())
+	if err != nil {
+		t.Fatalf("Unexpected error connecting: %v", err)
+	}
+	t.Cleanup(nc.Close)
+	return nc
+}
+
+// run runs a source until fn was called n times, and returns the payloads of
+// the messages by key.
+func run(t *testing.T, s *Source[string], n int, fn func(m jetstream.Msg) error, publish func()) map[string][]int {
+	t.Helper()
+	var mu sync.Mutex
+	got := map[string][]int{}
+	count := 0
+	enough := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx, func(ctx context.Context, m jetstream.Msg) error {
+			seq, _ := strconv.Atoi(string(m.Data()))
+			mu.Lock()
+			defer mu.Unlock()
+			key := s.key(m)
+			got[key] = append(got[key], seq)
+			if count++; count == n {
+				close(enough)
+			}
+			return fn(m)
+		})
+	}()
+	publish()
+
+	select {
+	case <-enough:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Expected %d messages, got %d", n, count)
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Unexpected error from Run: %v", err)
+	}
+	return got
+}
+
+func TestSubscribe(t *testing.T) {
+	nc := newServer(t)
+	s := Subscribe(nc, "orders.*", 4, 10, SubjectToken(1))
+
+	accounts := []string{"a", "b", "c", "d", "e"}
+	got := run(t, s, 50, func(jetstream.Msg) error { return nil }, func() {
+		// wait for the subscription
+		time.Sleep(100 * time.Millisecond)
+		for seq := range 10 {
+			for _, account := range accounts {
+				if err := nc.Publish("orders."+account, []byte(strconv.Itoa(seq))); err != nil {
+					t.Errorf("Unexpected error publishing: %v", err)
+				}
+			}
+		}
+	})
+	for _, account := range accounts {
+		if !slices.Equal(got[account], []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}) {
+			t.Errorf("Expected the messages of %s in order, got %v", account, got[account])
+		}
+	}
+}
+
+func TestConsume(t *testing.T) {
+	nc := newServer(t)
+	js, err := jetstream.New(nc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	stream, err := js.CreateStream(ctx, jetstream.StreamConfig{Name: "ORDERS", Subjects: []string{"orders"}})
+	if err != nil {
+		t.Fatalf("Unexpected error creating the stream: %v", err)
+	}
+	cons, err := stream.CreateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:    "billing",
+		AckPolicy:  jetstream.AckExplicitPolicy,
+		AckWait:    5 * time.Second,
+		MaxDeliver: 2,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error creating the consumer: %v", err)
+	}
+
+	var mu sync.Mutex
+	var deadLetters []string
+	s := Consume(cons, 4, 10, HeaderKey("account"), WithDeadLetter(func(m jetstream.Msg, err error) {
+		md, _ := m.Metadata()
+		mu.Lock()
+		defer mu.Unlock()
+		deadLetters = append(deadLetters, m.Headers().Get("account")+" "+strconv.Itoa(int(md.NumDelivered)))
+	}))
+
+	// the message of b fails on both deliveries, the one of c is permanent
+	got := run(t, s, 5, func(m jetstream.Msg) error {
+		switch m.Headers().Get("account") {
+		case "b":
+			return errors.New("unavailable")
+		case "c":
+			return shardqueue.Permanent(errors.New("invalid"))
+		}
+		return nil
+	}, func() {
+		for _, account := range []string{"a", "b", "c", "a"} {
+			msg := nats.NewMsg("orders")
+			msg.Header.Set("account", account)
+			msg.Data = []byte("1")
+			if _, err := js.PublishMsg(ctx, msg); err != nil {
+				t.Errorf("Unexpected error publishing: %v", err)
+			}
+		}
+	})
+
+	if len(got["a"]) != 2 || len(got["b"]) != 2 || len(got["c"]) != 1 {
+		t.Errorf("Expected b redelivered once, got %v", got)
+	}
+	mu.Lock()
+	slices.Sort(deadLetters)
+	if !slices.Equal(deadLetters, []string{"b 2", "c 1"}) {
+		t.Errorf("Expected b dead-lettered on its last delivery and c at once, got %v", deadLetters)
+	}
+	mu.Unlock()
+
+	if err := nc.Flush(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info, err := cons.Info(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info.NumAckPending != 0 || info.NumPending != 0 {
+		t.Errorf("Expected every message acked or terminated, got %d pending acks and %d pending", info.NumAckPending, info.NumPending)
+	}
+}
+
+func TestSubjectToken(t *testing.T) {
+	m := &coreMsg{&nats.Msg{Subject: "orders.eu.a"}}
+	tests := []struct {
+		i    int
+		want string
+	}{{0, "orders"}, {2, "a"}, {-1, "a"}, {-2, "eu"}, {3, ""}, {-4, ""}}
+	for _, tt := range tests {
+		if got := SubjectToken(tt.i)(m); got != tt.want {
+			t.Errorf("Expected token %d to be %q, got %q", tt.i, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Package shardqueueprom exports the per-shard metrics of a shard queue to
+// Prometheus.
+package shardqueueprom
+
+import (
+	"strconv"
+
+	"github.com/joripage/go_util/pkg/shardqueue"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// StatsSource is implemented by every shardqueue.ShardQueue.
+type StatsSource interface {
+	Stats() []shardqueue.ShardStats
+}
+
+// Collector is a prometheus.Collector reading the stats of a queue on every
+// scrape. The metrics are labeled by queue name and shard index.
+type Collector struct {
+	name   string
+	source StatsSource
+
+	depth     *prometheus.Desc
+	enqueued  *prometheus.Desc
+	processed *prometheus.Desc
+	failed    *prometheus.Desc
+	expired   *prometheus.Desc
+	dropped   *prometheus.Desc
+	latency   *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector returns a Collector of the queue source, named name in the
+// queue label. Register one per queue, e.g.
+// prometheus.MustRegister(shardqueueprom.NewCollector("orders", sq)).
+func NewCollector(name string, source StatsSource) *Collector {
+	labels := []string{"queue", "shard"}
+	return &Collector{
+		name:   name,
+		source: source,
+		depth: prometheus.NewDesc("shardqueue_depth",
+			"Number of messages waiting in the shard.", labels, nil),
+		enqueued: prometheus.NewDesc("shardqueue_enqueued_total",
+			"Number of messages queued in the shard.", labels, nil),
+		processed: prometheus.NewDesc("shardqueue_processed_total",
+			"Number of messages processed by the shard.", labels, nil),
+		failed: prometheus.NewDesc("shardqueue_failed_total",
+			"Number of messages the process function failed for.", labels, nil),
+		expired: prometheus.NewDesc("shardqueue_expired_total",
+			"Number of messages dropped because of their TTL.", labels, nil),
+		dropped: prometheus.NewDesc("shardqueue_dropped_total",
+			"Number of messages dropped or rejected because the shard was full.", labels, nil),
+		latency: prometheus.NewDesc("shardqueue_processing_seconds",
+			"Processing time of the messages of the shard.", labels, nil),
+	}
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.depth
+	ch <- c.enqueued
+	ch <- c.processed
+	ch <- c.failed
+	ch <- c.expired
+	ch <- c.dropped
+	ch <- c.latency
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range c.source.Stats() {
+		shard := strconv.Itoa(s.Shard)
+		ch <- prometheus.MustNewConstMetric(c.depth, prometheus.GaugeValue, float64(s.Depth), c.name, shard)
+		ch <- prometheus.MustNewConstMetric(c.enqueued, prometheus.CounterValue, float64(s.Enqueued), c.name, shard)
+		ch <- prometheus.MustNewConstMetric(c.processed, prometheus.CounterValue, float64(s.Processed), c.name, shard)
+		ch <- prometheus.MustNewConstMetric(c.failed, prometheus.CounterValue, float64(s.Failed), c.name, shard)
+		ch <- prometheus.MustNewConstMetric(c.expired, prometheus.CounterValue, float64(s.Expired), c.name, shard)
+		ch <- prometheus.MustNewConstMetric(c.dropped, prometheus.CounterValue, float64(s.Dropped), c.name, shard)
+
+		buckets := make(map[float64]uint64, len(shardqueue.LatencyBuckets))
+		for i, bound := range shardqueue.LatencyBuckets {
+			buckets[bound.Seconds()] = s.LatencyHistogram[i]
+		}
+		ch <- prometheus.MustNewConstHistogram(c.latency, s.Processed, s.LatencySum.Seconds(), buckets, c.name, shard)
+	}
+}
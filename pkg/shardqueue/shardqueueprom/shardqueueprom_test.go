@@ -0,0 +1,42 @@
+package shardqueueprom
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/joripage/go_util/pkg/shardqueue"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector(t *testing.T) {
+	sq := shardqueue.NewShardQueue[string, int](2, 10)
+	_ = sq.Start(context.Background(), func(ctx context.Context, msg int) error { return nil })
+	for msg := range 3 {
+		_ = sq.Shard("key", msg)
+	}
+	sq.Stop()
+
+	reg := prometheus.NewPedanticRegistry()
+	reg.MustRegister(NewCollector("orders", sq))
+
+	// 7 series per shard
+	if n, err := testutil.GatherAndCount(reg); err != nil || n != 14 {
+		t.Fatalf("Expected 14 series, got %d (%v)", n, err)
+	}
+	stats := sq.Stats()
+	want := fmt.Sprintf(`
+# HELP shardqueue_processed_total Number of messages processed by the shard.
+# TYPE shardqueue_processed_total counter
+shardqueue_processed_total{queue="orders",shard="0"} %d
+shardqueue_processed_total{queue="orders",shard="1"} %d
+`, stats[0].Processed, stats[1].Processed)
+	if stats[0].Processed+stats[1].Processed != 3 {
+		t.Fatalf("Expected 3 processed messages, got %+v", stats)
+	}
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), "shardqueue_processed_total"); err != nil {
+		t.Error(err)
+	}
+}
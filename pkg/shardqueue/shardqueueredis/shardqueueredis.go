@@ -0,0 +1,507 @@
+// Package shardqueueredis shares the shards of a queue between processes
+// with Redis Streams.
+//
+// Every shard is a stream read by a consumer group. Shard appends a message
+// to the stream of its shard, from any process. Start leases a share of the
+// shards in Redis and processes their messages in a local shard queue, so
+// the messages of a key are processed in order by one process at a time. The
+// shards are rebalanced as processes start and stop. The shards of a process
+// that stopped renewing its leases, e.g. after a crash, are taken over once
+// the leases expire, and their next owner claims the messages left pending
+// before reading the new ones.
+package shardqueueredis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/joripage/go_util/pkg/shardqueue"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DefaultKeyPrefix = "shardqueue:"
+	DefaultLeaseTTL  = 15 * time.Second
+	// DefaultReadCount is how many messages of a shard are read at once.
+	DefaultReadCount = 100
+)
+
+var (
+	renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// Queue is a shard queue whose shards are Redis streams shared by the
+// processes starting it.
+type Queue[K comparable, V any] struct {
+	client   redis.UniversalClient
+	name     string
+	numShard int
+	codec    shardqueue.Codec[K, V]
+	local    *shardqueue.ShardQueue[K, entry[K, V]]
+	consumer string
+	config
+
+	mu      sync.Mutex
+	started bool
+	cancel  context.CancelFunc
+	leases  map[int]*lease // owned shards
+	done    chan struct{}
+}
+
+type Option func(*config)
+
+type config struct {
+	prefix    string
+	leaseTTL  time.Duration
+	readCount int64
+	maxLen    int64
+	queueOpts []shardqueue.Option
+}
+
+// WithKeyPrefix prefixes the Redis keys of the queue.
+func WithKeyPrefix(prefix string) Option {
+	return func(c *config) {
+		c.prefix = prefix
+	}
+}
+
+// WithLeaseTTL sets how long the shards of a process that stopped renewing
+// its leases stay unprocessed. The leases are renewed, and the shards
+// rebalanced, every third of it.
+func WithLeaseTTL(d time.Duration) Option {
+	return func(c *config) {
+		c.leaseTTL = d
+	}
+}
+
+// WithMaxLen trims the streams to about n messages. Messages trimmed before
+// they were processed are lost, so n must exceed the backlog of a shard.
+func WithMaxLen(n int64) Option {
+	return func(c *config) {
+		c.maxLen = n
+	}
+}
+
+// WithQueueOptions configures the local shard queue, except for
+// shardqueue.WithOnHandled which the queue uses to ack the messages. Every
+// process must route the keys alike, so the keys are hashed with a stable
+// hasher unless shardqueue.WithHasher is set, see New.
+func WithQueueOptions(opts ...shardqueue.Option) Option {
+	return func(c *config) {
+		c.queueOpts = append(c.queueOpts, opts...)
+	}
+}
+
+// New returns a Queue named name, of numShard streams whose messages are
+// encoded with codec. The local shard queue processing the leased shards
+// holds queueSize messages per shard. Every process sharing the queue must
+// use the same name, number of shards and routing. String and integer keys
+// are routed by the default hasher of shardqueue, which is stable across
+// processes, and other keys by shardqueue.FNV of their %v form, so they must
+// print alike in every process: no pointers or maps.
+func New[K comparable, V any](client redis.UniversalClient, name string, numShard, queueSize int, codec shardqueue.Codec[K, V], opts ...Option) *Queue[K, V] {
+	q := &Queue[K, V]{
+		client:   client,
+		name:     name,
+		numShard: numShard,
+		codec:    codec,
+		consumer: newToken(),
+		config: config{
+			prefix:    DefaultKeyPrefix,
+			leaseTTL:  DefaultLeaseTTL,
+			readCount: DefaultReadCount,
+		},
+		leases: map[int]*lease{},
+	}
+	for _, opt := range opts {
+		opt(&q.config)
+	}
+
+	queueOpts := []shardqueue.Option{}
+	if !stableByDefault[K]() {
+		queueOpts = append(queueOpts, shardqueue.WithHasher(func(key K) uint64 {
+			return shardqueue.FNV(fmt.Sprint(key))
+		}))
+	}
+	queueOpts = append(queueOpts, q.queueOpts...)
+	queueOpts = append(queueOpts, shardqueue.WithOnHandled(q.handled))
+	q.local = shardqueue.NewShardQueue[K, entry[K, V]](numShard, queueSize, queueOpts...)
+	return q
+}
+
+// stableByDefault reports whether the default hasher of shardqueue routes
+// the keys of type K alike in every process.
+func stableByDefault[K comparable]() bool {
+	switch reflect.TypeFor[K]().Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// entry is a message read from the stream of a shard.
+type entry[K comparable, V any] struct {
+	lease *lease
+	id    string
+	msg   V
+}
+
+// lease is a shard owned by the process.
+type lease struct {
+	shard    int
+	cancel   context.CancelFunc // stops reading the shard
+	read     chan struct{}      // closed once the shard isn't read anymore
+	inflight atomic.Int64       // messages read and not acked yet
+	draining bool
+}
+
+// drained reports whether the shard isn't read anymore and its messages
+// were all acked.
+func (l *lease) drained() bool {
+	select {
+	case <-l.read:
+		return l.inflight.Load() == 0
+	default:
+		return false
+	}
+}
+
+func (q *Queue[K, V]) key(parts ...string) string {
+	return q.prefix + q.name + ":" + strings.Join(parts, ":")
+}
+
+func (q *Queue[K, V]) stream(shard int) string {
+	return q.key(strconv.Itoa(shard))
+}
+
+func (q *Queue[K, V]) leaseKey(shard int) string {
+	return q.key(strconv.Itoa(shard), "owner")
+}
+
+// Shard appends the message to the stream of the shard of the key. It
+// doesn't need the queue to be started.
+func (q *Queue[K, V]) Shard(routingKey K, msg V) error {
+	data, err := q.codec.Encode(routingKey, msg)
+	if err != nil {
+		return err
+	}
+	return q.client.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: q.stream(q.WhichShard(routingKey)),
+		MaxLen: q.maxLen,
+		Approx: q.maxLen > 0,
+		Values: []string{"data", string(data)},
+	}).Err()
+}
+
+// WhichShard returns the shard of a routing key.
+func (q *Queue[K, V]) WhichShard(key K) int {
+	return q.local.WhichShard(key)
+}
+
+// Start creates the consumer groups of the streams if needed, and processes
+// the messages of the shards leased by the process with fn until Stop is
+// called or ctx is done.
+func (q *Queue[K, V]) Start(ctx context.Context, fn func(ctx context.Context, msg V) error) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.started {
+		return shardqueue.ErrAlreadyStarted
+	}
+	for shard := range q.numShard {
+		err := q.client.XGroupCreateMkStream(ctx, q.stream(shard), q.name, "0").Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return err
+		}
+	}
+	if err := q.local.Start(ctx, func(ctx context.Context, e entry[K, V]) error {
+		return fn(ctx, e.msg)
+	}); err != nil {
+		return err
+	}
+
+	q.started = true
+	ctx, q.cancel = context.WithCancel(ctx)
+	q.done = make(chan struct{})
+	go q.balance(ctx)
+	return nil
+}
+
+// Stop stops reading the shards, waits for the messages read to be
+// processed, then releases the shards for the other processes.
+func (q *Queue[K, V]) Stop() {
+	q.mu.Lock()
+	if !q.started {
+		q.mu.Unlock()
+		return
+	}
+	q.cancel()
+	q.mu.Unlock()
+	<-q.done
+
+	q.local.Stop()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), q.leaseTTL)
+	defer cancel()
+	for _, l := range q.leases {
+		q.release(ctx, l)
+	}
+	if err := q.client.ZRem(ctx, q.key("consumers"), q.consumer).Err(); err != nil {
+		log.Printf("Redis queue %s: %v", q.name, err)
+	}
+}
+
+// Owned returns the shards leased by the process.
+func (q *Queue[K, V]) Owned() []int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	shards := make([]int, 0, len(q.leases))
+	for shard := range q.leases {
+		shards = append(shards, shard)
+	}
+	slices.Sort(shards)
+	return shards
+}
+
+// Stats returns the stats of the local shard queue.
+func (q *Queue[K, V]) Stats() []shardqueue.ShardStats {
+	return q.local.Stats()
+}
+
+// balance rebalances the shards every third of the lease TTL until ctx is
+// done, then stops reading them.
+func (q *Queue[K, V]) balance(ctx context.Context) {
+	defer close(q.done)
+	ticker := time.NewTicker(q.leaseTTL / 3)
+	defer ticker.Stop()
+	for {
+		q.rebalance(ctx)
+		select {
+		case <-ctx.Done():
+			q.mu.Lock()
+			leases := slices.Collect(maps.Values(q.leases))
+			q.mu.Unlock()
+			for _, l := range leases {
+				l.cancel()
+				<-l.read
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// rebalance registers the process as alive, renews its leases and leases or
+// releases shards for every live process to own its share.
+func (q *Queue[K, V]) rebalance(ctx context.Context) {
+	consumers := q.key("consumers")
+	now := time.Now()
+	var live *redis.IntCmd
+	_, err := q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, consumers, redis.Z{Score: float64(now.Add(q.leaseTTL).UnixMilli()), Member: q.consumer})
+		pipe.ZRemRangeByScore(ctx, consumers, "-inf", strconv.FormatInt(now.UnixMilli(), 10))
+		live = pipe.ZCard(ctx, consumers)
+		return nil
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Redis queue %s: %v", q.name, err)
+		}
+		return
+	}
+	n := max(int(live.Val()), 1)
+	share := (q.numShard + n - 1) / n
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	owned := 0
+	for shard, l := range q.leases {
+		if !q.renew(ctx, l) {
+			log.Printf("Redis queue %s: lost shard %d", q.name, shard)
+			l.cancel()
+			delete(q.leases, shard)
+			continue
+		}
+		if l.draining && l.drained() {
+			q.release(ctx, l)
+			continue
+		}
+		if !l.draining {
+			owned++
+		}
+	}
+
+	// release the surplus, the highest shards first
+	for shard := q.numShard - 1; shard >= 0 && owned > share; shard-- {
+		if l := q.leases[shard]; l != nil && !l.draining {
+			l.draining = true
+			l.cancel()
+			owned--
+		}
+	}
+	for shard := 0; shard < q.numShard && owned < share; shard++ {
+		if q.leases[shard] != nil {
+			continue
+		}
+		ok, err := q.client.SetNX(ctx, q.leaseKey(shard), q.consumer, q.leaseTTL).Result()
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Redis queue %s: %v", q.name, err)
+			}
+			return
+		}
+		if ok {
+			q.read(ctx, shard)
+			owned++
+		}
+	}
+}
+
+func (q *Queue[K, V]) renew(ctx context.Context, l *lease) bool {
+	n, err := renewScript.Run(ctx, q.client, []string{q.leaseKey(l.shard)}, q.consumer, q.leaseTTL.Milliseconds()).Int()
+	// keep the lease on errors, it expires if they last
+	return err != nil || n == 1
+}
+
+func (q *Queue[K, V]) release(ctx context.Context, l *lease) {
+	delete(q.leases, l.shard)
+	if err := releaseScript.Run(ctx, q.client, []string{q.leaseKey(l.shard)}, q.consumer).Err(); err != nil {
+		log.Printf("Redis queue %s: release shard %d: %v", q.name, l.shard, err)
+	}
+}
+
+// read starts reading a leased shard.
+func (q *Queue[K, V]) read(ctx context.Context, shard int) {
+	ctx, cancel := context.WithCancel(ctx)
+	l := &lease{shard: shard, cancel: cancel, read: make(chan struct{})}
+	q.leases[shard] = l
+	go func() {
+		defer close(l.read)
+		q.claim(ctx, l)
+		q.consume(ctx, l)
+	}()
+}
+
+// claim queues the messages of the shard left pending by its previous
+// owners, in order.
+func (q *Queue[K, V]) claim(ctx context.Context, l *lease) {
+	start := "0-0"
+	for ctx.Err() == nil {
+		msgs, next, err := q.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   q.stream(l.shard),
+			Group:    q.name,
+			Consumer: q.consumer,
+			Start:    start,
+			Count:    q.readCount,
+		}).Result()
+		if err != nil {
+			q.retry(ctx, l, err)
+			continue
+		}
+		if !q.queue(ctx, l, msgs) || next == "0-0" {
+			return
+		}
+		start = next
+	}
+}
+
+// consume queues the new messages of the shard until ctx is done.
+func (q *Queue[K, V]) consume(ctx context.Context, l *lease) {
+	for ctx.Err() == nil {
+		streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    q.name,
+			Consumer: q.consumer,
+			Streams:  []string{q.stream(l.shard), ">"},
+			Count:    q.readCount,
+			Block:    q.leaseTTL / 3,
+		}).Result()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			q.retry(ctx, l, err)
+			continue
+		}
+		for _, s := range streams {
+			if !q.queue(ctx, l, s.Messages) {
+				return
+			}
+		}
+	}
+}
+
+func (q *Queue[K, V]) retry(ctx context.Context, l *lease, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	log.Printf("Redis queue %s: read shard %d: %v", q.name, l.shard, err)
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+	}
+}
+
+// queue hands the messages to the local shard queue, and returns false if it
+// was interrupted. The messages not queued stay pending.
+func (q *Queue[K, V]) queue(ctx context.Context, l *lease, msgs []redis.XMessage) bool {
+	for _, m := range msgs {
+		data, _ := m.Values["data"].(string)
+		key, msg, err := q.codec.Decode([]byte(data))
+		if err != nil {
+			log.Printf("Redis queue %s: message %s dropped: %v", q.name, m.ID, err)
+			q.ack(l.shard, m.ID)
+			continue
+		}
+		l.inflight.Add(1)
+		if err := q.local.ShardCtx(ctx, key, entry[K, V]{lease: l, id: m.ID, msg: msg}); err != nil {
+			l.inflight.Add(-1)
+			return false
+		}
+	}
+	return true
+}
+
+// handled acks a message the local queue is done with.
+func (q *Queue[K, V]) handled(msg any) {
+	e := msg.(entry[K, V])
+	q.ack(e.lease.shard, e.id)
+	e.lease.inflight.Add(-1)
+}
+
+func (q *Queue[K, V]) ack(shard int, id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), q.leaseTTL)
+	defer cancel()
+	if err := q.client.XAck(ctx, q.stream(shard), q.name, id).Err(); err != nil {
+		log.Printf("Redis queue %s: ack %s: %v", q.name, id, err)
+	}
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
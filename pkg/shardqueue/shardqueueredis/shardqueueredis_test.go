@@ -0,0 +1,219 @@
+package shardqueueredis
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/joripage/go_util/pkg/shardqueue"
+	"github.com/redis/go-redis/v9"
+)
+
+const ttl = 300 * time.Millisecond
+
+var keys = []string{"a", "b", "c", "d", "e"}
+
+func newClient(t *testing.T) (*redis.Client, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client, mr
+}
+
+func newQueue(client *redis.Client) *Queue[string, int] {
+	return New(client, "orders", 4, 10, shardqueue.JSONCodec[string, int]{}, WithLeaseTTL(ttl))
+}
+
+// recorder records the messages processed, as msg = seq*5 + index of the
+// key, by key and by process.
+type recorder struct {
+	mu          sync.Mutex
+	got         map[string][]int
+	processedBy map[string]map[*Queue[string, int]]bool
+}
+
+func newRecorder() *recorder {
+	return &recorder{got: map[string][]int{}, processedBy: map[string]map[*Queue[string, int]]bool{}}
+}
+
+func (r *recorder) process(q *Queue[string, int]) func(ctx context.Context, msg int) error {
+	return func(ctx context.Context, msg int) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		key := keys[msg%5]
+		r.got[key] = append(r.got[key], msg/5)
+		if r.processedBy[key] == nil {
+			r.processedBy[key] = map[*Queue[string, int]]bool{}
+		}
+		r.processedBy[key][q] = true
+		return nil
+	}
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, msgs := range r.got {
+		n += len(msgs)
+	}
+	return n
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestQueue(t *testing.T) {
+	client, mr := newClient(t)
+	ctx := context.Background()
+
+	a, b := newQueue(client), newQueue(client)
+	rec := newRecorder()
+	for _, q := range []*Queue[string, int]{a, b} {
+		if err := q.Start(ctx, rec.process(q)); err != nil {
+			t.Fatalf("Unexpected error starting: %v", err)
+		}
+	}
+	if err := a.Start(ctx, rec.process(a)); err != shardqueue.ErrAlreadyStarted {
+		t.Errorf("Expected ErrAlreadyStarted, got %v", err)
+	}
+
+	// every process leases its share of the shards
+	waitFor(t, func() bool { return len(a.Owned()) == 2 && len(b.Owned()) == 2 })
+	if shards := slices.Sorted(slices.Values(append(a.Owned(), b.Owned()...))); !slices.Equal(shards, []int{0, 1, 2, 3}) {
+		t.Errorf("Expected every shard leased once, got %v", shards)
+	}
+
+	// any process can queue messages, even one that isn't started
+	producer := newQueue(client)
+	for seq := range 10 {
+		for i, key := range keys {
+			if err := producer.Shard(key, seq*5+i); err != nil {
+				t.Fatalf("Unexpected error queuing: %v", err)
+			}
+		}
+	}
+	waitFor(t, func() bool { return rec.count() == 50 })
+
+	rec.mu.Lock()
+	for _, key := range keys {
+		if !slices.Equal(rec.got[key], []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}) {
+			t.Errorf("Expected the messages of %s in order, got %v", key, rec.got[key])
+		}
+		if len(rec.processedBy[key]) != 1 {
+			t.Errorf("Expected the messages of %s processed by one process, got %d", key, len(rec.processedBy[key]))
+		}
+	}
+	rec.mu.Unlock()
+
+	// the shards of a stopped process are taken over
+	a.Stop()
+	waitFor(t, func() bool { return len(b.Owned()) == 4 })
+	b.Stop()
+	for shard := range 4 {
+		if mr.Exists(b.leaseKey(shard)) {
+			t.Errorf("Expected the lease of shard %d released", shard)
+		}
+	}
+	if members, _ := mr.ZMembers(b.key("consumers")); len(members) != 0 {
+		t.Errorf("Expected the stopped processes unregistered, got %v", members)
+	}
+}
+
+func TestQueue_ClaimsPending(t *testing.T) {
+	client, mr := newClient(t)
+	ctx := context.Background()
+
+	q := newQueue(client)
+	shard := q.WhichShard("a")
+	stream := q.stream(shard)
+	for seq := range 3 {
+		if err := q.Shard("a", seq*5); err != nil {
+			t.Fatalf("Unexpected error queuing: %v", err)
+		}
+	}
+
+	// a crashed process read 2 messages and still holds the shard
+	if err := client.XGroupCreateMkStream(ctx, stream, "orders", "0").Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := client.XReadGroup(ctx, &redis.XReadGroupArgs{Group: "orders", Consumer: "crashed", Streams: []string{stream, ">"}, Count: 2}).Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := client.Set(ctx, q.leaseKey(shard), "crashed", ttl).Err(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	rec := newRecorder()
+	if err := q.Start(ctx, rec.process(q)); err != nil {
+		t.Fatalf("Unexpected error starting: %v", err)
+	}
+	defer q.Stop()
+	waitFor(t, func() bool { return len(q.Owned()) == 3 })
+	time.Sleep(100 * time.Millisecond)
+	if n := rec.count(); n != 0 {
+		t.Errorf("Expected the leased shard not to be processed, got %d messages", n)
+	}
+
+	mr.FastForward(ttl)
+	waitFor(t, func() bool { return rec.count() == 3 })
+	if !slices.Equal(rec.got["a"], []int{0, 1, 2}) {
+		t.Errorf("Expected the pending messages claimed first, got %v", rec.got["a"])
+	}
+	waitFor(t, func() bool {
+		pending, err := client.XPending(ctx, stream, "orders").Result()
+		return err == nil && pending.Count == 0
+	})
+}
+
+type route struct {
+	Region  string
+	Account int
+}
+
+func TestNew_StableRouting(t *testing.T) {
+	client, _ := newClient(t)
+	ctx := context.Background()
+
+	// two processes
+	first := New(client, "routes", 8, 10, shardqueue.JSONCodec[route, int]{})
+	second := New(client, "routes", 8, 10, shardqueue.JSONCodec[route, int]{})
+	shards := map[int]bool{}
+	for i := range 20 {
+		key := route{Region: "eu", Account: i}
+		shard := first.WhichShard(key)
+		if again := second.WhichShard(key); again != shard {
+			t.Errorf("Expected %v on shard %d in both processes, got %d", key, shard, again)
+		}
+		shards[shard] = true
+	}
+	if len(shards) < 2 {
+		t.Errorf("Expected the keys spread over the shards, got %v", shards)
+	}
+
+	key := route{Region: "us", Account: 42}
+	_ = first.Shard(key, 1)
+	_ = second.Shard(key, 2)
+	if n := client.XLen(ctx, first.stream(first.WhichShard(key))).Val(); n != 2 {
+		t.Errorf("Expected both messages in the stream of the key, got %d", n)
+	}
+
+	byID := New(client, "ids", 8, 10, shardqueue.JSONCodec[int64, int]{})
+	byIDAgain := New(client, "ids", 8, 10, shardqueue.JSONCodec[int64, int]{})
+	for _, id := range []int64{-1, 0, 7, 1 << 40} {
+		if byID.WhichShard(id) != byIDAgain.WhichShard(id) {
+			t.Errorf("Expected key %d on the same shard in both processes", id)
+		}
+	}
+}
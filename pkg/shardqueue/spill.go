@@ -0,0 +1,389 @@
+package shardqueue
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Codec encodes the routing key and the message of the spilled messages, see
+// WithSpill.
+type Codec[K comparable, V any] interface {
+	Encode(key K, msg V) ([]byte, error)
+	Decode(data []byte) (K, V, error)
+}
+
+// JSONCodec is a Codec encoding the key and the message with encoding/json.
+type JSONCodec[K comparable, V any] struct{}
+
+type jsonRecord[K comparable, V any] struct {
+	Key K `json:"key"`
+	Msg V `json:"msg"`
+}
+
+func (JSONCodec[K, V]) Encode(key K, msg V) ([]byte, error) {
+	return json.Marshal(jsonRecord[K, V]{Key: key, Msg: msg})
+}
+
+func (JSONCodec[K, V]) Decode(data []byte) (K, V, error) {
+	var r jsonRecord[K, V]
+	err := json.Unmarshal(data, &r)
+	return r.Key, r.Msg, err
+}
+
+// WithSpill spills the messages of a full shard to segment files in a
+// directory of dir per shard, encoded with codec, instead of blocking or
+// dropping them. They are replayed to the shard in order as it frees up, and
+// the messages of a shard with spilled messages are spilled after them, so
+// the messages of a key stay in order. The directories are cleared on Start
+// and removed once the workers return. The overflow policy only applies if
+// writing to disk fails. It has no effect with WithPriorities. With
+// WithOnHandled, the spilled messages are also kept in memory, to report
+// them if they can't be read back from disk.
+//
+// The codec must be a Codec of the key and message types of the queue, e.g.
+// JSONCodec[K, V]{}, New returns ErrOptionType otherwise.
+func WithSpill(dir string, codec any) Option {
+	return func(o *options) {
+		o.spillDir = dir
+		o.codec = codec
+	}
+}
+
+// spillSegmentSize is the size past which a new segment file is started, so
+// the replayed segments can be removed during long bursts.
+const spillSegmentSize = 4 << 20
+
+// spill holds the spilled messages of a shard in segment files, written to
+// by the senders and read by the replay goroutine of the shard.
+type spill[K comparable, V any] struct {
+	dir     string
+	codec   Codec[K, V]
+	wal     *wal          // of the shard, see WithWAL
+	keepMsg bool          // see WithOnHandled
+	pending chan struct{} // signaled when a message is spilled
+
+	mu    sync.Mutex
+	n     int           // spilled messages not yet replayed
+	refs  []spillRef[V] // of the spilled messages, in order
+	w     *os.File
+	wseq  int // segment written to
+	wsize int
+
+	// only used by the replay goroutine
+	r    *os.File
+	rbuf *bufio.Reader
+	rseq int // segment read from
+}
+
+// spillRef holds what a spilled message keeps in memory, to account for it
+// if it can't be read back.
+type spillRef[V any] struct {
+	gen    *flushGen // see Flush
+	future *Future   // see ShardAsync
+	seq    uint64    // in the WAL
+	msg    V         // only if keepMsg
+}
+
+// spillHeaderSize is the size of the header of a record: the length of the
+// encoded message, the enqueue time, the deadline and the WAL sequence number.
+const spillHeaderSize = 4 + 8 + 8 + 8
+
+func codecOf[K comparable, V any](codec any) (Codec[K, V], error) {
+	c, ok := codec.(Codec[K, V])
+	if !ok {
+		var key K
+		var msg V
+		return nil, fmt.Errorf("%w: codec %T doesn't encode %T keys and %T messages", ErrOptionType, codec, key, msg)
+	}
+	return c, nil
+}
+
+// newSpill clears the directory of the spilled messages of a shard.
+func newSpill[K comparable, V any](dir string, codec Codec[K, V]) (*spill[K, V], error) {
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &spill[K, V]{
+		dir:     dir,
+		codec:   codec,
+		pending: make(chan struct{}, 1),
+	}, nil
+}
+
+func (s *spill[K, V]) segment(seq int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d.spill", seq))
+}
+
+// append writes e at the end of the last segment.
+func (s *spill[K, V]) append(e envelope[K, V]) error {
+	data, err := s.codec.Encode(e.key, e.msg)
+	if err != nil {
+		return err
+	}
+	if s.w == nil {
+		if s.w, err = os.OpenFile(s.segment(s.wseq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+			return err
+		}
+	}
+
+	record := make([]byte, spillHeaderSize+len(data))
+	binary.LittleEndian.PutUint32(record, uint32(len(data)))
+	binary.LittleEndian.PutUint64(record[4:], uint64(e.enqueuedAt.UnixNano()))
+	var deadline int64
+	if !e.deadline.IsZero() {
+		deadline = e.deadline.UnixNano()
+	}
+	binary.LittleEndian.PutUint64(record[12:], uint64(deadline))
+	binary.LittleEndian.PutUint64(record[20:], e.seq)
+	copy(record[spillHeaderSize:], data)
+	if _, err := s.w.Write(record); err != nil {
+		return err
+	}
+
+	s.n++
+	ref := spillRef[V]{gen: e.gen, future: e.future, seq: e.seq}
+	if s.keepMsg {
+		ref.msg = e.msg
+	}
+	s.refs = append(s.refs, ref)
+	s.wsize += len(record)
+	if s.wsize >= spillSegmentSize {
+		s.w.Close()
+		s.w = nil
+		s.wseq++
+		s.wsize = 0
+	}
+	signal(s.pending)
+	return nil
+}
+
+// next reads the oldest spilled message not yet replayed, if any.
+func (s *spill[K, V]) next() (envelope[K, V], bool, error) {
+	var e envelope[K, V]
+	s.mu.Lock()
+	n := s.n
+	s.mu.Unlock()
+	if n == 0 {
+		return e, false, nil
+	}
+
+	for {
+		if s.r == nil {
+			r, err := os.Open(s.segment(s.rseq))
+			if err != nil {
+				return e, false, err
+			}
+			s.r, s.rbuf = r, bufio.NewReader(r)
+		}
+		var header [spillHeaderSize]byte
+		_, err := io.ReadFull(s.rbuf, header[:])
+		if err == io.EOF {
+			// the message was spilled after the segment was full
+			s.r.Close()
+			os.Remove(s.segment(s.rseq))
+			s.r = nil
+			s.rseq++
+			continue
+		}
+		if err != nil {
+			return e, false, err
+		}
+
+		data := make([]byte, binary.LittleEndian.Uint32(header[:]))
+		if _, err := io.ReadFull(s.rbuf, data); err != nil {
+			return e, false, err
+		}
+		if e.key, e.msg, err = s.codec.Decode(data); err != nil {
+			return e, false, err
+		}
+		e.enqueuedAt = time.Unix(0, int64(binary.LittleEndian.Uint64(header[4:])))
+		if deadline := int64(binary.LittleEndian.Uint64(header[12:])); deadline != 0 {
+			e.deadline = time.Unix(0, deadline)
+		}
+		if s.wal != nil {
+			e.wal, e.seq = s.wal, binary.LittleEndian.Uint64(header[20:])
+		}
+		s.mu.Lock()
+		e.gen, e.future = s.refs[0].gen, s.refs[0].future
+		s.refs[0] = spillRef[V]{}
+		s.refs = s.refs[1:]
+		s.mu.Unlock()
+		return e, true, nil
+	}
+}
+
+// replayed accounts for a message handed to the shard, and starts over from
+// an empty segment once they all were.
+func (s *spill[K, V]) replayed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.n--
+	if s.n > 0 {
+		return
+	}
+	s.reset()
+}
+
+// reset removes the segments and returns the refs of the messages left,
+// which are not replayed.
+func (s *spill[K, V]) reset() []spillRef[V] {
+	if s.w != nil {
+		s.w.Close()
+		s.w = nil
+	}
+	if s.r != nil {
+		s.r.Close()
+		s.r, s.rbuf = nil, nil
+	}
+	for seq := s.rseq; seq <= s.wseq; seq++ {
+		os.Remove(s.segment(seq))
+	}
+	refs := s.refs
+	s.refs = nil
+	s.n = 0
+	s.wseq++
+	s.rseq = s.wseq
+	s.wsize = 0
+	return refs
+}
+
+func (s *spill[K, V]) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.n
+}
+
+// close drops the messages left and removes the directory. They are only
+// left if the workers were stopped by the context of Start, so they stay in
+// the WAL.
+func (s *spill[K, V]) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, ref := range s.reset() {
+		if ref.gen != nil {
+			ref.gen.done()
+		}
+		ref.future.resolve(ErrDropped)
+	}
+	if err := os.RemoveAll(s.dir); err != nil {
+		log.Printf("Spill %s: %v", s.dir, err)
+	}
+}
+
+// newSpills clears the spill directories of the shards.
+func (sq *ShardQueue[K, V]) newSpills() ([]*spill[K, V], error) {
+	spills := make([]*spill[K, V], sq.numShard)
+	for i := range spills {
+		s, err := newSpill(filepath.Join(sq.spillDir, strconv.Itoa(i)), sq.codec)
+		if err != nil {
+			return nil, fmt.Errorf("spill of shard %d: %w", i, err)
+		}
+		if sq.wals != nil {
+			s.wal = sq.wals[i]
+		}
+		s.keepMsg = sq.onHandled != nil
+		spills[i] = s
+	}
+	return spills, nil
+}
+
+// startReplays runs a replay goroutine per shard, once the shards exist.
+func (sq *ShardQueue[K, V]) startReplays(ctx context.Context) {
+	sq.spillsDone = make(chan struct{})
+	for i, s := range sq.spills {
+		sq.replayers.Add(1)
+		go func() {
+			defer sq.replayers.Done()
+			defer s.close()
+			sq.replay(ctx, i, s)
+		}()
+	}
+}
+
+// replay hands the spilled messages of a shard back to it, until ctx is done
+// or the queue is stopped and they were all replayed.
+func (sq *ShardQueue[K, V]) replay(ctx context.Context, shard int, s *spill[K, V]) {
+	for {
+		e, ok, err := s.next()
+		if err != nil {
+			log.Printf("Shard %d spill error, %d spilled message(s) dropped: %v", shard, s.len(), err)
+			s.mu.Lock()
+			refs := s.reset()
+			s.mu.Unlock()
+			sq.dropSpilled(shard, s, refs)
+			continue
+		}
+		if !ok {
+			select {
+			case <-s.pending:
+				continue
+			case <-ctx.Done():
+				return
+			case <-sq.spillsDone:
+				// the senders have returned, nothing is spilled anymore
+				if s.len() == 0 {
+					return
+				}
+				continue
+			}
+		}
+		if !sq.queue[shard].push(e, nil, ctx.Done()) {
+			return
+		}
+		s.replayed()
+	}
+}
+
+// dropSpilled handles the spilled messages that can't be read back like the
+// ones dropped by the overflow policy.
+func (sq *ShardQueue[K, V]) dropSpilled(shard int, s *spill[K, V], refs []spillRef[V]) {
+	for _, ref := range refs {
+		e := envelope[K, V]{msg: ref.msg, gen: ref.gen, future: ref.future}
+		if s.wal != nil {
+			e.wal, e.seq = s.wal, ref.seq
+		}
+		sq.handled(e)
+		e.future.resolve(ErrDropped)
+		sq.metrics[shard].dropped.Add(1)
+	}
+}
+
+// trySpill queues e in the shard, or spills it if the shard is full or
+// already has spilled messages. It reports false if spilling failed.
+func (sq *ShardQueue[K, V]) trySpill(shard int, e envelope[K, V]) bool {
+	s := sq.spills[shard]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.n == 0 && sq.queue[shard].tryPush(e) {
+		sq.metrics[shard].enqueued.Add(1)
+		return true
+	}
+	if err := s.append(e); err != nil {
+		log.Printf("Shard %d spill error: %v", shard, err)
+		return false
+	}
+	sq.metrics[shard].enqueued.Add(1)
+	return true
+}
+
+// spilled returns the number of spilled messages of the shard.
+func (sq *ShardQueue[K, V]) spilled(shard int) int {
+	if sq.spills == nil {
+		return 0
+	}
+	return sq.spills[shard].len()
+}
@@ -0,0 +1,145 @@
+package shardqueue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithSpill(t *testing.T) {
+	dir := t.TempDir()
+	sq, release, processed := fullQueue(t, WithSpill(dir, JSONCodec[string, int]{}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 2; i <= 100; i++ {
+			_ = sq.Shard("key", i)
+		}
+		if err := sq.TryShard("key", 101); err != nil {
+			t.Errorf("Expected TryShard to spill, got %v", err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected Shard not to block on a full shard")
+	}
+
+	if spilled := sq.Stats()[0].Spilled; spilled != 100 {
+		t.Errorf("Expected 100 spilled messages, got %d", spilled)
+	}
+	if segments, _ := filepath.Glob(filepath.Join(dir, "0", "*.spill")); len(segments) == 0 {
+		t.Error("Expected a spill segment on disk")
+	}
+
+	close(release)
+	sq.Stop()
+
+	want := make([]int, 102)
+	for i := range want {
+		want[i] = i
+	}
+	if got := processed(); !slices.Equal(got, want) {
+		t.Errorf("Expected the spilled messages replayed in order, got %v", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "0")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the spill directory removed, got %v", err)
+	}
+}
+
+func TestWithSpill_KeepsTTL(t *testing.T) {
+	var expired []any
+	sq, release, processed := fullQueue(t,
+		WithSpill(t.TempDir(), JSONCodec[string, int]{}),
+		WithOnExpired(func(shard int, msg any, waited time.Duration) {
+			expired = append(expired, msg)
+		}),
+	)
+
+	_ = sq.ShardWithTTL("key", 2, 10*time.Millisecond)
+	_ = sq.Shard("key", 3)
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	sq.Stop()
+
+	if got := processed(); !slices.Equal(got, []int{0, 1, 3}) {
+		t.Errorf("Expected the spilled message to expire, got %v", got)
+	}
+	if !slices.Equal(expired, []any{2}) {
+		t.Errorf("Expected the expired message reported, got %v", expired)
+	}
+}
+
+func TestWithSpill_CodecMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected NewShardQueue to panic")
+		}
+	}()
+	NewShardQueue[string, int](1, 1, WithSpill(t.TempDir(), JSONCodec[int, int]{}))
+}
+
+// unreadableCodec fails to decode the message 3.
+type unreadableCodec struct {
+	JSONCodec[string, int]
+}
+
+func (c unreadableCodec) Decode(data []byte) (string, int, error) {
+	key, msg, err := c.JSONCodec.Decode(data)
+	if err == nil && msg == 3 {
+		err = errors.New("corrupt record")
+	}
+	return key, msg, err
+}
+
+func TestWithSpill_ReadError(t *testing.T) {
+	dir := t.TempDir()
+	var mu sync.Mutex
+	var handled []int
+	sq, release, processed := fullQueue(t,
+		WithWAL(filepath.Join(dir, "wal"), unreadableCodec{}),
+		WithSpill(filepath.Join(dir, "spill"), unreadableCodec{}),
+		WithOnHandled(func(msg any) {
+			mu.Lock()
+			defer mu.Unlock()
+			handled = append(handled, msg.(int))
+		}),
+	)
+
+	futures := []*Future{}
+	for i := 2; i <= 4; i++ {
+		f, _ := sq.ShardAsync("key", i)
+		futures = append(futures, f)
+	}
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	for i, f := range futures[1:] {
+		if err := f.Wait(ctx); !errors.Is(err, ErrDropped) {
+			t.Errorf("Expected the unreadable message %d dropped, got %v", i+3, err)
+		}
+	}
+	sq.Stop()
+
+	if got := processed(); !slices.Equal(got, []int{0, 1, 2}) {
+		t.Errorf("Expected the messages before the unreadable one processed, got %v", got)
+	}
+	mu.Lock()
+	slices.Sort(handled)
+	mu.Unlock()
+	if !slices.Equal(handled, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("Expected the dropped messages reported to WithOnHandled, got %v", handled)
+	}
+	if dropped := sq.Stats()[0].Dropped; dropped != 2 {
+		t.Errorf("Expected 2 dropped messages, got %d", dropped)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "wal", "0")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the dropped messages acknowledged in the WAL, got %v", err)
+	}
+}
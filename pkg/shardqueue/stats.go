@@ -0,0 +1,152 @@
+package shardqueue
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencySamples is the number of recent processing latencies kept per shard
+// to compute the percentiles.
+const latencySamples = 1024
+
+// LatencyBuckets are the upper bounds of the buckets of
+// ShardStats.LatencyHistogram.
+var LatencyBuckets = [...]time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// ShardStats is a point-in-time snapshot of a shard.
+type ShardStats struct {
+	Shard int
+	// Depth is the number of messages waiting in the shard, and Spilled the
+	// number of messages waiting on disk, see WithSpill.
+	Depth   int
+	Spilled int
+	// Enqueued counts the messages queued in the shard, Processed those the
+	// process function was called with and Failed those it failed for.
+	Enqueued  uint64
+	Processed uint64
+	Failed    uint64
+	// Expired counts the messages dropped because of their TTL, see WithTTL.
+	Expired uint64
+	// Dropped counts the messages dropped or rejected because the shard was
+	// full, see WithOverflowPolicy.
+	Dropped uint64
+	// Latency are percentiles of the processing time of the last messages.
+	Latency Latency
+	// LatencySum is the total processing time and LatencyHistogram counts
+	// the processed messages by processing time, cumulatively: bucket i
+	// holds the messages processed within LatencyBuckets[i].
+	LatencySum       time.Duration
+	LatencyHistogram [len(LatencyBuckets)]uint64
+}
+
+// Latency holds processing time percentiles, zero until a message was
+// processed.
+type Latency struct {
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+type shardMetrics struct {
+	enqueued  atomic.Uint64
+	processed atomic.Uint64
+	failed    atomic.Uint64
+	expired   atomic.Uint64
+	dropped   atomic.Uint64
+	sum       atomic.Int64
+	buckets   [len(LatencyBuckets)]atomic.Uint64
+
+	mu      sync.Mutex
+	samples []time.Duration // ring buffer of the last latencies
+	next    int
+}
+
+func (m *shardMetrics) observe(d time.Duration, err error) {
+	m.processed.Add(1)
+	if err != nil {
+		m.failed.Add(1)
+	}
+	m.sum.Add(int64(d))
+	for i, bound := range LatencyBuckets {
+		if d <= bound {
+			m.buckets[i].Add(1)
+			break
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.samples) < latencySamples {
+		m.samples = append(m.samples, d)
+		return
+	}
+	m.samples[m.next] = d
+	m.next = (m.next + 1) % latencySamples
+}
+
+func (m *shardMetrics) histogram() [len(LatencyBuckets)]uint64 {
+	var cumulative [len(LatencyBuckets)]uint64
+	var count uint64
+	for i := range m.buckets {
+		count += m.buckets[i].Load()
+		cumulative[i] = count
+	}
+	return cumulative
+}
+
+func (m *shardMetrics) latency() Latency {
+	m.mu.Lock()
+	sorted := slices.Clone(m.samples)
+	m.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return Latency{}
+	}
+	slices.Sort(sorted)
+	at := func(p int) time.Duration {
+		return sorted[(len(sorted)-1)*p/100]
+	}
+	return Latency{P50: at(50), P90: at(90), P99: at(99), Max: sorted[len(sorted)-1]}
+}
+
+// Stats returns a snapshot of every shard, e.g. to find hot shards or to
+// size the queue.
+func (sq *ShardQueue[K, V]) Stats() []ShardStats {
+	sq.mu.RLock()
+	defer sq.mu.RUnlock()
+
+	stats := make([]ShardStats, sq.numShard)
+	for i := range stats {
+		m := &sq.metrics[i]
+		stats[i] = ShardStats{
+			Shard:     i,
+			Depth:     sq.depth(i),
+			Spilled:   sq.spilled(i),
+			Enqueued:  m.enqueued.Load(),
+			Processed: m.processed.Load(),
+			Failed:    m.failed.Load(),
+			Expired:   m.expired.Load(),
+			Dropped:   m.dropped.Load(),
+			Latency:   m.latency(),
+
+			LatencySum:       time.Duration(m.sum.Load()),
+			LatencyHistogram: m.histogram(),
+		}
+	}
+	return stats
+}
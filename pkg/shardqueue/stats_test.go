@@ -0,0 +1,66 @@
+package shardqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestShardQueue_Stats(t *testing.T) {
+	sq := NewShardQueue[int, int](2, 10, WithErrorHandler(func(int, any, error) {}))
+	_ = sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		time.Sleep(time.Duration(msg) * time.Millisecond)
+		if msg%2 == 1 {
+			return errors.New("odd")
+		}
+		return nil
+	})
+	for msg := range 6 {
+		_ = sq.Shard(0, msg)
+	}
+	sq.Stop()
+
+	stats := sq.Stats()
+	if len(stats) != 2 {
+		t.Fatalf("Expected the stats of 2 shards, got %d", len(stats))
+	}
+	var enqueued, processed, failed uint64
+	var busy ShardStats
+	for _, s := range stats {
+		enqueued += s.Enqueued
+		processed += s.Processed
+		failed += s.Failed
+		if s.Processed > 0 {
+			busy = s
+		}
+		if s.Depth != 0 {
+			t.Errorf("Expected the shards to be drained, shard %d has %d messages", s.Shard, s.Depth)
+		}
+	}
+	if enqueued != 6 || processed != 6 || failed != 3 {
+		t.Errorf("Expected 6 enqueued, 6 processed and 3 failed, got %d, %d and %d", enqueued, processed, failed)
+	}
+	if busy.Processed != 6 {
+		t.Errorf("Expected a single key to hit a single shard, got %+v", stats)
+	}
+	if l := busy.Latency; l.Max < 5*time.Millisecond || l.P50 < 2*time.Millisecond || l.P50 > l.P90 || l.P99 > l.Max {
+		t.Errorf("Expected ordered percentiles of the 0 to 5ms runs, got %+v", l)
+	}
+}
+
+func TestShardMetrics_Window(t *testing.T) {
+	var m shardMetrics
+	for i := range latencySamples + 10 {
+		m.observe(time.Duration(i), nil)
+	}
+	if h := m.histogram(); h[0] != latencySamples+10 || h[len(h)-1] != latencySamples+10 {
+		t.Errorf("Expected every sample in the first bucket, got %v", h)
+	}
+	if len(m.samples) != latencySamples {
+		t.Fatalf("Expected %d samples kept, got %d", latencySamples, len(m.samples))
+	}
+	if l := m.latency(); l.Max != latencySamples+9 || l.P50 < 10 {
+		t.Errorf("Expected the percentiles of the last samples, got %+v", l)
+	}
+}
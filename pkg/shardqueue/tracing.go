@@ -0,0 +1,113 @@
+package shardqueue
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/joripage/go_util/pkg/shardqueue"
+
+// WithTracerProvider opens a consumer span from tp for every message
+// processed, with its shard, the time it waited in the queue and the outcome
+// of its processing, and hands the process function its context. The span is
+// a child of the span found in the context given to ShardCtx or the other
+// Ctx variants, e.g. ShardAsyncCtx, so the trace of the producer shows the
+// time spent in the queue. The messages queued without a context, e.g. by
+// Shard, start their own trace, like the ones spilled to disk or recovered
+// from a WAL. StartBatch opens a span per batch, linked to its messages.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(o *options) {
+		o.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// traceFrom makes the span of e a child of the span of ctx.
+func (sq *ShardQueue[K, V]) traceFrom(ctx context.Context, e *envelope[K, V]) {
+	if sq.tracer != nil {
+		e.trace = trace.SpanContextFromContext(ctx)
+	}
+}
+
+// Outcomes of the processing of a message, recorded on its span.
+const (
+	outcomeProcessed   = "processed"
+	outcomeFailed      = "failed"
+	outcomeExpired     = "expired"
+	outcomeInterrupted = "interrupted"
+)
+
+// startSpan opens the span of a message, if tracing. The span is nil
+// otherwise.
+func (sq *ShardQueue[K, V]) startSpan(ctx context.Context, shard int, e envelope[K, V]) (context.Context, trace.Span) {
+	if sq.tracer == nil {
+		return ctx, nil
+	}
+	if e.trace.IsValid() {
+		ctx = trace.ContextWithRemoteSpanContext(ctx, e.trace)
+	}
+	return sq.tracer.Start(ctx, "shardqueue.process",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.Int("shardqueue.shard", shard),
+			attribute.Float64("shardqueue.queue_time", time.Since(e.enqueuedAt).Seconds()),
+		),
+	)
+}
+
+// startBatchSpan opens the span of a batch, linked to the spans its messages
+// were queued in, if tracing.
+func (sq *ShardQueue[K, V]) startBatchSpan(ctx context.Context, shard int, batch []envelope[K, V]) (context.Context, trace.Span) {
+	if sq.tracer == nil {
+		return ctx, nil
+	}
+	var links []trace.Link
+	for _, e := range batch {
+		if e.trace.IsValid() {
+			links = append(links, trace.Link{SpanContext: e.trace})
+		}
+	}
+	return sq.tracer.Start(ctx, "shardqueue.process_batch",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithLinks(links...),
+		trace.WithAttributes(
+			attribute.Int("shardqueue.shard", shard),
+			attribute.Int("shardqueue.batch_size", len(batch)),
+			attribute.Float64("shardqueue.queue_time", time.Since(batch[0].enqueuedAt).Seconds()),
+		),
+	)
+}
+
+// endSpan records the outcome of the processing on span, if any, and ends
+// it.
+func endSpan(span trace.Span, outcome string, err error, attempts int) {
+	if span == nil {
+		return
+	}
+	span.SetAttributes(attribute.String("shardqueue.outcome", outcome))
+	if attempts > 0 {
+		span.SetAttributes(attribute.Int("shardqueue.attempts", attempts))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else if outcome == outcomeProcessed {
+		span.SetStatus(codes.Ok, "")
+	}
+	span.End()
+}
+
+// outcomeOf returns the outcome of a processing that returned err.
+func outcomeOf(ctx context.Context, err error) string {
+	switch {
+	case err == nil:
+		return outcomeProcessed
+	case ctx.Err() != nil:
+		return outcomeInterrupted
+	default:
+		return outcomeFailed
+	}
+}
@@ -0,0 +1,219 @@
+package shardqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestWithTracerProvider_SpanPerMessage(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	sq := NewShardQueue[string, int](4, 10, WithTracerProvider(tp))
+
+	boom := errors.New("boom")
+	var processCtx context.Context
+	sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		if msg == 1 {
+			processCtx = ctx
+			return nil
+		}
+		return boom
+	})
+
+	callerCtx, caller := tp.Tracer("test").Start(context.Background(), "request")
+	if err := sq.ShardCtx(callerCtx, "key", 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	caller.End()
+	sq.Shard("key", 2)
+	sq.Stop()
+
+	spans := map[int]sdktrace.ReadOnlySpan{}
+	for _, s := range recorder.Ended() {
+		if s.Name() == "shardqueue.process" {
+			spans[len(spans)+1] = s
+		}
+	}
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 shardqueue.process spans, got %d", len(spans))
+	}
+
+	span := spans[1]
+	if span.SpanKind() != trace.SpanKindConsumer {
+		t.Errorf("Expected a consumer span, got %v", span.SpanKind())
+	}
+	if span.Parent().SpanID() != caller.SpanContext().SpanID() {
+		t.Error("Expected the span to be a child of the span of ShardCtx")
+	}
+	attrs := spanAttributes(span)
+	if attrs["shardqueue.shard"].AsInt64() != int64(sq.WhichShard("key")) || attrs["shardqueue.outcome"].AsString() != "processed" {
+		t.Errorf("Unexpected span attributes: %v", span.Attributes())
+	}
+	if _, ok := attrs["shardqueue.queue_time"]; !ok {
+		t.Error("Expected the queue time recorded")
+	}
+	if got := trace.SpanContextFromContext(processCtx).SpanID(); got != span.SpanContext().SpanID() {
+		t.Errorf("Expected the process context to carry the span, got %s", got)
+	}
+
+	span = spans[2]
+	if span.Parent().IsValid() {
+		t.Error("Expected the span of a message queued by Shard to be a root")
+	}
+	if attrs := spanAttributes(span); attrs["shardqueue.outcome"].AsString() != "failed" {
+		t.Errorf("Expected a failed outcome, got %v", span.Attributes())
+	}
+	if span.Status().Code != codes.Error || span.Status().Description != "boom" {
+		t.Errorf("Expected error status, got %+v", span.Status())
+	}
+}
+
+func TestWithTracerProvider_ShardStartsTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	sq := NewShardQueue[string, int](4, 10,
+		WithTracerProvider(tp),
+		WithKeyExtractor(func(msg int) string { return "key" }),
+	)
+	sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		return nil
+	})
+
+	// the producer is traced, but the calls can't see its span
+	_, caller := tp.Tracer("test").Start(context.Background(), "request")
+	sq.Shard("key", 1)
+	sq.TryShard("key", 2)
+	sq.ShardWithTTL("key", 3, time.Minute)
+	sq.ShardAsync("key", 4)
+	sq.Enqueue(5)
+	caller.End()
+	sq.Stop()
+
+	n := 0
+	for _, span := range recorder.Ended() {
+		if span.Name() != "shardqueue.process" {
+			continue
+		}
+		n++
+		if span.Parent().IsValid() {
+			t.Errorf("Expected the span of a message queued without context to be a root, got parent %s", span.Parent().SpanID())
+		}
+		if span.SpanContext().TraceID() == caller.SpanContext().TraceID() {
+			t.Error("Expected the span of a message queued without context in its own trace")
+		}
+	}
+	if n != 5 {
+		t.Errorf("Expected 5 shardqueue.process spans, got %d", n)
+	}
+}
+
+func TestWithTracerProvider_CtxVariants(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	sq := NewShardQueue[string, int](4, 10,
+		WithTracerProvider(tp),
+		WithPriorities(),
+		WithKeyExtractor(func(msg int) string { return "key" }),
+	)
+	sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		return nil
+	})
+
+	ctx, caller := tp.Tracer("test").Start(context.Background(), "request")
+	sq.TryShardCtx(ctx, "key", 1)
+	sq.ShardWithTTLCtx(ctx, "key", 2, time.Minute)
+	sq.ShardWithPriorityCtx(ctx, "key", 3, PriorityHigh)
+	if f, err := sq.ShardAsyncCtx(ctx, "key", 4); err == nil {
+		f.Wait(context.Background())
+	}
+	sq.EnqueueCtx(ctx, 5)
+	caller.End()
+	sq.Stop()
+
+	n := 0
+	for _, span := range recorder.Ended() {
+		if span.Name() != "shardqueue.process" {
+			continue
+		}
+		n++
+		if span.Parent().SpanID() != caller.SpanContext().SpanID() {
+			t.Errorf("Expected the span to be a child of the span of the caller, got parent %s", span.Parent().SpanID())
+		}
+	}
+	if n != 5 {
+		t.Errorf("Expected 5 shardqueue.process spans, got %d", n)
+	}
+}
+
+func TestWithTracerProvider_Expired(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	sq := NewShardQueue[string, int](1, 10, WithTracerProvider(tp))
+	started, release := make(chan struct{}), make(chan struct{})
+	sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		if msg == 0 {
+			close(started)
+			<-release
+		}
+		return nil
+	})
+
+	sq.Shard("key", 0)
+	<-started
+	sq.ShardWithTTL("key", 1, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	sq.Stop()
+
+	for _, s := range recorder.Ended() {
+		if spanAttributes(s)["shardqueue.outcome"].AsString() == "expired" {
+			return
+		}
+	}
+	t.Error("Expected a span with an expired outcome")
+}
+
+func TestWithTracerProvider_Batch(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	sq := NewShardQueue[string, int](1, 10, WithTracerProvider(tp))
+
+	sq.StartBatch(context.Background(), 2, time.Second, func(ctx context.Context, msgs []int) error {
+		return nil
+	})
+	ctx, caller := tp.Tracer("test").Start(context.Background(), "request")
+	sq.ShardCtx(ctx, "key", 1)
+	sq.ShardCtx(ctx, "key", 2)
+	caller.End()
+	sq.Stop()
+
+	for _, s := range recorder.Ended() {
+		if s.Name() != "shardqueue.process_batch" {
+			continue
+		}
+		if len(s.Links()) != 2 || s.Links()[0].SpanContext.SpanID() != caller.SpanContext().SpanID() {
+			t.Errorf("Expected the batch span linked to its messages, got %+v", s.Links())
+		}
+		if spanAttributes(s)["shardqueue.batch_size"].AsInt64() != 2 {
+			t.Errorf("Unexpected span attributes: %v", s.Attributes())
+		}
+		return
+	}
+	t.Error("Expected a shardqueue.process_batch span")
+}
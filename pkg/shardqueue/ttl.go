@@ -0,0 +1,65 @@
+package shardqueue
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// WithTTL drops the messages that waited more than ttl in their shard
+// instead of processing them, see WithOnExpired. ShardWithTTL sets the TTL
+// of a single message.
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithOnExpired calls fn with the messages dropped because of their TTL and
+// how long they waited, instead of logging them.
+func WithOnExpired(fn func(shard int, msg any, waited time.Duration)) Option {
+	return func(o *options) {
+		o.onExpired = fn
+	}
+}
+
+// ShardWithTTL is like Shard but the message is dropped instead of processed
+// if it waited more than ttl in the shard. A non-positive ttl never expires.
+func (sq *ShardQueue[K, V]) ShardWithTTL(routingKey K, msg V, ttl time.Duration) error {
+	return sq.ShardWithTTLCtx(context.Background(), routingKey, msg, ttl)
+}
+
+// ShardWithTTLCtx is like ShardWithTTL but the message is processed in the
+// trace of ctx, see WithTracerProvider, and it gives up waiting for room
+// once ctx is done, returning an *EnqueueError wrapping its cause.
+func (sq *ShardQueue[K, V]) ShardWithTTLCtx(ctx context.Context, routingKey K, msg V, ttl time.Duration) error {
+	return sq.shard(ctx, routingKey, msg, ttl, PriorityNormal, nil)
+}
+
+func (sq *ShardQueue[K, V]) envelope(key K, msg V, ttl time.Duration) envelope[K, V] {
+	e := envelope[K, V]{key: key, msg: msg, enqueuedAt: time.Now()}
+	if ttl > 0 {
+		e.deadline = e.enqueuedAt.Add(ttl)
+	}
+	return e
+}
+
+// expired reports whether the message is past its TTL, after reporting it.
+func (sq *ShardQueue[K, V]) expired(shard int, e envelope[K, V]) bool {
+	if e.deadline.IsZero() {
+		return false
+	}
+	now := time.Now()
+	if now.Before(e.deadline) {
+		return false
+	}
+
+	sq.metrics[shard].expired.Add(1)
+	waited := now.Sub(e.enqueuedAt)
+	if sq.onExpired != nil {
+		sq.onExpired(shard, e.msg, waited)
+	} else {
+		log.Printf("Shard %d dropped a message expired after %v", shard, waited)
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package shardqueue
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithTTL(t *testing.T) {
+	type expiry struct {
+		msg    any
+		waited time.Duration
+	}
+	var mu sync.Mutex
+	var expired []expiry
+	sq := NewShardQueue[string, int](1, 10, WithTTL(20*time.Millisecond), WithOnExpired(func(shard int, msg any, waited time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		expired = append(expired, expiry{msg, waited})
+	}))
+	started, release := make(chan struct{}), make(chan struct{})
+	var processed []int
+	_ = sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		if msg == 0 {
+			close(started)
+			<-release
+		}
+		processed = append(processed, msg)
+		return nil
+	})
+
+	_ = sq.Shard("key", 0)
+	<-started
+	_ = sq.Shard("key", 1)
+	_ = sq.ShardWithTTL("key", 2, time.Minute)
+	_ = sq.ShardWithTTL("key", 3, 0)
+	time.Sleep(30 * time.Millisecond)
+	close(release)
+	sq.Stop()
+
+	if !slices.Equal(processed, []int{0, 2, 3}) {
+		t.Errorf("Expected the messages within their TTL to be processed, got %v", processed)
+	}
+	if len(expired) != 1 || expired[0].msg != 1 || expired[0].waited < 20*time.Millisecond {
+		t.Errorf("Expected message 1 to expire after 20ms, got %+v", expired)
+	}
+	if stats := sq.Stats()[0]; stats.Expired != 1 || stats.Processed != 3 {
+		t.Errorf("Expected 1 expired and 3 processed messages, got %+v", stats)
+	}
+}
+
+func TestWithTTL_Batch(t *testing.T) {
+	sq := NewShardQueue[string, int](1, 10, WithOnExpired(func(int, any, time.Duration) {}))
+	batches := make(chan []int, 2)
+	_ = sq.StartBatch(context.Background(), 3, time.Minute, func(ctx context.Context, msgs []int) error {
+		batches <- slices.Clone(msgs)
+		return nil
+	})
+
+	_ = sq.ShardWithTTL("key", 1, 10*time.Millisecond)
+	_ = sq.Shard("key", 2)
+	// expires while the batch lingers
+	time.Sleep(20 * time.Millisecond)
+	sq.Stop()
+
+	if got := <-batches; !slices.Equal(got, []int{2}) {
+		t.Errorf("Expected the expired message left out of the batch, got %v", got)
+	}
+}
@@ -0,0 +1,412 @@
+package shardqueue
+
+import (
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithWAL appends the messages to a write-ahead log per shard under dir,
+// encoded with codec, before queuing them, and acknowledges them once they
+// were handled: processed, dead-lettered, expired or dropped. Start replays
+// the messages left unacknowledged by a previous run, e.g. after a crash,
+// before the new ones, in their shard for the current number of shards.
+// Messages whose processing was interrupted by the cancellation of the
+// context of Start are not acknowledged, so a message can be processed more
+// than once but isn't lost. The logs are not synced to disk, they survive
+// the crash of the process but not of the machine.
+//
+// The codec must be a Codec of the key and message types of the queue, e.g.
+// JSONCodec[K, V]{}, New returns ErrOptionType otherwise. WithSpill and WithWAL
+// share their codec.
+func WithWAL(dir string, codec any) Option {
+	return func(o *options) {
+		o.walDir = dir
+		o.codec = codec
+	}
+}
+
+// walSegmentSize is the size past which a new segment file is started, so
+// the acknowledged segments can be removed.
+const walSegmentSize = 4 << 20
+
+const (
+	walMessage byte = iota + 1
+	walAck
+)
+
+// walHeaderSize is the size of the header of a message record: its type,
+// sequence number, the length of the encoded message, the enqueue time and
+// the deadline. An ack record is a type and a sequence number.
+const (
+	walHeaderSize = 1 + 8 + 4 + 8 + 8
+	walAckSize    = 1 + 8
+)
+
+// wal is the write-ahead log of a shard, made of segment files holding the
+// message and ack records, named by increasing ID.
+type wal struct {
+	dir string
+
+	mu       sync.Mutex
+	seq      uint64 // last sequence number
+	w        *os.File
+	active   *walSegment // written to, nil until the next append
+	segments []*walSegment
+	unacked  map[uint64]*walSegment // segment of the unacknowledged messages
+	nextID   int
+	closed   bool
+}
+
+type walSegment struct {
+	id      int
+	size    int
+	unacked int
+}
+
+// walRecord is an unacknowledged message read from a WAL.
+type walRecord struct {
+	seq        uint64
+	enqueuedAt time.Time
+	deadline   time.Time
+	data       []byte
+}
+
+// openWAL opens the WAL in dir and returns its unacknowledged messages in
+// order. A record cut by a crash ends its segment.
+func openWAL(dir string) (*wal, []walRecord, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, nil, err
+	}
+	names, err := filepath.Glob(filepath.Join(dir, "*.wal"))
+	if err != nil {
+		return nil, nil, err
+	}
+	slices.Sort(names)
+
+	l := &wal{dir: dir, unacked: map[uint64]*walSegment{}}
+	records := map[uint64]walRecord{}
+	for _, name := range names {
+		id, err := strconv.Atoi(strings.TrimSuffix(filepath.Base(name), ".wal"))
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		seg := &walSegment{id: id, size: len(data)}
+		l.segments = append(l.segments, seg)
+		l.nextID = id + 1
+
+		for len(data) >= walAckSize {
+			seq := binary.LittleEndian.Uint64(data[1:])
+			l.seq = max(l.seq, seq)
+			if data[0] == walAck {
+				if r, ok := records[seq]; ok {
+					l.unacked[seq].unacked--
+					delete(l.unacked, seq)
+					delete(records, r.seq)
+				}
+				data = data[walAckSize:]
+				continue
+			}
+			if len(data) < walHeaderSize {
+				break
+			}
+			n := int(binary.LittleEndian.Uint32(data[9:]))
+			if len(data) < walHeaderSize+n {
+				break
+			}
+			r := walRecord{
+				seq:        seq,
+				enqueuedAt: time.Unix(0, int64(binary.LittleEndian.Uint64(data[13:]))),
+				data:       data[walHeaderSize : walHeaderSize+n],
+			}
+			if deadline := int64(binary.LittleEndian.Uint64(data[21:])); deadline != 0 {
+				r.deadline = time.Unix(0, deadline)
+			}
+			records[seq] = r
+			l.unacked[seq] = seg
+			seg.unacked++
+			data = data[walHeaderSize+n:]
+		}
+	}
+	l.compact()
+
+	unacked := make([]walRecord, 0, len(records))
+	for _, r := range records {
+		unacked = append(unacked, r)
+	}
+	slices.SortFunc(unacked, func(a, b walRecord) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+	return l, unacked, nil
+}
+
+func (l *wal) segment(id int) string {
+	return filepath.Join(l.dir, fmt.Sprintf("%020d.wal", id))
+}
+
+// write appends a record to the active segment, starting a new one if
+// needed.
+func (l *wal) write(record []byte) error {
+	if l.closed {
+		return ErrStopped
+	}
+	if l.w == nil {
+		w, err := os.OpenFile(l.segment(l.nextID), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		l.w = w
+		l.active = &walSegment{id: l.nextID}
+		l.segments = append(l.segments, l.active)
+		l.nextID++
+	}
+	if _, err := l.w.Write(record); err != nil {
+		return err
+	}
+	l.active.size += len(record)
+	return nil
+}
+
+// rotate closes the active segment once it is full.
+func (l *wal) rotate() {
+	if l.active != nil && l.active.size >= walSegmentSize {
+		l.w.Close()
+		l.w, l.active = nil, nil
+	}
+}
+
+// append logs an encoded message and returns its sequence number.
+func (l *wal) append(data []byte, enqueuedAt, deadline time.Time) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record := make([]byte, walHeaderSize+len(data))
+	record[0] = walMessage
+	binary.LittleEndian.PutUint64(record[1:], l.seq+1)
+	binary.LittleEndian.PutUint32(record[9:], uint32(len(data)))
+	binary.LittleEndian.PutUint64(record[13:], uint64(enqueuedAt.UnixNano()))
+	if !deadline.IsZero() {
+		binary.LittleEndian.PutUint64(record[21:], uint64(deadline.UnixNano()))
+	}
+	copy(record[walHeaderSize:], data)
+	if err := l.write(record); err != nil {
+		return 0, err
+	}
+
+	l.seq++
+	l.unacked[l.seq] = l.active
+	l.active.unacked++
+	l.rotate()
+	return l.seq, nil
+}
+
+// ack acknowledges the message with the sequence number, once.
+func (l *wal) ack(seq uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	seg, ok := l.unacked[seq]
+	if !ok {
+		return
+	}
+	delete(l.unacked, seq)
+	seg.unacked--
+	if len(l.unacked) == 0 {
+		// nothing to replay, start over
+		l.compact()
+		return
+	}
+
+	var record [walAckSize]byte
+	record[0] = walAck
+	binary.LittleEndian.PutUint64(record[1:], seq)
+	if err := l.write(record[:]); err != nil {
+		log.Printf("WAL %s ack: %v", l.dir, err)
+		return
+	}
+	l.rotate()
+	l.compact()
+}
+
+// compact removes the oldest segments without unacknowledged messages, and
+// every segment if there is none. A segment is only removed once the older
+// ones were, as it may hold the acks of their messages.
+func (l *wal) compact() {
+	if len(l.unacked) == 0 {
+		if l.w != nil {
+			l.w.Close()
+			l.w, l.active = nil, nil
+		}
+	}
+	for len(l.segments) > 0 && l.segments[0].unacked == 0 && l.segments[0] != l.active {
+		if err := os.Remove(l.segment(l.segments[0].id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("WAL %s: %v", l.dir, err)
+			return
+		}
+		l.segments = l.segments[1:]
+	}
+}
+
+// close closes the active segment, and removes the directory if no message
+// is left to replay.
+func (l *wal) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.w != nil {
+		l.w.Close()
+		l.w, l.active = nil, nil
+	}
+	l.closed = true
+	if len(l.unacked) == 0 {
+		l.compact()
+		os.Remove(l.dir)
+	}
+}
+
+// openWALs opens the WAL of every shard, and of the shards of a previous run
+// with more shards, and returns the unacknowledged messages by their shard.
+func (sq *ShardQueue[K, V]) openWALs() ([][]envelope[K, V], error) {
+	entries, err := os.ReadDir(sq.walDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	ids := []int{}
+	for _, entry := range entries {
+		if id, err := strconv.Atoi(entry.Name()); err == nil && entry.IsDir() && id >= sq.numShard {
+			ids = append(ids, id)
+		}
+	}
+	for i := range sq.numShard {
+		ids = append(ids, i)
+	}
+	slices.Sort(ids)
+
+	wals := make([]*wal, sq.numShard)
+	var stale []*wal
+	recovered := make([][]envelope[K, V], sq.numShard)
+	for _, id := range ids {
+		l, records, err := openWAL(filepath.Join(sq.walDir, strconv.Itoa(id)))
+		if err != nil {
+			for _, l := range append(wals, stale...) {
+				if l != nil {
+					l.close()
+				}
+			}
+			return nil, fmt.Errorf("wal of shard %d: %w", id, err)
+		}
+		if id < sq.numShard {
+			wals[id] = l
+		} else {
+			stale = append(stale, l)
+		}
+
+		for _, r := range records {
+			e := envelope[K, V]{enqueuedAt: r.enqueuedAt, deadline: r.deadline, wal: l, seq: r.seq}
+			if e.key, e.msg, err = sq.codec.Decode(r.data); err != nil {
+				log.Printf("WAL %s: message %d dropped: %v", l.dir, r.seq, err)
+				l.ack(r.seq)
+				continue
+			}
+			shard := sq.WhichShard(e.key)
+			sq.track(shard, &e)
+			recovered[shard] = append(recovered[shard], e)
+			sq.metrics[shard].enqueued.Add(1)
+		}
+	}
+	sq.wals = wals
+	sq.staleWALs = stale
+	return recovered, nil
+}
+
+func (sq *ShardQueue[K, V]) closeWALs() {
+	for _, l := range append(sq.wals, sq.staleWALs...) {
+		l.close()
+	}
+}
+
+// appendWAL appends e to the WAL of the shard, if any.
+func (sq *ShardQueue[K, V]) appendWAL(shard int, e *envelope[K, V]) error {
+	if sq.wals == nil {
+		return nil
+	}
+	data, err := sq.codec.Encode(e.key, e.msg)
+	if err != nil {
+		return fmt.Errorf("wal of shard %d: %w", shard, err)
+	}
+	seq, err := sq.wals[shard].append(data, e.enqueuedAt, e.deadline)
+	if err != nil {
+		return fmt.Errorf("wal of shard %d: %w", shard, err)
+	}
+	e.wal, e.seq = sq.wals[shard], seq
+	return nil
+}
+
+// ack acknowledges a handled message in its WAL, if any, and for Flush.
+func (sq *ShardQueue[K, V]) ack(e envelope[K, V]) {
+	if e.wal != nil {
+		e.wal.ack(e.seq)
+	}
+	if e.gen != nil {
+		e.gen.done()
+	}
+}
+
+// handled acks a message done with and reports it to WithOnHandled.
+func (sq *ShardQueue[K, V]) handled(e envelope[K, V]) {
+	sq.ack(e)
+	if sq.onHandled != nil {
+		sq.onHandled(e.msg)
+	}
+}
+
+// acked acks e if it wasn't queued because of err.
+func (sq *ShardQueue[K, V]) acked(e envelope[K, V], err error) error {
+	if err != nil {
+		sq.ack(e)
+	}
+	return err
+}
+
+// recoveredBuffer hands the worker of a shard the messages recovered from
+// the WALs before the ones of the buffer.
+type recoveredBuffer[E any] struct {
+	buffer[E]
+	pending []E // only used by the worker
+	n       atomic.Int64
+}
+
+func newRecoveredBuffer[E any](buf buffer[E], pending []E) *recoveredBuffer[E] {
+	b := &recoveredBuffer[E]{buffer: buf, pending: pending}
+	b.n.Store(int64(len(pending)))
+	return b
+}
+
+func (b *recoveredBuffer[E]) pop(done <-chan struct{}, timeout <-chan time.Time) (E, popResult) {
+	if len(b.pending) == 0 {
+		return b.buffer.pop(done, timeout)
+	}
+	e := b.pending[0]
+	var zero E
+	b.pending[0] = zero
+	b.pending = b.pending[1:]
+	b.n.Add(-1)
+	return e, popped
+}
+
+func (b *recoveredBuffer[E]) len() int {
+	return b.buffer.len() + int(b.n.Load())
+}
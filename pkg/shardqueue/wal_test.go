@@ -0,0 +1,104 @@
+package shardqueue
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// payment is an order with exported fields for JSONCodec.
+type payment struct {
+	Account string
+	Seq     int
+}
+
+func TestWithWAL_Replay(t *testing.T) {
+	dir := t.TempDir()
+
+	// the first run is interrupted while processing 3
+	sq := NewShardQueue[string, int](1, 10, WithWAL(dir, JSONCodec[string, int]{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	interrupted := make(chan struct{})
+	_ = sq.Start(ctx, func(ctx context.Context, msg int) error {
+		if msg == 3 {
+			close(interrupted)
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	})
+	for i := range 10 {
+		_ = sq.Shard("key", i)
+	}
+	<-interrupted
+	cancel()
+	sq.Stop()
+
+	// a record cut by a crash
+	segments, _ := filepath.Glob(filepath.Join(dir, "0", "*.wal"))
+	if len(segments) == 0 {
+		t.Fatal("Expected a WAL segment on disk")
+	}
+	f, _ := os.OpenFile(segments[len(segments)-1], os.O_WRONLY|os.O_APPEND, 0)
+	f.Write([]byte{walMessage, 1, 2})
+	f.Close()
+
+	sq = NewShardQueue[string, int](1, 10, WithWAL(dir, JSONCodec[string, int]{}))
+	var got []int
+	_ = sq.Start(context.Background(), func(ctx context.Context, msg int) error {
+		got = append(got, msg)
+		return nil
+	})
+	sq.Stop()
+
+	if want := []int{3, 4, 5, 6, 7, 8, 9}; !slices.Equal(got, want) {
+		t.Errorf("Expected the unacknowledged messages %v replayed, got %v", want, got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "0")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected the WAL removed once every message was acknowledged, got %v", err)
+	}
+}
+
+func TestWithWAL_Reshard(t *testing.T) {
+	dir := t.TempDir()
+
+	// nothing is processed by the first run
+	sq := NewShardQueue[string, payment](4, 100, WithWAL(dir, JSONCodec[string, payment]{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = sq.Start(ctx, func(ctx context.Context, msg payment) error { return nil })
+	accounts := []string{"a", "b", "c", "d", "e"}
+	for seq := range 4 {
+		for _, account := range accounts {
+			_ = sq.Shard(account, payment{Account: account, Seq: seq})
+		}
+	}
+	sq.Stop()
+
+	sq = NewShardQueue[string, payment](2, 100, WithWAL(dir, JSONCodec[string, payment]{}))
+	var mu sync.Mutex
+	got := map[string][]int{}
+	_ = sq.Start(context.Background(), func(ctx context.Context, msg payment) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got[msg.Account] = append(got[msg.Account], msg.Seq)
+		return nil
+	})
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer stopCancel()
+	_ = sq.StopContext(stopCtx)
+
+	for _, account := range accounts {
+		if !slices.Equal(got[account], []int{0, 1, 2, 3}) {
+			t.Errorf("Expected the messages of %s replayed in order, got %v", account, got[account])
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected the WALs of the 4 shards removed, got %d", len(entries))
+	}
+}
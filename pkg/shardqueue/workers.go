@@ -0,0 +1,49 @@
+package shardqueue
+
+import (
+	"context"
+	"hash/maphash"
+	"sync"
+)
+
+// WithWorkersPerShard runs n workers per shard instead of one, e.g. for CPU
+// bound process functions. The messages of a key still go to the same
+// worker, so they are processed in order. A busy worker can hold back the
+// messages of the other keys of its shard.
+func WithWorkersPerShard(n int) Option {
+	return func(o *options) {
+		o.workersPerShard = n
+	}
+}
+
+// dispatch runs the workers of a shard and hands them the messages of the
+// shard by key, until the shard is closed or ctx is done.
+func (sq *ShardQueue[K, V]) dispatch(ctx context.Context, id int, buf buffer[envelope[K, V]], worker func(id int, buf buffer[envelope[K, V]])) {
+	var wg sync.WaitGroup
+	subs := make([]chanBuffer[envelope[K, V]], sq.workersPerShard)
+	for i := range subs {
+		subs[i] = make(chanBuffer[envelope[K, V]], sq.queueSize)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			worker(id, subs[i])
+		}()
+	}
+	defer func() {
+		for _, sub := range subs {
+			sub.close()
+		}
+		wg.Wait()
+	}()
+
+	for {
+		e, res := buf.pop(ctx.Done(), nil)
+		if res != popped {
+			return
+		}
+		sub := subs[maphash.Comparable(sq.subSeed, e.key)%uint64(len(subs))]
+		if !sub.push(e, nil, ctx.Done()) {
+			return
+		}
+	}
+}
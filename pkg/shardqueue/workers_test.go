@@ -0,0 +1,51 @@
+package shardqueue
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithWorkersPerShard(t *testing.T) {
+	sq := NewShardQueue[int, order](1, 100, WithWorkersPerShard(4))
+
+	var mu sync.Mutex
+	got := map[string][]int{}
+	var running, maxRunning atomic.Int32
+	_ = sq.Start(context.Background(), func(ctx context.Context, msg order) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+		got[msg.account] = append(got[msg.account], msg.seq)
+		return nil
+	})
+
+	accounts := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	for seq := range 10 {
+		for key, account := range accounts {
+			_ = sq.Shard(key, order{account: account, seq: seq})
+		}
+	}
+	sq.Stop()
+
+	for _, account := range accounts {
+		if !slices.IsSorted(got[account]) || len(got[account]) != 10 {
+			t.Errorf("Expected the 10 messages of %s in order, got %v", account, got[account])
+		}
+	}
+	if maxRunning.Load() < 2 {
+		t.Errorf("Expected the workers of the shard to run concurrently, at most %d did", maxRunning.Load())
+	}
+}
@@ -0,0 +1,62 @@
+package taskmanager
+
+import (
+	"context"
+	"time"
+)
+
+// WithStopTimeout gives the task d to return once it is stopped or its
+// context is canceled. A task still running after that is marked as
+// StatusAbandoned, recorded in Status and History and reported as an
+// EventAbandoned, instead of being silently forgotten. Its eventual result
+// is still passed to WithOnComplete and the waiters.
+func WithStopTimeout(d time.Duration) TaskOption {
+	return func(cfg *taskConfig) {
+		cfg.stopTimeout = d
+	}
+}
+
+// watchStop abandons the task if it has not returned within timeout of ctx
+// being done.
+func (s *TaskManager) watchStop(ctx context.Context, t *task, timeout time.Duration) {
+	select {
+	case <-t.done:
+		return
+	case <-ctx.Done():
+	}
+
+	timer := s.clock.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-t.done:
+	case <-timer.C():
+		s.abandon(t, timeout, context.Cause(ctx))
+	}
+}
+
+func (s *TaskManager) abandon(t *task, timeout time.Duration, cause error) {
+	if !t.markAbandoned() {
+		return
+	}
+	now := s.clock.Now()
+	duration := runningFor(t.started(), now)
+	s.logger.Printf("Task %s did not return within %v of being stopped, abandoning it", t.id, timeout)
+	s.emit(EventAbandoned, t, duration, cause)
+	s.recordFinished(t)
+	if s.history != nil {
+		s.history.add(t.info(now))
+	}
+}
+
+// markAbandoned flags a task that has not finished yet as abandoned and
+// reports whether it did.
+func (t *task) markAbandoned() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.finishedAt.IsZero() {
+		return false
+	}
+	t.status = StatusAbandoned
+	return true
+}
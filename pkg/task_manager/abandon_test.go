@@ -0,0 +1,80 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithStopTimeout_Abandoned(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tm := NewTaskManager(WithClock(clock), WithHistorySize(10))
+	events, unsubscribe := tm.Subscribe()
+	defer unsubscribe()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	h, _ := tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return ctx.Err()
+	}, WithStopTimeout(time.Second))
+	<-started
+
+	tm.StopTask("task")
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+
+	timeout := time.After(500 * time.Millisecond)
+	for abandoned := false; !abandoned; {
+		select {
+		case e := <-events:
+			if e.Type == EventAbandoned {
+				abandoned = true
+				if !errors.Is(e.Err, ErrTaskStopped) {
+					t.Errorf("Expected the event to carry ErrTaskStopped, got %v", e.Err)
+				}
+			}
+		case <-timeout:
+			t.Fatal("Expected an EventAbandoned")
+		}
+	}
+	if info, _ := tm.Status("task"); info.Status != StatusAbandoned {
+		t.Errorf("Expected the task to be abandoned, got %s", info.Status)
+	}
+
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := h.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the late result of the task, got %v", err)
+	}
+	if info, _ := tm.Status("task"); info.Status != StatusAbandoned || info.FinishedAt.IsZero() {
+		t.Errorf("Expected the task to stay abandoned once finished, got %s", info.Status)
+	}
+	if history := tm.History(); len(history) != 1 || history[0].Status != StatusAbandoned {
+		t.Errorf("Expected a single abandoned run in the history, got %v", history)
+	}
+}
+
+func TestWithStopTimeout_ReturnedInTime(t *testing.T) {
+	tm := NewTaskManager(WithHistorySize(10))
+
+	h, _ := tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithStopTimeout(20*time.Millisecond))
+	h.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_ = h.Wait(ctx)
+	time.Sleep(40 * time.Millisecond)
+
+	if info, _ := tm.Status("task"); info.Status != StatusCanceled {
+		t.Errorf("Expected the task to be canceled, got %s", info.Status)
+	}
+	if history := tm.History(); len(history) != 1 {
+		t.Errorf("Expected a single run in the history, got %d", len(history))
+	}
+}
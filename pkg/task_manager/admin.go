@@ -0,0 +1,270 @@
+package taskmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// defaultAdminShutdownTimeout is used by the shutdown endpoint when no
+// timeout is given.
+const defaultAdminShutdownTimeout = 30 * time.Second
+
+// defaultAdminIdempotencyTTL is how long a completed start of a registered
+// task is remembered for its Idempotency-Key header by default.
+const defaultAdminIdempotencyTTL = 24 * time.Hour
+
+// AdminOption configures AdminHandler.
+type AdminOption func(*adminConfig)
+
+type adminConfig struct {
+	idempotencyTTL time.Duration
+}
+
+// WithAdminIdempotencyTTL sets how long a completed start of a registered
+// task is remembered for its Idempotency-Key header, 24h by default.
+func WithAdminIdempotencyTTL(ttl time.Duration) AdminOption {
+	return func(cfg *adminConfig) {
+		cfg.idempotencyTTL = ttl
+	}
+}
+
+type taskJSON struct {
+	ID          string            `json:"id"`
+	RunID       uint64            `json:"run_id"`
+	Status      string            `json:"status"`
+	Error       string            `json:"error,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	ScheduledAt *time.Time        `json:"scheduled_at,omitempty"`
+	StartedAt   *time.Time        `json:"started_at,omitempty"`
+	FinishedAt  *time.Time        `json:"finished_at,omitempty"`
+	Running     string            `json:"running"`
+	ParentError string            `json:"parent_error,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Priority    int               `json:"priority"`
+	ParentID    string            `json:"parent_id,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Health      string            `json:"health,omitempty"`
+	Progress    *progressJSON     `json:"progress,omitempty"`
+	// stacks of a failed run, see WithErrorStacks
+	CreationStack string     `json:"creation_stack,omitempty"`
+	FailureStack  string     `json:"failure_stack,omitempty"`
+	Usage         *usageJSON `json:"usage,omitempty"`
+}
+
+type usageJSON struct {
+	CPUTime   string             `json:"cpu_time"`
+	Allocated uint64             `json:"allocated"`
+	Gauges    map[string]float64 `json:"gauges,omitempty"`
+}
+
+type progressJSON struct {
+	Percent   float64   `json:"percent"`
+	Message   string    `json:"message,omitempty"`
+	Data      any       `json:"data,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newTaskJSON(info TaskInfo) taskJSON {
+	j := taskJSON{
+		ID:          info.ID,
+		RunID:       info.RunID,
+		Status:      info.Status.String(),
+		Error:       errString(info.Err),
+		CreatedAt:   info.CreatedAt,
+		ScheduledAt: timePtr(info.ScheduledAt),
+		StartedAt:   timePtr(info.StartedAt),
+		FinishedAt:  timePtr(info.FinishedAt),
+		Running:     info.Running.String(),
+		ParentError: errString(info.ParentErr),
+		Tags:        info.Tags,
+		Priority:    info.Priority,
+		ParentID:    info.ParentID,
+		Metadata:    info.Metadata,
+		Health:      errString(info.Health),
+
+		CreationStack: string(info.CreationStack),
+		FailureStack:  string(info.FailureStack),
+	}
+	if u := info.Usage; u.CPUTime > 0 || u.Allocated > 0 || len(u.Gauges) > 0 {
+		j.Usage = &usageJSON{CPUTime: u.CPUTime.String(), Allocated: u.Allocated, Gauges: u.Gauges}
+	}
+	if p := info.Progress; !p.UpdatedAt.IsZero() {
+		j.Progress = &progressJSON{
+			Percent:   p.Percent,
+			Message:   p.Message,
+			Data:      p.Data,
+			UpdatedAt: p.UpdatedAt,
+		}
+	}
+	return j
+}
+
+func newTaskListJSON(infos []TaskInfo) []taskJSON {
+	list := make([]taskJSON, 0, len(infos))
+	for _, info := range infos {
+		list = append(list, newTaskJSON(info))
+	}
+	return list
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// AdminHandler returns an http.Handler exposing the manager as JSON:
+//
+//	GET  /tasks               running and pending tasks
+//	GET  /tasks/{id}          status of a task or of its last run
+//	GET  /tasks/{id}/stack    stack traces of a running task, as text
+//	POST /tasks/{id}/stop     stop a task
+//	GET  /registered          names of the registered task functions
+//	POST /registered/{name}   start a registered task, the body is an optional
+//	                          JSON object of string params; a retried request
+//	                          with the same Idempotency-Key header reuses the run,
+//	                          see WithAdminIdempotencyTTL,
+//	                          429 when the tenant quota is exceeded
+//	GET  /history             finished runs, see WithHistorySize
+//	GET  /healthz             200 if every running task is healthy, 503 otherwise
+//	POST /quiesce             reject new tasks, the running ones continue
+//	POST /shutdown?timeout=   graceful shutdown, waiting up to timeout (default 30s),
+//	                          lists the tasks still running after it
+//
+// Mount it under a prefix with http.StripPrefix. It has no authentication of
+// its own.
+func (s *TaskManager) AdminHandler(opts ...AdminOption) http.Handler {
+	cfg := adminConfig{idempotencyTTL: defaultAdminIdempotencyTTL}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /tasks", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, newTaskListJSON(s.ListTasks()))
+	})
+
+	mux.HandleFunc("GET /tasks/{id}", func(w http.ResponseWriter, r *http.Request) {
+		info, ok := s.Status(r.PathValue("id"))
+		if !ok {
+			writeError(w, http.StatusNotFound, ErrTaskNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, newTaskJSON(info))
+	})
+
+	mux.HandleFunc("GET /tasks/{id}/stack", func(w http.ResponseWriter, r *http.Request) {
+		dump, err := s.DumpTask(r.PathValue("id"))
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrTaskNotFound) {
+				status = http.StatusNotFound
+			}
+			writeError(w, status, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write(dump)
+	})
+
+	mux.HandleFunc("POST /tasks/{id}/stop", func(w http.ResponseWriter, r *http.Request) {
+		if !s.StopTaskContext(r.Context(), r.PathValue("id")) {
+			writeError(w, http.StatusNotFound, ErrTaskNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]bool{"stopped": true})
+	})
+
+	mux.HandleFunc("GET /registered", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.Registered())
+	})
+
+	mux.HandleFunc("POST /registered/{name}", func(w http.ResponseWriter, r *http.Request) {
+		var params map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&params); err != nil && !errors.Is(err, io.EOF) {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		var opts []TaskOption
+		if key := r.Header.Get("Idempotency-Key"); key != "" {
+			opts = append(opts, WithIdempotencyKey(key, cfg.idempotencyTTL))
+		}
+		// the task outlives the request
+		err := s.StartRegistered(context.WithoutCancel(r.Context()), r.PathValue("name"), params, opts...)
+		switch {
+		case errors.Is(err, ErrTaskNotRegistered):
+			writeError(w, http.StatusNotFound, err)
+		case errors.Is(err, ErrShuttingDown):
+			writeError(w, http.StatusServiceUnavailable, err)
+		case errors.Is(err, ErrQuotaExceeded):
+			writeError(w, http.StatusTooManyRequests, err)
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err)
+		default:
+			writeJSON(w, http.StatusOK, map[string]string{"id": r.PathValue("name")})
+		}
+	})
+
+	mux.HandleFunc("GET /history", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, newTaskListJSON(s.History()))
+	})
+
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		if err := s.Healthy(r.Context()); err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{"healthy": false, "error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]bool{"healthy": true})
+	})
+
+	mux.HandleFunc("POST /quiesce", func(w http.ResponseWriter, r *http.Request) {
+		s.Quiesce()
+		writeJSON(w, http.StatusOK, map[string]bool{"quiesced": true})
+	})
+
+	mux.HandleFunc("POST /shutdown", func(w http.ResponseWriter, r *http.Request) {
+		timeout := defaultAdminShutdownTimeout
+		if v := r.URL.Query().Get("timeout"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err)
+				return
+			}
+			timeout = d
+		}
+		// keep the caller, see WithCaller, but not the cancellation of the
+		// request
+		ctx, cancel := s.timeoutContext(context.WithoutCancel(r.Context()), timeout)
+		defer cancel()
+		remaining := []string{}
+		var shutdownErr *ShutdownError
+		if errors.As(s.GracefulShutdownContext(ctx), &shutdownErr) {
+			remaining = shutdownErr.Remaining
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"shutdown": true, "remaining": remaining})
+	})
+
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,241 @@
+package taskmanager
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func doAdmin(t *testing.T, h http.Handler, method, path string, out any) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: invalid JSON %q: %v", method, path, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestAdminHandler_ListAndStop(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+	h := tm.AdminHandler()
+
+	_, _ = tm.StartTask(context.Background(), "task1", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTags("sync"))
+	time.Sleep(10 * time.Millisecond)
+
+	var tasks []map[string]any
+	if code := doAdmin(t, h, http.MethodGet, "/tasks", &tasks); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if len(tasks) != 1 || tasks[0]["id"] != "task1" || tasks[0]["status"] != "running" {
+		t.Fatalf("Unexpected task list: %v", tasks)
+	}
+
+	if code := doAdmin(t, h, http.MethodPost, "/tasks/task1/stop", nil); code != http.StatusOK {
+		t.Fatalf("Expected 200 stopping task1, got %d", code)
+	}
+	if code := doAdmin(t, h, http.MethodPost, "/tasks/task1/stop", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 stopping task1 twice, got %d", code)
+	}
+	_, _ = tm.WaitTask(context.Background(), "task1")
+	time.Sleep(10 * time.Millisecond)
+
+	var task map[string]any
+	if code := doAdmin(t, h, http.MethodGet, "/tasks/task1", &task); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if task["status"] != "canceled" || task["error"] != "task stopped: context canceled" {
+		t.Errorf("Expected canceled task with error, got %v", task)
+	}
+
+	var errBody map[string]string
+	if code := doAdmin(t, h, http.MethodGet, "/tasks/missing", &errBody); code != http.StatusNotFound || errBody["error"] != ErrTaskNotFound.Error() {
+		t.Errorf("Expected 404 with error body, got %d %v", code, errBody)
+	}
+}
+
+func TestAdminHandler_HistoryAndShutdown(t *testing.T) {
+	tm := NewTaskManager(WithHistorySize(10))
+	h := tm.AdminHandler()
+
+	_, _ = tm.StartTask(context.Background(), "done", func(ctx context.Context) error { return nil })
+	_, _ = tm.WaitTask(context.Background(), "done")
+
+	stopped := make(chan struct{})
+	_, _ = tm.StartTask(context.Background(), "long", func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stopped)
+		return nil
+	})
+
+	if code := doAdmin(t, h, http.MethodPost, "/shutdown?timeout=bad", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad timeout, got %d", code)
+	}
+	var shutdown map[string]any
+	if code := doAdmin(t, h, http.MethodPost, "/shutdown?timeout=500ms", &shutdown); code != http.StatusOK {
+		t.Fatalf("Expected 200 from shutdown, got %d", code)
+	}
+	if remaining, ok := shutdown["remaining"].([]any); !ok || len(remaining) != 0 {
+		t.Errorf("Expected no remaining task, got %v", shutdown)
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Error("Expected running task to be stopped by shutdown")
+	}
+
+	var runs []map[string]any
+	if code := doAdmin(t, h, http.MethodGet, "/history", &runs); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if len(runs) != 2 || runs[0]["id"] != "done" || runs[0]["status"] != "completed" {
+		t.Errorf("Unexpected history: %v", runs)
+	}
+
+	if code := doAdmin(t, h, http.MethodGet, "/shutdown", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET /shutdown, got %d", code)
+	}
+}
+
+func TestAdminHandler_Registered(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+	h := tm.AdminHandler()
+
+	got := make(chan map[string]string, 1)
+	_ = tm.Register("rebuild-index", func(ctx context.Context, params map[string]string) error {
+		got <- params
+		return nil
+	})
+	_ = tm.Register("cleanup", func(ctx context.Context, params map[string]string) error { return nil })
+
+	var names []string
+	if code := doAdmin(t, h, http.MethodGet, "/registered", &names); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if !slices.Equal(names, []string{"cleanup", "rebuild-index"}) {
+		t.Errorf("Expected the sorted names, got %v", names)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/registered/rebuild-index", strings.NewReader(`{"shard":"eu"}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	select {
+	case params := <-got:
+		if params["shard"] != "eu" {
+			t.Errorf("Expected the params of the request, got %v", params)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected the registered task to run")
+	}
+
+	if code := doAdmin(t, h, http.MethodPost, "/registered/missing", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown name, got %d", code)
+	}
+}
+
+func TestAdminHandler_RegisteredIdempotencyKey(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+	h := tm.AdminHandler()
+
+	var runs atomic.Int64
+	_ = tm.Register("charge", func(ctx context.Context, params map[string]string) error {
+		runs.Add(1)
+		return nil
+	})
+
+	start := func(key string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/registered/charge", nil)
+		req.Header.Set("Idempotency-Key", key)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+		}
+		_, _ = tm.WaitTask(context.Background(), "charge")
+	}
+	start("req-1")
+	start("req-1")
+	if n := runs.Load(); n != 1 {
+		t.Errorf("Expected a retried request to reuse the run, got %d runs", n)
+	}
+	start("req-2")
+	if n := runs.Load(); n != 2 {
+		t.Errorf("Expected a new key to run the task again, got %d runs", n)
+	}
+}
+
+func TestAdminHandler_RegisteredQuota(t *testing.T) {
+	tm := NewTaskManager(WithTenantQuota(TenantQuota{
+		Tenant:  func(TaskInfo) string { return "acme" },
+		Default: 1,
+	}))
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+	h := tm.AdminHandler()
+
+	block := func(ctx context.Context, params map[string]string) error {
+		<-ctx.Done()
+		return nil
+	}
+	_ = tm.Register("first", block)
+	_ = tm.Register("second", block)
+
+	if code := doAdmin(t, h, http.MethodPost, "/registered/first", nil); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if code := doAdmin(t, h, http.MethodPost, "/registered/second", nil); code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 beyond the quota, got %d", code)
+	}
+}
+
+func TestAdminHandler_IdempotencyTTL(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tm := NewTaskManager(WithClock(clock))
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+	h := tm.AdminHandler(WithAdminIdempotencyTTL(time.Minute))
+
+	var runs atomic.Int64
+	_ = tm.Register("charge", func(ctx context.Context, params map[string]string) error {
+		runs.Add(1)
+		return nil
+	})
+
+	start := func() {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/registered/charge", nil)
+		req.Header.Set("Idempotency-Key", "req-1")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+		}
+		_, _ = tm.WaitTask(context.Background(), "charge")
+	}
+	start()
+	clock.Advance(30 * time.Second)
+	start()
+	if n := runs.Load(); n != 1 {
+		t.Errorf("Expected a retry within the TTL to reuse the run, got %d runs", n)
+	}
+	clock.Advance(time.Minute)
+	start()
+	if n := runs.Load(); n != 2 {
+		t.Errorf("Expected a retry after the TTL to run the task again, got %d runs", n)
+	}
+}
@@ -0,0 +1,165 @@
+package taskmanager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+// AuditAction is what an AuditRecord records.
+type AuditAction string
+
+const (
+	// AuditStart records a task run being started.
+	AuditStart AuditAction = "start"
+	// AuditStop records a request to stop a task.
+	AuditStop AuditAction = "stop"
+	// AuditFinish records a task run returning, with its status and error.
+	AuditFinish AuditAction = "finish"
+	// AuditShutdown records the start of a graceful shutdown.
+	AuditShutdown AuditAction = "shutdown"
+)
+
+// AuditRecord is an entry of the audit trail, see WithAuditSink. The records
+// are chained: Hash covers the record and the Hash of the previous one, so
+// that VerifyAuditTrail detects records altered, removed or reordered.
+type AuditRecord struct {
+	Seq    uint64      `json:"seq"`
+	Time   time.Time   `json:"time"`
+	Action AuditAction `json:"action"`
+	TaskID string      `json:"task_id,omitempty"`
+	RunID  uint64      `json:"run_id,omitempty"`
+	// Caller is who asked for the action, see WithCaller.
+	Caller   string            `json:"caller,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Status and Error are set for AuditFinish.
+	Status   string `json:"status,omitempty"`
+	Error    string `json:"error,omitempty"`
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+// hash returns the hex SHA-256 of the record fields and PrevHash.
+func (r AuditRecord) hash() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00%s\x00%s\x00%s\x00%d\x00%s\x00%s\x00%s\x00%s\x00",
+		r.Seq, r.Time.UTC().Format(time.RFC3339Nano), r.Action, r.TaskID, r.RunID, r.Caller, r.Status, r.Error, r.PrevHash)
+	for _, k := range slices.Sorted(maps.Keys(r.Metadata)) {
+		fmt.Fprintf(h, "%s=%s\x00", k, r.Metadata[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// VerifyAuditTrail checks that records are an unaltered run of consecutive
+// records as written by the manager, and returns an error describing the
+// first one that isn't.
+func VerifyAuditTrail(records []AuditRecord) error {
+	for i, r := range records {
+		if i > 0 {
+			prev := records[i-1]
+			if r.Seq != prev.Seq+1 || r.PrevHash != prev.Hash {
+				return fmt.Errorf("audit record %d: does not follow record %d", r.Seq, prev.Seq)
+			}
+		}
+		if r.hash() != r.Hash {
+			return fmt.Errorf("audit record %d: hash mismatch", r.Seq)
+		}
+	}
+	return nil
+}
+
+// AuditSink receives the audit records, in order. It is called synchronously
+// by the action recorded, so it should be fast; a failing write is logged.
+type AuditSink interface {
+	WriteAudit(ctx context.Context, record AuditRecord) error
+}
+
+type jsonAuditSink struct {
+	enc *json.Encoder
+}
+
+// NewJSONAuditSink returns an AuditSink writing every record to w as a line
+// of JSON, e.g. to an append-only file.
+func NewJSONAuditSink(w io.Writer) AuditSink {
+	return jsonAuditSink{enc: json.NewEncoder(w)}
+}
+
+func (s jsonAuditSink) WriteAudit(ctx context.Context, record AuditRecord) error {
+	return s.enc.Encode(record)
+}
+
+// WithAuditSink records the starts, stops and ends of the tasks and the
+// shutdowns to sink.
+func WithAuditSink(sink AuditSink) Option {
+	return func(s *TaskManager) {
+		s.audit = &auditor{sink: sink}
+	}
+}
+
+type callerKey struct{}
+
+// WithCaller returns a copy of ctx naming who acts on the manager, e.g. the
+// authenticated user of an admin request, for the audit trail. Pass it to
+// StartTask, StopTaskContext or Shutdown; the admin handler and the gRPC
+// service use the context of the request.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFrom returns the caller set by WithCaller, or "".
+func CallerFrom(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+type auditor struct {
+	sink AuditSink
+
+	mu   sync.Mutex
+	seq  uint64
+	last string
+}
+
+// record chains r after the previous record and writes it to the sink.
+func (s *TaskManager) record(ctx context.Context, r AuditRecord) {
+	if s.audit == nil {
+		return
+	}
+	a := s.audit
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.seq++
+	r.Seq = a.seq
+	r.Time = s.clock.Now()
+	r.Caller = CallerFrom(ctx)
+	r.PrevHash = a.last
+	r.Hash = r.hash()
+	a.last = r.Hash
+	if err := a.sink.WriteAudit(context.WithoutCancel(ctx), r); err != nil {
+		s.logger.Printf("Failed to write audit record %d: %v", r.Seq, err)
+	}
+}
+
+// auditTask records action on t.
+func (s *TaskManager) auditTask(ctx context.Context, action AuditAction, t *task) {
+	if s.audit == nil {
+		return
+	}
+	r := AuditRecord{Action: action, TaskID: t.id, RunID: t.run, Metadata: maps.Clone(t.metadata)}
+	if action == AuditFinish {
+		err := t.result()
+		r.Status = statusOf(err).String()
+		if err != nil {
+			r.Error = err.Error()
+		}
+	}
+	s.record(ctx, r)
+}
@@ -0,0 +1,98 @@
+package taskmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingSink struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (r *recordingSink) WriteAudit(ctx context.Context, record AuditRecord) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, record)
+	return nil
+}
+
+func TestWithAuditSink(t *testing.T) {
+	sink := &recordingSink{}
+	tm := NewTaskManager(WithAuditSink(sink))
+
+	ctx := WithCaller(context.Background(), "alice")
+	h, _ := tm.StartTask(ctx, "export", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithMetadata(map[string]string{"request_id": "42"}))
+	if !tm.StopTaskContext(WithCaller(context.Background(), "bob"), "export") {
+		t.Fatal("Expected the task to be stopped")
+	}
+	waitCtx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_ = h.Wait(waitCtx)
+	if err := tm.Shutdown(WithCaller(waitCtx, "deployer")); err != nil {
+		t.Fatalf("Unexpected shutdown error: %v", err)
+	}
+
+	sink.mu.Lock()
+	records := sink.records
+	sink.mu.Unlock()
+	want := []struct {
+		action AuditAction
+		caller string
+	}{
+		{AuditStart, "alice"},
+		{AuditStop, "bob"},
+		{AuditFinish, ""},
+		{AuditShutdown, "deployer"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("Expected %d records, got %+v", len(want), records)
+	}
+	for i, w := range want {
+		if r := records[i]; r.Action != w.action || r.Caller != w.caller {
+			t.Errorf("Record %d: expected %s by %q, got %s by %q", i, w.action, w.caller, r.Action, r.Caller)
+		}
+	}
+	if r := records[0]; r.TaskID != "export" || r.RunID != h.RunID() || r.Metadata["request_id"] != "42" {
+		t.Errorf("Expected the task and its metadata in the start record, got %+v", r)
+	}
+	if r := records[2]; r.Status != StatusCanceled.String() || r.Error == "" {
+		t.Errorf("Expected the outcome in the finish record, got %+v", r)
+	}
+
+	if err := VerifyAuditTrail(records); err != nil {
+		t.Errorf("Unexpected error verifying the trail: %v", err)
+	}
+	tampered := append([]AuditRecord(nil), records...)
+	tampered[1].Caller = "mallory"
+	if err := VerifyAuditTrail(tampered); err == nil {
+		t.Error("Expected an altered record to be detected")
+	}
+	if err := VerifyAuditTrail(append(records[:1:1], records[2:]...)); err == nil {
+		t.Error("Expected a removed record to be detected")
+	}
+}
+
+func TestNewJSONAuditSink(t *testing.T) {
+	var buf bytes.Buffer
+	tm := NewTaskManager(WithAuditSink(NewJSONAuditSink(&buf)))
+	_ = tm.Shutdown(WithCaller(context.Background(), "ops"))
+
+	var record AuditRecord
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a JSON line, got %q: %v", buf.String(), err)
+	}
+	if record.Action != AuditShutdown || record.Caller != "ops" || record.Hash == "" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+	if err := VerifyAuditTrail([]AuditRecord{record}); err != nil {
+		t.Errorf("Expected the decoded record to verify, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// TaskSpec describes a task started by StartTasks.
+type TaskSpec struct {
+	ID      string
+	Fn      func(ctx context.Context) error
+	Options []TaskOption
+}
+
+// StartTasks starts all the tasks of specs or none of them. It first checks
+// every spec and returns the errors of the invalid ones, e.g. ErrInvalidTaskID,
+// ErrNilTaskFunc or ErrDuplicateTaskID for an ID used twice in specs. If a
+// task then fails to start, e.g. because ctx was canceled meanwhile, the tasks
+// already started are stopped.
+func (s *TaskManager) StartTasks(ctx context.Context, specs []TaskSpec) error {
+	if err := s.validateSpecs(specs); err != nil {
+		return err
+	}
+
+	// runs up to this one were not started by this call, see WithDedupWindow
+	lastRun := s.runs.Load()
+	handles := make([]*TaskHandle, 0, len(specs))
+	for _, spec := range specs {
+		h, err := s.StartTask(ctx, spec.ID, spec.Fn, spec.Options...)
+		if err != nil {
+			for _, h := range handles {
+				if h.RunID() > lastRun {
+					h.Stop()
+				}
+			}
+			return fmt.Errorf("task %s: %w", spec.ID, err)
+		}
+		handles = append(handles, h)
+	}
+	return nil
+}
+
+func (s *TaskManager) validateSpecs(specs []TaskSpec) error {
+	errs := []error{}
+	seen := make(map[string]bool, len(specs))
+	for i, spec := range specs {
+		var err error
+		switch {
+		case spec.ID == "":
+			err = ErrInvalidTaskID
+		case seen[spec.ID]:
+			err = ErrDuplicateTaskID
+		case spec.Fn == nil:
+			err = ErrNilTaskFunc
+		case newTaskConfig(spec.Options).singleton && s.locks == nil:
+			err = ErrNoLockProvider
+		}
+		seen[spec.ID] = true
+		if err != nil {
+			errs = append(errs, fmt.Errorf("spec %d (%q): %w", i, spec.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,53 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStartTasks_All(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	err := tm.StartTasks(context.Background(), []TaskSpec{
+		{ID: "reader", Fn: block},
+		{ID: "writer", Fn: block, Options: []TaskOption{WithTags("io")}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error starting tasks: %v", err)
+	}
+	if !tm.HasTask("reader") || !tm.HasTask("writer") {
+		t.Error("Expected both tasks to run")
+	}
+	if tasks := tm.ListTasksByTag("io"); len(tasks) != 1 || tasks[0].ID != "writer" {
+		t.Errorf("Expected the options of the spec to apply, got %+v", tasks)
+	}
+}
+
+func TestStartTasks_None(t *testing.T) {
+	tm := NewTaskManager()
+	fn := func(ctx context.Context) error { return nil }
+
+	err := tm.StartTasks(context.Background(), []TaskSpec{
+		{ID: "ok", Fn: fn},
+		{ID: "", Fn: fn},
+		{ID: "ok", Fn: fn},
+		{ID: "nil"},
+		{ID: "leader", Fn: fn, Options: []TaskOption{WithSingleton()}},
+	})
+
+	for _, want := range []error{ErrInvalidTaskID, ErrDuplicateTaskID, ErrNilTaskFunc, ErrNoLockProvider} {
+		if !errors.Is(err, want) {
+			t.Errorf("Expected the error to include %v, got %v", want, err)
+		}
+	}
+	if tm.HasTask("ok") {
+		t.Error("Expected no task to start when a spec is invalid")
+	}
+}
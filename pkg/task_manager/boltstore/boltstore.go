@@ -0,0 +1,74 @@
+// Package boltstore implements a taskmanager.Store on top of a bbolt
+// database.
+package boltstore
+
+import (
+	"context"
+	"encoding/json"
+
+	taskmanager "github.com/joripage/go_util/pkg/task_manager"
+	bolt "go.etcd.io/bbolt"
+)
+
+// DefaultBucket is the bucket used when none is given to New.
+const DefaultBucket = "task_definitions"
+
+// Store keeps the task definitions as JSON in a bucket, keyed by task ID.
+type Store struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+var _ taskmanager.Store = (*Store)(nil)
+
+// New returns a Store using bucket in db, creating the bucket if needed. An
+// empty bucket defaults to DefaultBucket. The caller keeps ownership of db.
+func New(db *bolt.DB, bucket string) (*Store, error) {
+	if bucket == "" {
+		bucket = DefaultBucket
+	}
+	s := &Store{db: db, bucket: []byte(bucket)}
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(s.bucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) Save(ctx context.Context, def taskmanager.TaskDefinition) error {
+	data, err := json.Marshal(def)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).Put([]byte(def.ID), data)
+	})
+}
+
+func (s *Store) Delete(ctx context.Context, id string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).Delete([]byte(id))
+	})
+}
+
+// List returns the definitions ordered by task ID.
+func (s *Store) List(ctx context.Context) ([]taskmanager.TaskDefinition, error) {
+	defs := []taskmanager.TaskDefinition{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(s.bucket).ForEach(func(k, v []byte) error {
+			var def taskmanager.TaskDefinition
+			if err := json.Unmarshal(v, &def); err != nil {
+				return err
+			}
+			defs = append(defs, def)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return defs, nil
+}
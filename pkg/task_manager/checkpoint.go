@@ -0,0 +1,108 @@
+package taskmanager
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// CheckpointStore persists the checkpoints of tasks, see WithCheckpointStore.
+type CheckpointStore interface {
+	// SaveCheckpoint creates or replaces the value of key for the task id.
+	SaveCheckpoint(ctx context.Context, id, key string, value []byte) error
+	// LoadCheckpoint returns ErrNoCheckpoint if key was not saved for the
+	// task id.
+	LoadCheckpoint(ctx context.Context, id, key string) ([]byte, error)
+	// DeleteCheckpoints removes every checkpoint of the task id, deleting
+	// an unknown ID is not an error.
+	DeleteCheckpoints(ctx context.Context, id string) error
+}
+
+// MemoryCheckpointStore is a CheckpointStore keeping the checkpoints in
+// memory, mostly useful in tests.
+type MemoryCheckpointStore struct {
+	mu          sync.Mutex
+	checkpoints map[string]map[string][]byte
+}
+
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{checkpoints: map[string]map[string][]byte{}}
+}
+
+func (m *MemoryCheckpointStore) SaveCheckpoint(ctx context.Context, id, key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.checkpoints[id] == nil {
+		m.checkpoints[id] = map[string][]byte{}
+	}
+	m.checkpoints[id][key] = slices.Clone(value)
+	return nil
+}
+
+func (m *MemoryCheckpointStore) LoadCheckpoint(ctx context.Context, id, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	value, ok := m.checkpoints[id][key]
+	if !ok {
+		return nil, ErrNoCheckpoint
+	}
+	return slices.Clone(value), nil
+}
+
+func (m *MemoryCheckpointStore) DeleteCheckpoints(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.checkpoints, id)
+	return nil
+}
+
+// WithCheckpointStore keeps the checkpoints saved by tasks through
+// CheckpointerFrom in store. The checkpoints of a task are deleted once it
+// completes successfully, so that its next run starts over.
+func WithCheckpointStore(store CheckpointStore) Option {
+	return func(s *TaskManager) {
+		s.checkpoints = store
+	}
+}
+
+// Checkpointer saves and loads the checkpoints of a task, e.g. the last
+// processed order of a bulk job, so that a later run of the same task ID,
+// after a failure or a restart of the process, resumes from there.
+type Checkpointer struct {
+	store CheckpointStore
+	id    string
+}
+
+// CheckpointerFrom returns the Checkpointer of the task owning ctx. Outside
+// a managed task, or without WithCheckpointStore, its methods return
+// ErrNoCheckpointStore.
+func CheckpointerFrom(ctx context.Context) Checkpointer {
+	if t, ok := taskFromContext(ctx); ok {
+		return Checkpointer{store: t.checkpoints, id: t.id}
+	}
+	return Checkpointer{}
+}
+
+// Save creates or replaces the checkpoint key of the task.
+func (c Checkpointer) Save(ctx context.Context, key string, value []byte) error {
+	if c.store == nil {
+		return ErrNoCheckpointStore
+	}
+	return c.store.SaveCheckpoint(ctx, c.id, key, value)
+}
+
+// Load returns the checkpoint key of the task, or ErrNoCheckpoint if it was
+// never saved.
+func (c Checkpointer) Load(ctx context.Context, key string) ([]byte, error) {
+	if c.store == nil {
+		return nil, ErrNoCheckpointStore
+	}
+	return c.store.LoadCheckpoint(ctx, c.id, key)
+}
+
+// clearCheckpoints deletes the checkpoints of a task that completed.
+func (s *TaskManager) clearCheckpoints(t *task) {
+	if err := s.checkpoints.DeleteCheckpoints(context.Background(), t.id); err != nil {
+		s.logger.Printf("Task %s: failed to delete checkpoints: %v", t.id, err)
+	}
+}
@@ -0,0 +1,74 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCheckpointer_Resume(t *testing.T) {
+	store := NewMemoryCheckpointStore()
+	ctx := context.Background()
+
+	var processed []int
+	failAt := 3
+	process := func(ctx context.Context) error {
+		cp := CheckpointerFrom(ctx)
+		next := 0
+		if v, err := cp.Load(ctx, "next"); err == nil {
+			next, _ = strconv.Atoi(string(v))
+		} else if !errors.Is(err, ErrNoCheckpoint) {
+			return err
+		}
+		for ; next < 5; next++ {
+			if next == failAt {
+				return errors.New("boom")
+			}
+			processed = append(processed, next)
+			if err := cp.Save(ctx, "next", []byte(strconv.Itoa(next+1))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// a new manager, as after a restart of the process
+	for i, want := range []error{errors.New("boom"), nil} {
+		tm := NewTaskManager(WithCheckpointStore(store))
+		h, _ := tm.StartTask(ctx, "orders", process)
+		waitCtx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+		err := h.Wait(waitCtx)
+		cancel()
+		if (err == nil) != (want == nil) {
+			t.Fatalf("Run %d: expected %v, got %v", i+1, want, err)
+		}
+		failAt = -1
+	}
+
+	if want := []int{0, 1, 2, 3, 4}; !slices.Equal(processed, want) {
+		t.Errorf("Expected every item processed once %v, got %v", want, processed)
+	}
+	if _, err := store.LoadCheckpoint(ctx, "orders", "next"); !errors.Is(err, ErrNoCheckpoint) {
+		t.Errorf("Expected the checkpoints deleted after success, got %v", err)
+	}
+}
+
+func TestCheckpointerFrom_NoStore(t *testing.T) {
+	if err := CheckpointerFrom(context.Background()).Save(context.Background(), "key", nil); !errors.Is(err, ErrNoCheckpointStore) {
+		t.Errorf("Expected ErrNoCheckpointStore outside a task, got %v", err)
+	}
+
+	tm := NewTaskManager()
+	h, _ := tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		_, err := CheckpointerFrom(ctx).Load(ctx, "key")
+		return err
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := h.Wait(ctx); !errors.Is(err, ErrNoCheckpointStore) {
+		t.Errorf("Expected ErrNoCheckpointStore without a store, got %v", err)
+	}
+}
@@ -0,0 +1,25 @@
+package taskmanager
+
+import "context"
+
+// StartChildTask starts a task as a child of the running task parentID: its
+// context derives from the parent's, so it is canceled when the parent is
+// stopped or replaced, and with ErrParentFinished once the parent function
+// has returned. It returns ErrTaskNotFound if the parent is not running.
+func (s *TaskManager) StartChildTask(parentID, childID string, fn func(ctx context.Context) error, opts ...TaskOption) (*TaskHandle, error) {
+	v, ok := s.tasks.Load(parentID)
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	parent := v.(*task)
+	opts = append(opts, func(cfg *taskConfig) {
+		cfg.parentID = parentID
+	})
+	return s.StartTask(parent.ctx, childID, fn, opts...)
+}
+
+// Children returns the running children of the task id, see StartChildTask.
+// Use TaskInfo.ParentID to render the tree of all the tasks.
+func (s *TaskManager) Children(id string) []TaskInfo {
+	return s.listTasks(func(t *task) bool { return t.parentID == id })
+}
@@ -0,0 +1,63 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStartChildTask_CanceledWithParent(t *testing.T) {
+	tm := NewTaskManager()
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	parent, _ := tm.StartTask(context.Background(), "parent", block)
+	child, err := tm.StartChildTask("parent", "child", block)
+	if err != nil {
+		t.Fatalf("Unexpected error starting child task: %v", err)
+	}
+
+	if children := tm.Children("parent"); len(children) != 1 || children[0].ID != "child" || children[0].ParentID != "parent" {
+		t.Errorf("Expected child under parent, got %+v", children)
+	}
+
+	parent.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := child.Wait(ctx); !errors.Is(err, ErrTaskStopped) {
+		t.Errorf("Expected the child to be canceled with its parent, got %v", err)
+	}
+}
+
+func TestStartChildTask_CanceledWhenParentFinishes(t *testing.T) {
+	tm := NewTaskManager()
+
+	release := make(chan struct{})
+	_, _ = tm.StartTask(context.Background(), "parent", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	child, _ := tm.StartChildTask("parent", "child", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := child.Wait(ctx); !errors.Is(err, ErrParentFinished) {
+		t.Errorf("Expected ErrParentFinished, got %v", err)
+	}
+}
+
+func TestStartChildTask_ParentNotFound(t *testing.T) {
+	tm := NewTaskManager()
+
+	_, err := tm.StartChildTask("missing", "child", func(ctx context.Context) error { return nil })
+	if !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Expected ErrTaskNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package taskmanager
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Clock is the source of time of the manager: timeouts, schedules, retry
+// backoffs, heartbeats and shutdown timeouts. Tests can use a FakeClock
+// instead of sleeping.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+	// AfterFunc calls f in its own goroutine after d.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a time.Timer obtained from a Clock.
+type Timer interface {
+	// C is nil for timers created by AfterFunc.
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// WithClock sets the clock of the manager, the real time by default.
+func WithClock(c Clock) Option {
+	return func(s *TaskManager) {
+		s.clock = c
+	}
+}
+
+type realClock struct{}
+
+type realTimer struct {
+	*time.Timer
+}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (realClock) NewTimer(d time.Duration) Timer         { return realTimer{time.NewTimer(d)} }
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+// timeoutContext returns a copy of parent canceled once timeout has elapsed
+// on the manager clock, with context.DeadlineExceeded as cause.
+func (s *TaskManager) timeoutContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	timer := s.clock.AfterFunc(timeout, func() { cancel(context.DeadlineExceeded) })
+	return ctx, func() {
+		timer.Stop()
+		cancel(nil)
+	}
+}
+
+// FakeClock is a Clock whose time only moves with Advance.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*fakeTimer
+	changed chan struct{} // closed and replaced when timers are added
+}
+
+type fakeTimer struct {
+	clock *FakeClock
+	when  time.Time
+	c     chan time.Time
+	f     func()
+}
+
+// NewFakeClock returns a FakeClock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now, changed: make(chan struct{})}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1)}
+	t.Reset(d)
+	return t
+}
+
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) Timer {
+	t := &fakeTimer{clock: c, f: f}
+	t.Reset(d)
+	return t
+}
+
+// Advance moves the time forward by d, firing the timers due by then in
+// order.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	due := []*fakeTimer{}
+	c.timers = slices.DeleteFunc(c.timers, func(t *fakeTimer) bool {
+		if t.when.After(c.now) {
+			return false
+		}
+		due = append(due, t)
+		return true
+	})
+	c.mu.Unlock()
+
+	slices.SortStableFunc(due, func(a, b *fakeTimer) int { return a.when.Compare(b.when) })
+	for _, t := range due {
+		t.fire()
+	}
+}
+
+// BlockUntil blocks until at least n timers are waiting, e.g. until the
+// task under test has armed its timer before calling Advance.
+func (c *FakeClock) BlockUntil(n int) {
+	for {
+		c.mu.Lock()
+		waiting, changed := len(c.timers), c.changed
+		c.mu.Unlock()
+		if waiting >= n {
+			return
+		}
+		<-changed
+	}
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) fire() {
+	if t.f != nil {
+		go t.f()
+		return
+	}
+	select {
+	case t.c <- t.when:
+	default:
+	}
+}
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i := slices.Index(c.timers, t)
+	if i < 0 {
+		return false
+	}
+	c.timers = slices.Delete(c.timers, i, i+1)
+	return true
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	c := t.clock
+	c.mu.Lock()
+	i := slices.Index(c.timers, t)
+	active := i >= 0
+	t.when = c.now.Add(d)
+	if d <= 0 {
+		if active {
+			c.timers = slices.Delete(c.timers, i, i+1)
+		}
+		c.mu.Unlock()
+		t.fire()
+		return active
+	}
+	if !active {
+		c.timers = append(c.timers, t)
+		close(c.changed)
+		c.changed = make(chan struct{})
+	}
+	c.mu.Unlock()
+	return active
+}
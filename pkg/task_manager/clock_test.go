@@ -0,0 +1,122 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFakeClock_Advance(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+
+	timer := clock.NewTimer(time.Second)
+	fired := make(chan struct{})
+	clock.AfterFunc(2*time.Second, func() { close(fired) })
+
+	clock.Advance(500 * time.Millisecond)
+	select {
+	case <-timer.C():
+		t.Fatal("Expected the timer not to fire before its time")
+	default:
+	}
+
+	clock.Advance(2 * time.Second)
+	select {
+	case at := <-timer.C():
+		if !at.Equal(start.Add(time.Second)) {
+			t.Errorf("Expected the timer to fire at %v, got %v", start.Add(time.Second), at)
+		}
+	default:
+		t.Fatal("Expected the timer to fire")
+	}
+	select {
+	case <-fired:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected the AfterFunc callback to run")
+	}
+	if now := clock.Now(); !now.Equal(start.Add(2500 * time.Millisecond)) {
+		t.Errorf("Expected the clock at %v, got %v", start.Add(2500*time.Millisecond), now)
+	}
+}
+
+func TestFakeClock_Stop(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+
+	timer := clock.NewTimer(time.Second)
+	if !timer.Stop() {
+		t.Error("Expected Stop to report an active timer")
+	}
+	clock.Advance(time.Minute)
+	select {
+	case <-timer.C():
+		t.Error("Expected a stopped timer not to fire")
+	default:
+	}
+	if timer.Stop() {
+		t.Error("Expected Stop to report an already stopped timer")
+	}
+}
+
+func TestWithClock_Timeout(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tm := NewTaskManager(WithClock(clock))
+
+	done := make(chan error, 1)
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTimeout(time.Hour), WithOnComplete(func(err error) { done <- err }))
+
+	clock.BlockUntil(1)
+	clock.Advance(59 * time.Minute)
+	select {
+	case err := <-done:
+		t.Fatalf("Expected the task to run until the timeout, got %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	clock.Advance(time.Minute)
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrTaskTimedOut) {
+			t.Errorf("Expected ErrTaskTimedOut, got %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected the task to time out")
+	}
+}
+
+func TestWithClock_Retry(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tm := NewTaskManager(WithClock(clock))
+
+	attempts := make(chan struct{}, 3)
+	done := make(chan error, 1)
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		attempts <- struct{}{}
+		return errors.New("boom")
+	}, WithRetry(3, time.Hour), WithOnComplete(func(err error) { done <- err }))
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-attempts:
+		case <-time.After(500 * time.Millisecond):
+			t.Fatalf("Expected attempt %d", i+1)
+		}
+		if i < 2 {
+			clock.BlockUntil(1)
+			clock.Advance(24 * time.Hour)
+		}
+	}
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Expected the last attempt error")
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected the task to finish")
+	}
+}
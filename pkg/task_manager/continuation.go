@@ -0,0 +1,95 @@
+package taskmanager
+
+import "context"
+
+type continuation struct {
+	onSuccess bool
+	id        string
+	fn        func(ctx context.Context) error
+	opts      []TaskOption
+}
+
+type previousKey struct{}
+
+// Then starts fn as the task id once this run completes without error, with
+// the context the run was started with. fn reads this run through Previous,
+// e.g. the value it passed to SetResult. If the run already completed, the
+// task is started right away. It returns h to chain calls.
+func (h *TaskHandle) Then(id string, fn func(ctx context.Context) error, opts ...TaskOption) *TaskHandle {
+	h.tm.addContinuation(h.t, continuation{onSuccess: true, id: id, fn: fn, opts: opts})
+	return h
+}
+
+// OnFailure is like Then but starts fn when this run fails or times out,
+// e.g. to compensate for it. It is not started when the run is canceled.
+func (h *TaskHandle) OnFailure(id string, fn func(ctx context.Context) error, opts ...TaskOption) *TaskHandle {
+	h.tm.addContinuation(h.t, continuation{id: id, fn: fn, opts: opts})
+	return h
+}
+
+// SetResult stores v as the result of the task owning ctx, passed to its
+// continuations and reported in TaskInfo.Result. It is a no-op outside a
+// managed task.
+func SetResult(ctx context.Context, v any) {
+	t, ok := taskFromContext(ctx)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.value = v
+}
+
+// Previous returns the run that started the continuation owning ctx, see
+// TaskHandle.Then and TaskHandle.OnFailure.
+func Previous(ctx context.Context) (TaskInfo, bool) {
+	info, ok := ctx.Value(previousKey{}).(TaskInfo)
+	return info, ok
+}
+
+func (s *TaskManager) addContinuation(t *task, c continuation) {
+	t.mu.Lock()
+	if !t.continued {
+		t.continuations = append(t.continuations, c)
+		t.mu.Unlock()
+		return
+	}
+	t.mu.Unlock()
+	s.startContinuation(t.parent, t.info(s.clock.Now()), c)
+}
+
+// runContinuations starts the continuations matching the result of t, which
+// has finished.
+func (s *TaskManager) runContinuations(t *task) {
+	t.mu.Lock()
+	t.continued = true
+	continuations := t.continuations
+	t.continuations = nil
+	t.mu.Unlock()
+
+	if len(continuations) == 0 {
+		return
+	}
+	info := t.info(s.clock.Now())
+	for _, c := range continuations {
+		s.startContinuation(t.parent, info, c)
+	}
+}
+
+// startContinuation starts c with the parent context of the previous run,
+// if prev ended the way c expects.
+func (s *TaskManager) startContinuation(parent context.Context, prev TaskInfo, c continuation) {
+	switch {
+	case s.shuttingDown.Load():
+		return
+	case c.onSuccess && prev.Status != StatusCompleted:
+		return
+	case !c.onSuccess && prev.Status != StatusFailed && prev.Status != StatusTimedOut:
+		return
+	}
+
+	ctx := context.WithValue(parent, previousKey{}, prev)
+	if _, err := s.StartTask(ctx, c.id, c.fn, c.opts...); err != nil {
+		s.logger.Printf("Task %s: failed to start continuation %s: %v", prev.ID, c.id, err)
+	}
+}
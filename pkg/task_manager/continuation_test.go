@@ -0,0 +1,119 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHandle_ThenPassesResult(t *testing.T) {
+	tm := NewTaskManager()
+
+	release := make(chan struct{})
+	_, _ = tm.StartTask(context.Background(), "extract", func(ctx context.Context) error {
+		<-release
+		SetResult(ctx, 42)
+		return nil
+	})
+	h, ok := tm.Handle("extract")
+	if !ok || h.ID() != "extract" {
+		t.Fatal("Expected a handle to the running task")
+	}
+
+	got := make(chan TaskInfo, 1)
+	compensated := make(chan struct{}, 1)
+	h.Then("load", func(ctx context.Context) error {
+		prev, _ := Previous(ctx)
+		got <- prev
+		return nil
+	}).OnFailure("compensate", func(ctx context.Context) error {
+		compensated <- struct{}{}
+		return nil
+	})
+	close(release)
+
+	select {
+	case prev := <-got:
+		if prev.ID != "extract" || prev.Result != 42 || prev.Status != StatusCompleted {
+			t.Errorf("Expected the previous run with its result, got %+v", prev)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected the continuation to start")
+	}
+	select {
+	case <-compensated:
+		t.Error("Expected OnFailure not to run after a success")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestHandle_OnFailure(t *testing.T) {
+	tm := NewTaskManager()
+	boom := errors.New("boom")
+
+	release := make(chan struct{})
+	_, _ = tm.StartTask(context.Background(), "charge", func(ctx context.Context) error {
+		<-release
+		return boom
+	})
+	h, _ := tm.Handle("charge")
+
+	got := make(chan error, 1)
+	h.OnFailure("refund", func(ctx context.Context) error {
+		prev, _ := Previous(ctx)
+		got <- prev.Err
+		return nil
+	})
+	h.Then("notify", func(ctx context.Context) error {
+		t.Error("Expected Then not to run after a failure")
+		return nil
+	})
+	close(release)
+
+	select {
+	case err := <-got:
+		if !errors.Is(err, boom) {
+			t.Errorf("Expected the failure to be passed on, got %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected the compensation to start")
+	}
+	time.Sleep(20 * time.Millisecond)
+}
+
+func TestHandle_AfterFinishAndCancel(t *testing.T) {
+	tm := NewTaskManager()
+
+	_, _ = tm.StartTask(context.Background(), "done", func(ctx context.Context) error { return nil })
+	h, _ := tm.Handle("done")
+	tm.WaitTask(context.Background(), "done")
+	time.Sleep(10 * time.Millisecond)
+
+	started := make(chan struct{}, 1)
+	h.Then("next", func(ctx context.Context) error {
+		started <- struct{}{}
+		return nil
+	})
+	select {
+	case <-started:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected a continuation added after the run finished to start right away")
+	}
+
+	_, _ = tm.StartTask(context.Background(), "canceled", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	h, _ = tm.Handle("canceled")
+	h.OnFailure("compensate", func(ctx context.Context) error {
+		t.Error("Expected OnFailure not to run after a cancellation")
+		return nil
+	})
+	tm.StopTaskAndWait("canceled", 500*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok := tm.Handle("missing"); ok {
+		t.Error("Expected no handle for an unknown task")
+	}
+}
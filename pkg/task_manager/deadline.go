@@ -0,0 +1,108 @@
+package taskmanager
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// deadlineCtx is a context canceled at a deadline that can be pushed back
+// while it is not done, see ExtendDeadline. It expires by canceling the
+// embedded context with ErrTaskTimedOut as cause, so that context.Cause
+// works as with context.WithDeadlineCause.
+type deadlineCtx struct {
+	context.Context
+	cancel context.CancelCauseFunc
+
+	clock    Clock
+	mu       sync.Mutex
+	deadline time.Time
+	timer    Timer
+	expired  bool
+}
+
+func withExtendableDeadline(parent context.Context, deadline time.Time, clock Clock) (*deadlineCtx, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	c := &deadlineCtx{Context: ctx, cancel: cancel, clock: clock, deadline: deadline}
+
+	c.mu.Lock()
+	c.timer = clock.AfterFunc(deadline.Sub(clock.Now()), c.expire)
+	c.mu.Unlock()
+
+	return c, func() {
+		c.mu.Lock()
+		c.timer.Stop()
+		c.mu.Unlock()
+		cancel(nil)
+	}
+}
+
+func (c *deadlineCtx) expire() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Context.Err() != nil {
+		return
+	}
+	// extended while the timer was firing
+	if d := c.deadline.Sub(c.clock.Now()); d > 0 {
+		c.timer.Reset(d)
+		return
+	}
+	c.expired = true
+	c.cancel(ErrTaskTimedOut)
+}
+
+// extend pushes the deadline back by d. It returns false once the context is
+// done.
+func (c *deadlineCtx) extend(d time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Context.Err() != nil {
+		return false
+	}
+	c.deadline = c.deadline.Add(d)
+	c.timer.Reset(c.deadline.Sub(c.clock.Now()))
+	return true
+}
+
+func (c *deadlineCtx) Deadline() (time.Time, bool) {
+	c.mu.Lock()
+	deadline := c.deadline
+	c.mu.Unlock()
+
+	if parent, ok := c.Context.Deadline(); ok && parent.Before(deadline) {
+		return parent, true
+	}
+	return deadline, true
+}
+
+func (c *deadlineCtx) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.expired {
+		return context.DeadlineExceeded
+	}
+	return c.Context.Err()
+}
+
+// ExtendDeadline grants d more time to the running task with the given id,
+// which must have been started with WithTimeout or WithDeadline. It returns
+// ErrTaskNotFound if no such task is running and ErrNoDeadline if the task
+// has no deadline, has not started yet or is already done.
+func (s *TaskManager) ExtendDeadline(id string, d time.Duration) error {
+	v, ok := s.tasks.Load(id)
+	if !ok {
+		return ErrTaskNotFound
+	}
+	t := v.(*task)
+	t.mu.Lock()
+	dctx := t.deadlineCtx
+	t.mu.Unlock()
+
+	if dctx == nil || !dctx.extend(d) {
+		return ErrNoDeadline
+	}
+	deadline, _ := dctx.Deadline()
+	s.logger.Printf("Task %s deadline extended to %s", id, deadline.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,94 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExtendDeadline_GrantsMoreTime(t *testing.T) {
+	tm := NewTaskManager()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTimeout(50*time.Millisecond), WithOnComplete(func(err error) { done <- err }))
+
+	time.Sleep(20 * time.Millisecond)
+	before, _ := tm.Status("task")
+	if err := tm.ExtendDeadline("task", 100*time.Millisecond); err != nil {
+		t.Fatalf("Unexpected error extending deadline: %v", err)
+	}
+	if after, _ := tm.Status("task"); after.Deadline.Sub(before.Deadline) != 100*time.Millisecond {
+		t.Errorf("Expected the deadline to move by 100ms, went from %v to %v", before.Deadline, after.Deadline)
+	}
+
+	err := <-done
+	if elapsed := time.Since(start); elapsed < 140*time.Millisecond {
+		t.Errorf("Expected the task to run until the extended deadline, ran %v", elapsed)
+	}
+	if !errors.Is(err, ErrTaskTimedOut) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected ErrTaskTimedOut wrapping context.DeadlineExceeded, got %v", err)
+	}
+	if info, _ := tm.Status("task"); info.Status != StatusTimedOut {
+		t.Errorf("Expected status timed_out, got %s", info.Status)
+	}
+}
+
+func TestExtendDeadline_KeepsCancellation(t *testing.T) {
+	tm := NewTaskManager()
+
+	started, extended := make(chan struct{}), make(chan struct{})
+	causes := make(chan error, 1)
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		close(started)
+		<-extended
+		if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) < time.Minute {
+			t.Errorf("Expected the extended deadline on the task context, got %v", deadline)
+		}
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return ctx.Err()
+	}, WithTimeout(50*time.Millisecond))
+
+	<-started
+	if err := tm.ExtendDeadline("task", time.Hour); err != nil {
+		t.Fatalf("Unexpected error extending deadline: %v", err)
+	}
+	close(extended)
+	time.Sleep(100 * time.Millisecond)
+
+	tm.StopTask("task")
+	select {
+	case cause := <-causes:
+		if !errors.Is(cause, ErrTaskStopped) {
+			t.Errorf("Expected the task to be stopped rather than timed out, got %v", cause)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected StopTask to cancel a task with an extended deadline")
+	}
+}
+
+func TestExtendDeadline_Errors(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	if err := tm.ExtendDeadline("missing", time.Second); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Expected ErrTaskNotFound, got %v", err)
+	}
+	_, _ = tm.StartTask(context.Background(), "no_timeout", block)
+	_ = tm.StartTaskAt(context.Background(), "not_started", block, time.Now().Add(time.Hour), WithTimeout(time.Second))
+	time.Sleep(10 * time.Millisecond)
+	for _, id := range []string{"no_timeout", "not_started"} {
+		if err := tm.ExtendDeadline(id, time.Second); !errors.Is(err, ErrNoDeadline) {
+			t.Errorf("Expected ErrNoDeadline for %s, got %v", id, err)
+		}
+	}
+}
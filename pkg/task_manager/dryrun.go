@@ -0,0 +1,75 @@
+package taskmanager
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// PlannedRun is a call of a task function skipped in dry-run mode, see
+// WithDryRun.
+type PlannedRun struct {
+	ID    string
+	RunID uint64
+	Time  time.Time
+}
+
+// WithDryRun makes the manager skip the task functions: every call is
+// recorded in Plan and reported as an EventPlanned instead, and returns nil.
+// Everything else works as usual, schedules, WithMaxConcurrent, retries,
+// continuations..., so that the wiring of the tasks can be tested; with a
+// FakeClock, Advance plays the schedules forward.
+func WithDryRun() Option {
+	return func(s *TaskManager) {
+		s.dryRun = &plan{}
+	}
+}
+
+type plan struct {
+	mu   sync.Mutex
+	runs []PlannedRun
+}
+
+// Plan returns the calls skipped so far in dry-run mode, in order. It is
+// empty without WithDryRun.
+func (s *TaskManager) Plan() []PlannedRun {
+	if s.dryRun == nil {
+		return []PlannedRun{}
+	}
+	s.dryRun.mu.Lock()
+	defer s.dryRun.mu.Unlock()
+	return slices.Clone(s.dryRun.runs)
+}
+
+// body returns fn, or in dry-run mode a function recording the call instead.
+// The managers's own wrappers, such as the loop of a recurring task, call it
+// on the function they wrap and are started with wrapped.
+func (s *TaskManager) body(id string, fn func(ctx context.Context) error) func(ctx context.Context) error {
+	if s.dryRun == nil {
+		return fn
+	}
+	return func(ctx context.Context) error {
+		run := PlannedRun{ID: id, Time: s.clock.Now()}
+		t, ok := taskFromContext(ctx)
+		if ok {
+			run.RunID = t.run
+		}
+		s.dryRun.mu.Lock()
+		s.dryRun.runs = append(s.dryRun.runs, run)
+		s.dryRun.mu.Unlock()
+
+		s.logger.Printf("Task %s: dry run, skipping the task function", id)
+		if ok {
+			s.emit(EventPlanned, t, 0, nil)
+		}
+		return nil
+	}
+}
+
+// wrapped marks a task whose function wraps the task function, see body.
+func wrapped() TaskOption {
+	return func(cfg *taskConfig) {
+		cfg.wrapped = true
+	}
+}
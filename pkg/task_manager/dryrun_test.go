@@ -0,0 +1,80 @@
+package taskmanager
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWithDryRun(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	tm := NewTaskManager(WithDryRun(), WithClock(clock))
+	events, unsubscribe := tm.Subscribe()
+	defer unsubscribe()
+
+	called := make(chan string, 10)
+	body := func(id string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			called <- id
+			return nil
+		}
+	}
+
+	h, _ := tm.StartTask(context.Background(), "extract", body("extract"))
+	h.Then("load", body("load"))
+	_ = tm.StartRecurringTask(context.Background(), "report", body("report"), Every(time.Hour))
+
+	// runs are recorded asynchronously
+	waitPlanned := func(n int) {
+		deadline := time.Now().Add(500 * time.Millisecond)
+		for len(tm.Plan()) < n {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %d planned runs, got %v", n, tm.Plan())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitPlanned(2)
+	for i := 0; i < 2; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(time.Hour)
+		waitPlanned(3 + i)
+	}
+	if err := tm.StopTaskAndWait("report", 500*time.Millisecond); err != nil {
+		t.Fatalf("Unexpected error stopping the task: %v", err)
+	}
+
+	select {
+	case id := <-called:
+		t.Fatalf("Expected no task function called, got %s", id)
+	default:
+	}
+
+	got := []string{}
+	for _, run := range tm.Plan() {
+		got = append(got, run.ID+"@"+run.Time.Sub(start).String())
+	}
+	slices.Sort(got)
+	want := []string{"extract@0s", "load@0s", "report@1h0m0s", "report@2h0m0s"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected the plan %v, got %v", want, got)
+	}
+
+	planned := 0
+	for {
+		select {
+		case e := <-events:
+			if e.Type == EventPlanned {
+				planned++
+			}
+			continue
+		default:
+		}
+		break
+	}
+	if planned != len(want) {
+		t.Errorf("Expected %d planned events, got %d", len(want), planned)
+	}
+}
@@ -0,0 +1,66 @@
+package taskmanager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime/pprof"
+	"strconv"
+)
+
+// Profiler labels set on the goroutine of every task, and inherited by the
+// goroutines it starts. They also show in CPU and goroutine profiles.
+const (
+	labelTaskID = "task_id"
+	labelRunID  = "run_id"
+)
+
+// labelGoroutine labels the calling goroutine with the ID and run of t.
+func labelGoroutine(ctx context.Context, t *task) {
+	pprof.SetGoroutineLabels(pprof.WithLabels(ctx, pprof.Labels(
+		labelTaskID, t.id,
+		labelRunID, strconv.FormatUint(t.run, 10),
+	)))
+}
+
+// DumpTask returns the stack traces of the goroutines of the running task id:
+// the goroutine running the task function and the goroutines it started. It
+// is a filtered goroutine profile in the debug=1 text format, to diagnose a
+// wedged task without a full goroutine dump, see also the
+// /tasks/{id}/stack admin endpoint. It returns ErrTaskNotFound if id is not
+// running.
+func (s *TaskManager) DumpTask(id string) ([]byte, error) {
+	v, ok := s.tasks.Load(id)
+	if !ok {
+		return nil, ErrTaskNotFound
+	}
+	t := v.(*task)
+
+	var profile bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&profile, 1); err != nil {
+		return nil, err
+	}
+
+	// records are separated by blank lines, the first one is the header
+	idLabel := fmt.Appendf(nil, "%q:%q", labelTaskID, t.id)
+	runLabel := fmt.Appendf(nil, "%q:%q", labelRunID, strconv.FormatUint(t.run, 10))
+	var dump bytes.Buffer
+	for _, record := range bytes.Split(profile.Bytes(), []byte("\n\n")) {
+		labels := labelsOf(record)
+		if bytes.Contains(labels, idLabel) && bytes.Contains(labels, runLabel) {
+			dump.Write(bytes.TrimSpace(record))
+			dump.WriteString("\n\n")
+		}
+	}
+	return dump.Bytes(), nil
+}
+
+// labelsOf returns the "# labels: {...}" line of a goroutine profile record.
+func labelsOf(record []byte) []byte {
+	for _, line := range bytes.Split(record, []byte("\n")) {
+		if labels, ok := bytes.CutPrefix(line, []byte("# labels: ")); ok {
+			return labels
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package taskmanager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func waitForDump(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func otherWaitForDump(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestDumpTask(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	started := make(chan struct{}, 2)
+	_, _ = tm.StartTask(context.Background(), "wedged", func(ctx context.Context) error {
+		started <- struct{}{}
+		return waitForDump(ctx)
+	})
+	_, _ = tm.StartTask(context.Background(), "other", func(ctx context.Context) error {
+		started <- struct{}{}
+		return otherWaitForDump(ctx)
+	})
+	<-started
+	<-started
+
+	dump, err := tm.DumpTask("wedged")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !bytes.Contains(dump, []byte("waitForDump")) {
+		t.Errorf("Expected the stack of the task, got %s", dump)
+	}
+	if bytes.Contains(dump, []byte("otherWaitForDump")) {
+		t.Errorf("Expected only the stack of the task, got %s", dump)
+	}
+
+	if _, err := tm.DumpTask("missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Expected ErrTaskNotFound, got %v", err)
+	}
+	if code := doAdmin(t, tm.AdminHandler(), http.MethodGet, "/tasks/missing/stack", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", code)
+	}
+}
@@ -1,9 +1,143 @@
 package taskmanager
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var (
-	ErrInvalidTaskID    = errors.New("invalid task id")
-	ErrNilTaskFunc      = errors.New("task function cannot be nil")
-	ErrTaskAlreadyExist = errors.New("task with this ID is already running")
+	ErrInvalidTaskID         = errors.New("invalid task id")
+	ErrNilTaskFunc           = errors.New("task function cannot be nil")
+	ErrNilSchedule           = errors.New("schedule cannot be nil")
+	ErrInvalidInterval       = errors.New("interval must be positive")
+	ErrTaskAlreadyExist      = errors.New("task with this ID is already running")
+	ErrTaskNotFound          = errors.New("task not found")
+	ErrDuplicateTaskID       = errors.New("duplicate task id")
+	ErrShuttingDown          = errors.New("task manager is not accepting new tasks")
+	ErrNoCheckpoint          = errors.New("no checkpoint")
+	ErrNoCheckpointStore     = errors.New("no checkpoint store")
+	ErrNilPanicHandler       = errors.New("panic policy requires a handler")
+	ErrQuotaExceeded         = errors.New("quota exceeded")
+	ErrStopTimeout           = errors.New("timed out waiting for task to stop")
+	ErrReplaceTimeout        = errors.New("timed out waiting for the replaced task to return")
+	ErrTaskTimedOut          = errors.New("task deadline exceeded")
+	ErrTaskAlreadyRegistered = errors.New("task with this name is already registered")
+	ErrTaskNotRegistered     = errors.New("no task registered with this name")
+	ErrNoStore               = errors.New("no store configured")
+	ErrNoLockProvider        = errors.New("no lock provider configured")
+	ErrLockLost              = errors.New("task lock lost")
+	ErrTaskStuck             = errors.New("task missed its heartbeat")
+	ErrPoolClosed            = errors.New("pool closed")
+	ErrNoDeadline            = errors.New("task has no active deadline")
+	ErrNoLease               = errors.New("task has no lease")
+	ErrLeaseExpired          = errors.New("task lease expired")
+	// ErrTaskStopped, ErrTaskReplaced and ErrShutdown are the causes of the
+	// task context cancellation, see StopTaskWithCause.
+	ErrTaskStopped  = errors.New("task stopped")
+	ErrTaskReplaced = errors.New("task replaced by a new run")
+	ErrShutdown     = errors.New("task manager shutting down")
+	// ErrParentFinished cancels the child tasks once their parent returned.
+	ErrParentFinished = errors.New("parent task finished")
 )
+
+// ShutdownError is returned by GracefulShutdownContext when tasks are still
+// running once its context is done.
+type ShutdownError struct {
+	// Remaining are the IDs of the tasks still running, sorted.
+	Remaining []string
+	// Err is the cause of the context, context.DeadlineExceeded on timeout.
+	Err error
+}
+
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("graceful shutdown: %v, %d task(s) still running: %s", e.Err, len(e.Remaining), strings.Join(e.Remaining, ", "))
+}
+
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
+}
+
+// QuotaError is returned by StartTask when the tenant of the task has reached
+// its quota, see WithTenantQuota. It matches ErrQuotaExceeded.
+type QuotaError struct {
+	Tenant string
+	Limit  int
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("tenant %s: %v (%d tasks)", e.Tenant, ErrQuotaExceeded, e.Limit)
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// PermanentError marks a failure that must not be retried nor restarted, see
+// Permanent.
+type PermanentError struct {
+	Err error
+}
+
+// Permanent wraps err so that WithRetry doesn't retry it and Supervise
+// doesn't restart the task, which ends with it. Permanent(nil) is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// IsPermanent reports whether err was wrapped by Permanent.
+func IsPermanent(err error) bool {
+	var permanent *PermanentError
+	return errors.As(err, &permanent)
+}
+
+// RetryableError marks a failure that must be retried, see Retryable.
+type RetryableError struct {
+	Err error
+}
+
+// Retryable wraps err so that WithRetry retries it even when it would not
+// otherwise, e.g. for a context.Canceled error of a sub-operation while the
+// task itself is still running. Permanent takes precedence over it.
+// Retryable(nil) is nil.
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &RetryableError{Err: err}
+}
+
+func (e *RetryableError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryableError) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable reports whether err was wrapped by Retryable.
+func IsRetryable(err error) bool {
+	var retryable *RetryableError
+	return errors.As(err, &retryable)
+}
+
+// PanicError is the error a task ends with when its function panics.
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("task panicked: %v", e.Value)
+}
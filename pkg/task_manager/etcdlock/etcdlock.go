@@ -0,0 +1,85 @@
+// Package etcdlock implements a taskmanager.LockProvider on top of etcd.
+//
+// Every lock is an etcd mutex bound to its own lease, kept alive by the
+// client. The lock frees itself once the lease expires, e.g. when the holder
+// dies.
+package etcdlock
+
+import (
+	"context"
+	"errors"
+
+	taskmanager "github.com/joripage/go_util/pkg/task_manager"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/client/v3/concurrency"
+)
+
+const (
+	// DefaultTTL is the lease TTL in seconds.
+	DefaultTTL       = 15
+	DefaultKeyPrefix = "/taskmanager/lock/"
+)
+
+// Provider hands out locks stored in etcd.
+type Provider struct {
+	client *clientv3.Client
+	ttl    int
+	prefix string
+}
+
+var _ taskmanager.LockProvider = (*Provider)(nil)
+
+type Option func(*Provider)
+
+// WithTTL sets the lease TTL in seconds, i.e. how long a lock outlives a
+// holder that stopped renewing it.
+func WithTTL(seconds int) Option {
+	return func(p *Provider) {
+		p.ttl = seconds
+	}
+}
+
+func WithKeyPrefix(prefix string) Option {
+	return func(p *Provider) {
+		p.prefix = prefix
+	}
+}
+
+func New(client *clientv3.Client, opts ...Option) *Provider {
+	p := &Provider{
+		client: client,
+		ttl:    DefaultTTL,
+		prefix: DefaultKeyPrefix,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *Provider) Acquire(ctx context.Context, key string) (taskmanager.Lock, error) {
+	// the session keeps its lease alive in the background, it must not
+	// depend on ctx
+	session, err := concurrency.NewSession(p.client, concurrency.WithTTL(p.ttl))
+	if err != nil {
+		return nil, err
+	}
+	mutex := concurrency.NewMutex(session, p.prefix+key)
+	if err := mutex.Lock(ctx); err != nil {
+		return nil, errors.Join(err, session.Close())
+	}
+	return &lock{session: session, mutex: mutex}, nil
+}
+
+type lock struct {
+	session *concurrency.Session
+	mutex   *concurrency.Mutex
+}
+
+func (l *lock) Lost() <-chan struct{} {
+	return l.session.Done()
+}
+
+func (l *lock) Release(ctx context.Context) error {
+	return errors.Join(l.mutex.Unlock(ctx), l.session.Close())
+}
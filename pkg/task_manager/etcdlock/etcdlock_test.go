@@ -0,0 +1,56 @@
+package etcdlock
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// The tests need a running etcd, e.g.
+// ETCD_ENDPOINTS=localhost:2379 go test ./pkg/task_manager/etcdlock
+func newProvider(t *testing.T) *Provider {
+	endpoints := os.Getenv("ETCD_ENDPOINTS")
+	if endpoints == "" {
+		t.Skip("ETCD_ENDPOINTS not set")
+	}
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   strings.Split(endpoints, ","),
+		DialTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error connecting to etcd: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return New(client, WithTTL(5), WithKeyPrefix("/taskmanager-test/"+t.Name()+"/"))
+}
+
+func TestProvider_AcquireRelease(t *testing.T) {
+	p := newProvider(t)
+	ctx := context.Background()
+
+	l, err := p.Acquire(ctx, "job")
+	if err != nil {
+		t.Fatalf("Unexpected error acquiring lock: %v", err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 200*time.Millisecond)
+	defer cancel()
+	if _, err := p.Acquire(waitCtx, "job"); err == nil {
+		t.Fatal("Expected a held lock not to be acquired twice")
+	}
+
+	if err := l.Release(ctx); err != nil {
+		t.Fatalf("Unexpected error releasing lock: %v", err)
+	}
+	waitCtx, cancel = context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	l, err = p.Acquire(waitCtx, "job")
+	if err != nil {
+		t.Fatalf("Expected a released lock to be acquired, got %v", err)
+	}
+	_ = l.Release(ctx)
+}
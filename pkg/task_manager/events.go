@@ -0,0 +1,121 @@
+package taskmanager
+
+import (
+	"sync"
+	"time"
+)
+
+type EventType int
+
+const (
+	EventStarted EventType = iota
+	EventCompleted
+	// EventFailed is also sent for tasks that panicked or timed out, Err
+	// tells them apart.
+	EventFailed
+	EventCanceled
+	// EventReplaced is sent for a running task when a new task with the same
+	// ID is started and cancels it.
+	EventReplaced
+	// EventStuck is sent when a running task misses its heartbeat timeout.
+	EventStuck
+	// EventSuppressed is sent when a scheduled run of a recurring task falls
+	// inside an exclusion window, see WithExclusionWindow.
+	EventSuppressed
+	// EventPlanned is sent instead of calling a task function in dry-run
+	// mode, see WithDryRun.
+	EventPlanned
+	// EventAbandoned is sent when a stopped task did not return in time, see
+	// WithStopTimeout.
+	EventAbandoned
+)
+
+var eventNames = [...]string{
+	EventStarted:    "started",
+	EventCompleted:  "completed",
+	EventFailed:     "failed",
+	EventCanceled:   "canceled",
+	EventReplaced:   "replaced",
+	EventStuck:      "stuck",
+	EventSuppressed: "suppressed",
+	EventPlanned:    "planned",
+	EventAbandoned:  "abandoned",
+}
+
+func (e EventType) String() string {
+	if e < 0 || int(e) >= len(eventNames) {
+		return "unknown"
+	}
+	return eventNames[e]
+}
+
+// TaskEvent describes a task lifecycle transition.
+type TaskEvent struct {
+	Type EventType
+	ID   string
+	// RunID tells runs of the same ID apart, see TaskHandle.RunID.
+	RunID    uint64
+	Time     time.Time
+	Duration time.Duration
+	Err      error
+}
+
+// subscriberBuffer is the number of events buffered per subscriber. Events
+// that don't fit are dropped so that slow subscribers never block tasks.
+const subscriberBuffer = 64
+
+type broker struct {
+	mu   sync.Mutex
+	subs map[chan TaskEvent]struct{}
+}
+
+func (b *broker) subscribe() (chan TaskEvent, func()) {
+	ch := make(chan TaskEvent, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subs == nil {
+		b.subs = map[chan TaskEvent]struct{}{}
+	}
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subs, ch)
+			close(ch)
+		})
+	}
+}
+
+func (b *broker) publish(e TaskEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving every task lifecycle event from now
+// on and a function to unsubscribe, which closes the channel. Events are
+// dropped for a subscriber whose buffer is full.
+func (s *TaskManager) Subscribe() (<-chan TaskEvent, func()) {
+	return s.events.subscribe()
+}
+
+func (s *TaskManager) emit(typ EventType, t *task, duration time.Duration, err error) {
+	s.events.publish(TaskEvent{
+		Type:     typ,
+		ID:       t.id,
+		RunID:    t.run,
+		Time:     s.clock.Now(),
+		Duration: duration,
+		Err:      err,
+	})
+}
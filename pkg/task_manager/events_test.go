@@ -0,0 +1,101 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func nextEvent(t *testing.T, events <-chan TaskEvent) TaskEvent {
+	t.Helper()
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Timed out waiting for event")
+		return TaskEvent{}
+	}
+}
+
+func TestSubscribe_LifecycleEvents(t *testing.T) {
+	tm := NewTaskManager()
+	events, unsubscribe := tm.Subscribe()
+	defer unsubscribe()
+
+	boom := errors.New("boom")
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		return boom
+	})
+
+	if e := nextEvent(t, events); e.Type != EventStarted || e.ID != "task" || e.Time.IsZero() {
+		t.Errorf("Expected started event, got %+v", e)
+	}
+	if e := nextEvent(t, events); e.Type != EventFailed || !errors.Is(e.Err, boom) {
+		t.Errorf("Expected failed event, got %+v", e)
+	}
+}
+
+func TestSubscribe_ReplacedAndCanceled(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	_, _ = tm.StartTask(context.Background(), "task", block)
+	time.Sleep(10 * time.Millisecond)
+
+	events, unsubscribe := tm.Subscribe()
+	defer unsubscribe()
+	_, _ = tm.StartTask(context.Background(), "task", block)
+
+	got := map[EventType]bool{}
+	for range 3 {
+		got[nextEvent(t, events).Type] = true
+	}
+	for _, typ := range []EventType{EventReplaced, EventCanceled, EventStarted} {
+		if !got[typ] {
+			t.Errorf("Expected %v event, got %v", typ, got)
+		}
+	}
+}
+
+func TestSubscribe_MultipleAndSlowSubscribers(t *testing.T) {
+	tm := NewTaskManager()
+	slow, unsubscribeSlow := tm.Subscribe()
+	defer unsubscribeSlow()
+	fast, unsubscribeFast := tm.Subscribe()
+	defer unsubscribeFast()
+
+	// nobody reads slow, tasks must keep running regardless
+	for i := range subscriberBuffer {
+		_, _ = tm.StartTask(context.Background(), fmt.Sprint("task", i), func(ctx context.Context) error { return nil })
+		if e := nextEvent(t, fast); e.Type != EventStarted {
+			t.Fatalf("Expected started event, got %+v", e)
+		}
+		if e := nextEvent(t, fast); e.Type != EventCompleted {
+			t.Fatalf("Expected completed event, got %+v", e)
+		}
+	}
+
+	if len(slow) != subscriberBuffer {
+		t.Errorf("Expected slow subscriber buffer to be full, got %d", len(slow))
+	}
+}
+
+func TestSubscribe_Unsubscribe(t *testing.T) {
+	tm := NewTaskManager()
+	events, unsubscribe := tm.Subscribe()
+
+	unsubscribe()
+	unsubscribe() // must be safe to call twice
+
+	if _, ok := <-events; ok {
+		t.Error("Expected channel to be closed after unsubscribe")
+	}
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error { return nil })
+	_, _ = tm.WaitTask(context.Background(), "task")
+}
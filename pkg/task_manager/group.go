@@ -0,0 +1,116 @@
+package taskmanager
+
+import (
+	"context"
+	"sync"
+)
+
+// Group runs tasks together with errgroup semantics, see StartGroup.
+type Group struct {
+	tm       *TaskManager
+	id       string
+	ctx      context.Context
+	cancel   context.CancelCauseFunc
+	failFast bool
+
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	err  error // first member error
+	once sync.Once
+	done chan struct{} // closed once Wait saw every member return
+}
+
+type GroupOption func(*Group)
+
+// WithoutFailFast keeps the other members running when one fails. By
+// default the first failure cancels the whole group.
+func WithoutFailFast() GroupOption {
+	return func(g *Group) {
+		g.failFast = false
+	}
+}
+
+// StartGroup starts the group task groupID, which runs until Wait has
+// returned. Members started with Go are tasks named "groupID/id" and tagged
+// with groupID. The group is listed by ListTasks like any task, and stopping
+// it with StopTask stops all of its members.
+func (s *TaskManager) StartGroup(ctx context.Context, groupID string, opts ...GroupOption) (*Group, error) {
+	gctx, cancel := context.WithCancelCause(ctx)
+	g := &Group{
+		tm:       s,
+		id:       groupID,
+		ctx:      gctx,
+		cancel:   cancel,
+		failFast: true,
+		done:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	_, err := s.StartTask(ctx, groupID, func(ctx context.Context) error {
+		select {
+		case <-g.done:
+			return g.result()
+		case <-ctx.Done():
+			g.cancel(context.Cause(ctx))
+			return ctx.Err()
+		}
+	}, wrapped())
+	if err != nil {
+		cancel(err)
+		return nil, err
+	}
+	return g, nil
+}
+
+// ID returns the ID of the group task.
+func (g *Group) ID() string {
+	return g.id
+}
+
+// Go starts fn as the member task "groupID/id". The first member to fail
+// cancels the other members, unless WithoutFailFast was given.
+func (g *Group) Go(id string, fn func(ctx context.Context) error, opts ...TaskOption) error {
+	g.wg.Add(1)
+	opts = append(opts, WithTags(g.id), alsoOnComplete(g.memberDone))
+	if _, err := g.tm.StartTask(g.ctx, g.id+"/"+id, fn, opts...); err != nil {
+		g.wg.Done()
+		return err
+	}
+	return nil
+}
+
+func (g *Group) memberDone(err error) {
+	defer g.wg.Done()
+	if err == nil {
+		return
+	}
+
+	g.mu.Lock()
+	first := g.err == nil
+	if first {
+		g.err = err
+	}
+	g.mu.Unlock()
+	if first && g.failFast {
+		g.cancel(err)
+	}
+}
+
+// Wait blocks until every member has returned, then ends the group task and
+// returns the first member error.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.once.Do(func() {
+		close(g.done)
+		g.cancel(nil)
+	})
+	return g.result()
+}
+
+func (g *Group) result() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.err
+}
@@ -0,0 +1,120 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroup_FirstFailureCancelsMembers(t *testing.T) {
+	tm := NewTaskManager()
+	boom := errors.New("boom")
+
+	g, err := tm.StartGroup(context.Background(), "import")
+	if err != nil {
+		t.Fatalf("Unexpected error starting group: %v", err)
+	}
+	_ = g.Go("fails", func(ctx context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return boom
+	})
+	_ = g.Go("blocks", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	if !tm.HasTask("import") || !tm.HasTask("import/blocks") {
+		t.Error("Expected the group and its members to be listed")
+	}
+	if got := len(tm.ListTasksByTag("import")); got != 2 {
+		t.Errorf("Expected 2 members tagged with the group ID, got %d", got)
+	}
+
+	if err := g.Wait(); !errors.Is(err, boom) {
+		t.Errorf("Expected the first failure, got %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if info, _ := tm.Status("import"); info.Status != StatusFailed || !errors.Is(info.Err, boom) {
+		t.Errorf("Expected the group task to fail with the first error, got %+v", info)
+	}
+	if info, _ := tm.Status("import/blocks"); !errors.Is(info.Err, boom) {
+		t.Errorf("Expected the other member to be canceled because of the failure, got %v", info.Err)
+	}
+}
+
+func TestGroup_WithoutFailFast(t *testing.T) {
+	tm := NewTaskManager()
+	boom := errors.New("boom")
+
+	g, _ := tm.StartGroup(context.Background(), "import", WithoutFailFast())
+	var completed atomic.Bool
+	_ = g.Go("fails", func(ctx context.Context) error { return boom })
+	_ = g.Go("slow", func(ctx context.Context) error {
+		select {
+		case <-time.After(50 * time.Millisecond):
+			completed.Store(true)
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	if err := g.Wait(); !errors.Is(err, boom) {
+		t.Errorf("Expected the failure to be reported, got %v", err)
+	}
+	if !completed.Load() {
+		t.Error("Expected the other member to keep running")
+	}
+}
+
+func TestGroup_StopTaskStopsMembers(t *testing.T) {
+	tm := NewTaskManager()
+
+	g, _ := tm.StartGroup(context.Background(), "import")
+	var onComplete atomic.Int64
+	for _, id := range []string{"a", "b"} {
+		_ = g.Go(id, func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}, WithOnComplete(func(err error) { onComplete.Add(1) }))
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	if !tm.StopTask("import") {
+		t.Fatal("Expected the group to be stoppable")
+	}
+	done := make(chan error, 1)
+	go func() { done <- g.Wait() }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrTaskStopped) {
+			t.Errorf("Expected members to be stopped with the group, got %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected stopping the group to stop its members")
+	}
+	if n := onComplete.Load(); n != 2 {
+		t.Errorf("Expected the members' own WithOnComplete to run, ran %d times", n)
+	}
+}
+
+func TestGroup_Success(t *testing.T) {
+	tm := NewTaskManager()
+
+	g, _ := tm.StartGroup(context.Background(), "import")
+	for _, id := range []string{"a", "b", "c"} {
+		_ = g.Go(id, func(ctx context.Context) error { return nil })
+	}
+	if err := g.Wait(); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if info, _ := tm.Status("import"); info.Status != StatusCompleted {
+		t.Errorf("Expected the group task to complete, got %s", info.Status)
+	}
+	if _, err := tm.StartGroup(context.Background(), ""); !errors.Is(err, ErrInvalidTaskID) {
+		t.Errorf("Expected ErrInvalidTaskID, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package taskmanager
+
+import "context"
+
+// TaskHandle refers to one run of a task. Unlike the methods of TaskManager
+// taking an ID, its methods never act on another run started with the same
+// ID, e.g. a replacement started by someone else.
+type TaskHandle struct {
+	tm *TaskManager
+	t  *task
+}
+
+// Handle returns a handle to the running task with the given id.
+func (s *TaskManager) Handle(id string) (*TaskHandle, bool) {
+	v, ok := s.tasks.Load(id)
+	if !ok {
+		return nil, false
+	}
+	return &TaskHandle{tm: s, t: v.(*task)}, true
+}
+
+// ID returns the task ID.
+func (h *TaskHandle) ID() string {
+	return h.t.id
+}
+
+// RunID returns the ID of this run, unique within the manager and increasing
+// with every started task.
+func (h *TaskHandle) RunID() uint64 {
+	return h.t.run
+}
+
+// Stop stops this run. It reports whether the run was still the running task
+// of its ID; a run that was replaced is canceled already.
+func (h *TaskHandle) Stop() bool {
+	if !h.tm.tasks.CompareAndDelete(h.t.id, h.t) {
+		return false
+	}
+	h.tm.auditTask(context.Background(), AuditStop, h.t)
+	h.t.cancel(ErrTaskStopped)
+	return true
+}
+
+// Wait blocks until this run returns and yields the error of the task
+// function, or ctx.Err() if ctx is done first.
+func (h *TaskHandle) Wait(ctx context.Context) error {
+	select {
+	case <-h.t.done:
+		return h.t.result()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Done is closed once this run has finished.
+func (h *TaskHandle) Done() <-chan struct{} {
+	return h.t.done
+}
+
+// Info returns a snapshot of this run.
+func (h *TaskHandle) Info() TaskInfo {
+	return h.t.info(h.tm.clock.Now())
+}
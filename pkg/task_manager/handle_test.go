@@ -0,0 +1,101 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTaskHandle_RunID(t *testing.T) {
+	tm := NewTaskManager()
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	first, err := tm.StartTask(context.Background(), "task", block)
+	if err != nil {
+		t.Fatalf("Unexpected error starting task: %v", err)
+	}
+	second, _ := tm.StartTask(context.Background(), "task", block)
+	defer second.Stop()
+
+	if first.ID() != "task" || second.ID() != "task" {
+		t.Errorf("Expected both handles to have the task ID, got %q and %q", first.ID(), second.ID())
+	}
+	if second.RunID() <= first.RunID() {
+		t.Errorf("Expected increasing run IDs, got %d then %d", first.RunID(), second.RunID())
+	}
+	if info, _ := tm.Status("task"); info.RunID != second.RunID() {
+		t.Errorf("Expected the status of run %d, got %d", second.RunID(), info.RunID)
+	}
+}
+
+func TestTaskHandle_StopKeepsReplacement(t *testing.T) {
+	tm := NewTaskManager()
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	first, _ := tm.StartTask(context.Background(), "task", block)
+	second, _ := tm.StartTask(context.Background(), "task", block)
+
+	if first.Stop() {
+		t.Error("Expected Stop of a replaced run to report false")
+	}
+	if !tm.HasTask("task") {
+		t.Fatal("Expected the replacement to keep running")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := first.Wait(ctx); !errors.Is(err, ErrTaskReplaced) {
+		t.Errorf("Expected the first run to end with ErrTaskReplaced, got %v", err)
+	}
+
+	if !second.Stop() {
+		t.Error("Expected Stop of the running task to report true")
+	}
+	if err := second.Wait(ctx); !errors.Is(err, ErrTaskStopped) {
+		t.Errorf("Expected the second run to end with ErrTaskStopped, got %v", err)
+	}
+	if tm.HasTask("task") {
+		t.Error("Expected no running task")
+	}
+}
+
+func TestTaskHandle_WaitContext(t *testing.T) {
+	tm := NewTaskManager()
+
+	h, _ := tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	defer h.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := h.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the context error, got %v", err)
+	}
+}
+
+func TestTaskHandle_Dedup(t *testing.T) {
+	tm := NewTaskManager()
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}
+
+	first, _ := tm.StartTask(context.Background(), "task", block, WithDedupWindow(time.Minute))
+	defer first.Stop()
+	second, err := tm.StartTask(context.Background(), "task", block, WithDedupWindow(time.Minute))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if second.RunID() != first.RunID() {
+		t.Errorf("Expected the handle of the running task, got run %d instead of %d", second.RunID(), first.RunID())
+	}
+}
@@ -0,0 +1,57 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// WithHealthCheck sets a function reporting whether the running task is
+// healthy, e.g. whether its connection is still up. It is called by Healthy,
+// ListTasks and Status, so it must be fast and safe for concurrent use. A task
+// flagged as stuck by WithHeartbeatTimeout is unhealthy whatever fn returns.
+func WithHealthCheck(fn func(ctx context.Context) error) TaskOption {
+	return func(cfg *taskConfig) {
+		cfg.healthCheck = fn
+	}
+}
+
+// Healthy checks every running task and returns nil if all of them are
+// healthy, or the errors of the unhealthy ones, sorted by task ID. It suits
+// liveness or readiness probes, see also the /healthz admin endpoint.
+func (s *TaskManager) Healthy(ctx context.Context) error {
+	tasks := []*task{}
+	s.tasks.Range(func(key, value interface{}) bool {
+		tasks = append(tasks, value.(*task))
+		return true
+	})
+	slices.SortFunc(tasks, func(a, b *task) int { return strings.Compare(a.id, b.id) })
+
+	errs := []error{}
+	for _, t := range tasks {
+		if err := t.checkHealth(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("task %s: %w", t.id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkHealth returns nil unless the task is running and either stuck or
+// failing its health check.
+func (t *task) checkHealth(ctx context.Context) error {
+	t.mu.Lock()
+	running, stuck := t.status == StatusRunning, t.stuck
+	t.mu.Unlock()
+
+	switch {
+	case !running:
+		return nil
+	case stuck:
+		return ErrTaskStuck
+	case t.healthCheck != nil:
+		return t.healthCheck(ctx)
+	}
+	return nil
+}
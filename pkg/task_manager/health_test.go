@@ -0,0 +1,65 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealthy(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	var broken atomic.Bool
+	errBroken := errors.New("connection lost")
+	started := make(chan struct{})
+	_, _ = tm.StartTask(context.Background(), "consumer", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithHealthCheck(func(ctx context.Context) error {
+		if broken.Load() {
+			return errBroken
+		}
+		return nil
+	}))
+	<-started
+
+	if err := tm.Healthy(context.Background()); err != nil {
+		t.Errorf("Expected a healthy manager, got %v", err)
+	}
+
+	broken.Store(true)
+	if err := tm.Healthy(context.Background()); !errors.Is(err, errBroken) {
+		t.Errorf("Expected the health check error, got %v", err)
+	}
+	if info, _ := tm.Status("consumer"); !errors.Is(info.Health, errBroken) {
+		t.Errorf("Expected the task health in Status, got %v", info.Health)
+	}
+	if tasks := tm.ListTasks(); len(tasks) != 1 || !errors.Is(tasks[0].Health, errBroken) {
+		t.Errorf("Expected the task health in ListTasks, got %+v", tasks)
+	}
+
+	var body map[string]any
+	if code := doAdmin(t, tm.AdminHandler(), http.MethodGet, "/healthz", &body); code != http.StatusServiceUnavailable || body["healthy"] != false {
+		t.Errorf("Expected 503 from /healthz, got %d %v", code, body)
+	}
+}
+
+func TestHealthy_Stuck(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	_, _ = tm.StartTask(context.Background(), "silent", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithHeartbeatTimeout(20*time.Millisecond, StuckFlag))
+
+	time.Sleep(60 * time.Millisecond)
+	if err := tm.Healthy(context.Background()); !errors.Is(err, ErrTaskStuck) {
+		t.Errorf("Expected ErrTaskStuck for a stuck task, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// StuckAction is what the manager does with a task that stopped sending
+// heartbeats, see WithHeartbeatTimeout.
+type StuckAction int
+
+const (
+	// StuckFlag only reports the task as stuck. It is no longer reported
+	// once it sends a heartbeat again.
+	StuckFlag StuckAction = iota
+	// StuckCancel cancels the task, which fails with ErrTaskStuck.
+	StuckCancel
+	// StuckRestart cancels the task function and calls it again.
+	StuckRestart
+)
+
+// WithHeartbeatTimeout declares the task stuck when it has not called
+// Heartbeat for d, counting from the start of the task. A stuck task is
+// logged, reported to the OnStuck hooks and as an EventStuck, then handled
+// according to action.
+func WithHeartbeatTimeout(d time.Duration, action StuckAction) TaskOption {
+	return func(cfg *taskConfig) {
+		cfg.heartbeatTimeout = d
+		cfg.stuckAction = action
+	}
+}
+
+// Heartbeat tells the manager that the task owning ctx is making progress.
+// It is a no-op outside a managed task.
+func Heartbeat(ctx context.Context) {
+	if t, ok := taskFromContext(ctx); ok {
+		t.beat(t.clock.Now())
+	}
+}
+
+// OnStuck registers a hook called when a task is declared stuck, see
+// WithHeartbeatTimeout.
+func (s *TaskManager) OnStuck(fn Hook) {
+	s.hooks.add(hookStuck, fn)
+}
+
+// executeWatched runs the task function under the heartbeat watchdog, if
+// the task has a heartbeat timeout.
+func (s *TaskManager) executeWatched(ctx context.Context, t *task, fn func(ctx context.Context) error, cfg taskConfig) error {
+	if cfg.heartbeatTimeout <= 0 {
+		return s.execute(ctx, t.id, fn, cfg)
+	}
+
+	for {
+		t.beat(s.clock.Now())
+		runCtx, cancel := context.WithCancelCause(ctx)
+		go s.watchHeartbeat(runCtx, t, cfg, cancel)
+
+		err := s.execute(runCtx, t.id, fn, cfg)
+		stuck := errors.Is(context.Cause(runCtx), ErrTaskStuck)
+		cancel(nil)
+
+		if !stuck {
+			return err
+		}
+		if cfg.stuckAction == StuckRestart && ctx.Err() == nil {
+			s.logger.Printf("Task %s restarting after being stuck", t.id)
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrTaskStuck) {
+			err = fmt.Errorf("%w: %w", ErrTaskStuck, err)
+		}
+		return err
+	}
+}
+
+func (s *TaskManager) watchHeartbeat(ctx context.Context, t *task, cfg taskConfig, cancel context.CancelCauseFunc) {
+	interval := cfg.heartbeatTimeout / 4
+	timer := s.clock.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C():
+			timer.Reset(interval)
+		}
+
+		silent := s.clock.Now().Sub(t.lastBeat())
+		if silent < cfg.heartbeatTimeout {
+			continue
+		}
+		if t.markStuck() {
+			s.logger.Printf("Task %s is stuck, no heartbeat for %s", t.id, silent.Round(time.Millisecond))
+			duration := runningFor(t.started(), s.clock.Now())
+			s.hooks.run(hookStuck, t.id, duration, ErrTaskStuck)
+			s.emit(EventStuck, t, duration, ErrTaskStuck)
+		}
+		if cfg.stuckAction != StuckFlag {
+			cancel(ErrTaskStuck)
+			return
+		}
+	}
+}
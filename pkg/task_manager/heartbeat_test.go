@@ -0,0 +1,103 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHeartbeat_FlagStuck(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	var stuckHooks atomic.Int64
+	tm.OnStuck(func(id string, duration time.Duration, err error) {
+		stuckHooks.Add(1)
+	})
+	events, unsubscribe := tm.Subscribe()
+	defer unsubscribe()
+
+	beat := make(chan struct{})
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		for {
+			select {
+			case <-beat:
+				Heartbeat(ctx)
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}, WithHeartbeatTimeout(40*time.Millisecond, StuckFlag))
+
+	nextEvent(t, events) // started
+	if e := nextEvent(t, events); e.Type != EventStuck || !errors.Is(e.Err, ErrTaskStuck) {
+		t.Fatalf("Expected a stuck event, got %+v", e)
+	}
+	if info, _ := tm.Status("task"); !info.Stuck || info.Status != StatusRunning {
+		t.Errorf("Expected a running task flagged as stuck, got %+v", info)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if n := stuckHooks.Load(); n != 1 {
+		t.Errorf("Expected the stuck hook to run once, ran %d times", n)
+	}
+
+	beat <- struct{}{}
+	time.Sleep(5 * time.Millisecond)
+	if info, _ := tm.Status("task"); info.Stuck || info.LastHeartbeat.IsZero() {
+		t.Errorf("Expected a heartbeat to clear the stuck flag, got %+v", info)
+	}
+}
+
+func TestHeartbeat_CancelStuck(t *testing.T) {
+	tm := NewTaskManager()
+
+	done := make(chan error, 1)
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithHeartbeatTimeout(40*time.Millisecond, StuckCancel), WithOnComplete(func(err error) { done <- err }))
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrTaskStuck) {
+			t.Errorf("Expected ErrTaskStuck, got %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected the stuck task to be canceled")
+	}
+	if info, _ := tm.Status("task"); info.Status != StatusFailed {
+		t.Errorf("Expected status failed, got %s", info.Status)
+	}
+}
+
+func TestHeartbeat_RestartStuck(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	var runs atomic.Int64
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		if runs.Add(1) > 1 {
+			for {
+				Heartbeat(ctx)
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(5 * time.Millisecond):
+				}
+			}
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithHeartbeatTimeout(40*time.Millisecond, StuckRestart))
+
+	time.Sleep(200 * time.Millisecond)
+	if n := runs.Load(); n != 2 {
+		t.Errorf("Expected the stuck task to be restarted once, ran %d times", n)
+	}
+	if info, _ := tm.Status("task"); info.Status != StatusRunning || info.Stuck {
+		t.Errorf("Expected the restarted task to be running, got %+v", info)
+	}
+}
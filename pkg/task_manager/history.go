@@ -0,0 +1,54 @@
+package taskmanager
+
+import "sync"
+
+// history keeps the last finished task runs in a ring buffer.
+type history struct {
+	mu   sync.Mutex
+	runs []TaskInfo
+	next int
+	full bool
+}
+
+func newHistory(size int) *history {
+	return &history{runs: make([]TaskInfo, size)}
+}
+
+func (h *history) add(info TaskInfo) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.runs[h.next] = info
+	h.next = (h.next + 1) % len(h.runs)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+func (h *history) list() []TaskInfo {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.full {
+		return append([]TaskInfo{}, h.runs[:h.next]...)
+	}
+	return append(append([]TaskInfo{}, h.runs[h.next:]...), h.runs[:h.next]...)
+}
+
+// WithHistorySize keeps the last n finished task runs, see History.
+func WithHistorySize(n int) Option {
+	return func(s *TaskManager) {
+		if n > 0 {
+			s.history = newHistory(n)
+		}
+	}
+}
+
+// History returns the finished task runs kept because of WithHistorySize,
+// oldest first.
+func (s *TaskManager) History() []TaskInfo {
+	if s.history == nil {
+		return []TaskInfo{}
+	}
+	return s.history.list()
+}
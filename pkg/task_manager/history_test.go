@@ -0,0 +1,51 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestHistory_KeepsLastRuns(t *testing.T) {
+	tm := NewTaskManager(WithHistorySize(3))
+	boom := errors.New("boom")
+
+	for i := range 5 {
+		id := fmt.Sprint("task", i)
+		_, _ = tm.StartTask(context.Background(), id, func(ctx context.Context) error {
+			if i == 4 {
+				return boom
+			}
+			return nil
+		})
+		_, _ = tm.WaitTask(context.Background(), id)
+	}
+
+	runs := tm.History()
+	if len(runs) != 3 {
+		t.Fatalf("Expected 3 runs in history, got %d", len(runs))
+	}
+	for i, run := range runs {
+		if want := fmt.Sprint("task", i+2); run.ID != want {
+			t.Errorf("Expected %s at position %d, got %s", want, i, run.ID)
+		}
+		if run.StartedAt.IsZero() || run.FinishedAt.IsZero() {
+			t.Errorf("Expected timestamps for %s, got %+v", run.ID, run)
+		}
+	}
+	if last := runs[2]; last.Status != StatusFailed || !errors.Is(last.Err, boom) {
+		t.Errorf("Expected last run to have failed, got %+v", last)
+	}
+}
+
+func TestHistory_Disabled(t *testing.T) {
+	tm := NewTaskManager()
+
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error { return nil })
+	_, _ = tm.WaitTask(context.Background(), "task")
+
+	if runs := tm.History(); len(runs) != 0 {
+		t.Errorf("Expected no history by default, got %d runs", len(runs))
+	}
+}
@@ -0,0 +1,65 @@
+package taskmanager
+
+import (
+	"sync"
+	"time"
+)
+
+// Hook is called on a task lifecycle transition. duration is how long the
+// task has been running and err is its result, both zero for OnStart.
+type Hook func(id string, duration time.Duration, err error)
+
+type hookKind int
+
+const (
+	hookStart hookKind = iota
+	hookComplete
+	hookError
+	hookCancel
+	hookStuck
+	numHookKinds
+)
+
+type hooks struct {
+	mu  sync.RWMutex
+	fns [numHookKinds][]Hook
+}
+
+func (h *hooks) add(kind hookKind, fn Hook) {
+	if fn == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fns[kind] = append(h.fns[kind], fn)
+}
+
+func (h *hooks) run(kind hookKind, id string, duration time.Duration, err error) {
+	h.mu.RLock()
+	fns := h.fns[kind]
+	h.mu.RUnlock()
+
+	for _, fn := range fns {
+		fn(id, duration, err)
+	}
+}
+
+// OnStart registers a hook called when a task starts running.
+func (s *TaskManager) OnStart(fn Hook) {
+	s.hooks.add(hookStart, fn)
+}
+
+// OnComplete registers a hook called when a task returns without error.
+func (s *TaskManager) OnComplete(fn Hook) {
+	s.hooks.add(hookComplete, fn)
+}
+
+// OnError registers a hook called when a task fails, times out or panics.
+func (s *TaskManager) OnError(fn Hook) {
+	s.hooks.add(hookError, fn)
+}
+
+// OnCancel registers a hook called when a task ends because it was canceled.
+func (s *TaskManager) OnCancel(fn Hook) {
+	s.hooks.add(hookCancel, fn)
+}
@@ -0,0 +1,89 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type hookCall struct {
+	kind     string
+	id       string
+	duration time.Duration
+	err      error
+}
+
+func recordHooks(tm *TaskManager) func() []hookCall {
+	var mu sync.Mutex
+	var calls []hookCall
+	record := func(kind string) Hook {
+		return func(id string, duration time.Duration, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, hookCall{kind, id, duration, err})
+		}
+	}
+	tm.OnStart(record("start"))
+	tm.OnComplete(record("complete"))
+	tm.OnError(record("error"))
+	tm.OnCancel(record("cancel"))
+
+	return func() []hookCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]hookCall(nil), calls...)
+	}
+}
+
+func TestHooks_Complete(t *testing.T) {
+	tm := NewTaskManager()
+	calls := recordHooks(tm)
+
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	_, _ = tm.WaitTask(context.Background(), "task")
+
+	got := calls()
+	if len(got) != 2 || got[0].kind != "start" || got[1].kind != "complete" {
+		t.Fatalf("Expected start and complete hooks, got %+v", got)
+	}
+	if got[1].id != "task" || got[1].duration < 10*time.Millisecond || got[1].err != nil {
+		t.Errorf("Unexpected complete hook call: %+v", got[1])
+	}
+}
+
+func TestHooks_ErrorAndCancel(t *testing.T) {
+	tm := NewTaskManager()
+	calls := recordHooks(tm)
+
+	boom := errors.New("boom")
+	_, _ = tm.StartTask(context.Background(), "failing", func(ctx context.Context) error {
+		return boom
+	})
+	_, _ = tm.WaitTask(context.Background(), "failing")
+
+	_, _ = tm.StartTask(context.Background(), "canceled", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	time.Sleep(10 * time.Millisecond)
+	_ = tm.StopTaskAndWait("canceled", 500*time.Millisecond)
+
+	kinds := map[string]hookCall{}
+	for _, call := range calls() {
+		kinds[call.kind+":"+call.id] = call
+	}
+	if call, ok := kinds["error:failing"]; !ok || !errors.Is(call.err, boom) {
+		t.Errorf("Expected error hook with task error, got %+v", call)
+	}
+	if call, ok := kinds["cancel:canceled"]; !ok || !errors.Is(call.err, context.Canceled) {
+		t.Errorf("Expected cancel hook, got %+v", call)
+	}
+	if _, ok := kinds["complete:failing"]; ok {
+		t.Error("Complete hook should not run for a failed task")
+	}
+}
@@ -0,0 +1,75 @@
+package taskmanager
+
+import "time"
+
+// WithIdempotencyKey makes starting the task a no-op while a run of the same
+// ID started with the same key is running or completed less than ttl ago:
+// the returned TaskHandle refers to that run instead, so its Wait and
+// Info().Result give the cached result. Runs that failed or were canceled
+// are not cached. It suits expensive tasks triggered repeatedly, e.g.
+// generating the same report.
+func WithIdempotencyKey(key string, ttl time.Duration) TaskOption {
+	return func(cfg *taskConfig) {
+		cfg.idempotencyKey = key
+		cfg.idempotencyTTL = ttl
+	}
+}
+
+type resultKey struct {
+	id, key string
+}
+
+// cacheRun caches t for the ID and idempotency key of cfg, unless another run
+// was cached meanwhile, which it returns.
+func (s *TaskManager) cacheRun(t *task, cfg taskConfig) (*task, bool) {
+	if cfg.idempotencyKey == "" {
+		return t, true
+	}
+	key := resultKey{t.id, cfg.idempotencyKey}
+	for {
+		v, loaded := s.results.LoadOrStore(key, t)
+		if !loaded {
+			return t, true
+		}
+		if cached := v.(*task); s.fresh(cached, cfg) {
+			return cached, false
+		}
+		if s.results.CompareAndSwap(key, v, t) {
+			return t, true
+		}
+	}
+}
+
+// uncacheRun drops t, cached by cacheRun for a start that failed.
+func (s *TaskManager) uncacheRun(t *task, cfg taskConfig) {
+	if cfg.idempotencyKey != "" {
+		s.results.CompareAndDelete(resultKey{t.id, cfg.idempotencyKey}, t)
+	}
+}
+
+// fresh reports whether the cached run t is running or finished less than
+// the TTL ago. The expiry timer of expireRun may not have run yet.
+func (s *TaskManager) fresh(t *task, cfg taskConfig) bool {
+	select {
+	case <-t.done:
+		return s.clock.Now().Before(t.info(s.clock.Now()).FinishedAt.Add(cfg.idempotencyTTL))
+	default:
+		return true
+	}
+}
+
+// expireRun keeps the finished run t cached for the TTL if it completed, and
+// drops it otherwise.
+func (s *TaskManager) expireRun(t *task, cfg taskConfig) {
+	if cfg.idempotencyKey == "" {
+		return
+	}
+	key := resultKey{t.id, cfg.idempotencyKey}
+	if statusOf(t.result()) != StatusCompleted || cfg.idempotencyTTL <= 0 {
+		s.results.CompareAndDelete(key, t)
+		return
+	}
+	s.clock.AfterFunc(cfg.idempotencyTTL, func() {
+		s.results.CompareAndDelete(key, t)
+	})
+}
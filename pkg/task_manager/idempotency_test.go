@@ -0,0 +1,61 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithIdempotencyKey_CachesResult(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tm := NewTaskManager(WithClock(clock))
+
+	var runs atomic.Int32
+	report := func(ctx context.Context) error {
+		SetResult(ctx, runs.Add(1))
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	first, _ := tm.StartTask(context.Background(), "report", report, WithIdempotencyKey("2024-01", time.Hour))
+	_ = first.Wait(ctx)
+
+	cached, _ := tm.StartTask(context.Background(), "report", report, WithIdempotencyKey("2024-01", time.Hour))
+	if err := cached.Wait(ctx); err != nil || cached.RunID() != first.RunID() || cached.Info().Result != int32(1) {
+		t.Errorf("Expected the cached run, got run %d with result %v (%v)", cached.RunID(), cached.Info().Result, err)
+	}
+
+	other, _ := tm.StartTask(context.Background(), "report", report, WithIdempotencyKey("2024-02", time.Hour))
+	if _ = other.Wait(ctx); other.Info().Result != int32(2) {
+		t.Errorf("Expected another key to run the task, got %v", other.Info().Result)
+	}
+
+	clock.Advance(time.Hour)
+	expired, _ := tm.StartTask(context.Background(), "report", report, WithIdempotencyKey("2024-01", time.Hour))
+	if _ = expired.Wait(ctx); expired.Info().Result != int32(3) {
+		t.Errorf("Expected the task to run again once the TTL elapsed, got %v", expired.Info().Result)
+	}
+}
+
+func TestWithIdempotencyKey_FailuresNotCached(t *testing.T) {
+	tm := NewTaskManager()
+
+	var runs atomic.Int32
+	fail := func(ctx context.Context) error {
+		runs.Add(1)
+		return errors.New("boom")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	for i := 0; i < 2; i++ {
+		h, _ := tm.StartTask(context.Background(), "task", fail, WithIdempotencyKey("key", time.Hour))
+		_ = h.Wait(ctx)
+	}
+	if n := runs.Load(); n != 2 {
+		t.Errorf("Expected a failed run to be executed again, got %d runs", n)
+	}
+}
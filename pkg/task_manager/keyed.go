@@ -0,0 +1,58 @@
+package taskmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Keyed addresses the tasks of a TaskManager by keys of type K instead of
+// string IDs, e.g. a struct holding a tenant and a job name, so that callers
+// don't have to build IDs by concatenating strings.
+//
+// The task ID of a key is its String method for a fmt.Stringer, the key
+// itself for a string, and its Go syntax representation (%#v) otherwise,
+// which keeps distinct struct keys apart.
+type Keyed[K comparable] struct {
+	tm *TaskManager
+}
+
+func NewKeyed[K comparable](tm *TaskManager) *Keyed[K] {
+	return &Keyed[K]{tm: tm}
+}
+
+// ID returns the task ID of key.
+func (k *Keyed[K]) ID(key K) string {
+	switch v := any(key).(type) {
+	case fmt.Stringer:
+		return v.String()
+	case string:
+		return v
+	default:
+		return fmt.Sprintf("%#v", key)
+	}
+}
+
+func (k *Keyed[K]) StartTask(ctx context.Context, key K, fn func(ctx context.Context) error, opts ...TaskOption) (*TaskHandle, error) {
+	return k.tm.StartTask(ctx, k.ID(key), fn, opts...)
+}
+
+func (k *Keyed[K]) HasTask(key K) bool {
+	return k.tm.HasTask(k.ID(key))
+}
+
+func (k *Keyed[K]) StopTask(key K) bool {
+	return k.tm.StopTask(k.ID(key))
+}
+
+func (k *Keyed[K]) StopTaskAndWait(key K, timeout time.Duration) error {
+	return k.tm.StopTaskAndWait(k.ID(key), timeout)
+}
+
+func (k *Keyed[K]) WaitTask(ctx context.Context, key K) (error, bool) {
+	return k.tm.WaitTask(ctx, k.ID(key))
+}
+
+func (k *Keyed[K]) Status(key K) (TaskInfo, bool) {
+	return k.tm.Status(k.ID(key))
+}
@@ -0,0 +1,58 @@
+package taskmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type jobKey struct {
+	Tenant string
+	Job    string
+}
+
+type shardKey int
+
+func (k shardKey) String() string {
+	return "shard-" + string(rune('0'+k))
+}
+
+func TestKeyed_CompositeKeysDontCollide(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+	jobs := NewKeyed[jobKey](tm)
+
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}
+	a := jobKey{Tenant: "acme-eu", Job: "sync"}
+	b := jobKey{Tenant: "acme", Job: "eu-sync"}
+	_, _ = jobs.StartTask(context.Background(), a, block)
+	_, _ = jobs.StartTask(context.Background(), b, block)
+
+	if jobs.ID(a) == jobs.ID(b) {
+		t.Fatalf("Expected distinct IDs, both are %s", jobs.ID(a))
+	}
+	if len(tm.ListTasks()) != 2 {
+		t.Errorf("Expected both tasks to run, got %d", len(tm.ListTasks()))
+	}
+	if !jobs.StopTask(a) || jobs.HasTask(a) || !jobs.HasTask(b) {
+		t.Error("Expected only the task of key a to be stopped")
+	}
+	if info, ok := jobs.Status(b); !ok || info.ID != jobs.ID(b) {
+		t.Errorf("Expected the status of key b, got %+v", info)
+	}
+}
+
+func TestKeyed_ID(t *testing.T) {
+	if id := NewKeyed[shardKey](NewTaskManager()).ID(3); id != "shard-3" {
+		t.Errorf("Expected a Stringer key to use String, got %s", id)
+	}
+	if id := NewKeyed[string](NewTaskManager()).ID("job"); id != "job" {
+		t.Errorf("Expected a string key to be used as is, got %s", id)
+	}
+	if id := NewKeyed[int](NewTaskManager()).ID(42); id != "42" {
+		t.Errorf("Expected %%#v for other keys, got %s", id)
+	}
+}
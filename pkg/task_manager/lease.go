@@ -0,0 +1,91 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// WithLease cancels the task with ErrLeaseExpired unless it renews its lease
+// with RenewLease at least every ttl, counting from its start. It is a safety
+// net for long-running tasks that should have been bounded.
+func WithLease(ttl time.Duration) TaskOption {
+	return func(cfg *taskConfig) {
+		cfg.leaseTTL = ttl
+	}
+}
+
+// RenewLease extends the lease of the task owning ctx by its ttl. It returns
+// ErrNoLease outside a task started with WithLease, and ErrLeaseExpired if
+// the lease has already expired.
+func RenewLease(ctx context.Context) error {
+	t, ok := taskFromContext(ctx)
+	if !ok {
+		return ErrNoLease
+	}
+	t.mu.Lock()
+	l := t.lease
+	t.mu.Unlock()
+	if l == nil {
+		return ErrNoLease
+	}
+	return l.renew()
+}
+
+type lease struct {
+	ttl   time.Duration
+	clock Clock
+
+	mu      sync.Mutex
+	timer   Timer
+	expires time.Time
+	expired bool
+}
+
+func (l *lease) renew() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// Stop fails once the expiry is under way
+	if l.expired || !l.timer.Stop() {
+		return ErrLeaseExpired
+	}
+	l.timer.Reset(l.ttl)
+	l.expires = l.clock.Now().Add(l.ttl)
+	return nil
+}
+
+func (l *lease) expiry() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.expires
+}
+
+// executeLeased runs the task function under its lease, if it has one.
+func (s *TaskManager) executeLeased(ctx context.Context, t *task, fn func(ctx context.Context) error, cfg taskConfig) error {
+	if cfg.leaseTTL <= 0 {
+		return s.executeWatched(ctx, t, fn, cfg)
+	}
+
+	leaseCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	l := &lease{ttl: cfg.leaseTTL, clock: s.clock, expires: s.clock.Now().Add(cfg.leaseTTL)}
+	l.mu.Lock()
+	l.timer = s.clock.AfterFunc(cfg.leaseTTL, func() {
+		l.mu.Lock()
+		l.expired = true
+		l.mu.Unlock()
+		s.logger.Printf("Task %s lease expired", t.id)
+		cancel(ErrLeaseExpired)
+	})
+	l.mu.Unlock()
+	defer l.timer.Stop()
+	t.setLease(l)
+
+	err := s.executeWatched(leaseCtx, t, fn, cfg)
+	if err != nil && errors.Is(context.Cause(leaseCtx), ErrLeaseExpired) && !errors.Is(err, ErrLeaseExpired) {
+		err = fmt.Errorf("%w: %w", ErrLeaseExpired, err)
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithLease_Expires(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	tm := NewTaskManager(WithClock(clock))
+
+	renew := make(chan chan error)
+	h, _ := tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case res := <-renew:
+				res <- RenewLease(ctx)
+			}
+		}
+	}, WithLease(time.Minute))
+	renewLease := func() error {
+		res := make(chan error)
+		renew <- res
+		return <-res
+	}
+
+	clock.BlockUntil(1)
+	clock.Advance(50 * time.Second)
+	if err := renewLease(); err != nil {
+		t.Fatalf("Unexpected error renewing the lease: %v", err)
+	}
+	if info := h.Info(); !info.LeaseExpires.Equal(clock.Now().Add(time.Minute)) {
+		t.Errorf("Expected the lease to expire a minute after the renewal, got %v", info.LeaseExpires)
+	}
+
+	clock.Advance(50 * time.Second)
+	if info := h.Info(); info.Status != StatusRunning {
+		t.Fatalf("Expected the renewed task to keep running, got %s", info.Status)
+	}
+
+	clock.Advance(10 * time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := h.Wait(ctx); !errors.Is(err, ErrLeaseExpired) {
+		t.Errorf("Expected ErrLeaseExpired, got %v", err)
+	}
+	if info := h.Info(); info.Status != StatusFailed {
+		t.Errorf("Expected status failed, got %s", info.Status)
+	}
+}
+
+func TestRenewLease_NoLease(t *testing.T) {
+	if err := RenewLease(context.Background()); !errors.Is(err, ErrNoLease) {
+		t.Errorf("Expected ErrNoLease outside a task, got %v", err)
+	}
+
+	tm := NewTaskManager()
+	h, _ := tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		return RenewLease(ctx)
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := h.Wait(ctx); !errors.Is(err, ErrNoLease) {
+		t.Errorf("Expected ErrNoLease for a task without lease, got %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package taskmanager
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// limiter caps the number of running tasks. Tasks over the limit wait in a
+// queue ordered by priority, then by arrival.
+type limiter struct {
+	mu      sync.Mutex
+	max     int
+	running int
+	queue   []*waiter
+
+	// weighted fair queuing across the first tag of the tasks, see
+	// WithFairQueuing. pass is the virtual time at which the next task of a
+	// tag is due, it moves by 1/weight for every task started.
+	weights map[string]int
+	pass    map[string]float64
+	vtime   float64
+}
+
+type waiter struct {
+	t     *task
+	ready chan struct{}
+}
+
+func newLimiter(max int) *limiter {
+	return &limiter{max: max}
+}
+
+// acquire blocks until t may run or ctx is done.
+func (l *limiter) acquire(ctx context.Context, t *task) error {
+	l.mu.Lock()
+	if l.running < l.max && len(l.queue) == 0 {
+		l.running++
+		l.mu.Unlock()
+		return nil
+	}
+	w := &waiter{t: t, ready: make(chan struct{})}
+	i := slices.IndexFunc(l.queue, func(q *waiter) bool {
+		return q.t.priority < t.priority
+	})
+	if i < 0 {
+		i = len(l.queue)
+	}
+	l.queue = slices.Insert(l.queue, i, w)
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		i := slices.Index(l.queue, w)
+		if i >= 0 {
+			l.queue = slices.Delete(l.queue, i, i+1)
+		}
+		l.mu.Unlock()
+		if i < 0 {
+			// the slot was handed over concurrently, pass it on
+			l.release()
+		}
+		return ctx.Err()
+	}
+}
+
+// release frees a slot, handing it to the next queued task if any.
+func (l *limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.queue) == 0 {
+		l.running--
+		return
+	}
+	i := l.next(l.queue, l.pass, &l.vtime)
+	w := l.queue[i]
+	l.queue = slices.Delete(l.queue, i, i+1)
+	close(w.ready)
+}
+
+// next returns the index of the waiter of queue to start next and charges its
+// tag. Without weights, it is the head of the queue. Otherwise it is the
+// first waiter of the tag with the lowest pass; a tag that had no waiters
+// starts at the current virtual time so that it can't save up a share.
+func (l *limiter) next(queue []*waiter, pass map[string]float64, vtime *float64) int {
+	if l.weights == nil {
+		return 0
+	}
+	best, bestPass := -1, 0.0
+	seen := map[string]bool{}
+	for i, w := range queue {
+		key := fairKey(w.t)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if p := max(pass[key], *vtime); best < 0 || p < bestPass {
+			best, bestPass = i, p
+		}
+	}
+
+	key := fairKey(queue[best].t)
+	weight := 1.0
+	if w := l.weights[key]; w > 0 {
+		weight = float64(w)
+	}
+	*vtime = bestPass
+	pass[key] = bestPass + 1/weight
+	return best
+}
+
+// fairKey returns the tag t is queued under with WithFairQueuing, its first
+// tag or "" for an untagged task.
+func fairKey(t *task) string {
+	if len(t.tags) == 0 {
+		return ""
+	}
+	return t.tags[0]
+}
+
+// queued returns the waiting tasks in the order they will start.
+func (l *limiter) queued() []*task {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// replay the choices of release on copies
+	queue, pass, vtime := slices.Clone(l.queue), maps.Clone(l.pass), l.vtime
+	tasks := make([]*task, 0, len(queue))
+	for len(queue) > 0 {
+		i := l.next(queue, pass, &vtime)
+		tasks = append(tasks, queue[i].t)
+		queue = slices.Delete(queue, i, i+1)
+	}
+	return tasks
+}
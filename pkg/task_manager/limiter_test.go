@@ -0,0 +1,164 @@
+package taskmanager
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWithMaxConcurrent_QueuesTasks(t *testing.T) {
+	tm := NewTaskManager(WithMaxConcurrent(2))
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	var running, maxRunning int32
+	release := make(chan struct{})
+	fn := func(ctx context.Context) error {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+		return nil
+	}
+
+	for _, id := range []string{"t1", "t2", "t3", "t4"} {
+		_, _ = tm.StartTask(context.Background(), id, fn)
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if got := atomic.LoadInt32(&running); got != 2 {
+		t.Fatalf("Expected 2 running tasks, got %d", got)
+	}
+	pending := tm.PendingTasks()
+	if len(pending) != 2 || pending[0].ID != "t3" || pending[1].ID != "t4" {
+		t.Fatalf("Expected t3 and t4 queued in order, got %+v", pending)
+	}
+	if !tm.HasTask("t3") {
+		t.Error("Expected queued task to be registered")
+	}
+
+	close(release)
+	for _, id := range []string{"t1", "t2", "t3", "t4"} {
+		if err, _ := tm.WaitTask(context.Background(), id); err != nil {
+			t.Errorf("Unexpected error for %s: %v", id, err)
+		}
+	}
+	if got := atomic.LoadInt32(&maxRunning); got != 2 {
+		t.Errorf("Expected at most 2 concurrent tasks, got %d", got)
+	}
+}
+
+func TestWithMaxConcurrent_StopQueuedTask(t *testing.T) {
+	tm := NewTaskManager(WithMaxConcurrent(1))
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}
+	ran := make(chan struct{})
+	_, _ = tm.StartTask(context.Background(), "running", block)
+	time.Sleep(10 * time.Millisecond)
+	_, _ = tm.StartTask(context.Background(), "queued", func(ctx context.Context) error {
+		close(ran)
+		return nil
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	if err := tm.StopTaskAndWait("queued", 500*time.Millisecond); err != nil {
+		t.Fatalf("Expected queued task to stop, got %v", err)
+	}
+	if pending := tm.PendingTasks(); len(pending) != 0 {
+		t.Errorf("Expected empty queue, got %+v", pending)
+	}
+
+	tm.StopTask("running")
+	select {
+	case <-ran:
+		t.Error("Stopped queued task should never run")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestWithMaxConcurrent_PriorityOrder(t *testing.T) {
+	tm := NewTaskManager(WithMaxConcurrent(1))
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}
+	_, _ = tm.StartTask(context.Background(), "running", block)
+	time.Sleep(10 * time.Millisecond)
+	_, _ = tm.StartTask(context.Background(), "low", block, WithPriority(1))
+	time.Sleep(5 * time.Millisecond)
+	_, _ = tm.StartTask(context.Background(), "high", block, WithPriority(10))
+	time.Sleep(5 * time.Millisecond)
+	_, _ = tm.StartTask(context.Background(), "low2", block, WithPriority(1))
+	time.Sleep(10 * time.Millisecond)
+
+	pending := tm.PendingTasks()
+	if len(pending) != 3 || pending[0].ID != "high" || pending[1].ID != "low" || pending[2].ID != "low2" {
+		t.Errorf("Expected queue [high low low2], got %+v", pending)
+	}
+}
+
+func TestWithFairQueuing(t *testing.T) {
+	tm := NewTaskManager(WithMaxConcurrent(1), WithFairQueuing(map[string]int{"tenant-a": 3}))
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	_, _ = tm.StartTask(context.Background(), "running", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	var mu sync.Mutex
+	started := []string{}
+	done := make(chan struct{}, 8)
+	for _, id := range []string{"a1", "a2", "a3", "a4", "a5", "a6", "b1", "b2"} {
+		tenant := "tenant-" + id[:1]
+		_, _ = tm.StartTask(context.Background(), id, func(ctx context.Context) error {
+			mu.Lock()
+			started = append(started, id)
+			mu.Unlock()
+			done <- struct{}{}
+			return nil
+		}, WithTags(tenant))
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	want := []string{"a1", "b1", "a2", "a3", "a4", "b2", "a5", "a6"}
+	pending := []string{}
+	for _, info := range tm.PendingTasks() {
+		pending = append(pending, info.ID)
+	}
+	if !slices.Equal(pending, want) {
+		t.Errorf("Expected the queue %v, got %v", want, pending)
+	}
+
+	tm.StopTask("running")
+	for range want {
+		select {
+		case <-done:
+		case <-time.After(500 * time.Millisecond):
+			t.Fatal("Expected the queued tasks to run")
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(started, want) {
+		t.Errorf("Expected tenant-a to get 3 slots for each one of tenant-b %v, got %v", want, started)
+	}
+}
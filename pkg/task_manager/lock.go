@@ -0,0 +1,76 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Lock is a distributed lock held by this instance, renewed by its provider
+// until it is released.
+type Lock interface {
+	// Lost is closed when the lock could not be renewed, another instance
+	// may hold it from then on.
+	Lost() <-chan struct{}
+	Release(ctx context.Context) error
+}
+
+// LockProvider hands out distributed locks, see WithSingleton.
+type LockProvider interface {
+	// Acquire blocks until the lock named key is held or ctx is done.
+	Acquire(ctx context.Context, key string) (Lock, error)
+}
+
+// WithLockProvider sets the provider of the locks taken by WithSingleton
+// tasks.
+func WithLockProvider(p LockProvider) Option {
+	return func(s *TaskManager) {
+		s.locks = p
+	}
+}
+
+// WithSingleton runs the task only while this instance holds the distributed
+// lock named after the task ID, so that a task started on several instances
+// runs on one of them at a time. The task stays pending until the lock is
+// acquired. If the lock is lost, the task context is canceled and the task
+// fails with ErrLockLost, letting another instance take over. A recurring
+// task holds the lock for its whole lifetime.
+func WithSingleton() TaskOption {
+	return func(cfg *taskConfig) {
+		cfg.singleton = true
+	}
+}
+
+// runSingleton holds the task lock while the task runs, when asked to.
+func (s *TaskManager) runSingleton(ctx context.Context, t *task, fn func(ctx context.Context) error, cfg taskConfig) error {
+	if !cfg.singleton {
+		return s.runLimited(ctx, t, fn, cfg)
+	}
+
+	lock, err := s.locks.Acquire(ctx, t.id)
+	if err != nil {
+		return err
+	}
+	s.logger.Printf("Task %s acquired its lock", t.id)
+	defer func() {
+		if err := lock.Release(context.WithoutCancel(ctx)); err != nil {
+			s.logger.Printf("Task %s: failed to release lock: %v", t.id, err)
+		}
+	}()
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go func() {
+		select {
+		case <-lock.Lost():
+			cancel(ErrLockLost)
+		case <-ctx.Done():
+		}
+	}()
+
+	err = s.runLimited(ctx, t, fn, cfg)
+	if err != nil && errors.Is(context.Cause(ctx), ErrLockLost) && !errors.Is(err, ErrLockLost) {
+		err = fmt.Errorf("%w: %w", ErrLockLost, err)
+	}
+	return err
+}
@@ -0,0 +1,134 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memLocks is an in-process LockProvider shared by several managers.
+type memLocks struct {
+	mu    sync.Mutex
+	held  map[string]*memLock
+	freed chan struct{}
+}
+
+type memLock struct {
+	locks *memLocks
+	key   string
+	lost  chan struct{}
+}
+
+func newMemLocks() *memLocks {
+	return &memLocks{held: map[string]*memLock{}, freed: make(chan struct{})}
+}
+
+func (m *memLocks) Acquire(ctx context.Context, key string) (Lock, error) {
+	for {
+		m.mu.Lock()
+		if _, ok := m.held[key]; !ok {
+			l := &memLock{locks: m, key: key, lost: make(chan struct{})}
+			m.held[key] = l
+			m.mu.Unlock()
+			return l, nil
+		}
+		freed := m.freed
+		m.mu.Unlock()
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// expire simulates the holder of key dying: its lock is lost and freed.
+func (m *memLocks) expire(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l, ok := m.held[key]; ok {
+		close(l.lost)
+		m.free(key)
+	}
+}
+
+func (m *memLocks) free(key string) {
+	delete(m.held, key)
+	close(m.freed)
+	m.freed = make(chan struct{})
+}
+
+func (l *memLock) Lost() <-chan struct{} { return l.lost }
+
+func (l *memLock) Release(ctx context.Context) error {
+	l.locks.mu.Lock()
+	defer l.locks.mu.Unlock()
+	if l.locks.held[l.key] == l {
+		l.locks.free(l.key)
+	}
+	return nil
+}
+
+func TestSingleton_RunsOnOneInstance(t *testing.T) {
+	locks := newMemLocks()
+	a := NewTaskManager(WithLockProvider(locks))
+	b := NewTaskManager(WithLockProvider(locks))
+
+	running := make(chan string, 2)
+	fn := func(name string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			running <- name
+			<-ctx.Done()
+			return ctx.Err()
+		}
+	}
+
+	aErr := make(chan error, 1)
+	_, _ = a.StartTask(context.Background(), "leader", fn("a"), WithSingleton(), WithOnComplete(func(err error) { aErr <- err }))
+	if got := <-running; got != "a" {
+		t.Fatalf("Expected instance a to run the task, got %s", got)
+	}
+	_, _ = b.StartTask(context.Background(), "leader", fn("b"), WithSingleton())
+
+	select {
+	case got := <-running:
+		t.Fatalf("Expected only one instance to run the task, %s ran too", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if info, _ := b.Status("leader"); info.Status != StatusPending {
+		t.Errorf("Expected the waiting instance to be pending, got %s", info.Status)
+	}
+
+	// the holder dies, b takes over
+	locks.expire("leader")
+	select {
+	case got := <-running:
+		if got != "b" {
+			t.Errorf("Expected instance b to take over, got %s", got)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected instance b to take over once the lock was lost")
+	}
+	if err := <-aErr; !errors.Is(err, ErrLockLost) {
+		t.Errorf("Expected ErrLockLost on the instance that lost the lock, got %v", err)
+	}
+	if info, _ := a.Status("leader"); info.Status != StatusFailed {
+		t.Errorf("Expected status failed after losing the lock, got %s", info.Status)
+	}
+
+	b.GracefulShutdown(true, 500*time.Millisecond)
+	if len(locks.held) != 0 {
+		t.Errorf("Expected the lock to be released when the task ended, got %v", locks.held)
+	}
+}
+
+func TestSingleton_NoLockProvider(t *testing.T) {
+	tm := NewTaskManager()
+	_, err := tm.StartTask(context.Background(), "leader", func(ctx context.Context) error { return nil }, WithSingleton())
+	if !errors.Is(err, ErrNoLockProvider) {
+		t.Errorf("Expected ErrNoLockProvider, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package taskmanager
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+)
+
+// Logger receives the task lifecycle logs. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger adapts l to Logger, logging every message at info level.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l: l}
+}
+
+func (l slogLogger) Printf(format string, v ...any) {
+	l.l.Log(context.Background(), slog.LevelInfo, fmt.Sprintf(format, v...))
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(string, ...any) {}
+
+// NopLogger discards every message.
+var NopLogger Logger = nopLogger{}
+
+var _ Logger = (*log.Logger)(nil)
+
+// WithTaskLogger sets the logger returned by LoggerFrom inside tasks,
+// slog.Default() at the start of each task by default.
+func WithTaskLogger(l *slog.Logger) Option {
+	return func(s *TaskManager) {
+		s.taskLogger = l
+	}
+}
+
+// LoggerFrom returns the logger of the task owning ctx, with the task_id,
+// run_id and tags attributes of the task, so that the logs of task functions
+// are correlated. Outside a managed task it returns slog.Default().
+func LoggerFrom(ctx context.Context) *slog.Logger {
+	if t, ok := taskFromContext(ctx); ok {
+		return t.logger
+	}
+	return slog.Default()
+}
+
+func (s *TaskManager) newTaskLogger(t *task) *slog.Logger {
+	l := s.taskLogger
+	if l == nil {
+		l = slog.Default()
+	}
+	attrs := []any{slog.String("task_id", t.id), slog.Uint64("run_id", t.run)}
+	if len(t.tags) > 0 {
+		attrs = append(attrs, slog.Any("tags", t.tags))
+	}
+	return l.With(attrs...)
+}
@@ -0,0 +1,88 @@
+package taskmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *recordLogger) Printf(format string, v ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func (l *recordLogger) contains(substr string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range l.lines {
+		if strings.Contains(line, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWithLogger_RoutesLifecycleLogs(t *testing.T) {
+	logger := &recordLogger{}
+	tm := NewTaskManager(WithLogger(logger))
+
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		return nil
+	})
+	_, _ = tm.WaitTask(context.Background(), "task")
+
+	if !logger.contains("Task task completed successfully") {
+		t.Errorf("Expected completion to be logged through the custom logger, got %v", logger.lines)
+	}
+}
+
+func TestNewSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	logger.Printf("Task %s failed: %v", "task", "boom")
+
+	if out := buf.String(); !strings.Contains(out, "level=INFO") || !strings.Contains(out, `msg="Task task failed: boom"`) {
+		t.Errorf("Unexpected slog output: %q", out)
+	}
+}
+
+func TestLoggerFrom_TaskAttributes(t *testing.T) {
+	var buf bytes.Buffer
+	tm := NewTaskManager(WithTaskLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+
+	h, _ := tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		LoggerFrom(ctx).Info("working")
+		return nil
+	}, WithTags("sync"))
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_ = h.Wait(ctx)
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("Expected one JSON log line, got %q: %v", buf.String(), err)
+	}
+	if line["msg"] != "working" || line["task_id"] != "task" || line["run_id"] != float64(h.RunID()) {
+		t.Errorf("Expected the task attributes on the log line, got %v", line)
+	}
+	if tags, _ := line["tags"].([]any); len(tags) != 1 || tags[0] != "sync" {
+		t.Errorf("Expected the task tags on the log line, got %v", line["tags"])
+	}
+
+	if LoggerFrom(context.Background()) != slog.Default() {
+		t.Error("Expected the default logger outside a task")
+	}
+}
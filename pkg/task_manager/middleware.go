@@ -0,0 +1,37 @@
+package taskmanager
+
+import (
+	"context"
+	"sync"
+)
+
+// TaskMiddleware wraps the function of the task with the given id, e.g. to
+// log, measure or trace every task. It is applied once per task run, and the
+// function it returns is called once per attempt, see WithRetry.
+type TaskMiddleware func(id string, next func(ctx context.Context) error) func(ctx context.Context) error
+
+type middlewares struct {
+	mu  sync.RWMutex
+	mws []TaskMiddleware
+}
+
+// Use adds middlewares wrapping the function of every task run from then
+// on. The first middleware added is the outermost.
+func (s *TaskManager) Use(mw ...TaskMiddleware) {
+	s.middlewares.mu.Lock()
+	defer s.middlewares.mu.Unlock()
+	for _, m := range mw {
+		if m != nil {
+			s.middlewares.mws = append(s.middlewares.mws, m)
+		}
+	}
+}
+
+func (m *middlewares) wrap(id string, fn func(ctx context.Context) error) func(ctx context.Context) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for i := len(m.mws) - 1; i >= 0; i-- {
+		fn = m.mws[i](id, fn)
+	}
+	return fn
+}
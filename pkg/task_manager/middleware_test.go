@@ -0,0 +1,69 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestUse_WrapsTasksInOrder(t *testing.T) {
+	tm := NewTaskManager()
+
+	var mu sync.Mutex
+	calls := []string{}
+	record := func(name string) TaskMiddleware {
+		return func(id string, next func(ctx context.Context) error) func(ctx context.Context) error {
+			return func(ctx context.Context) error {
+				mu.Lock()
+				calls = append(calls, name+" "+id)
+				mu.Unlock()
+				return next(ctx)
+			}
+		}
+	}
+	tm.Use(record("outer"), nil, record("inner"))
+
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		mu.Lock()
+		calls = append(calls, "fn")
+		mu.Unlock()
+		return nil
+	})
+	tm.WaitTask(context.Background(), "task")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"outer task", "inner task", "fn"}; !slices.Equal(calls, want) {
+		t.Errorf("Expected %v, got %v", want, calls)
+	}
+}
+
+func TestUse_SeesEveryAttemptAndCanChangeResult(t *testing.T) {
+	tm := NewTaskManager()
+	boom := errors.New("boom")
+
+	attempts := 0
+	tm.Use(func(id string, next func(ctx context.Context) error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			attempts++
+			if err := next(ctx); err != nil {
+				return err
+			}
+			return boom
+		}
+	})
+
+	done := make(chan error, 1)
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		return nil
+	}, WithRetry(3, 0), WithOnComplete(func(err error) { done <- err }))
+
+	if err := <-done; !errors.Is(err, boom) {
+		t.Errorf("Expected the middleware error, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected the middleware to run for each of the 3 attempts, ran %d times", attempts)
+	}
+}
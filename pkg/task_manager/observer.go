@@ -0,0 +1,59 @@
+package taskmanager
+
+import "sync"
+
+// TaskObserver is notified of the task lifecycle and of shutdowns, e.g. by
+// APM or platform integrations. Methods are called synchronously from the
+// task goroutine and must not block. Embed BaseObserver to implement only
+// some of them and keep compiling when methods are added.
+type TaskObserver interface {
+	// TaskStarted is called when the task function is about to run.
+	TaskStarted(info TaskInfo)
+	// TaskFinished is called once the task has finished, info holds its
+	// final status and error.
+	TaskFinished(info TaskInfo)
+	// TaskReplaced is called when a new task with the same ID was started,
+	// right before the old task is canceled.
+	TaskReplaced(old TaskInfo)
+	// ShutdownBegan is called when GracefulShutdown or
+	// GracefulShutdownContext cancels the tasks.
+	ShutdownBegan()
+	// ShutdownEnded is called when GracefulShutdownContext returns, with its
+	// error. It is not called by GracefulShutdown without wait.
+	ShutdownEnded(err error)
+}
+
+// BaseObserver implements TaskObserver with no-op methods.
+type BaseObserver struct{}
+
+func (BaseObserver) TaskStarted(TaskInfo)  {}
+func (BaseObserver) TaskFinished(TaskInfo) {}
+func (BaseObserver) TaskReplaced(TaskInfo) {}
+func (BaseObserver) ShutdownBegan()        {}
+func (BaseObserver) ShutdownEnded(error)   {}
+
+type observers struct {
+	mu  sync.RWMutex
+	obs []TaskObserver
+}
+
+// AddObserver registers o, notified in registration order with the other
+// observers.
+func (s *TaskManager) AddObserver(o TaskObserver) {
+	if o == nil {
+		return
+	}
+	s.observers.mu.Lock()
+	defer s.observers.mu.Unlock()
+	s.observers.obs = append(s.observers.obs, o)
+}
+
+func (o *observers) notify(fn func(TaskObserver)) {
+	o.mu.RLock()
+	obs := o.obs
+	o.mu.RUnlock()
+
+	for _, ob := range obs {
+		fn(ob)
+	}
+}
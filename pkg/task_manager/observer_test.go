@@ -0,0 +1,106 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingObserver struct {
+	BaseObserver
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recordingObserver) record(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingObserver) recorded() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.calls)
+}
+
+func (r *recordingObserver) TaskStarted(info TaskInfo) {
+	r.record("started %s %s", info.ID, info.Status)
+}
+
+func (r *recordingObserver) TaskFinished(info TaskInfo) {
+	r.record("finished %s %s", info.ID, info.Status)
+}
+
+func (r *recordingObserver) TaskReplaced(old TaskInfo) {
+	r.record("replaced %s", old.ID)
+}
+
+func (r *recordingObserver) ShutdownBegan() {
+	r.record("shutdown began")
+}
+
+func (r *recordingObserver) ShutdownEnded(err error) {
+	r.record("shutdown ended %v", err)
+}
+
+func TestAddObserver_Lifecycle(t *testing.T) {
+	tm := NewTaskManager()
+	first, second := &recordingObserver{}, &recordingObserver{}
+	tm.AddObserver(first)
+	tm.AddObserver(second)
+
+	h, _ := tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		return errors.New("boom")
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_ = h.Wait(ctx)
+
+	started := make(chan struct{})
+	old, _ := tm.StartTask(context.Background(), "replaced", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-started
+	restarted := make(chan struct{})
+	_, _ = tm.StartTask(context.Background(), "replaced", func(ctx context.Context) error {
+		close(restarted)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-restarted
+	_ = old.Wait(ctx)
+
+	if err := tm.GracefulShutdownContext(ctx); err != nil {
+		t.Fatalf("Unexpected shutdown error: %v", err)
+	}
+
+	for _, r := range []*recordingObserver{first, second} {
+		got := r.recorded()
+		if len(got) != 9 {
+			t.Fatalf("Expected 9 calls, got %q", got)
+		}
+		// the old run finishes concurrently with the start of its replacement
+		slices.Sort(got[4:6])
+		want := []string{
+			"started task running",
+			"finished task failed",
+			"started replaced running",
+			"replaced replaced",
+			"finished replaced canceled",
+			"started replaced running",
+			"shutdown began",
+			"finished replaced canceled",
+			"shutdown ended <nil>",
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("Expected the calls %q, got %q", want, got)
+		}
+	}
+}
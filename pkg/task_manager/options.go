@@ -0,0 +1,224 @@
+package taskmanager
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"time"
+)
+
+type Option func(*TaskManager)
+
+// WithPanicHandler sets the function called when a task panics. The default
+// handler logs the panic value and stack.
+func WithPanicHandler(fn func(id string, v any, stack []byte)) Option {
+	return func(s *TaskManager) {
+		s.panicHandler = fn
+	}
+}
+
+// WithLogger routes the task lifecycle logs to l instead of the standard
+// logger. Use NopLogger to silence them.
+func WithLogger(l Logger) Option {
+	return func(s *TaskManager) {
+		s.logger = l
+	}
+}
+
+// WithShutdownEscalation sets fn, called for each task still running when a
+// graceful shutdown times out, e.g. to dump stacks, page someone or exit the
+// process. info is the task, with its run duration, and remaining lists
+// every such task sorted by ID.
+func WithShutdownEscalation(fn func(info TaskInfo, remaining []TaskInfo)) Option {
+	return func(s *TaskManager) {
+		s.escalate = fn
+	}
+}
+
+// WithMaxConcurrent limits the number of tasks running at once to n. Tasks
+// started over the limit are queued, highest priority first then in start
+// order, and start as running tasks finish. A recurring task holds its slot
+// for its whole lifetime.
+func WithMaxConcurrent(n int) Option {
+	return func(s *TaskManager) {
+		if n > 0 {
+			s.limiter = newLimiter(n)
+		}
+	}
+}
+
+// WithFairQueuing shares the slots of WithMaxConcurrent between the tags of
+// the waiting tasks in proportion to weights, so that a tag or tenant
+// starting many tasks doesn't starve the others. A task is queued under its
+// first tag; untagged tasks share the "" tag and a tag missing from weights
+// has weight 1. Priorities only order the tasks of the same tag. It has no
+// effect without WithMaxConcurrent.
+func WithFairQueuing(weights map[string]int) Option {
+	return func(s *TaskManager) {
+		s.fairWeights = maps.Clone(weights)
+		if s.fairWeights == nil {
+			s.fairWeights = map[string]int{}
+		}
+	}
+}
+
+type TaskOption func(*taskConfig)
+
+type taskConfig struct {
+	tags        []string
+	timeout     time.Duration
+	deadline    time.Time
+	maxAttempts int
+	backoff     time.Duration
+	onComplete  func(err error)
+	priority    int
+	startAt     time.Time
+	parentID    string
+	overlap     OverlapPolicy
+	exclusions  []exclusion
+	definition  *TaskDefinition // saved once the task starts, see WithStore
+	wrapped     bool
+	singleton   bool
+
+	heartbeatTimeout time.Duration
+	stuckAction      StuckAction
+	dedupWindow      time.Duration
+	replaceWait      time.Duration
+	detached         bool
+	healthCheck      func(ctx context.Context) error
+	leaseTTL         time.Duration
+	idempotencyKey   string
+	idempotencyTTL   time.Duration
+	jitter           float64
+	metadata         map[string]string
+	fixedDelay       bool
+	stopTimeout      time.Duration
+}
+
+func newTaskConfig(opts []TaskOption) taskConfig {
+	cfg := taskConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// WithTags labels a task so it can be listed or stopped as part of a group.
+func WithTags(tags ...string) TaskOption {
+	return func(cfg *taskConfig) {
+		for _, tag := range tags {
+			if tag != "" && !slices.Contains(cfg.tags, tag) {
+				cfg.tags = append(cfg.tags, tag)
+			}
+		}
+	}
+}
+
+// WithTimeout cancels the task context once d has elapsed since the task
+// started. A task that fails because of it ends with ErrTaskTimedOut.
+func WithTimeout(d time.Duration) TaskOption {
+	return func(cfg *taskConfig) {
+		cfg.timeout = d
+	}
+}
+
+// WithDeadline is like WithTimeout but cancels the task context at t.
+func WithDeadline(t time.Time) TaskOption {
+	return func(cfg *taskConfig) {
+		cfg.deadline = t
+	}
+}
+
+// WithRetry re-runs the task function up to maxAttempts times in total while
+// it keeps failing. The delay between attempts starts at backoff and doubles
+// after every attempt, with jitter. Cancellation and Permanent errors are not
+// retried, unless marked Retryable, and the current attempt is available
+// through Attempt(ctx).
+func WithRetry(maxAttempts int, backoff time.Duration) TaskOption {
+	return func(cfg *taskConfig) {
+		cfg.maxAttempts = maxAttempts
+		cfg.backoff = backoff
+	}
+}
+
+// WithOnComplete registers a callback invoked with the task result once the
+// task has finished, after any retries.
+func WithOnComplete(fn func(err error)) TaskOption {
+	return func(cfg *taskConfig) {
+		cfg.onComplete = fn
+	}
+}
+
+// alsoOnComplete is like WithOnComplete but keeps the callback set by the
+// caller, if any, and calls fn after it.
+func alsoOnComplete(fn func(err error)) TaskOption {
+	return func(cfg *taskConfig) {
+		onComplete := cfg.onComplete
+		cfg.onComplete = func(err error) {
+			if onComplete != nil {
+				onComplete(err)
+			}
+			fn(err)
+		}
+	}
+}
+
+// WithPriority sets the task priority. When tasks are queued because of
+// WithMaxConcurrent, higher priorities start first.
+func WithPriority(priority int) TaskOption {
+	return func(cfg *taskConfig) {
+		cfg.priority = priority
+	}
+}
+
+// WithMetadata attaches key/value pairs to the task, e.g. a description, an
+// owner or the ID of the request that started it. They are returned in
+// TaskInfo.Metadata. Repeated options are merged.
+func WithMetadata(metadata map[string]string) TaskOption {
+	return func(cfg *taskConfig) {
+		if cfg.metadata == nil {
+			cfg.metadata = make(map[string]string, len(metadata))
+		}
+		maps.Copy(cfg.metadata, metadata)
+	}
+}
+
+// WithReplaceWait makes a task replacing a running task with the same ID
+// wait up to timeout for the old task to return before it starts, so that
+// both never run at once. The new task stays pending meanwhile and fails with
+// ErrReplaceTimeout if the old one is still running after timeout.
+func WithReplaceWait(timeout time.Duration) TaskOption {
+	return func(cfg *taskConfig) {
+		cfg.replaceWait = timeout
+	}
+}
+
+// WithDetached keeps the task running through GracefulShutdown, e.g. to
+// flush final metrics. The shutdown doesn't cancel it but still waits for it
+// like for the other tasks. StopTask and the parent context stop it as usual.
+func WithDetached() TaskOption {
+	return func(cfg *taskConfig) {
+		cfg.detached = true
+	}
+}
+
+// WithDedupWindow makes starting the task a no-op while a task with the same
+// ID is running and was started less than d ago, instead of replacing it.
+// This absorbs bursts of restarts, e.g. from retry loops upstream.
+func WithDedupWindow(d time.Duration) TaskOption {
+	return func(cfg *taskConfig) {
+		cfg.dedupWindow = d
+	}
+}
+
+// deadlineFrom returns the earliest of the configured deadline and timeout,
+// the latter counted from start.
+func (cfg taskConfig) deadlineFrom(start time.Time) (time.Time, bool) {
+	deadline := cfg.deadline
+	if cfg.timeout > 0 {
+		if d := start.Add(cfg.timeout); deadline.IsZero() || d.Before(deadline) {
+			deadline = d
+		}
+	}
+	return deadline, !deadline.IsZero()
+}
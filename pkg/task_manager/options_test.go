@@ -0,0 +1,272 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStartTaskWithOptions_Timeout(t *testing.T) {
+	tm := NewTaskManager()
+
+	done := make(chan error, 1)
+	err := tm.StartTaskWithOptions(context.Background(), "task", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTimeout(20*time.Millisecond), WithOnComplete(func(err error) {
+		done <- err
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error starting task: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Task was not canceled by its timeout")
+	}
+}
+
+func TestStartTaskWithOptions_RetryUntilSuccess(t *testing.T) {
+	tm := NewTaskManager()
+
+	var attempts int32
+	done := make(chan error, 1)
+	_ = tm.StartTaskWithOptions(context.Background(), "task", func(ctx context.Context) error {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			return errors.New("boom")
+		}
+		return nil
+	}, WithRetry(5, time.Millisecond), WithOnComplete(func(err error) {
+		done <- err
+	}))
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected task to succeed after retries, got %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Task did not complete in time")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+}
+
+func TestStartTaskWithOptions_RetryGivesUp(t *testing.T) {
+	tm := NewTaskManager()
+
+	var attempts int32
+	boom := errors.New("boom")
+	done := make(chan error, 1)
+	_ = tm.StartTaskWithOptions(context.Background(), "task", func(ctx context.Context) error {
+		atomic.AddInt32(&attempts, 1)
+		return boom
+	}, WithRetry(2, time.Millisecond), WithOnComplete(func(err error) {
+		done <- err
+	}))
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, boom) {
+			t.Errorf("Expected last error to be reported, got %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Task did not complete in time")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 2 {
+		t.Errorf("Expected 2 attempts, got %d", got)
+	}
+}
+
+func TestStartTaskWithOptions_RetryStopsOnCancel(t *testing.T) {
+	tm := NewTaskManager()
+
+	var attempts int32
+	done := make(chan error, 1)
+	_ = tm.StartTaskWithOptions(context.Background(), "task", func(ctx context.Context) error {
+		atomic.AddInt32(&attempts, 1)
+		return errors.New("boom")
+	}, WithRetry(10, time.Second), WithOnComplete(func(err error) {
+		done <- err
+	}))
+
+	time.Sleep(20 * time.Millisecond)
+	tm.StopTask("task")
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Retry backoff was not interrupted by StopTask")
+	}
+	if got := atomic.LoadInt32(&attempts); got != 1 {
+		t.Errorf("Expected 1 attempt, got %d", got)
+	}
+}
+
+func TestStartTaskWithOptions_Priority(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	_ = tm.StartTaskWithOptions(context.Background(), "task", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}, WithPriority(7))
+
+	infos := tm.ListTasks()
+	if len(infos) != 1 || infos[0].Priority != 7 {
+		t.Errorf("Expected task with priority 7, got %+v", infos)
+	}
+}
+
+func TestStartTaskWithOptions_TimeoutReportsTimedOut(t *testing.T) {
+	tm := NewTaskManager()
+
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTimeout(20*time.Millisecond))
+
+	err, ok := tm.WaitTask(context.Background(), "task")
+	if !ok {
+		t.Fatal("Expected WaitTask to find the running task")
+	}
+	if !errors.Is(err, ErrTaskTimedOut) {
+		t.Errorf("Expected ErrTaskTimedOut, got %v", err)
+	}
+}
+
+func TestStartTaskWithOptions_Deadline(t *testing.T) {
+	tm := NewTaskManager()
+
+	start := time.Now()
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithDeadline(start.Add(20*time.Millisecond)), WithTimeout(time.Second))
+
+	err, _ := tm.WaitTask(context.Background(), "task")
+	if !errors.Is(err, ErrTaskTimedOut) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected ErrTaskTimedOut wrapping context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected the earliest deadline to apply, task ran for %v", elapsed)
+	}
+}
+
+func TestStartTaskWithOptions_ParentDeadlineIsNotTimedOut(t *testing.T) {
+	tm := NewTaskManager()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, _ = tm.StartTask(ctx, "task", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTimeout(time.Second))
+
+	err, _ := tm.WaitTask(context.Background(), "task")
+	if errors.Is(err, ErrTaskTimedOut) {
+		t.Errorf("Expected parent deadline not to be reported as task timeout, got %v", err)
+	}
+}
+
+func TestStartTaskWithOptions_DedupWindow(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	var runs atomic.Int64
+	fn := func(ctx context.Context) error {
+		runs.Add(1)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	_, _ = tm.StartTask(context.Background(), "task", fn, WithDedupWindow(50*time.Millisecond))
+	first, _ := tm.Status("task")
+	for range 3 {
+		if _, err := tm.StartTask(context.Background(), "task", fn, WithDedupWindow(50*time.Millisecond)); err != nil {
+			t.Fatalf("Expected a deduplicated start to succeed, got %v", err)
+		}
+	}
+	if info, _ := tm.Status("task"); !info.CreatedAt.Equal(first.CreatedAt) {
+		t.Error("Expected restarts within the window to keep the running task")
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	_, _ = tm.StartTask(context.Background(), "task", fn, WithDedupWindow(50*time.Millisecond))
+	time.Sleep(10 * time.Millisecond)
+	if info, _ := tm.Status("task"); info.CreatedAt.Equal(first.CreatedAt) {
+		t.Error("Expected a restart after the window to replace the task")
+	}
+	if n := runs.Load(); n != 2 {
+		t.Errorf("Expected 2 runs, got %d", n)
+	}
+}
+
+func TestWithReplaceWait_NoOverlap(t *testing.T) {
+	tm := NewTaskManager()
+
+	var running, overlaps atomic.Int32
+	fn := func(ctx context.Context) error {
+		if running.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		defer running.Add(-1)
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond) // cleanup ignoring the cancellation
+		return ctx.Err()
+	}
+
+	var last *TaskHandle
+	for i := 0; i < 3; i++ {
+		last, _ = tm.StartTask(context.Background(), "task", fn, WithReplaceWait(time.Second))
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if info := last.Info(); info.Status != StatusRunning {
+		t.Errorf("Expected the last task to run once the others returned, got %s", info.Status)
+	}
+	last.Stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_ = last.Wait(ctx)
+
+	if n := overlaps.Load(); n != 0 {
+		t.Errorf("Expected no overlapping runs, got %d", n)
+	}
+}
+
+func TestWithReplaceWait_Timeout(t *testing.T) {
+	tm := NewTaskManager()
+
+	release := make(chan struct{})
+	defer close(release)
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	called := false
+	h, _ := tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		called = true
+		return nil
+	}, WithReplaceWait(20*time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := h.Wait(ctx); !errors.Is(err, ErrReplaceTimeout) {
+		t.Errorf("Expected ErrReplaceTimeout, got %v", err)
+	}
+	if called {
+		t.Error("Expected the new task not to run while the old one is running")
+	}
+}
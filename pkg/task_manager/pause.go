@@ -0,0 +1,75 @@
+package taskmanager
+
+import "context"
+
+// PauseTask asks the task with the given id to pause. Pausing is
+// cooperative: the task function suspends itself at its next call to
+// WaitIfPaused, or checks Paused. It returns false if no such task is
+// running.
+func (s *TaskManager) PauseTask(id string) bool {
+	v, ok := s.tasks.Load(id)
+	if !ok {
+		return false
+	}
+	t := v.(*task)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.resume == nil {
+		t.resume = make(chan struct{})
+		s.logger.Printf("Task %s paused", id)
+	}
+	return true
+}
+
+// ResumeTask lets a paused task go on. It returns false if no such task is
+// running.
+func (s *TaskManager) ResumeTask(id string) bool {
+	v, ok := s.tasks.Load(id)
+	if !ok {
+		return false
+	}
+	t := v.(*task)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.resume != nil {
+		close(t.resume)
+		t.resume = nil
+		s.logger.Printf("Task %s resumed", id)
+	}
+	return true
+}
+
+// Paused reports whether the task owning ctx has been asked to pause. It is
+// false outside a managed task.
+func Paused(ctx context.Context) bool {
+	t, ok := taskFromContext(ctx)
+	if !ok {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.resume != nil
+}
+
+// WaitIfPaused blocks while the task owning ctx is paused. It returns
+// ctx.Err() if ctx is done first, e.g. because the task was stopped while
+// paused, and returns at once outside a managed task.
+func WaitIfPaused(ctx context.Context) error {
+	t, ok := taskFromContext(ctx)
+	if !ok {
+		return ctx.Err()
+	}
+	t.mu.Lock()
+	resume := t.resume
+	t.mu.Unlock()
+	if resume == nil {
+		return ctx.Err()
+	}
+
+	select {
+	case <-resume:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,93 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPause_SuspendsAndResumes(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	var processed atomic.Int64
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		for {
+			if err := WaitIfPaused(ctx); err != nil {
+				return err
+			}
+			processed.Add(1)
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	if !tm.PauseTask("task") {
+		t.Fatal("Expected PauseTask to find the task")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if info, _ := tm.Status("task"); !info.Paused || info.Status != StatusRunning {
+		t.Errorf("Expected a paused running task, got %+v", info)
+	}
+
+	before := processed.Load()
+	time.Sleep(50 * time.Millisecond)
+	if after := processed.Load(); after != before {
+		t.Errorf("Expected no progress while paused, went from %d to %d", before, after)
+	}
+
+	if !tm.ResumeTask("task") {
+		t.Fatal("Expected ResumeTask to find the task")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if processed.Load() == before {
+		t.Error("Expected the task to go on once resumed")
+	}
+	if info, _ := tm.Status("task"); info.Paused {
+		t.Error("Expected the task not to be paused anymore")
+	}
+}
+
+func TestPause_StopWhilePaused(t *testing.T) {
+	tm := NewTaskManager()
+
+	paused := make(chan struct{})
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		for !Paused(ctx) {
+			time.Sleep(time.Millisecond)
+		}
+		close(paused)
+		return WaitIfPaused(ctx)
+	})
+	tm.PauseTask("task")
+	<-paused
+
+	done := make(chan error, 1)
+	go func() {
+		err, _ := tm.WaitTask(context.Background(), "task")
+		done <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	tm.StopTask("task")
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected a paused task to stop")
+	}
+}
+
+func TestPause_UnknownTask(t *testing.T) {
+	tm := NewTaskManager()
+	if tm.PauseTask("does_not_exist") || tm.ResumeTask("does_not_exist") {
+		t.Error("Expected false for unknown task")
+	}
+	if Paused(context.Background()) || WaitIfPaused(context.Background()) != nil {
+		t.Error("Expected no pause outside a managed task")
+	}
+}
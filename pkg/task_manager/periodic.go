@@ -0,0 +1,75 @@
+package taskmanager
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// WithJitter randomizes every interval of a periodic task by up to
+// ±fraction of it (capped to 1), so that instances started together don't
+// run in sync.
+func WithJitter(fraction float64) TaskOption {
+	return func(cfg *taskConfig) {
+		cfg.jitter = min(max(fraction, 0), 1)
+	}
+}
+
+// WithFixedDelay counts the interval of a periodic task from the end of the
+// previous run instead of its start.
+func WithFixedDelay() TaskOption {
+	return func(cfg *taskConfig) {
+		cfg.fixedDelay = true
+	}
+}
+
+// StartPeriodicTask registers a task that calls fn every interval until it is
+// stopped, first after one interval. By default the interval is counted from
+// the start of the previous run (fixed rate), and a run longer than the
+// interval is followed by the next one right away; runs never overlap. Errors
+// of single runs are logged and do not end the task.
+func (s *TaskManager) StartPeriodicTask(ctx context.Context, id string, fn func(ctx context.Context) error, interval time.Duration, opts ...TaskOption) error {
+	if fn == nil {
+		return ErrNilTaskFunc
+	}
+	if interval <= 0 {
+		return ErrInvalidInterval
+	}
+	cfg := newTaskConfig(opts)
+	opts = append(opts, wrapped())
+	return s.StartTaskWithOptions(ctx, id, s.periodic(id, s.body(id, fn), interval, cfg.jitter, cfg.fixedDelay), opts...)
+}
+
+func (s *TaskManager) periodic(id string, fn func(ctx context.Context) error, interval time.Duration, jitter float64, fixedDelay bool) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		timer := s.clock.NewTimer(jittered(interval, jitter))
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-timer.C():
+			}
+
+			started := s.clock.Now()
+			if err := s.call(ctx, id, fn); err != nil {
+				s.logger.Printf("Task %s run failed: %v", id, err)
+			}
+
+			delay := jittered(interval, jitter)
+			if !fixedDelay {
+				delay -= s.clock.Now().Sub(started)
+			}
+			timer.Reset(delay)
+		}
+	}
+}
+
+// jittered returns d moved by a random amount of up to ±fraction of it.
+func jittered(d time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 {
+		return d
+	}
+	return d + time.Duration((rand.Float64()*2-1)*fraction*float64(d))
+}
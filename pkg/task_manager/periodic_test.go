@@ -0,0 +1,82 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// runPeriodic starts a periodic task whose runs take 30ms on the fake clock
+// and returns the offsets of its first runs from the start.
+func runPeriodic(t *testing.T, opts ...TaskOption) []time.Duration {
+	t.Helper()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	tm := NewTaskManager(WithClock(clock))
+
+	starts := make(chan time.Time, 1)
+	err := tm.StartPeriodicTask(context.Background(), "task", func(ctx context.Context) error {
+		starts <- clock.Now()
+		clock.Advance(30 * time.Millisecond)
+		return errors.New("run errors don't end the task")
+	}, 100*time.Millisecond, opts...)
+	if err != nil {
+		t.Fatalf("Unexpected error starting periodic task: %v", err)
+	}
+	defer tm.StopTaskAndWait("task", 500*time.Millisecond)
+
+	var offsets []time.Duration
+	for len(offsets) < 3 {
+		clock.BlockUntil(1)
+		clock.Advance(10 * time.Millisecond)
+		select {
+		case at := <-starts:
+			offsets = append(offsets, at.Sub(start))
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	return offsets
+}
+
+func TestStartPeriodicTask_FixedRate(t *testing.T) {
+	got := runPeriodic(t)
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected runs at %v counted from the previous start, got %v", want, got)
+			break
+		}
+	}
+}
+
+func TestStartPeriodicTask_FixedDelay(t *testing.T) {
+	got := runPeriodic(t, WithFixedDelay())
+	want := []time.Duration{100 * time.Millisecond, 230 * time.Millisecond, 360 * time.Millisecond}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected runs at %v counted from the previous end, got %v", want, got)
+			break
+		}
+	}
+}
+
+func TestStartPeriodicTask_InvalidInterval(t *testing.T) {
+	tm := NewTaskManager()
+
+	err := tm.StartPeriodicTask(context.Background(), "task", func(ctx context.Context) error { return nil }, 0)
+	if !errors.Is(err, ErrInvalidInterval) {
+		t.Errorf("Expected ErrInvalidInterval, got %v", err)
+	}
+}
+
+func TestJittered(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		if d := jittered(time.Second, 0.2); d < 800*time.Millisecond || d > 1200*time.Millisecond {
+			t.Fatalf("Expected a delay within 20%% of 1s, got %v", d)
+		}
+	}
+	if d := jittered(time.Second, 0); d != time.Second {
+		t.Errorf("Expected no jitter, got %v", d)
+	}
+}
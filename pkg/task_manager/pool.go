@@ -0,0 +1,107 @@
+package taskmanager
+
+import (
+	"context"
+	"sync"
+)
+
+// Pool runs small jobs on a fixed number of workers under a single task,
+// see StartPool.
+type Pool struct {
+	tm        *TaskManager
+	id        string
+	jobs      chan func(ctx context.Context) error
+	closeOnce sync.Once
+	closed    chan struct{}
+	done      chan struct{} // closed once the pool task has finished
+}
+
+// StartPool starts the task id running workers goroutines that execute the
+// jobs passed to Submit. Stopping the task, or shutting the manager down,
+// stops the whole pool. Job errors and panics are logged and don't stop the
+// pool.
+func (s *TaskManager) StartPool(ctx context.Context, id string, workers int, opts ...TaskOption) (*Pool, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	p := &Pool{
+		tm:     s,
+		id:     id,
+		jobs:   make(chan func(ctx context.Context) error),
+		closed: make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+
+	opts = append(opts, alsoOnComplete(func(err error) { close(p.done) }), wrapped())
+	_, err := s.StartTask(ctx, id, func(ctx context.Context) error {
+		var wg sync.WaitGroup
+		for range workers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				p.work(ctx)
+			}()
+		}
+		wg.Wait()
+		return ctx.Err()
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *Pool) work(ctx context.Context) {
+	for {
+		select {
+		case job := <-p.jobs:
+			if err := p.tm.call(ctx, p.id, p.tm.body(p.id, job)); err != nil {
+				p.tm.logger.Printf("Task %s job failed: %v", p.id, err)
+			}
+		case <-p.closed:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ID returns the ID of the pool task.
+func (p *Pool) ID() string {
+	return p.id
+}
+
+// Submit blocks until a worker picks job up, or until ctx is done. It
+// returns ErrPoolClosed once the pool was closed or its task has finished.
+// The job context is the one of the pool task.
+func (p *Pool) Submit(ctx context.Context, job func(ctx context.Context) error) error {
+	if job == nil {
+		return ErrNilTaskFunc
+	}
+	select {
+	case <-p.closed:
+		return ErrPoolClosed
+	case <-p.done:
+		return ErrPoolClosed
+	default:
+	}
+
+	select {
+	case p.jobs <- job:
+		return nil
+	case <-p.closed:
+		return ErrPoolClosed
+	case <-p.done:
+		return ErrPoolClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting jobs. The pool task completes once the running jobs
+// have returned.
+func (p *Pool) Close() {
+	p.closeOnce.Do(func() {
+		close(p.closed)
+	})
+}
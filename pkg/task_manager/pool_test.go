@@ -0,0 +1,86 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool_RunsJobsOnWorkers(t *testing.T) {
+	tm := NewTaskManager()
+
+	p, err := tm.StartPool(context.Background(), "resize", 3)
+	if err != nil {
+		t.Fatalf("Unexpected error starting pool: %v", err)
+	}
+
+	var running, maxRunning, done atomic.Int64
+	for i := range 10 {
+		err := p.Submit(context.Background(), func(ctx context.Context) error {
+			n := running.Add(1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			running.Add(-1)
+			done.Add(1)
+			if i == 0 {
+				panic("job panicked")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error submitting job: %v", err)
+		}
+	}
+	if n := len(tm.ListTasks()); n != 1 {
+		t.Errorf("Expected the pool to be a single task, got %d", n)
+	}
+
+	p.Close()
+	if err, _ := tm.WaitTask(context.Background(), "resize"); err != nil {
+		t.Errorf("Expected the pool to complete once closed, got %v", err)
+	}
+	if n := done.Load(); n != 10 {
+		t.Errorf("Expected 10 jobs to run, got %d", n)
+	}
+	if n := maxRunning.Load(); n > 3 {
+		t.Errorf("Expected at most 3 jobs at once, got %d", n)
+	}
+	if err := p.Submit(context.Background(), func(ctx context.Context) error { return nil }); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Expected ErrPoolClosed after Close, got %v", err)
+	}
+}
+
+func TestPool_StopTaskStopsJobs(t *testing.T) {
+	tm := NewTaskManager()
+	p, _ := tm.StartPool(context.Background(), "resize", 2)
+
+	canceled := make(chan struct{}, 2)
+	for range 2 {
+		_ = p.Submit(context.Background(), func(ctx context.Context) error {
+			<-ctx.Done()
+			canceled <- struct{}{}
+			return ctx.Err()
+		})
+	}
+
+	if err := tm.StopTaskAndWait("resize", 500*time.Millisecond); err != nil {
+		t.Fatalf("Unexpected error stopping pool: %v", err)
+	}
+	if len(canceled) != 2 {
+		t.Errorf("Expected both jobs to be canceled, got %d", len(canceled))
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := p.Submit(ctx, func(ctx context.Context) error { return nil }); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Expected ErrPoolClosed once the pool task ended, got %v", err)
+	}
+}
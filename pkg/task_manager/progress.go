@@ -0,0 +1,49 @@
+package taskmanager
+
+import (
+	"context"
+	"time"
+)
+
+// Progress is the latest progress published by a task.
+type Progress struct {
+	Percent   float64
+	Message   string
+	Data      any
+	UpdatedAt time.Time
+}
+
+type taskKey struct{}
+
+// taskFromContext returns the managed task owning ctx, if any.
+func taskFromContext(ctx context.Context) (*task, bool) {
+	t, ok := ctx.Value(taskKey{}).(*task)
+	return t, ok
+}
+
+// ReportProgress publishes the progress of the task owning ctx, readable
+// through TaskManager.Progress. It is a no-op outside a managed task.
+func ReportProgress(ctx context.Context, percent float64, message string, data any) {
+	t, ok := taskFromContext(ctx)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.progress = Progress{
+		Percent:   percent,
+		Message:   message,
+		Data:      data,
+		UpdatedAt: t.clock.Now(),
+	}
+}
+
+// Progress returns the latest progress reported by the task with the given
+// id. The bool is false if the task is unknown or never reported progress.
+func (s *TaskManager) Progress(id string) (Progress, bool) {
+	info, ok := s.Status(id)
+	if !ok || info.Progress.UpdatedAt.IsZero() {
+		return Progress{}, false
+	}
+	return info.Progress, true
+}
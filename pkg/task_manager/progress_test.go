@@ -0,0 +1,41 @@
+package taskmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestProgress_ReportedByTask(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	reported := make(chan struct{})
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		ReportProgress(ctx, 50, "half way", map[string]int{"orders": 10})
+		close(reported)
+		<-ctx.Done()
+		return nil
+	})
+
+	<-reported
+	p, ok := tm.Progress("task")
+	if !ok {
+		t.Fatal("Expected progress to be available")
+	}
+	if p.Percent != 50 || p.Message != "half way" || p.Data.(map[string]int)["orders"] != 10 || p.UpdatedAt.IsZero() {
+		t.Errorf("Unexpected progress: %+v", p)
+	}
+	if infos := tm.ListTasks(); len(infos) != 1 || infos[0].Progress.Percent != 50 {
+		t.Errorf("Expected progress in ListTasks, got %+v", infos)
+	}
+}
+
+func TestProgress_OutsideTask(t *testing.T) {
+	ReportProgress(context.Background(), 10, "ignored", nil)
+
+	tm := NewTaskManager()
+	if _, ok := tm.Progress("does_not_exist"); ok {
+		t.Error("Expected no progress for unknown task")
+	}
+}
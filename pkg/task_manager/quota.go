@@ -0,0 +1,168 @@
+package taskmanager
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// TenantQuota limits how many tasks each tenant of a multi-tenant service may
+// have running or pending at once, see WithTenantQuota.
+type TenantQuota struct {
+	// Tenant returns the tenant of a task, "" for a task not subject to the
+	// quota, see TenantFromTag and TenantFromMetadata. It only sees the ID,
+	// tags, priority and metadata of the task.
+	Tenant func(info TaskInfo) string
+	// Limits are the quotas by tenant, Default applies to the tenants missing
+	// from it. 0 means no limit.
+	Limits  map[string]int
+	Default int
+}
+
+// TenantFromTag returns a TenantQuota.Tenant reading the tenant from the
+// first tag starting with prefix, e.g. "acme" from "tenant:acme" with the
+// prefix "tenant:".
+func TenantFromTag(prefix string) func(info TaskInfo) string {
+	return func(info TaskInfo) string {
+		for _, tag := range info.Tags {
+			if tenant, ok := strings.CutPrefix(tag, prefix); ok {
+				return tenant
+			}
+		}
+		return ""
+	}
+}
+
+// TenantFromMetadata returns a TenantQuota.Tenant reading the tenant from the
+// metadata key, see WithMetadata.
+func TenantFromMetadata(key string) func(info TaskInfo) string {
+	return func(info TaskInfo) string {
+		return info.Metadata[key]
+	}
+}
+
+// WithTenantQuota rejects the start of a task with a *QuotaError, matching
+// ErrQuotaExceeded, when its tenant already has as many tasks as its quota.
+// Replacing a task of the tenant with the same ID is always allowed. The
+// rejections are counted in Stats and by tenant in Quotas.
+func WithTenantQuota(q TenantQuota) Option {
+	return func(s *TaskManager) {
+		if q.Tenant == nil {
+			return
+		}
+		q.Limits = maps.Clone(q.Limits)
+		s.quotas = &quotas{
+			quota:    q,
+			active:   map[string]map[string]*task{},
+			rejected: map[string]int64{},
+		}
+	}
+}
+
+// QuotaInfo is the quota usage of a tenant.
+type QuotaInfo struct {
+	Tenant string
+	// Active is the number of running or pending tasks of the tenant.
+	Active int
+	// Limit is the quota of the tenant, 0 means no limit.
+	Limit int
+	// Rejected counts the tasks of the tenant rejected so far.
+	Rejected int64
+}
+
+// Quotas returns the usage of the tenants that have tasks or had rejections,
+// sorted by tenant. It is empty without WithTenantQuota.
+func (s *TaskManager) Quotas() []QuotaInfo {
+	if s.quotas == nil {
+		return []QuotaInfo{}
+	}
+	return s.quotas.usage()
+}
+
+type quotas struct {
+	quota TenantQuota
+
+	mu       sync.Mutex
+	active   map[string]map[string]*task // by tenant, then task ID
+	rejected map[string]int64
+	total    int64
+}
+
+func (q *quotas) limit(tenant string) int {
+	if limit, ok := q.quota.Limits[tenant]; ok {
+		return limit
+	}
+	return q.quota.Default
+}
+
+// acquire counts t against the quota of its tenant, or returns a *QuotaError
+// if the tenant has no room left.
+func (q *quotas) acquire(t *task) error {
+	t.tenant = q.quota.Tenant(TaskInfo{ID: t.id, Tags: t.tags, Priority: t.priority, Metadata: maps.Clone(t.metadata)})
+	if t.tenant == "" {
+		return nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	tasks := q.active[t.tenant]
+	_, replacing := tasks[t.id]
+	if limit := q.limit(t.tenant); limit > 0 && !replacing && len(tasks) >= limit {
+		q.rejected[t.tenant]++
+		q.total++
+		return &QuotaError{Tenant: t.tenant, Limit: limit}
+	}
+	if tasks == nil {
+		tasks = map[string]*task{}
+		q.active[t.tenant] = tasks
+	}
+	tasks[t.id] = t
+	return nil
+}
+
+// release frees the slot of t, unless a replacement took it over.
+func (q *quotas) release(t *task) {
+	if t.tenant == "" {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if tasks := q.active[t.tenant]; tasks[t.id] == t {
+		delete(tasks, t.id)
+		if len(tasks) == 0 {
+			delete(q.active, t.tenant)
+		}
+	}
+}
+
+func (q *quotas) rejections() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.total
+}
+
+func (q *quotas) usage() []QuotaInfo {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	tenants := slices.Collect(maps.Keys(q.active))
+	for tenant := range q.rejected {
+		if _, ok := q.active[tenant]; !ok {
+			tenants = append(tenants, tenant)
+		}
+	}
+	slices.Sort(tenants)
+
+	infos := make([]QuotaInfo, 0, len(tenants))
+	for _, tenant := range tenants {
+		infos = append(infos, QuotaInfo{
+			Tenant:   tenant,
+			Active:   len(q.active[tenant]),
+			Limit:    q.limit(tenant),
+			Rejected: q.rejected[tenant],
+		})
+	}
+	return infos
+}
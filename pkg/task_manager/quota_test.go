@@ -0,0 +1,106 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWithTenantQuota(t *testing.T) {
+	tm := NewTaskManager(WithTenantQuota(TenantQuota{
+		Tenant:  TenantFromTag("tenant:"),
+		Limits:  map[string]int{"big": 3},
+		Default: 1,
+	}))
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}
+	ctx := context.Background()
+
+	if _, err := tm.StartTask(ctx, "small-1", block, WithTags("tenant:small")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err := tm.StartTask(ctx, "small-2", block, WithTags("tenant:small"))
+	var quotaErr *QuotaError
+	if !errors.As(err, &quotaErr) || !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected a QuotaError, got %v", err)
+	}
+	if quotaErr.Tenant != "small" || quotaErr.Limit != 1 {
+		t.Errorf("Expected the quota of 1 task of small, got %+v", quotaErr)
+	}
+
+	if _, err := tm.StartTask(ctx, "small-1", block, WithTags("tenant:small")); err != nil {
+		t.Errorf("Expected replacing a task to be allowed, got %v", err)
+	}
+	for _, id := range []string{"big-1", "big-2", "big-3"} {
+		if _, err := tm.StartTask(ctx, id, block, WithTags("tenant:big")); err != nil {
+			t.Errorf("Expected %s within the quota of big, got %v", id, err)
+		}
+	}
+	if _, err := tm.StartTask(ctx, "untagged", block); err != nil {
+		t.Errorf("Expected a task without tenant to be allowed, got %v", err)
+	}
+
+	if err := tm.StopTaskAndWait("small-1", 500*time.Millisecond); err != nil {
+		t.Fatalf("Unexpected error stopping the task: %v", err)
+	}
+	if _, err := tm.StartTask(ctx, "small-2", block, WithTags("tenant:small")); err != nil {
+		t.Errorf("Expected the quota freed by the stopped task, got %v", err)
+	}
+
+	if n := tm.Stats().QuotaRejected; n != 1 {
+		t.Errorf("Expected 1 rejection, got %d", n)
+	}
+	want := []QuotaInfo{
+		{Tenant: "big", Active: 3, Limit: 3},
+		{Tenant: "small", Active: 1, Limit: 1, Rejected: 1},
+	}
+	if got := tm.Quotas(); !slices.Equal(got, want) {
+		t.Errorf("Expected the usage %+v, got %+v", want, got)
+	}
+}
+
+func TestTenantFromMetadata(t *testing.T) {
+	tenant := TenantFromMetadata("tenant")
+	if got := tenant(TaskInfo{Metadata: map[string]string{"tenant": "acme"}}); got != "acme" {
+		t.Errorf("Expected acme, got %q", got)
+	}
+	if got := tenant(TaskInfo{}); got != "" {
+		t.Errorf("Expected no tenant, got %q", got)
+	}
+}
+
+func TestWithTenantQuota_CachedRun(t *testing.T) {
+	tm := NewTaskManager(WithTenantQuota(TenantQuota{
+		Tenant:  TenantFromTag("tenant:"),
+		Default: 1,
+	}))
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}
+	ctx := context.Background()
+	opts := []TaskOption{WithTags("tenant:acme"), WithIdempotencyKey("report", time.Hour)}
+
+	first, err := tm.StartTask(ctx, "report", block, opts...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	cached, err := tm.StartTask(ctx, "report", block, opts...)
+	if err != nil || cached.RunID() != first.RunID() {
+		t.Fatalf("Expected the cached run, got %v", err)
+	}
+	if _, err := tm.StartTask(ctx, "other", block, WithTags("tenant:acme")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected the running task to keep its slot, got %v", err)
+	}
+	if got := tm.Quotas(); len(got) != 1 || got[0].Active != 1 {
+		t.Errorf("Expected 1 active task, got %+v", got)
+	}
+}
@@ -0,0 +1,129 @@
+package taskmanager
+
+import (
+	"context"
+)
+
+// OverlapPolicy decides what a recurring task does when a run is due while
+// the previous run is still active.
+type OverlapPolicy int
+
+const (
+	// OverlapSkip drops the due run.
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue starts the due run once the active one has finished.
+	OverlapQueue
+	// OverlapReplace cancels the active run and starts the due one.
+	OverlapReplace
+)
+
+// WithOverlapPolicy sets the overlap policy of a recurring task, OverlapSkip
+// by default.
+func WithOverlapPolicy(policy OverlapPolicy) TaskOption {
+	return func(cfg *taskConfig) {
+		cfg.overlap = policy
+	}
+}
+
+// StartRecurringTask registers a task that calls fn on every run of schedule
+// until it is stopped or the schedule ends. Errors of single runs are logged
+// and do not end the task. The other options apply to the task as a whole,
+// e.g. WithTimeout bounds its total lifetime.
+func (s *TaskManager) StartRecurringTask(ctx context.Context, id string, fn func(ctx context.Context) error, schedule Schedule, opts ...TaskOption) error {
+	if fn == nil {
+		return ErrNilTaskFunc
+	}
+	if schedule == nil {
+		return ErrNilSchedule
+	}
+	cfg := newTaskConfig(opts)
+	opts = append(opts, wrapped())
+	return s.StartTaskWithOptions(ctx, id, s.recurring(id, s.body(id, fn), schedule, cfg.overlap, cfg.exclusions), opts...)
+}
+
+func (s *TaskManager) recurring(id string, fn func(ctx context.Context) error, schedule Schedule, policy OverlapPolicy, exclusions []exclusion) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var (
+			active    int
+			queued    int
+			cancelRun context.CancelFunc
+			finished  = make(chan struct{})
+		)
+		startRun := func() {
+			runCtx, cancel := context.WithCancel(ctx)
+			cancelRun = cancel
+			active++
+			go func() {
+				defer cancel()
+				if err := s.call(runCtx, id, fn); err != nil {
+					s.logger.Printf("Task %s run failed: %v", id, err)
+				}
+				finished <- struct{}{}
+			}()
+		}
+		// wait for the active runs before the task itself returns
+		defer func() {
+			for ; active > 0; active-- {
+				<-finished
+			}
+		}()
+
+		next := schedule.Next(s.clock.Now())
+		timer := s.clock.NewTimer(next.Sub(s.clock.Now()))
+		defer timer.Stop()
+		timerC := timer.C()
+		if next.IsZero() {
+			timerC = nil
+		}
+
+		// keep going while runs are due or still active
+		for timerC != nil || active > 0 || queued > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+
+			case <-finished:
+				active--
+				if active == 0 && queued > 0 {
+					queued--
+					startRun()
+				}
+
+			case <-timerC:
+				end, deferRun := excludedUntil(exclusions, s.clock.Now())
+				switch {
+				case !end.IsZero():
+					s.logger.Printf("Task %s run suppressed by an exclusion window until %v", id, end)
+					if t, ok := taskFromContext(ctx); ok {
+						s.emit(EventSuppressed, t, 0, nil)
+					}
+				case active == 0:
+					startRun()
+				case policy == OverlapQueue:
+					queued++
+				case policy == OverlapReplace:
+					cancelRun()
+					startRun()
+				default:
+					s.logger.Printf("Task %s is still running, skipping scheduled run", id)
+				}
+
+				// don't fire runs missed while we were busy
+				if next = schedule.Next(next); !next.IsZero() && next.Before(s.clock.Now()) {
+					next = schedule.Next(s.clock.Now())
+				}
+				// the runs due until the end of the window are suppressed
+				// anyway, fire once at its end instead
+				if deferRun {
+					next = end
+				}
+				if next.IsZero() {
+					timerC = nil
+				} else {
+					timer.Reset(next.Sub(s.clock.Now()))
+				}
+			}
+		}
+		return nil
+	}
+}
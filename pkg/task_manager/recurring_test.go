@@ -0,0 +1,111 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type limitedSchedule struct {
+	every time.Duration
+	runs  int32
+}
+
+func (l *limitedSchedule) Next(t time.Time) time.Time {
+	if atomic.AddInt32(&l.runs, -1) < 0 {
+		return time.Time{}
+	}
+	return t.Add(l.every)
+}
+
+func TestStartRecurringTask_RunsUntilStopped(t *testing.T) {
+	tm := NewTaskManager()
+
+	var runs int32
+	err := tm.StartRecurringTask(context.Background(), "task", func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return errors.New("run errors don't end the task")
+	}, Every(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Unexpected error starting recurring task: %v", err)
+	}
+
+	time.Sleep(75 * time.Millisecond)
+	if !tm.HasTask("task") {
+		t.Fatal("Expected recurring task to keep running")
+	}
+	if err := tm.StopTaskAndWait("task", 500*time.Millisecond); err != nil {
+		t.Fatalf("Expected recurring task to stop, got %v", err)
+	}
+
+	got := atomic.LoadInt32(&runs)
+	if got < 3 {
+		t.Errorf("Expected several runs, got %d", got)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if after := atomic.LoadInt32(&runs); after != got {
+		t.Errorf("Expected no runs after stop, got %d more", after-got)
+	}
+}
+
+func TestStartRecurringTask_EndsWithSchedule(t *testing.T) {
+	tm := NewTaskManager()
+
+	var runs int32
+	_ = tm.StartRecurringTask(context.Background(), "task", func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		return nil
+	}, &limitedSchedule{every: 5 * time.Millisecond, runs: 3})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err, _ := tm.WaitTask(ctx, "task"); err != nil {
+		t.Fatalf("Expected recurring task to complete, got %v", err)
+	}
+	if got := atomic.LoadInt32(&runs); got != 3 {
+		t.Errorf("Expected 3 runs, got %d", got)
+	}
+}
+
+func TestStartRecurringTask_OverlapPolicies(t *testing.T) {
+	run := func(policy OverlapPolicy) (started, canceled int32) {
+		tm := NewTaskManager()
+		_ = tm.StartRecurringTask(context.Background(), "task", func(ctx context.Context) error {
+			atomic.AddInt32(&started, 1)
+			select {
+			case <-ctx.Done():
+				atomic.AddInt32(&canceled, 1)
+				return ctx.Err()
+			case <-time.After(100 * time.Millisecond):
+				return nil
+			}
+		}, &limitedSchedule{every: 10 * time.Millisecond, runs: 4}, WithOverlapPolicy(policy))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		_, _ = tm.WaitTask(ctx, "task")
+		return atomic.LoadInt32(&started), atomic.LoadInt32(&canceled)
+	}
+
+	// runs are due at 10, 20, 30 and 40ms and each takes 100ms
+	if started, _ := run(OverlapSkip); started != 1 {
+		t.Errorf("OverlapSkip: expected 1 run, got %d", started)
+	}
+	if started, canceled := run(OverlapQueue); started != 4 || canceled != 0 {
+		t.Errorf("OverlapQueue: expected 4 runs and no cancellation, got %d runs, %d canceled", started, canceled)
+	}
+	if started, canceled := run(OverlapReplace); started != 4 || canceled != 3 {
+		t.Errorf("OverlapReplace: expected 4 runs with 3 canceled, got %d runs, %d canceled", started, canceled)
+	}
+}
+
+func TestStartRecurringTask_NilSchedule(t *testing.T) {
+	tm := NewTaskManager()
+
+	err := tm.StartRecurringTask(context.Background(), "task", func(ctx context.Context) error { return nil }, nil)
+	if !errors.Is(err, ErrNilSchedule) {
+		t.Errorf("Expected ErrNilSchedule, got %v", err)
+	}
+}
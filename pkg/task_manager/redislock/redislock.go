@@ -0,0 +1,172 @@
+// Package redislock implements a taskmanager.LockProvider on top of Redis.
+//
+// A lock is a key set with NX and a TTL to a random token. The holder renews
+// the TTL while it runs, so that the lock frees itself if the holder dies.
+package redislock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	taskmanager "github.com/joripage/go_util/pkg/task_manager"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	DefaultTTL           = 15 * time.Second
+	DefaultRetryInterval = time.Second
+	DefaultKeyPrefix     = "taskmanager:lock:"
+)
+
+var (
+	renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// Provider hands out locks stored in Redis.
+type Provider struct {
+	client redis.UniversalClient
+	ttl    time.Duration
+	retry  time.Duration
+	prefix string
+}
+
+var _ taskmanager.LockProvider = (*Provider)(nil)
+
+type Option func(*Provider)
+
+// WithTTL sets how long a lock outlives a holder that stopped renewing it.
+// The lock is renewed every third of it.
+func WithTTL(d time.Duration) Option {
+	return func(p *Provider) {
+		p.ttl = d
+	}
+}
+
+// WithRetryInterval sets how often Acquire retries while the lock is held
+// elsewhere.
+func WithRetryInterval(d time.Duration) Option {
+	return func(p *Provider) {
+		p.retry = d
+	}
+}
+
+func WithKeyPrefix(prefix string) Option {
+	return func(p *Provider) {
+		p.prefix = prefix
+	}
+}
+
+func New(client redis.UniversalClient, opts ...Option) *Provider {
+	p := &Provider{
+		client: client,
+		ttl:    DefaultTTL,
+		retry:  DefaultRetryInterval,
+		prefix: DefaultKeyPrefix,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *Provider) Acquire(ctx context.Context, key string) (taskmanager.Lock, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	key = p.prefix + key
+
+	ticker := time.NewTicker(p.retry)
+	defer ticker.Stop()
+	for {
+		ok, err := p.client.SetNX(ctx, key, token, p.ttl).Result()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			l := &lock{
+				provider: p,
+				key:      key,
+				token:    token,
+				lost:     make(chan struct{}),
+				stop:     make(chan struct{}),
+				stopped:  make(chan struct{}),
+			}
+			go l.renew()
+			return l, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+type lock struct {
+	provider *Provider
+	key      string
+	token    string
+	lost     chan struct{}
+	stop     chan struct{}
+	stopped  chan struct{}
+}
+
+// renew extends the TTL until the lock is released. The lock is lost when
+// another instance owns the key, or when it could not be renewed for a whole
+// TTL.
+func (l *lock) renew() {
+	defer close(l.stopped)
+
+	ticker := time.NewTicker(l.provider.ttl / 3)
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), l.provider.ttl/3)
+		n, err := renewScript.Run(ctx, l.provider.client, []string{l.key}, l.token, l.provider.ttl.Milliseconds()).Int()
+		cancel()
+		switch {
+		case err == nil && n == 1:
+			renewed = time.Now()
+		case err == nil || time.Since(renewed) >= l.provider.ttl:
+			close(l.lost)
+			return
+		}
+	}
+}
+
+func (l *lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *lock) Release(ctx context.Context) error {
+	close(l.stop)
+	<-l.stopped
+	return releaseScript.Run(ctx, l.provider.client, []string{l.key}, l.token).Err()
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
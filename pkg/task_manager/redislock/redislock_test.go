@@ -0,0 +1,74 @@
+package redislock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func newProvider(t *testing.T) (*Provider, *miniredis.Miniredis) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return New(client, WithTTL(300*time.Millisecond), WithRetryInterval(10*time.Millisecond)), mr
+}
+
+func TestProvider_AcquireRelease(t *testing.T) {
+	p, _ := newProvider(t)
+	ctx := context.Background()
+
+	l, err := p.Acquire(ctx, "job")
+	if err != nil {
+		t.Fatalf("Unexpected error acquiring lock: %v", err)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	if _, err := p.Acquire(waitCtx, "job"); err == nil {
+		t.Fatal("Expected a held lock not to be acquired twice")
+	}
+
+	// renewed past its TTL while held
+	time.Sleep(500 * time.Millisecond)
+	select {
+	case <-l.Lost():
+		t.Fatal("Expected the lock to be renewed while held")
+	default:
+	}
+
+	if err := l.Release(ctx); err != nil {
+		t.Fatalf("Unexpected error releasing lock: %v", err)
+	}
+	waitCtx, cancel = context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	l, err = p.Acquire(waitCtx, "job")
+	if err != nil {
+		t.Fatalf("Expected a released lock to be acquired, got %v", err)
+	}
+	_ = l.Release(ctx)
+}
+
+func TestProvider_Lost(t *testing.T) {
+	p, mr := newProvider(t)
+	ctx := context.Background()
+
+	l, err := p.Acquire(ctx, "job")
+	if err != nil {
+		t.Fatalf("Unexpected error acquiring lock: %v", err)
+	}
+	defer l.Release(ctx)
+
+	// another instance took the key over
+	mr.Set(DefaultKeyPrefix+"job", "someone-else")
+	select {
+	case <-l.Lost():
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected the lock to be lost")
+	}
+	if v, _ := mr.Get(DefaultKeyPrefix + "job"); v != "someone-else" {
+		t.Errorf("Expected the other owner's key to be kept, got %q", v)
+	}
+}
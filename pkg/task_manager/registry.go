@@ -0,0 +1,70 @@
+package taskmanager
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// RegisteredFunc is a task function registered by name, see Register.
+type RegisteredFunc func(ctx context.Context, params map[string]string) error
+
+type registry struct {
+	mu  sync.RWMutex
+	fns map[string]RegisteredFunc
+}
+
+// Register declares a task function under name so that it can be started
+// later, possibly remotely, with StartRegistered.
+func (s *TaskManager) Register(name string, fn RegisteredFunc) error {
+	if name == "" {
+		return ErrInvalidTaskID
+	}
+	if fn == nil {
+		return ErrNilTaskFunc
+	}
+
+	s.registry.mu.Lock()
+	defer s.registry.mu.Unlock()
+	if _, ok := s.registry.fns[name]; ok {
+		return ErrTaskAlreadyRegistered
+	}
+	if s.registry.fns == nil {
+		s.registry.fns = map[string]RegisteredFunc{}
+	}
+	s.registry.fns[name] = fn
+	return nil
+}
+
+// StartRegistered starts the function registered under name with params,
+// using name as the task ID. The task is persisted if a Store is configured,
+// once it started, so a failed start leaves nothing in the store.
+func (s *TaskManager) StartRegistered(ctx context.Context, name string, params map[string]string, opts ...TaskOption) error {
+	fn, err := s.registered(name)
+	if err != nil {
+		return err
+	}
+
+	opts = append(opts, persistAs(TaskDefinition{ID: name, Name: name, Params: params}))
+	return s.StartTaskWithOptions(ctx, name, func(ctx context.Context) error {
+		return fn(ctx, params)
+	}, opts...)
+}
+
+// Registered returns the names of the registered task functions, sorted.
+func (s *TaskManager) Registered() []string {
+	s.registry.mu.RLock()
+	defer s.registry.mu.RUnlock()
+	return slices.Sorted(maps.Keys(s.registry.fns))
+}
+
+func (s *TaskManager) registered(name string) (RegisteredFunc, error) {
+	s.registry.mu.RLock()
+	defer s.registry.mu.RUnlock()
+	fn, ok := s.registry.fns[name]
+	if !ok {
+		return nil, ErrTaskNotRegistered
+	}
+	return fn, nil
+}
@@ -0,0 +1,49 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRegister_StartRegistered(t *testing.T) {
+	tm := NewTaskManager()
+
+	got := make(chan map[string]string, 1)
+	err := tm.Register("rebuild-index", func(ctx context.Context, params map[string]string) error {
+		got <- params
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error registering task: %v", err)
+	}
+
+	if err := tm.StartRegistered(context.Background(), "rebuild-index", map[string]string{"index": "orders"}); err != nil {
+		t.Fatalf("Unexpected error starting registered task: %v", err)
+	}
+	if err, ok := tm.WaitTask(context.Background(), "rebuild-index"); !ok || err != nil {
+		t.Fatalf("Expected registered task to run under its name, got (%v, %v)", err, ok)
+	}
+	if params := <-got; params["index"] != "orders" {
+		t.Errorf("Expected params to be passed through, got %v", params)
+	}
+}
+
+func TestRegister_Errors(t *testing.T) {
+	tm := NewTaskManager()
+	fn := func(ctx context.Context, params map[string]string) error { return nil }
+
+	if err := tm.Register("", fn); !errors.Is(err, ErrInvalidTaskID) {
+		t.Errorf("Expected ErrInvalidTaskID, got %v", err)
+	}
+	if err := tm.Register("task", nil); !errors.Is(err, ErrNilTaskFunc) {
+		t.Errorf("Expected ErrNilTaskFunc, got %v", err)
+	}
+	_ = tm.Register("task", fn)
+	if err := tm.Register("task", fn); !errors.Is(err, ErrTaskAlreadyRegistered) {
+		t.Errorf("Expected ErrTaskAlreadyRegistered, got %v", err)
+	}
+	if err := tm.StartRegistered(context.Background(), "missing", nil); !errors.Is(err, ErrTaskNotRegistered) {
+		t.Errorf("Expected ErrTaskNotRegistered, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package taskmanager
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ShutdownReport describes the last graceful shutdown, for post-mortems of
+// slow shutdowns.
+type ShutdownReport struct {
+	Began time.Time
+	Ended time.Time
+	// Err is the error returned by GracefulShutdownContext.
+	Err error
+	// Tasks are the tasks running when the shutdown began, in the order they
+	// returned, followed by the ones that missed the deadline sorted by ID.
+	Tasks []ShutdownTaskReport
+}
+
+// ShutdownTaskReport is the outcome of a task during a shutdown.
+type ShutdownTaskReport struct {
+	ID    string
+	RunID uint64
+	// Detached tasks were not canceled by the shutdown, see WithDetached.
+	Detached bool
+	// MissedDeadline reports whether the task was still running when the
+	// shutdown ended.
+	MissedDeadline bool
+	// Status and Err are the result of the task. If it missed the deadline,
+	// they are StatusRunning and nil, or StatusAbandoned and nil after
+	// GracefulShutdown without wait.
+	Status TaskStatus
+	Err    error
+	// Duration is how long the task took to return after the shutdown
+	// began, or the duration of the shutdown if it missed the deadline.
+	Duration time.Duration
+}
+
+// LastShutdownReport returns the report of the last GracefulShutdownContext
+// or GracefulShutdown, and nil if there was none.
+func (s *TaskManager) LastShutdownReport() *ShutdownReport {
+	return s.lastShutdown.Load()
+}
+
+// recordShutdown stores the report of a shutdown, the tasks still running
+// being reported with the missed status.
+func (s *TaskManager) recordShutdown(began time.Time, tasks []*task, err error, missed TaskStatus) {
+	now := s.clock.Now()
+	report := &ShutdownReport{Began: began, Ended: now, Err: err, Tasks: make([]ShutdownTaskReport, 0, len(tasks))}
+	for _, t := range tasks {
+		r := ShutdownTaskReport{ID: t.id, RunID: t.run, Detached: t.detached, Status: missed}
+		select {
+		case <-t.done:
+			info := t.info(now)
+			r.Status, r.Err = info.Status, info.Err
+			r.Duration = max(info.FinishedAt.Sub(began), 0)
+		default:
+			r.MissedDeadline = true
+			r.Duration = now.Sub(began)
+		}
+		report.Tasks = append(report.Tasks, r)
+	}
+	slices.SortFunc(report.Tasks, func(a, b ShutdownTaskReport) int {
+		switch {
+		case a.MissedDeadline != b.MissedDeadline:
+			if a.MissedDeadline {
+				return 1
+			}
+			return -1
+		case a.MissedDeadline:
+			return strings.Compare(a.ID, b.ID)
+		}
+		return cmp.Or(cmp.Compare(a.Duration, b.Duration), strings.Compare(a.ID, b.ID))
+	})
+	s.lastShutdown.Store(report)
+}
@@ -0,0 +1,74 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// maxRetryBackoff caps the exponential growth of the retry delay.
+const maxRetryBackoff = time.Minute
+
+type attemptKey struct{}
+
+// Attempt returns the 1-based attempt number of the task run owning ctx, or 0
+// if ctx does not belong to a managed task.
+func Attempt(ctx context.Context) int {
+	attempt, _ := ctx.Value(attemptKey{}).(int)
+	return attempt
+}
+
+func (s *TaskManager) execute(ctx context.Context, id string, fn func(ctx context.Context) error, cfg taskConfig) error {
+	if !cfg.wrapped {
+		fn = s.body(id, fn)
+	}
+	fn = s.middlewares.wrap(id, fn)
+	err := s.call(context.WithValue(ctx, attemptKey{}, 1), id, fn)
+	for attempt := 1; attempt < cfg.maxAttempts; attempt++ {
+		if !shouldRetry(err) || ctx.Err() != nil {
+			break
+		}
+		delay := retryDelay(cfg.backoff, attempt)
+		s.logger.Printf("Task %s failed (attempt %d/%d), retrying in %v: %v", id, attempt, cfg.maxAttempts, delay, err)
+
+		timer := s.clock.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C():
+		}
+		err = s.call(context.WithValue(ctx, attemptKey{}, attempt+1), id, fn)
+	}
+	return err
+}
+
+// shouldRetry reports whether a failed attempt is retried: every error but
+// cancellations and permanent errors, see Permanent and Retryable.
+func shouldRetry(err error) bool {
+	switch {
+	case err == nil, IsPermanent(err):
+		return false
+	case IsRetryable(err):
+		return true
+	}
+	return !errors.Is(err, context.Canceled)
+}
+
+// retryDelay returns the delay before the retry following the given attempt:
+// backoff doubled per previous attempt, capped, with half of it jittered.
+func retryDelay(backoff time.Duration, attempt int) time.Duration {
+	if backoff <= 0 {
+		return 0
+	}
+	delay := backoff
+	for i := 1; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = max(maxRetryBackoff, backoff)
+	}
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
@@ -0,0 +1,105 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetry_AttemptFromContext(t *testing.T) {
+	tm := NewTaskManager()
+
+	var mu sync.Mutex
+	var attempts []int
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, Attempt(ctx))
+		if len(attempts) < 3 {
+			return errors.New("boom")
+		}
+		return nil
+	}, WithRetry(3, time.Millisecond))
+
+	if err, _ := tm.WaitTask(context.Background(), "task"); err != nil {
+		t.Fatalf("Expected task to succeed on third attempt, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(attempts) != 3 || attempts[0] != 1 || attempts[1] != 2 || attempts[2] != 3 {
+		t.Errorf("Expected attempts [1 2 3], got %v", attempts)
+	}
+}
+
+func TestRetry_AttemptOutsideTask(t *testing.T) {
+	if got := Attempt(context.Background()); got != 0 {
+		t.Errorf("Expected attempt 0 outside a task, got %d", got)
+	}
+}
+
+func TestRetryDelay_ExponentialWithJitter(t *testing.T) {
+	backoff := 100 * time.Millisecond
+	for attempt := 1; attempt <= 4; attempt++ {
+		full := backoff << (attempt - 1)
+		for range 100 {
+			d := retryDelay(backoff, attempt)
+			if d < full/2 || d > full {
+				t.Fatalf("Attempt %d: expected delay in [%v, %v], got %v", attempt, full/2, full, d)
+			}
+		}
+	}
+}
+
+func TestRetryDelay_Capped(t *testing.T) {
+	if d := retryDelay(time.Second, 50); d > maxRetryBackoff {
+		t.Errorf("Expected delay capped at %v, got %v", maxRetryBackoff, d)
+	}
+	if d := retryDelay(0, 3); d != 0 {
+		t.Errorf("Expected no delay without backoff, got %v", d)
+	}
+}
+
+func TestWithRetry_Permanent(t *testing.T) {
+	tm := NewTaskManager()
+
+	var attempts atomic.Int32
+	errInvalid := errors.New("invalid input")
+	h, _ := tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		attempts.Add(1)
+		return Permanent(errInvalid)
+	}, WithRetry(5, time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := h.Wait(ctx); !errors.Is(err, errInvalid) || !IsPermanent(err) {
+		t.Errorf("Expected the permanent error, got %v", err)
+	}
+	if n := attempts.Load(); n != 1 {
+		t.Errorf("Expected a single attempt, got %d", n)
+	}
+}
+
+func TestWithRetry_Retryable(t *testing.T) {
+	tm := NewTaskManager()
+
+	var attempts atomic.Int32
+	h, _ := tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		if attempts.Add(1) < 3 {
+			return Retryable(context.Canceled) // e.g. a request canceled by its server
+		}
+		return nil
+	}, WithRetry(5, time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := h.Wait(ctx); err != nil {
+		t.Errorf("Expected the task to succeed after retries, got %v", err)
+	}
+	if n := attempts.Load(); n != 3 {
+		t.Errorf("Expected 3 attempts, got %d", n)
+	}
+}
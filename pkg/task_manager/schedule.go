@@ -0,0 +1,213 @@
+package taskmanager
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule decides when a recurring task runs next.
+type Schedule interface {
+	// Next returns the first run time strictly after t, or the zero time if
+	// the schedule has no more runs.
+	Next(t time.Time) time.Time
+}
+
+type everySchedule time.Duration
+
+// Every returns a Schedule firing every d, counted from the previous run.
+func Every(d time.Duration) Schedule {
+	return everySchedule(d)
+}
+
+func (e everySchedule) Next(t time.Time) time.Time {
+	if e <= 0 {
+		return time.Time{}
+	}
+	return t.Add(time.Duration(e))
+}
+
+// cronSchedule holds one bit per allowed value of each cron field.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domStar and dowStar record an unrestricted field, which changes how
+	// day of month and day of week combine.
+	domStar, dowStar bool
+}
+
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMinute = cronField{min: 0, max: 59}
+	cronHour   = cronField{min: 0, max: 23}
+	cronDom    = cronField{min: 1, max: 31}
+	cronMonth  = cronField{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	cronDow = cronField{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a standard five field cron expression
+// (minute hour day-of-month month day-of-week), one of the descriptors
+// @yearly, @monthly, @weekly, @daily, @hourly, or "@every <duration>".
+// Times are evaluated in the location of the time passed to Next.
+func ParseCron(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := strings.CutPrefix(spec, "@every "); ok {
+		every, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil || every <= 0 {
+			return nil, fmt.Errorf("invalid cron spec %q: bad duration", spec)
+		}
+		return Every(every), nil
+	}
+	if expanded, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron spec %q: expected 5 fields, got %d", spec, len(fields))
+	}
+
+	var c cronSchedule
+	var err error
+	parsers := []struct {
+		field cronField
+		dst   *uint64
+	}{
+		{cronMinute, &c.minute},
+		{cronHour, &c.hour},
+		{cronDom, &c.dom},
+		{cronMonth, &c.month},
+		{cronDow, &c.dow},
+	}
+	for i, p := range parsers {
+		if *p.dst, err = p.field.parse(fields[i]); err != nil {
+			return nil, fmt.Errorf("invalid cron spec %q: %w", spec, err)
+		}
+	}
+	// 7 is an alias for Sunday
+	if c.dow&(1<<7) != 0 {
+		c.dow = c.dow&^(1<<7) | 1
+	}
+	c.domStar = fields[2] == "*" || fields[2] == "?"
+	c.dowStar = fields[4] == "*" || fields[4] == "?"
+	return &c, nil
+}
+
+func (f cronField) parse(expr string) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(expr, ",") {
+		rng, stepExpr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepExpr); err != nil || step <= 0 {
+				return 0, fmt.Errorf("bad step in %q", part)
+			}
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rng == "*" || rng == "?":
+		case strings.Contains(rng, "-"):
+			loExpr, hiExpr, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(loExpr); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(hiExpr); err != nil {
+				return 0, err
+			}
+		default:
+			v, err := f.value(rng)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			if !hasStep {
+				hi = v
+			}
+		}
+		if lo > hi {
+			return 0, fmt.Errorf("bad range in %q", part)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func (f cronField) value(expr string) (int, error) {
+	if v, ok := f.names[strings.ToLower(expr)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(expr)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %q out of range [%d, %d]", expr, f.min, f.max)
+	}
+	return v, nil
+}
+
+// maxCronSearch bounds the search for a matching time, so that specs that
+// can never fire (e.g. February 30th) return the zero time.
+const maxCronSearch = 5 * 366 * 24 * time.Hour
+
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxCronSearch)
+
+	for t.Before(limit) {
+		if !has(c.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !has(c.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !has(c.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches follows the usual cron rule: when both day of month and day of
+// week are restricted, a day matching either of them fires.
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	dom := has(c.dom, t.Day())
+	dow := has(c.dow, int(t.Weekday()))
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func has(set uint64, v int) bool {
+	return set&(1<<v) != 0
+}
@@ -0,0 +1,67 @@
+package taskmanager
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron_Next(t *testing.T) {
+	base := time.Date(2024, time.January, 15, 10, 30, 20, 0, time.UTC) // Monday
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 15, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 15, 10, 45, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2024, 1, 15, 11, 0, 0, 0, time.UTC)},
+		{"30 9 * * *", time.Date(2024, 1, 16, 9, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 * * fri", time.Date(2024, 1, 19, 12, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2024, 1, 21, 12, 0, 0, 0, time.UTC)},
+		{"0 0 29 feb *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 8-10/2 * * mon-wed", time.Date(2024, 1, 16, 8, 0, 0, 0, time.UTC)},
+		{"0 0 10,20 * 0", time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", base.Add(90 * time.Second)},
+	}
+
+	for _, tt := range tests {
+		schedule, err := ParseCron(tt.spec)
+		if err != nil {
+			t.Errorf("ParseCron(%q): unexpected error %v", tt.spec, err)
+			continue
+		}
+		if got := schedule.Next(base); !got.Equal(tt.want) {
+			t.Errorf("ParseCron(%q).Next = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *", "@every -1s"} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("ParseCron(%q): expected error", spec)
+		}
+	}
+}
+
+func TestParseCron_NeverFires(t *testing.T) {
+	schedule, err := ParseCron("0 0 30 feb *")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if next := schedule.Next(time.Now()); !next.IsZero() {
+		t.Errorf("Expected zero time for a spec that never fires, got %v", next)
+	}
+}
+
+func TestEvery_Next(t *testing.T) {
+	now := time.Now()
+	if got := Every(time.Minute).Next(now); !got.Equal(now.Add(time.Minute)) {
+		t.Errorf("Expected next run one minute later, got %v", got)
+	}
+	if got := Every(0).Next(now); !got.IsZero() {
+		t.Errorf("Expected zero time for a non-positive interval, got %v", got)
+	}
+}
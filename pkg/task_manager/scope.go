@@ -0,0 +1,123 @@
+package taskmanager
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scope is a namespace of a TaskManager: the IDs of its tasks are prefixed
+// with its name and a slash, e.g. "billing/invoices", and its tasks can be
+// listed, stopped and shut down together. Scoped tasks are tasks of the
+// manager, so its GracefulShutdown covers them too.
+type Scope struct {
+	tm     *TaskManager
+	prefix string
+}
+
+// Scope returns the scope with the given name.
+func (s *TaskManager) Scope(name string) *Scope {
+	return &Scope{tm: s, prefix: name + "/"}
+}
+
+// Scope returns a scope nested in this one, e.g. "billing/invoices".
+func (sc *Scope) Scope(name string) *Scope {
+	return &Scope{tm: sc.tm, prefix: sc.prefix + name + "/"}
+}
+
+// Name returns the full name of the scope.
+func (sc *Scope) Name() string {
+	return strings.TrimSuffix(sc.prefix, "/")
+}
+
+// ID returns the task ID of id in the manager.
+func (sc *Scope) ID(id string) string {
+	return sc.prefix + id
+}
+
+func (sc *Scope) StartTask(ctx context.Context, id string, fn func(ctx context.Context) error, opts ...TaskOption) (*TaskHandle, error) {
+	if id == "" {
+		return nil, ErrInvalidTaskID
+	}
+	return sc.tm.StartTask(ctx, sc.ID(id), fn, opts...)
+}
+
+func (sc *Scope) HasTask(id string) bool {
+	return sc.tm.HasTask(sc.ID(id))
+}
+
+func (sc *Scope) StopTask(id string) bool {
+	return sc.tm.StopTask(sc.ID(id))
+}
+
+func (sc *Scope) StopTaskAndWait(id string, timeout time.Duration) error {
+	return sc.tm.StopTaskAndWait(sc.ID(id), timeout)
+}
+
+func (sc *Scope) WaitTask(ctx context.Context, id string) (error, bool) {
+	return sc.tm.WaitTask(ctx, sc.ID(id))
+}
+
+func (sc *Scope) Status(id string) (TaskInfo, bool) {
+	return sc.tm.Status(sc.ID(id))
+}
+
+// ListTasks returns the running tasks of the scope and its nested scopes,
+// with their full IDs.
+func (sc *Scope) ListTasks() []TaskInfo {
+	return sc.tm.listTasks(sc.contains)
+}
+
+// StopAll stops every task of the scope and its nested scopes and returns how
+// many were stopped.
+func (sc *Scope) StopAll() int {
+	return len(sc.stop(ErrTaskStopped))
+}
+
+// Shutdown stops every task of the scope and its nested scopes and waits for
+// them to return. If ctx is done first, it returns a *ShutdownError listing
+// the tasks still running. Tasks started in the scope meanwhile are not
+// stopped.
+func (sc *Scope) Shutdown(ctx context.Context) error {
+	tasks := sc.stop(ErrShutdown)
+	for _, t := range tasks {
+		select {
+		case <-t.done:
+		case <-ctx.Done():
+			return sc.shutdownError(ctx, tasks)
+		}
+	}
+	return nil
+}
+
+func (sc *Scope) shutdownError(ctx context.Context, tasks []*task) error {
+	remaining := []string{}
+	for _, t := range tasks {
+		select {
+		case <-t.done:
+		default:
+			remaining = append(remaining, t.id)
+		}
+	}
+	slices.Sort(remaining)
+	return &ShutdownError{Remaining: remaining, Err: context.Cause(ctx)}
+}
+
+func (sc *Scope) contains(t *task) bool {
+	return strings.HasPrefix(t.id, sc.prefix)
+}
+
+func (sc *Scope) stop(cause error) []*task {
+	tasks := []*task{}
+	sc.tm.tasks.Range(func(key, value interface{}) bool {
+		t := value.(*task)
+		if sc.contains(t) && sc.tm.tasks.CompareAndDelete(key, t) {
+			sc.tm.auditTask(context.Background(), AuditStop, t)
+			t.cancel(cause)
+			tasks = append(tasks, t)
+		}
+		return true
+	})
+	return tasks
+}
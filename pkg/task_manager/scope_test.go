@@ -0,0 +1,71 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestScope_Namespacing(t *testing.T) {
+	tm := NewTaskManager()
+	billing := tm.Scope("billing")
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	_, _ = billing.StartTask(context.Background(), "sync", block)
+	_, _ = billing.Scope("invoices").StartTask(context.Background(), "send", block)
+	_, _ = tm.StartTask(context.Background(), "sync", block)
+	_, _ = tm.StartTask(context.Background(), "billing-report", block)
+	defer tm.GracefulShutdown(true, time.Second)
+
+	if !tm.HasTask("billing/sync") || !billing.HasTask("sync") {
+		t.Error("Expected the scoped task under its prefixed ID")
+	}
+	ids := []string{}
+	for _, info := range billing.ListTasks() {
+		ids = append(ids, info.ID)
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"billing/invoices/send", "billing/sync"}) {
+		t.Errorf("Expected the tasks of the scope and its nested scope, got %v", ids)
+	}
+
+	if n := billing.StopAll(); n != 2 {
+		t.Errorf("Expected 2 tasks stopped, got %d", n)
+	}
+	if !tm.HasTask("sync") || !tm.HasTask("billing-report") {
+		t.Error("Expected the tasks outside the scope to keep running")
+	}
+}
+
+func TestScope_Shutdown(t *testing.T) {
+	tm := NewTaskManager()
+	scope := tm.Scope("jobs")
+
+	release := make(chan struct{})
+	defer close(release)
+	_, _ = scope.StartTask(context.Background(), "quick", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	_, _ = scope.StartTask(context.Background(), "stubborn", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := scope.Shutdown(ctx)
+
+	var shutdownErr *ShutdownError
+	if !errors.As(err, &shutdownErr) || !slices.Equal(shutdownErr.Remaining, []string{"jobs/stubborn"}) {
+		t.Errorf("Expected jobs/stubborn to remain, got %v", err)
+	}
+	if info, _ := scope.Status("quick"); !errors.Is(info.Err, ErrShutdown) {
+		t.Errorf("Expected the scoped task to be canceled with ErrShutdown, got %v", info.Err)
+	}
+}
@@ -0,0 +1,45 @@
+package taskmanager
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// DefaultShutdownTimeout is how long RunUntilSignal waits for the tasks to
+// return unless WithShutdownTimeout is given.
+const DefaultShutdownTimeout = 30 * time.Second
+
+// WithShutdownTimeout sets how long RunUntilSignal waits for the tasks to
+// return once it shuts the manager down.
+func WithShutdownTimeout(d time.Duration) Option {
+	return func(s *TaskManager) {
+		s.shutdownTimeout = d
+	}
+}
+
+// RunUntilSignal blocks until one of sigs is received, SIGINT or SIGTERM
+// when none is given, or until ctx is done. It then shuts the manager down
+// like GracefulShutdownContext, waiting up to the shutdown timeout, see
+// WithShutdownTimeout.
+func (s *TaskManager) RunUntilSignal(ctx context.Context, sigs ...os.Signal) error {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sigs...)
+	defer signal.Stop(ch)
+
+	select {
+	case sig := <-ch:
+		s.logger.Printf("Received %v, shutting down", sig)
+	case <-ctx.Done():
+		s.logger.Printf("Context done, shutting down")
+	}
+
+	shutdownCtx, cancel := s.timeoutContext(context.Background(), s.shutdownTimeout)
+	defer cancel()
+	return s.GracefulShutdownContext(shutdownCtx)
+}
@@ -0,0 +1,67 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"testing"
+	"time"
+)
+
+func TestRunUntilSignal_Signal(t *testing.T) {
+	// keep the test process alive whatever happens to the signal
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, os.Interrupt)
+	defer signal.Stop(guard)
+
+	tm := NewTaskManager()
+	stopped := make(chan struct{})
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		<-ctx.Done()
+		close(stopped)
+		return nil
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- tm.RunUntilSignal(context.Background(), os.Interrupt) }()
+	time.Sleep(20 * time.Millisecond)
+
+	p, _ := os.FindProcess(os.Getpid())
+	if err := p.Signal(os.Interrupt); err != nil {
+		t.Skipf("Cannot send a signal to self: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected a clean shutdown, got %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected RunUntilSignal to return after the signal")
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Error("Expected the task to be stopped")
+	}
+}
+
+func TestRunUntilSignal_ContextTimeout(t *testing.T) {
+	tm := NewTaskManager(WithShutdownTimeout(20 * time.Millisecond))
+	release := make(chan struct{})
+	defer close(release)
+	_, _ = tm.StartTask(context.Background(), "stubborn", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := tm.RunUntilSignal(ctx)
+
+	var shutdownErr *ShutdownError
+	if !errors.As(err, &shutdownErr) || len(shutdownErr.Remaining) != 1 {
+		t.Errorf("Expected stubborn to be reported as remaining, got %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+// Package sqlstore implements a taskmanager.Store on top of database/sql.
+//
+// The table has the columns id (primary key), name, schedule and params, the
+// latter holding the params as a JSON object. CreateTable creates it with
+// portable column types.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	taskmanager "github.com/joripage/go_util/pkg/task_manager"
+)
+
+// DefaultTable is the table used unless WithTable is given.
+const DefaultTable = "task_definitions"
+
+// Store keeps the task definitions in a SQL table.
+type Store struct {
+	db          *sql.DB
+	table       string
+	placeholder func(n int) string
+}
+
+var _ taskmanager.Store = (*Store)(nil)
+
+type Option func(*Store)
+
+// WithTable sets the table name. It is used as is in the queries and must
+// not come from user input.
+func WithTable(table string) Option {
+	return func(s *Store) {
+		s.table = table
+	}
+}
+
+// WithDollarPlaceholders uses $1, $2... placeholders, as PostgreSQL expects,
+// instead of ?.
+func WithDollarPlaceholders() Option {
+	return func(s *Store) {
+		s.placeholder = func(n int) string { return "$" + strconv.Itoa(n) }
+	}
+}
+
+// New returns a Store using db. The caller keeps ownership of db.
+func New(db *sql.DB, opts ...Option) *Store {
+	s := &Store{
+		db:          db,
+		table:       DefaultTable,
+		placeholder: func(int) string { return "?" },
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// CreateTable creates the table if it does not exist.
+func (s *Store) CreateTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id VARCHAR(255) PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	schedule VARCHAR(255) NOT NULL,
+	params TEXT NOT NULL
+)`, s.table))
+	return err
+}
+
+func (s *Store) Save(ctx context.Context, def taskmanager.TaskDefinition) error {
+	params, err := json.Marshal(def.Params)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// delete then insert rather than an upsert, whose syntax differs
+	// between databases
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = %s", s.table, s.placeholder(1)), def.ID); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		fmt.Sprintf("INSERT INTO %s (id, name, schedule, params) VALUES (%s, %s, %s, %s)",
+			s.table, s.placeholder(1), s.placeholder(2), s.placeholder(3), s.placeholder(4)),
+		def.ID, def.Name, def.Schedule, string(params))
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) Delete(ctx context.Context, id string) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE id = %s", s.table, s.placeholder(1)), id)
+	return err
+}
+
+// List returns the definitions ordered by task ID.
+func (s *Store) List(ctx context.Context) ([]taskmanager.TaskDefinition, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT id, name, schedule, params FROM %s ORDER BY id", s.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	defs := []taskmanager.TaskDefinition{}
+	for rows.Next() {
+		var def taskmanager.TaskDefinition
+		var params string
+		if err := rows.Scan(&def.ID, &def.Name, &def.Schedule, &params); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(params), &def.Params); err != nil {
+			return nil, fmt.Errorf("task %s: decode params: %w", def.ID, err)
+		}
+		defs = append(defs, def)
+	}
+	return defs, rows.Err()
+}
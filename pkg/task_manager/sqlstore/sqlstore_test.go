@@ -0,0 +1,49 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	taskmanager "github.com/joripage/go_util/pkg/task_manager"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestStore_SaveListDelete(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "tasks.db"))
+	if err != nil {
+		t.Fatalf("Unexpected error opening db: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	s := New(db, WithTable("jobs"))
+	if err := s.CreateTable(ctx); err != nil {
+		t.Fatalf("Unexpected error creating table: %v", err)
+	}
+
+	_ = s.Save(ctx, taskmanager.TaskDefinition{ID: "b", Name: "b", Schedule: "@hourly"})
+	_ = s.Save(ctx, taskmanager.TaskDefinition{ID: "a", Name: "a", Params: map[string]string{"k": "v"}})
+	if err := s.Save(ctx, taskmanager.TaskDefinition{ID: "a", Name: "a", Params: map[string]string{"k": "w"}}); err != nil {
+		t.Fatalf("Unexpected error replacing a definition: %v", err)
+	}
+
+	defs, err := s.List(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error listing: %v", err)
+	}
+	if len(defs) != 2 || defs[0].ID != "a" || defs[1].ID != "b" {
+		t.Fatalf("Expected definitions a and b, got %+v", defs)
+	}
+	if defs[0].Params["k"] != "w" || defs[1].Schedule != "@hourly" {
+		t.Errorf("Expected saved fields to round-trip, got %+v", defs)
+	}
+
+	if err := s.Delete(ctx, "a"); err != nil {
+		t.Fatalf("Unexpected error deleting: %v", err)
+	}
+	if defs, _ := s.List(ctx); len(defs) != 1 || defs[0].ID != "b" {
+		t.Errorf("Expected only b to be left, got %+v", defs)
+	}
+}
@@ -0,0 +1,46 @@
+package taskmanager
+
+import (
+	"errors"
+	"runtime/debug"
+)
+
+// WithErrorStacks records the stack of the StartTask call of every task, and
+// reports it with the stack of the failure in TaskInfo.CreationStack and
+// FailureStack of the runs that fail, e.g. in History and the admin handler.
+// It costs a stack capture per started task.
+func WithErrorStacks() Option {
+	return func(s *TaskManager) {
+		s.errorStacks = true
+	}
+}
+
+// creationStack returns the stack of the caller starting a task if
+// WithErrorStacks is set.
+func (s *TaskManager) creationStack() []byte {
+	if !s.errorStacks {
+		return nil
+	}
+	return debug.Stack()
+}
+
+// stackError is implemented by errors carrying the stack they were created
+// at.
+type stackError interface {
+	Stack() []byte
+}
+
+// failureStack returns the stack err happened at, if known: the stack of a
+// panic, or the one of an error in the chain with a Stack() []byte method.
+// Go doesn't keep the stack of a plain returned error.
+func failureStack(err error) []byte {
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) {
+		return panicErr.Stack
+	}
+	var stackErr stackError
+	if errors.As(err, &stackErr) {
+		return stackErr.Stack()
+	}
+	return nil
+}
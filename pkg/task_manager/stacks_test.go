@@ -0,0 +1,46 @@
+package taskmanager
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func startFailingTask(tm *TaskManager, id string, fn func(ctx context.Context) error) *TaskHandle {
+	h, _ := tm.StartTask(context.Background(), id, fn)
+	return h
+}
+
+func TestWithErrorStacks(t *testing.T) {
+	tm := NewTaskManager(WithErrorStacks(), WithHistorySize(10))
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	failed := startFailingTask(tm, "failed", func(ctx context.Context) error { return errors.New("boom") })
+	_ = failed.Wait(ctx)
+	info := failed.Info()
+	if !bytes.Contains(info.CreationStack, []byte("startFailingTask")) {
+		t.Errorf("Expected the stack of the StartTask call, got %s", info.CreationStack)
+	}
+	if info.FailureStack != nil {
+		t.Errorf("Expected no failure stack for a returned error, got %s", info.FailureStack)
+	}
+
+	panicked := startFailingTask(tm, "panicked", func(ctx context.Context) error { panic("boom") })
+	_ = panicked.Wait(ctx)
+	if info := panicked.Info(); info.CreationStack == nil || !bytes.Contains(info.FailureStack, []byte("panic")) {
+		t.Errorf("Expected the creation and panic stacks, got %s and %s", info.CreationStack, info.FailureStack)
+	}
+
+	completed := startFailingTask(tm, "completed", func(ctx context.Context) error { return nil })
+	_ = completed.Wait(ctx)
+	if info := completed.Info(); info.CreationStack != nil {
+		t.Errorf("Expected no stacks for a completed run, got %s", info.CreationStack)
+	}
+
+	if history := tm.History(); len(history) != 3 || history[0].CreationStack == nil {
+		t.Errorf("Expected the stacks kept in the history, got %+v", history)
+	}
+}
@@ -0,0 +1,73 @@
+package taskmanager
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats are aggregate counters since the manager was created.
+type Stats struct {
+	// Running is the number of tasks currently running.
+	Running int64
+	// Started counts the task runs that started, finished or not.
+	Started   int64
+	Completed int64
+	Failed    int64
+	Canceled  int64
+	TimedOut  int64
+	// QuotaRejected counts the tasks rejected by WithTenantQuota.
+	QuotaRejected int64
+	// AverageDuration is the mean running time of the finished runs that
+	// started.
+	AverageDuration time.Duration
+}
+
+type counters struct {
+	running, started                      atomic.Int64
+	completed, failed, canceled, timedOut atomic.Int64
+	ran                                   atomic.Int64 // finished runs that started
+	totalDuration                         atomic.Int64
+}
+
+func (c *counters) start() {
+	c.started.Add(1)
+	c.running.Add(1)
+}
+
+func (c *counters) finish(status TaskStatus, started bool, duration time.Duration) {
+	if started {
+		c.running.Add(-1)
+		c.ran.Add(1)
+		c.totalDuration.Add(int64(duration))
+	}
+	switch status {
+	case StatusCompleted:
+		c.completed.Add(1)
+	case StatusFailed:
+		c.failed.Add(1)
+	case StatusCanceled:
+		c.canceled.Add(1)
+	case StatusTimedOut:
+		c.timedOut.Add(1)
+	}
+}
+
+// Stats returns the aggregate counters of the manager, e.g. to publish them
+// to a monitoring system.
+func (s *TaskManager) Stats() Stats {
+	stats := Stats{
+		Running:   s.counters.running.Load(),
+		Started:   s.counters.started.Load(),
+		Completed: s.counters.completed.Load(),
+		Failed:    s.counters.failed.Load(),
+		Canceled:  s.counters.canceled.Load(),
+		TimedOut:  s.counters.timedOut.Load(),
+	}
+	if s.quotas != nil {
+		stats.QuotaRejected = s.quotas.rejections()
+	}
+	if ran := s.counters.ran.Load(); ran > 0 {
+		stats.AverageDuration = time.Duration(s.counters.totalDuration.Load() / ran)
+	}
+	return stats
+}
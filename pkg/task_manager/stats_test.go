@@ -0,0 +1,46 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStats_Counters(t *testing.T) {
+	tm := NewTaskManager()
+
+	_, _ = tm.StartTask(context.Background(), "completed", func(ctx context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+	_, _ = tm.StartTask(context.Background(), "failed", func(ctx context.Context) error {
+		return errors.New("boom")
+	})
+	_, _ = tm.StartTask(context.Background(), "timed_out", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTimeout(10*time.Millisecond))
+	_ = tm.StartTaskAt(context.Background(), "never_started", func(ctx context.Context) error {
+		return nil
+	}, time.Now().Add(time.Hour))
+	_, _ = tm.StartTask(context.Background(), "running", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	time.Sleep(50 * time.Millisecond)
+	stats := tm.Stats()
+	if stats.Running != 1 || stats.Started != 4 || stats.Completed != 1 || stats.Failed != 1 || stats.TimedOut != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.AverageDuration <= 0 {
+		t.Errorf("Expected an average duration, got %v", stats.AverageDuration)
+	}
+
+	tm.GracefulShutdown(true, 500*time.Millisecond)
+	stats = tm.Stats()
+	if stats.Running != 0 || stats.Canceled != 2 {
+		t.Errorf("Expected the running and scheduled tasks to be canceled, got %+v", stats)
+	}
+}
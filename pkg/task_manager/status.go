@@ -0,0 +1,157 @@
+package taskmanager
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+type TaskStatus int
+
+const (
+	// StatusPending tasks are registered but waiting for their scheduled
+	// time or for a free slot.
+	StatusPending TaskStatus = iota
+	StatusRunning
+	StatusCompleted
+	StatusFailed
+	StatusCanceled
+	StatusTimedOut
+	// StatusAbandoned tasks did not return in time after being stopped, see
+	// WithStopTimeout. Their function may still be running.
+	StatusAbandoned
+)
+
+var statusNames = [...]string{
+	StatusPending:   "pending",
+	StatusRunning:   "running",
+	StatusCompleted: "completed",
+	StatusFailed:    "failed",
+	StatusCanceled:  "canceled",
+	StatusTimedOut:  "timed_out",
+	StatusAbandoned: "abandoned",
+}
+
+func (st TaskStatus) String() string {
+	if st < 0 || int(st) >= len(statusNames) {
+		return "unknown"
+	}
+	return statusNames[st]
+}
+
+// Done reports whether st is a final status.
+func (st TaskStatus) Done() bool {
+	return st >= StatusCompleted
+}
+
+// statusOf maps the result of a task to its final status.
+func statusOf(err error) TaskStatus {
+	switch {
+	case err == nil:
+		return StatusCompleted
+	case errors.Is(err, ErrLockLost), errors.Is(err, ErrTaskStuck), errors.Is(err, ErrLeaseExpired):
+		return StatusFailed
+	// before canceled, contexts derived from the task context see a timeout
+	// as a cancellation
+	case errors.Is(err, ErrTaskTimedOut):
+		return StatusTimedOut
+	case errors.Is(err, context.Canceled):
+		return StatusCanceled
+	default:
+		return StatusFailed
+	}
+}
+
+// Status returns the state of the task with the given id: the running task
+// if there is one, otherwise the last finished run. The bool reports whether
+// the id is known at all.
+func (s *TaskManager) Status(id string) (TaskInfo, bool) {
+	if v, ok := s.tasks.Load(id); ok {
+		t := v.(*task)
+		s.refreshUsage()
+		info := t.info(s.clock.Now())
+		info.Health = t.checkHealth(context.Background())
+		return info, true
+	}
+	if info, ok := s.finished.get(id); ok {
+		return info, true
+	}
+	return TaskInfo{}, false
+}
+
+// TaskInfo is like Status but returns ErrTaskNotFound for an unknown task.
+func (s *TaskManager) TaskInfo(id string) (TaskInfo, error) {
+	info, ok := s.Status(id)
+	if !ok {
+		return TaskInfo{}, ErrTaskNotFound
+	}
+	return info, nil
+}
+
+// DefaultFinishedSize is the number of task IDs whose last finished run is
+// kept by default, see WithFinishedSize.
+const DefaultFinishedSize = 10000
+
+// WithFinishedSize keeps the last finished run of the n task IDs that
+// finished last, for Status, WaitAll and WaitAny once they are not running
+// anymore. The older ones are forgotten, so that a service starting tasks
+// under unique IDs doesn't grow forever.
+func WithFinishedSize(n int) Option {
+	return func(s *TaskManager) {
+		if n > 0 {
+			s.finished = newFinishedRuns(n)
+		}
+	}
+}
+
+// finishedRuns keeps the last finished run of the task IDs, evicting the IDs
+// that finished least recently beyond its size.
+type finishedRuns struct {
+	mu    sync.Mutex
+	size  int
+	runs  map[string]*list.Element // of order
+	order *list.List               // of TaskInfo, the most recent first
+}
+
+func newFinishedRuns(size int) *finishedRuns {
+	return &finishedRuns{size: size, runs: map[string]*list.Element{}, order: list.New()}
+}
+
+// add keeps info as the last finished run of its id, unless a more recent
+// run has already finished.
+func (f *finishedRuns) add(info TaskInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if e, ok := f.runs[info.ID]; ok {
+		if e.Value.(TaskInfo).RunID > info.RunID {
+			return
+		}
+		e.Value = info
+		f.order.MoveToFront(e)
+		return
+	}
+	f.runs[info.ID] = f.order.PushFront(info)
+	if f.order.Len() > f.size {
+		oldest := f.order.Back()
+		f.order.Remove(oldest)
+		delete(f.runs, oldest.Value.(TaskInfo).ID)
+	}
+}
+
+func (f *finishedRuns) get(id string) (TaskInfo, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	e, ok := f.runs[id]
+	if !ok {
+		return TaskInfo{}, false
+	}
+	return e.Value.(TaskInfo), true
+}
+
+// recordFinished keeps a snapshot of t as the last finished run of its id.
+func (s *TaskManager) recordFinished(t *task) {
+	s.finished.add(t.info(s.clock.Now()))
+}
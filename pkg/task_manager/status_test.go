@@ -0,0 +1,154 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStatus_Lifecycle(t *testing.T) {
+	tm := NewTaskManager()
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	_ = tm.StartTaskAt(ctx, "task", func(ctx context.Context) error {
+		<-release
+		return nil
+	}, time.Now().Add(20*time.Millisecond))
+
+	if info, ok := tm.Status("task"); !ok || info.Status != StatusPending {
+		t.Fatalf("Expected pending task, got %v (found: %v)", info.Status, ok)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if info, _ := tm.Status("task"); info.Status != StatusRunning || info.StartedAt.IsZero() {
+		t.Fatalf("Expected running task with start time, got %+v", info)
+	}
+
+	close(release)
+	_, _ = tm.WaitTask(ctx, "task")
+	time.Sleep(10 * time.Millisecond)
+
+	info, ok := tm.Status("task")
+	if !ok || info.Status != StatusCompleted || info.Err != nil {
+		t.Fatalf("Expected completed task, got %+v (found: %v)", info, ok)
+	}
+	if info.FinishedAt.Before(info.StartedAt) || info.Running <= 0 {
+		t.Errorf("Expected finish timestamps, got %+v", info)
+	}
+	if tm.HasTask("task") {
+		t.Error("Finished task should not be reported by HasTask")
+	}
+}
+
+func TestStatus_FinalStates(t *testing.T) {
+	tm := NewTaskManager()
+	ctx := context.Background()
+	boom := errors.New("boom")
+
+	_, _ = tm.StartTask(ctx, "failed", func(ctx context.Context) error { return boom })
+	_, _ = tm.StartTask(ctx, "panicked", func(ctx context.Context) error { panic("boom") })
+	_, _ = tm.StartTask(ctx, "timed_out", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTimeout(10*time.Millisecond))
+	_, _ = tm.StartTask(ctx, "canceled", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	tm.StopTask("canceled")
+	_, _ = tm.WaitTask(ctx, "timed_out")
+	tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	tests := map[string]TaskStatus{
+		"failed":    StatusFailed,
+		"panicked":  StatusFailed,
+		"timed_out": StatusTimedOut,
+		"canceled":  StatusCanceled,
+	}
+	for id, want := range tests {
+		info, ok := tm.Status(id)
+		if !ok || info.Status != want {
+			t.Errorf("%s: expected status %v, got %v (found: %v)", id, want, info.Status, ok)
+		}
+	}
+	if info, _ := tm.Status("failed"); !errors.Is(info.Err, boom) {
+		t.Errorf("Expected final error to be kept, got %v", info.Err)
+	}
+}
+
+func TestStatus_UnknownTask(t *testing.T) {
+	tm := NewTaskManager()
+
+	if _, ok := tm.Status("does_not_exist"); ok {
+		t.Error("Expected unknown task not to be found")
+	}
+}
+
+func TestWithFinishedSize(t *testing.T) {
+	tm := NewTaskManager(WithFinishedSize(2))
+	ctx := context.Background()
+
+	for _, id := range []string{"first", "second", "third"} {
+		h, _ := tm.StartTask(ctx, id, func(ctx context.Context) error { return nil })
+		_ = h.Wait(ctx)
+	}
+	if _, ok := tm.Status("first"); ok {
+		t.Error("Expected the run that finished first to be forgotten")
+	}
+	if err := tm.WaitAll(ctx, "first"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Expected ErrTaskNotFound for a forgotten run, got %v", err)
+	}
+	for _, id := range []string{"second", "third"} {
+		if info, ok := tm.Status(id); !ok || info.Status != StatusCompleted {
+			t.Errorf("Expected the last run of %s kept, got %+v (found: %v)", id, info, ok)
+		}
+	}
+	if err := tm.WaitAll(ctx, "second", "third"); err != nil {
+		t.Errorf("Expected WaitAll to return at once for finished runs, got %v", err)
+	}
+}
+
+func TestTaskStatus_String(t *testing.T) {
+	if got := StatusTimedOut.String(); got != "timed_out" {
+		t.Errorf("Expected timed_out, got %s", got)
+	}
+	if got := TaskStatus(42).String(); got != "unknown" {
+		t.Errorf("Expected unknown, got %s", got)
+	}
+}
+
+func TestTaskInfo_Metadata(t *testing.T) {
+	tm := NewTaskManager()
+
+	release := make(chan struct{})
+	metadata := map[string]string{"owner": "billing"}
+	_ = tm.StartTaskWithOptions(context.Background(), "task", func(ctx context.Context) error {
+		<-release
+		return nil
+	}, WithMetadata(metadata), WithMetadata(map[string]string{"request_id": "42"}))
+	metadata["owner"] = "changed"
+
+	info, err := tm.TaskInfo("task")
+	if err != nil {
+		t.Fatalf("Unexpected error getting task info: %v", err)
+	}
+	if info.Metadata["owner"] != "billing" || info.Metadata["request_id"] != "42" {
+		t.Errorf("Expected the merged metadata, got %v", info.Metadata)
+	}
+	info.Metadata["owner"] = "changed"
+	if info, _ := tm.TaskInfo("task"); info.Metadata["owner"] != "billing" {
+		t.Errorf("Expected TaskInfo to return a copy of the metadata, got %v", info.Metadata)
+	}
+
+	close(release)
+	_, _ = tm.WaitTask(context.Background(), "task")
+	if info, _ := tm.TaskInfo("task"); info.Metadata["owner"] != "billing" {
+		t.Errorf("Expected the metadata of the finished run, got %v", info.Metadata)
+	}
+
+	if _, err := tm.TaskInfo("missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Expected ErrTaskNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// TaskDefinition describes a registered task to restart after a process
+// restart, see WithStore and Recover.
+type TaskDefinition struct {
+	ID string
+	// Name is the name the task function was registered under.
+	Name string
+	// Schedule is the cron spec of a recurring task, empty for a one-shot
+	// task.
+	Schedule string
+	Params   map[string]string
+}
+
+// Store persists the definitions of the registered tasks that are running.
+type Store interface {
+	// Save creates or replaces the definition with the same ID.
+	Save(ctx context.Context, def TaskDefinition) error
+	// Delete removes a definition, deleting an unknown ID is not an error.
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context) ([]TaskDefinition, error)
+}
+
+// MemoryStore is a Store keeping the definitions in memory, mostly useful in
+// tests.
+type MemoryStore struct {
+	mu   sync.Mutex
+	defs map[string]TaskDefinition
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{defs: map[string]TaskDefinition{}}
+}
+
+func (m *MemoryStore) Save(ctx context.Context, def TaskDefinition) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	def.Params = maps.Clone(def.Params)
+	m.defs[def.ID] = def
+	return nil
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.defs, id)
+	return nil
+}
+
+func (m *MemoryStore) List(ctx context.Context) ([]TaskDefinition, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defs := make([]TaskDefinition, 0, len(m.defs))
+	for _, def := range m.defs {
+		def.Params = maps.Clone(def.Params)
+		defs = append(defs, def)
+	}
+	slices.SortFunc(defs, func(a, b TaskDefinition) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return defs, nil
+}
+
+// WithStore persists the definition of every task started with
+// StartRegistered or StartRegisteredRecurring in store. A definition is
+// deleted once its task finishes or is stopped, but kept when the task is
+// canceled by GracefulShutdown, so that Recover restarts it.
+func WithStore(store Store) Option {
+	return func(s *TaskManager) {
+		s.store = store
+	}
+}
+
+// StartRegisteredRecurring is like StartRegistered but runs the registered
+// function on the cron schedule spec, see ParseCron.
+func (s *TaskManager) StartRegisteredRecurring(ctx context.Context, name string, params map[string]string, spec string, opts ...TaskOption) error {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		return err
+	}
+	fn, err := s.registered(name)
+	if err != nil {
+		return err
+	}
+
+	opts = append(opts, persistAs(TaskDefinition{ID: name, Name: name, Schedule: spec, Params: params}))
+	return s.StartRecurringTask(ctx, name, func(ctx context.Context) error {
+		return fn(ctx, params)
+	}, schedule, opts...)
+}
+
+// Recover restarts the tasks whose definitions are in the store, typically
+// right after the process started and registered its task functions. It
+// keeps going on errors and returns them joined.
+func (s *TaskManager) Recover(ctx context.Context) error {
+	if s.store == nil {
+		return ErrNoStore
+	}
+	defs, err := s.store.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, def := range defs {
+		if def.Schedule != "" {
+			err = s.StartRegisteredRecurring(ctx, def.Name, def.Params, def.Schedule)
+		} else {
+			err = s.StartRegistered(ctx, def.Name, def.Params)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("recover task %s: %w", def.ID, err))
+			continue
+		}
+		s.logger.Printf("Task %s recovered", def.ID)
+	}
+	return errors.Join(errs...)
+}
+
+// persistAs saves def once the task starts, so that a start that fails or
+// collapses into another run leaves nothing in the store.
+func persistAs(def TaskDefinition) TaskOption {
+	return func(cfg *taskConfig) {
+		cfg.definition = &def
+	}
+}
+
+func (s *TaskManager) persist(ctx context.Context, def TaskDefinition) error {
+	if s.store == nil {
+		return nil
+	}
+	return s.store.Save(ctx, def)
+}
+
+// unpersist deletes the definition of a finished task, unless the task was
+// canceled by a shutdown or has been replaced by a newer run.
+func (s *TaskManager) unpersist(t *task) {
+	if s.shuttingDown.Load() {
+		return
+	}
+	if v, ok := s.tasks.Load(t.id); ok && v.(*task) != t {
+		return
+	}
+	if err := s.store.Delete(context.Background(), t.id); err != nil {
+		s.logger.Printf("Task %s: failed to delete definition: %v", t.id, err)
+	}
+}
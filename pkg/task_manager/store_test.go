@@ -0,0 +1,191 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStore_DefinitionLifecycle(t *testing.T) {
+	store := NewMemoryStore()
+	tm := NewTaskManager(WithStore(store))
+
+	release := make(chan struct{})
+	_ = tm.Register("sync", func(ctx context.Context, params map[string]string) error {
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+
+	if err := tm.StartRegistered(context.Background(), "sync", map[string]string{"shard": "1"}); err != nil {
+		t.Fatalf("Unexpected error starting registered task: %v", err)
+	}
+	defs, _ := store.List(context.Background())
+	if len(defs) != 1 || defs[0].ID != "sync" || defs[0].Params["shard"] != "1" || defs[0].Schedule != "" {
+		t.Fatalf("Expected the definition to be saved, got %+v", defs)
+	}
+
+	close(release)
+	tm.WaitTask(context.Background(), "sync")
+	if defs, _ := store.List(context.Background()); len(defs) != 0 {
+		t.Errorf("Expected the definition to be deleted once the task finished, got %+v", defs)
+	}
+}
+
+func TestStore_RecoverAfterShutdown(t *testing.T) {
+	store := NewMemoryStore()
+	fn := func(ctx context.Context, params map[string]string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tm := NewTaskManager(WithStore(store))
+	_ = tm.Register("once", fn)
+	_ = tm.Register("cron", fn)
+	_ = tm.StartRegistered(context.Background(), "once", map[string]string{"k": "v"})
+	if err := tm.StartRegisteredRecurring(context.Background(), "cron", nil, "@hourly"); err != nil {
+		t.Fatalf("Unexpected error starting recurring task: %v", err)
+	}
+	tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	if defs, _ := store.List(context.Background()); len(defs) != 2 {
+		t.Fatalf("Expected the definitions to survive a shutdown, got %+v", defs)
+	}
+
+	restarted := NewTaskManager(WithStore(store))
+	got := make(chan map[string]string, 1)
+	_ = restarted.Register("once", func(ctx context.Context, params map[string]string) error {
+		got <- params
+		return nil
+	})
+	_ = restarted.Register("cron", fn)
+	if err := restarted.Recover(context.Background()); err != nil {
+		t.Fatalf("Unexpected error recovering: %v", err)
+	}
+	defer restarted.GracefulShutdown(true, 500*time.Millisecond)
+
+	select {
+	case params := <-got:
+		if params["k"] != "v" {
+			t.Errorf("Expected recovered task to get its params, got %v", params)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected the one-shot task to be restarted")
+	}
+	if !restarted.HasTask("cron") {
+		t.Error("Expected the recurring task to be restarted")
+	}
+}
+
+func TestStore_RecoverErrors(t *testing.T) {
+	tm := NewTaskManager()
+	if err := tm.Recover(context.Background()); !errors.Is(err, ErrNoStore) {
+		t.Errorf("Expected ErrNoStore, got %v", err)
+	}
+
+	store := NewMemoryStore()
+	_ = store.Save(context.Background(), TaskDefinition{ID: "gone", Name: "gone"})
+	tm = NewTaskManager(WithStore(store))
+	if err := tm.Recover(context.Background()); !errors.Is(err, ErrTaskNotRegistered) {
+		t.Errorf("Expected ErrTaskNotRegistered for an unknown name, got %v", err)
+	}
+}
+
+func TestStore_StopDeletesDefinition(t *testing.T) {
+	store := NewMemoryStore()
+	tm := NewTaskManager(WithStore(store))
+	_ = tm.Register("sync", func(ctx context.Context, params map[string]string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	_ = tm.StartRegistered(context.Background(), "sync", nil)
+
+	if err := tm.StopTaskAndWait("sync", 500*time.Millisecond); err != nil {
+		t.Fatalf("Unexpected error stopping task: %v", err)
+	}
+	if defs, _ := store.List(context.Background()); len(defs) != 0 {
+		t.Errorf("Expected a stopped task to be forgotten, got %+v", defs)
+	}
+}
+
+// failingStore fails to save the definitions.
+type failingStore struct {
+	*MemoryStore
+	err error
+}
+
+func (f failingStore) Save(ctx context.Context, def TaskDefinition) error {
+	return f.err
+}
+
+func TestStore_FailedStartNotPersisted(t *testing.T) {
+	store := NewMemoryStore()
+	tm := NewTaskManager(WithStore(store), WithTenantQuota(TenantQuota{
+		Tenant:  TenantFromTag("tenant:"),
+		Default: 1,
+	}))
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+	block := func(ctx context.Context, params map[string]string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	_ = tm.Register("first", block)
+	_ = tm.Register("second", block)
+	ctx := context.Background()
+
+	if err := tm.StartRegistered(ctx, "first", nil, WithTags("tenant:acme")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := tm.StartRegistered(ctx, "second", nil, WithTags("tenant:acme")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected the quota to reject the start, got %v", err)
+	}
+	if err := tm.StartRegisteredRecurring(ctx, "second", nil, "@hourly", WithTags("tenant:acme")); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected the quota to reject the recurring start, got %v", err)
+	}
+	tm.Quiesce()
+	if err := tm.StartRegistered(ctx, "second", nil); !errors.Is(err, ErrShuttingDown) {
+		t.Fatalf("Expected ErrShuttingDown, got %v", err)
+	}
+	if defs, _ := store.List(ctx); len(defs) != 1 || defs[0].ID != "first" {
+		t.Errorf("Expected only the definition of the started task, got %+v", defs)
+	}
+
+	boom := errors.New("boom")
+	failing := NewTaskManager(WithStore(failingStore{NewMemoryStore(), boom}))
+	_ = failing.Register("sync", block)
+	if err := failing.StartRegistered(ctx, "sync", nil); !errors.Is(err, boom) {
+		t.Errorf("Expected the error of the store, got %v", err)
+	}
+	if failing.HasTask("sync") {
+		t.Error("Expected the task not to start when its definition can't be saved")
+	}
+	if err := failing.StartRegisteredRecurring(ctx, "sync", nil, "@hourly"); !errors.Is(err, boom) {
+		t.Errorf("Expected the error of the store for a recurring task, got %v", err)
+	}
+	if failing.HasTask("sync") {
+		t.Error("Expected the recurring task not to start when its definition can't be saved")
+	}
+}
+
+func TestStore_CachedStartNotPersisted(t *testing.T) {
+	store := NewMemoryStore()
+	tm := NewTaskManager(WithStore(store))
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+	_ = tm.Register("charge", func(ctx context.Context, params map[string]string) error { return nil })
+	ctx := context.Background()
+
+	if err := tm.StartRegistered(ctx, "charge", nil, WithIdempotencyKey("req-1", time.Hour)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tm.WaitTask(ctx, "charge")
+	if err := tm.StartRegistered(ctx, "charge", nil, WithIdempotencyKey("req-1", time.Hour)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if defs, _ := store.List(ctx); len(defs) != 0 {
+		t.Errorf("Expected a start reusing a cached run not to be saved, got %+v", defs)
+	}
+}
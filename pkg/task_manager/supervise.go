@@ -0,0 +1,129 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// RestartMode tells when a supervised task function is restarted.
+type RestartMode int
+
+const (
+	// RestartAlways restarts the function whenever it returns.
+	RestartAlways RestartMode = iota
+	// RestartOnFailure restarts the function when it returns an error.
+	RestartOnFailure
+	// RestartNever runs the function once, like StartTask.
+	RestartNever
+)
+
+// RestartPolicy configures Supervise.
+type RestartPolicy struct {
+	Mode RestartMode
+	// MaxRestarts ends the task after that many restarts, 0 means no limit.
+	MaxRestarts int
+	// Backoff is the delay before the first restart. It doubles after every
+	// restart, with jitter, and is reset once the function ran for at least
+	// ResetAfter.
+	Backoff    time.Duration
+	ResetAfter time.Duration
+	// Panics, if set, handles the runs that panic instead of the fields
+	// above.
+	Panics *PanicPolicy
+}
+
+// PanicPolicy restarts a supervised function that panicked right away,
+// without backoff, see RestartPolicy.Panics.
+type PanicPolicy struct {
+	// MaxRestarts ends the task after that many restarts following a panic,
+	// 0 means no limit. They don't count towards RestartPolicy.MaxRestarts.
+	MaxRestarts int
+	// Handler is called with every panic before the restart, e.g. to report
+	// it, in place of the panic handler of the manager. It is required.
+	Handler func(id string, v any, stack []byte)
+}
+
+func (p RestartPolicy) restart(err error) bool {
+	switch p.Mode {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return err != nil
+	default:
+		return false
+	}
+}
+
+// Supervise runs fn as the task id and restarts it according to policy when
+// it returns, until the task is stopped. The task ends with the result of
+// the last run once the policy gives up, or a run fails with a Permanent
+// error. Runs that panic count as failures, unless policy.Panics is set.
+func (s *TaskManager) Supervise(ctx context.Context, id string, fn func(ctx context.Context) error, policy RestartPolicy, opts ...TaskOption) error {
+	if fn == nil {
+		return ErrNilTaskFunc
+	}
+	if policy.Panics != nil && policy.Panics.Handler == nil {
+		return ErrNilPanicHandler
+	}
+	opts = append(opts, wrapped())
+	return s.StartTaskWithOptions(ctx, id, s.supervise(id, s.body(id, fn), policy), opts...)
+}
+
+func (s *TaskManager) supervise(id string, fn func(ctx context.Context) error, policy RestartPolicy) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		restarts, backoffs, panics := 0, 0, 0
+		for {
+			started := s.clock.Now()
+			var err error
+			if policy.Panics != nil {
+				// the policy reports the panics instead of the panic handler
+				err = callReporting(ctx, fn, func(v any, stack []byte) {
+					policy.Panics.Handler(id, v, stack)
+				})
+			} else {
+				err = s.call(ctx, id, fn)
+			}
+			if ctx.Err() != nil {
+				return err
+			}
+			var panicErr *PanicError
+			if policy.Panics != nil && errors.As(err, &panicErr) {
+				if policy.Panics.MaxRestarts > 0 && panics >= policy.Panics.MaxRestarts {
+					s.logger.Printf("Task %s reached %d restarts after a panic, giving up", id, panics)
+					return err
+				}
+				panics++
+				s.logger.Printf("Task %s panicked, restarting now (panic restart %d)", id, panics)
+				continue
+			}
+			if !policy.restart(err) {
+				return err
+			}
+			if IsPermanent(err) {
+				s.logger.Printf("Task %s failed permanently, not restarting: %v", id, err)
+				return err
+			}
+			if policy.MaxRestarts > 0 && restarts >= policy.MaxRestarts {
+				s.logger.Printf("Task %s reached %d restarts, giving up", id, restarts)
+				return err
+			}
+
+			if policy.ResetAfter > 0 && s.clock.Now().Sub(started) >= policy.ResetAfter {
+				backoffs = 0
+			}
+			restarts++
+			backoffs++
+			delay := retryDelay(policy.Backoff, backoffs)
+			s.logger.Printf("Task %s exited (%v), restarting in %v (restart %d)", id, err, delay, restarts)
+
+			timer := s.clock.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C():
+			}
+		}
+	}
+}
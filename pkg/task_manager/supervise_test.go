@@ -0,0 +1,205 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSupervise_RestartOnFailure(t *testing.T) {
+	tm := NewTaskManager()
+	boom := errors.New("boom")
+
+	var runs atomic.Int64
+	done := make(chan error, 1)
+	err := tm.Supervise(context.Background(), "consumer", func(ctx context.Context) error {
+		if runs.Add(1) < 3 {
+			return boom
+		}
+		return nil
+	}, RestartPolicy{Mode: RestartOnFailure, Backoff: time.Millisecond}, WithOnComplete(func(err error) { done <- err }))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected the task to end after a successful run, got %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected the supervised task to end")
+	}
+	if n := runs.Load(); n != 3 {
+		t.Errorf("Expected 3 runs, got %d", n)
+	}
+}
+
+func TestSupervise_MaxRestarts(t *testing.T) {
+	tm := NewTaskManager()
+	boom := errors.New("boom")
+
+	var runs atomic.Int64
+	done := make(chan error, 1)
+	_ = tm.Supervise(context.Background(), "consumer", func(ctx context.Context) error {
+		runs.Add(1)
+		panic(boom)
+	}, RestartPolicy{Mode: RestartAlways, MaxRestarts: 2}, WithOnComplete(func(err error) { done <- err }))
+
+	var panicErr *PanicError
+	if err := <-done; !errors.As(err, &panicErr) {
+		t.Errorf("Expected the last run's panic, got %v", err)
+	}
+	if n := runs.Load(); n != 3 {
+		t.Errorf("Expected 1 run and 2 restarts, got %d runs", n)
+	}
+}
+
+func TestSupervise_AlwaysUntilStopped(t *testing.T) {
+	tm := NewTaskManager()
+
+	var runs atomic.Int64
+	_ = tm.Supervise(context.Background(), "consumer", func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	}, RestartPolicy{Mode: RestartAlways, Backoff: 5 * time.Millisecond})
+
+	time.Sleep(50 * time.Millisecond)
+	if err := tm.StopTaskAndWait("consumer", 500*time.Millisecond); err != nil {
+		t.Fatalf("Unexpected error stopping: %v", err)
+	}
+	if n := runs.Load(); n < 2 {
+		t.Errorf("Expected clean exits to be restarted too, got %d runs", n)
+	}
+	stopped := runs.Load()
+	time.Sleep(20 * time.Millisecond)
+	if runs.Load() != stopped {
+		t.Error("Expected no restart once stopped")
+	}
+}
+
+func TestSupervise_Never(t *testing.T) {
+	tm := NewTaskManager()
+
+	var runs atomic.Int64
+	_ = tm.Supervise(context.Background(), "consumer", func(ctx context.Context) error {
+		runs.Add(1)
+		return errors.New("boom")
+	}, RestartPolicy{Mode: RestartNever})
+	tm.WaitTask(context.Background(), "consumer")
+	time.Sleep(10 * time.Millisecond)
+
+	if n := runs.Load(); n != 1 {
+		t.Errorf("Expected a single run, got %d", n)
+	}
+	if err := tm.Supervise(context.Background(), "consumer", nil, RestartPolicy{}); !errors.Is(err, ErrNilTaskFunc) {
+		t.Errorf("Expected ErrNilTaskFunc, got %v", err)
+	}
+}
+
+func TestSupervise_Permanent(t *testing.T) {
+	tm := NewTaskManager()
+
+	var runs atomic.Int32
+	done := make(chan error, 1)
+	_ = tm.Supervise(context.Background(), "task", func(ctx context.Context) error {
+		runs.Add(1)
+		return Permanent(errors.New("misconfigured"))
+	}, RestartPolicy{Mode: RestartAlways, Backoff: time.Millisecond}, WithOnComplete(func(err error) { done <- err }))
+
+	select {
+	case err := <-done:
+		if !IsPermanent(err) {
+			t.Errorf("Expected the permanent error, got %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected the task to end on a permanent error")
+	}
+	if n := runs.Load(); n != 1 {
+		t.Errorf("Expected no restart, got %d runs", n)
+	}
+}
+
+func TestSupervise_PanicPolicy(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	var reported atomic.Int64
+	tm := NewTaskManager(WithClock(clock), WithPanicHandler(func(string, any, []byte) {
+		reported.Add(1)
+	}))
+
+	var runs, handled atomic.Int64
+	errored := make(chan struct{})
+	done := make(chan error, 1)
+	err := tm.Supervise(context.Background(), "consumer", func(ctx context.Context) error {
+		switch runs.Add(1) {
+		case 1, 2:
+			panic("boom")
+		case 3:
+			close(errored)
+			return errors.New("boom")
+		}
+		return nil
+	}, RestartPolicy{
+		Mode:    RestartOnFailure,
+		Backoff: time.Hour,
+		Panics: &PanicPolicy{Handler: func(id string, v any, stack []byte) {
+			handled.Add(1)
+		}},
+	}, WithOnComplete(func(err error) { done <- err }))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	select {
+	case <-errored:
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected the panics to be restarted without backoff")
+	}
+	if n := handled.Load(); n != 2 {
+		t.Errorf("Expected the panic handler called twice, got %d", n)
+	}
+	if n := reported.Load(); n != 0 {
+		t.Errorf("Expected the panics reported by the policy only, got %d reports to the manager", n)
+	}
+
+	clock.BlockUntil(1)
+	clock.Advance(2 * time.Hour)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected the task to end after a successful run, got %v", err)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Expected the error to be restarted after the backoff")
+	}
+}
+
+func TestSupervise_PanicPolicyMaxRestarts(t *testing.T) {
+	tm := NewTaskManager()
+
+	var runs atomic.Int64
+	done := make(chan error, 1)
+	_ = tm.Supervise(context.Background(), "consumer", func(ctx context.Context) error {
+		runs.Add(1)
+		panic("boom")
+	}, RestartPolicy{
+		Mode:   RestartAlways,
+		Panics: &PanicPolicy{MaxRestarts: 2, Handler: func(string, any, []byte) {}},
+	}, WithOnComplete(func(err error) { done <- err }))
+
+	var panicErr *PanicError
+	if err := <-done; !errors.As(err, &panicErr) {
+		t.Errorf("Expected the last run's panic, got %v", err)
+	}
+	if n := runs.Load(); n != 3 {
+		t.Errorf("Expected 1 run and 2 restarts, got %d runs", n)
+	}
+
+	err := tm.Supervise(context.Background(), "other", func(ctx context.Context) error { return nil },
+		RestartPolicy{Panics: &PanicPolicy{}})
+	if !errors.Is(err, ErrNilPanicHandler) {
+		t.Errorf("Expected ErrNilPanicHandler, got %v", err)
+	}
+}
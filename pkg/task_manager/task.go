@@ -0,0 +1,235 @@
+package taskmanager
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+)
+
+type task struct {
+	id          string
+	run         uint64
+	parent      context.Context
+	ctx         context.Context // the task context, parent of the child tasks
+	parentID    string
+	cancel      context.CancelCauseFunc
+	createdAt   time.Time
+	scheduledAt time.Time
+	tags        []string
+	priority    int
+	metadata    map[string]string
+	detached    bool
+	healthCheck func(ctx context.Context) error
+	logger      *slog.Logger // see LoggerFrom
+	checkpoints CheckpointStore
+	tenant      string // see WithTenantQuota
+	stack       []byte // see WithErrorStacks
+	clock       Clock
+
+	mu         sync.Mutex
+	status     TaskStatus
+	startedAt  time.Time // zero until the task function is first called
+	finishedAt time.Time
+	err        error // result of the task function, set before done is closed
+	progress   Progress
+	resume     chan struct{} // non-nil while paused, closed on resume
+	heartbeat  time.Time
+	stuck      bool
+	value      any // set by SetResult
+	usage      Usage
+
+	deadlineCtx *deadlineCtx // set once started if the task has a deadline
+	lease       *lease       // set once started if the task has a lease
+
+	continuations []continuation
+	continued     bool // set once the continuations were started
+
+	done chan struct{} // closed once the task has finished
+}
+
+// TaskInfo is a point-in-time snapshot of a task.
+type TaskInfo struct {
+	ID string
+	// RunID tells runs of the same ID apart, see TaskHandle.RunID.
+	RunID  uint64
+	Status TaskStatus
+	// Err is the result of a finished task.
+	Err       error
+	CreatedAt time.Time
+	// ScheduledAt is when a task started with StartTaskAt fires.
+	ScheduledAt time.Time
+	// StartedAt and Running are zero until the task function is called.
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Running    time.Duration
+	// ParentErr is the error of the context the task was started with,
+	// nil while that context is still active.
+	ParentErr error
+	Tags      []string
+	Priority  int
+	// ParentID is the ID of the parent of a child task, see StartChildTask.
+	ParentID string
+	// Metadata is set by WithMetadata.
+	Metadata map[string]string
+	// Health is the result of the health check of a running task, see
+	// WithHealthCheck. It is only set by ListTasks and Status.
+	Health error
+	// Detached reports whether GracefulShutdown leaves the task running, see
+	// WithDetached.
+	Detached bool
+	Progress Progress
+	// Paused reports whether the task was asked to pause, see PauseTask.
+	Paused bool
+	// LastHeartbeat is the last call to Heartbeat, or the start of the task.
+	LastHeartbeat time.Time
+	// Stuck reports whether the task missed its heartbeat timeout.
+	Stuck bool
+	// Result is the value passed to SetResult by the task.
+	Result any
+	// Deadline is when the running task times out, see WithTimeout and
+	// ExtendDeadline.
+	Deadline time.Time
+	// LeaseExpires is when the lease of the running task expires, see
+	// WithLease.
+	LeaseExpires time.Time
+	// CreationStack is the stack of the StartTask call of a failed run, and
+	// FailureStack the stack of its failure when known, see WithErrorStacks.
+	CreationStack []byte
+	FailureStack  []byte
+	// Usage is the resource usage of the task, see WithUsageTracking and
+	// ReportGauge.
+	Usage Usage
+}
+
+func (t *task) info(now time.Time) TaskInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	end := now
+	var deadline, leaseExpires time.Time
+	var creation, failure []byte
+	if t.err != nil && t.stack != nil {
+		creation, failure = t.stack, failureStack(t.err)
+	}
+	if !t.finishedAt.IsZero() {
+		end = t.finishedAt
+	} else {
+		if t.deadlineCtx != nil {
+			deadline, _ = t.deadlineCtx.Deadline()
+		}
+		if t.lease != nil {
+			leaseExpires = t.lease.expiry()
+		}
+	}
+	return TaskInfo{
+		ID:            t.id,
+		RunID:         t.run,
+		Status:        t.status,
+		Err:           t.err,
+		CreatedAt:     t.createdAt,
+		ScheduledAt:   t.scheduledAt,
+		StartedAt:     t.startedAt,
+		FinishedAt:    t.finishedAt,
+		Running:       runningFor(t.startedAt, end),
+		ParentErr:     t.parent.Err(),
+		Tags:          slices.Clone(t.tags),
+		Priority:      t.priority,
+		ParentID:      t.parentID,
+		Metadata:      maps.Clone(t.metadata),
+		Detached:      t.detached,
+		Progress:      t.progress,
+		Paused:        t.resume != nil,
+		LastHeartbeat: t.heartbeat,
+		Stuck:         t.stuck,
+		Result:        t.value,
+		Deadline:      deadline,
+		LeaseExpires:  leaseExpires,
+		CreationStack: creation,
+		FailureStack:  failure,
+		Usage:         t.usage.clone(),
+	}
+}
+
+func (t *task) markStarted(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.status = StatusRunning
+	t.startedAt = now
+}
+
+// markFinished records the result of the task and reports whether it was
+// abandoned, in which case its status is kept.
+func (t *task) markFinished(err error, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	abandoned := t.status == StatusAbandoned
+	if !abandoned {
+		t.status = statusOf(err)
+	}
+	t.err = err
+	t.finishedAt = now
+	return abandoned
+}
+
+func (t *task) setDeadline(dctx *deadlineCtx) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.deadlineCtx = dctx
+}
+
+func (t *task) setLease(l *lease) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lease = l
+}
+
+func (t *task) started() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.startedAt
+}
+
+// beat records a heartbeat, which clears the stuck flag.
+func (t *task) beat(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.heartbeat = now
+	t.stuck = false
+}
+
+func (t *task) lastBeat() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.heartbeat
+}
+
+// markStuck flags the task as stuck and reports whether it was not already.
+func (t *task) markStuck() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stuck {
+		return false
+	}
+	t.stuck = true
+	return true
+}
+
+func (t *task) result() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+func runningFor(startedAt, now time.Time) time.Duration {
+	if startedAt.IsZero() {
+		return 0
+	}
+	return now.Sub(startedAt)
+}
+
+func (t *task) hasTag(tag string) bool {
+	return slices.Contains(t.tags, tag)
+}
@@ -3,18 +3,73 @@ package taskmanager
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
+	"log/slog"
+	"path"
+	"runtime/debug"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 type TaskManager struct {
-	tasks sync.Map // key: string, value: context.CancelFunc
-	wg    sync.WaitGroup
+	tasks    sync.Map // key: string, value: *task
+	finished *finishedRuns
+	results  sync.Map // key: resultKey, value: *task, see WithIdempotencyKey
+	wg       sync.WaitGroup
+
+	hooks           hooks
+	middlewares     middlewares
+	observers       observers
+	counters        counters
+	clock           Clock
+	events          broker
+	history         *history
+	registry        registry
+	store           Store
+	checkpoints     CheckpointStore
+	shuttingDown    atomic.Bool
+	quiesced        atomic.Bool
+	locks           LockProvider
+	shutdownTimeout time.Duration
+	runs            atomic.Uint64
+	limiter         *limiter
+	fairWeights     map[string]int
+	audit           *auditor
+	errorStacks     bool
+	dryRun          *plan
+	usage           *usageTracker
+	quotas          *quotas
+	tracer          trace.Tracer
+	logger          Logger
+	taskLogger      *slog.Logger
+	panicHandler    func(id string, v any, stack []byte)
+	escalate        func(info TaskInfo, remaining []TaskInfo)
+	lastShutdown    atomic.Pointer[ShutdownReport]
 }
 
-func NewTaskManager() *TaskManager {
-	return &TaskManager{}
+func NewTaskManager(opts ...Option) *TaskManager {
+	s := &TaskManager{
+		logger:          log.Default(),
+		clock:           realClock{},
+		finished:        newFinishedRuns(DefaultFinishedSize),
+		shutdownTimeout: DefaultShutdownTimeout,
+		tracer:          noop.NewTracerProvider().Tracer(tracerName),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.limiter != nil && s.fairWeights != nil {
+		s.limiter.weights = s.fairWeights
+		s.limiter.pass = map[string]float64{}
+	}
+	return s
 }
 
 func (s *TaskManager) HasTask(id string) bool {
@@ -22,77 +77,587 @@ func (s *TaskManager) HasTask(id string) bool {
 	return ok
 }
 
-func (s *TaskManager) StartTask(ctx context.Context, id string, fn func(ctx context.Context) error) error {
+// StartTask starts a task, replacing the running task with the same ID, and
+// returns a handle to this run. See StartTaskWithOptions for the options.
+func (s *TaskManager) StartTask(ctx context.Context, id string, fn func(ctx context.Context) error, opts ...TaskOption) (*TaskHandle, error) {
+	t, err := s.start(ctx, id, fn, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &TaskHandle{tm: s, t: t}, nil
+}
+
+// StartTaskWithOptions starts a task configured by opts, see WithTags,
+// WithTimeout, WithDeadline, WithRetry, WithOnComplete, WithPriority,
+// WithSingleton and WithDedupWindow.
+func (s *TaskManager) StartTaskWithOptions(ctx context.Context, id string, fn func(ctx context.Context) error, opts ...TaskOption) error {
+	_, err := s.start(ctx, id, fn, opts)
+	return err
+}
+
+// start starts the task and returns it, or the running task it was
+// deduplicated with.
+func (s *TaskManager) start(ctx context.Context, id string, fn func(ctx context.Context) error, opts []TaskOption) (*task, error) {
 	if id == "" {
-		return ErrInvalidTaskID
+		return nil, ErrInvalidTaskID
 	}
 
 	if fn == nil {
-		return ErrNilTaskFunc
+		return nil, ErrNilTaskFunc
 	}
 
 	if ctx.Err() != nil {
-		log.Printf("Context already canceled, task %s not started", id)
-		return ctx.Err()
+		s.logger.Printf("Context already canceled, task %s not started", id)
+		return nil, ctx.Err()
 	}
 
-	if cancelFn, ok := s.tasks.Load(id); ok {
-		cancelFn.(context.CancelFunc)()
+	if s.quiesced.Load() {
+		return nil, ErrShuttingDown
 	}
 
-	ctxTask, cancel := context.WithCancel(ctx)
-	s.tasks.Store(id, cancel)
+	cfg := newTaskConfig(opts)
+	if cfg.singleton && s.locks == nil {
+		return nil, ErrNoLockProvider
+	}
+	if v, ok := s.tasks.Load(id); ok && cfg.dedupWindow > 0 && s.clock.Now().Sub(v.(*task).createdAt) < cfg.dedupWindow {
+		s.logger.Printf("Task %s restarted within %v, keeping the running task", id, cfg.dedupWindow)
+		return v.(*task), nil
+	}
+	ctxTask, cancel := context.WithCancelCause(ctx)
+	t := &task{
+		id:          id,
+		run:         s.runs.Add(1),
+		parent:      ctx,
+		ctx:         ctxTask,
+		parentID:    cfg.parentID,
+		cancel:      cancel,
+		createdAt:   s.clock.Now(),
+		scheduledAt: cfg.startAt,
+		tags:        cfg.tags,
+		priority:    cfg.priority,
+		metadata:    cfg.metadata,
+		detached:    cfg.detached,
+		healthCheck: cfg.healthCheck,
+		checkpoints: s.checkpoints,
+		stack:       s.creationStack(),
+		done:        make(chan struct{}),
+		clock:       s.clock,
+	}
+	t.logger = s.newTaskLogger(t)
+	// before the quota, a cached run takes no slot and leaves the slot of
+	// the running task alone
+	if cached, ok := s.cacheRun(t, cfg); !ok {
+		s.logger.Printf("Task %s already ran with key %s, reusing run %d", id, cfg.idempotencyKey, cached.run)
+		cancel(nil)
+		return cached, nil
+	}
+	if s.quotas != nil {
+		if err := s.quotas.acquire(t); err != nil {
+			s.logger.Printf("Task %s not started: %v", id, err)
+			s.uncacheRun(t, cfg)
+			cancel(nil)
+			return nil, err
+		}
+	}
+	if cfg.definition != nil {
+		if err := s.persist(ctx, *cfg.definition); err != nil {
+			s.uncacheRun(t, cfg)
+			if s.quotas != nil {
+				s.quotas.release(t)
+			}
+			cancel(nil)
+			return nil, err
+		}
+	}
+	var replaced *task
+	if old, loaded := s.tasks.Swap(id, t); loaded {
+		old := old.(*task)
+		s.observers.notify(func(o TaskObserver) { o.TaskReplaced(old.info(s.clock.Now())) })
+		old.cancel(ErrTaskReplaced)
+		s.emit(EventReplaced, old, runningFor(old.started(), s.clock.Now()), nil)
+		if cfg.replaceWait > 0 {
+			replaced = old
+		}
+	}
 	s.wg.Add(1)
+	s.auditTask(ctx, AuditStart, t)
 
-	go func() {
-		defer func() {
-			s.tasks.Delete(id)
-			s.wg.Done()
-		}()
-
-		err := fn(ctxTask)
-		if errors.Is(err, context.Canceled) {
-			log.Printf("Task %s was canceled", id)
-		} else if err != nil {
-			log.Printf("Task %s failed: %v", id, err)
+	go s.run(ctxTask, t, fn, cfg, replaced)
+
+	return t, nil
+}
+
+// StartTaskAt registers the task right away but only calls fn at the given
+// time. Stopping the task before then cancels it without calling fn.
+func (s *TaskManager) StartTaskAt(ctx context.Context, id string, fn func(ctx context.Context) error, at time.Time, opts ...TaskOption) error {
+	opts = append(opts, func(cfg *taskConfig) {
+		cfg.startAt = at
+	})
+	return s.StartTaskWithOptions(ctx, id, fn, opts...)
+}
+
+func (s *TaskManager) run(ctx context.Context, t *task, fn func(ctx context.Context) error, cfg taskConfig, replaced *task) {
+	defer func() {
+		// only remove our own entry, a replacement may already be stored
+		s.tasks.CompareAndDelete(t.id, t)
+		// before wg.Done so that a shutdown waits for the continuations
+		s.runContinuations(t)
+		s.wg.Done()
+	}()
+	labelGoroutine(ctx, t)
+	if cfg.stopTimeout > 0 {
+		go s.watchStop(ctx, t, cfg.stopTimeout)
+	}
+
+	err := s.waitReplaced(ctx, replaced, cfg.replaceWait)
+	if err == nil {
+		err = s.waitUntil(ctx, cfg.startAt)
+	}
+	if err == nil {
+		err = s.runSingleton(ctx, t, fn, cfg)
+	}
+	// tell why the task was canceled, see StopTaskWithCause
+	if cause := context.Cause(ctx); err != nil && cause != nil && cause != ctx.Err() && !errors.Is(err, cause) {
+		err = fmt.Errorf("%w: %w", cause, err)
+	}
+	s.finish(t, err)
+
+	now := s.clock.Now()
+	abandoned := t.markFinished(err, now)
+	if abandoned {
+		s.logger.Printf("Task %s returned after being abandoned", t.id)
+	}
+	if s.quotas != nil {
+		s.quotas.release(t)
+	}
+	s.auditTask(context.Background(), AuditFinish, t)
+	s.recordFinished(t)
+	s.expireRun(t, cfg)
+	info := t.info(now)
+	// an abandoned run is already in the history
+	if s.history != nil && !abandoned {
+		s.history.add(info)
+	}
+	s.observers.notify(func(o TaskObserver) { o.TaskFinished(info) })
+	if cfg.definition != nil && s.store != nil {
+		s.unpersist(t)
+	}
+	if err == nil && s.checkpoints != nil {
+		s.clearCheckpoints(t)
+	}
+	// stop the child tasks, see StartChildTask
+	t.cancel(ErrParentFinished)
+	close(t.done)
+
+	if cfg.onComplete != nil {
+		cfg.onComplete(err)
+	}
+}
+
+// runLimited waits for a free slot when the number of running tasks is
+// limited, then runs the task.
+func (s *TaskManager) runLimited(ctx context.Context, t *task, fn func(ctx context.Context) error, cfg taskConfig) error {
+	if s.limiter == nil {
+		return s.runStarted(ctx, t, fn, cfg)
+	}
+	if err := s.limiter.acquire(ctx, t); err != nil {
+		return err
+	}
+	defer s.limiter.release()
+	return s.runStarted(ctx, t, fn, cfg)
+}
+
+func (s *TaskManager) runStarted(ctx context.Context, t *task, fn func(ctx context.Context) error, cfg taskConfig) error {
+	startedAt := s.clock.Now()
+	t.markStarted(startedAt)
+	s.counters.start()
+	if s.usage != nil {
+		s.usage.started(t)
+		defer s.usage.finished(t)
+	}
+	ctx = context.WithValue(ctx, taskKey{}, t)
+
+	if deadline, ok := cfg.deadlineFrom(startedAt); ok {
+		dctx, cancelDeadline := withExtendableDeadline(ctx, deadline, s.clock)
+		defer cancelDeadline()
+		t.setDeadline(dctx)
+		ctx = dctx
+	}
+
+	ctx, span := s.startSpan(ctx, t)
+	s.hooks.run(hookStart, t.id, 0, nil)
+	s.emit(EventStarted, t, 0, nil)
+	s.observers.notify(func(o TaskObserver) { o.TaskStarted(t.info(startedAt)) })
+
+	err := s.executeLeased(ctx, t, fn, cfg)
+	if err != nil && errors.Is(context.Cause(ctx), ErrTaskTimedOut) && !errors.Is(err, ErrTaskTimedOut) {
+		err = fmt.Errorf("%w: %w", ErrTaskTimedOut, err)
+	}
+	endSpan(span, err)
+	return err
+}
+
+// waitReplaced waits up to timeout for the task replaced by this one to
+// return. It doesn't stop when ctx is done so that, if this task is replaced
+// in turn, its own replacement also waits for the older task.
+func (s *TaskManager) waitReplaced(ctx context.Context, replaced *task, timeout time.Duration) error {
+	if replaced == nil {
+		return nil
+	}
+	timer := s.clock.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-replaced.done:
+		return ctx.Err()
+	case <-timer.C():
+		return ErrReplaceTimeout
+	}
+}
+
+// waitUntil blocks until at or until ctx is done. A zero at returns at once.
+func (s *TaskManager) waitUntil(ctx context.Context, at time.Time) error {
+	if at.IsZero() {
+		return nil
+	}
+	timer := s.clock.NewTimer(at.Sub(s.clock.Now()))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
+
+// finish logs the task result, counts it and runs the matching lifecycle
+// hooks.
+func (s *TaskManager) finish(t *task, err error) {
+	startedAt := t.started()
+	duration := runningFor(startedAt, s.clock.Now())
+	status := statusOf(err)
+	s.counters.finish(status, !startedAt.IsZero(), duration)
+
+	switch status {
+	case StatusCanceled:
+		s.logger.Printf("Task %s was canceled", t.id)
+		s.hooks.run(hookCancel, t.id, duration, err)
+		s.emit(EventCanceled, t, duration, err)
+	case StatusTimedOut:
+		s.logger.Printf("Task %s timed out: %v", t.id, err)
+		s.hooks.run(hookError, t.id, duration, err)
+		s.emit(EventFailed, t, duration, err)
+	case StatusFailed:
+		s.logger.Printf("Task %s failed: %v", t.id, err)
+		s.hooks.run(hookError, t.id, duration, err)
+		s.emit(EventFailed, t, duration, err)
+	default:
+		s.logger.Printf("Task %s completed successfully", t.id)
+		s.hooks.run(hookComplete, t.id, duration, nil)
+		s.emit(EventCompleted, t, duration, nil)
+	}
+}
+
+// call runs fn, turning a panic into a *PanicError reported to the panic
+// handler.
+func (s *TaskManager) call(ctx context.Context, id string, fn func(ctx context.Context) error) error {
+	return callReporting(ctx, fn, func(v any, stack []byte) {
+		if s.panicHandler != nil {
+			s.panicHandler(id, v, stack)
 		} else {
-			log.Printf("Task %s completed successfully", id)
+			s.logger.Printf("Task %s panicked: %v\n%s", id, v, stack)
 		}
-	}()
+	})
+}
 
-	return nil
+// callReporting runs fn, turning a panic into a *PanicError reported to
+// report only.
+func callReporting(ctx context.Context, fn func(ctx context.Context) error, report func(v any, stack []byte)) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			stack := debug.Stack()
+			report(v, stack)
+			err = &PanicError{Value: v, Stack: stack}
+		}
+	}()
+	return fn(ctx)
 }
 
 func (s *TaskManager) StopTask(id string) bool {
-	if cancelFn, ok := s.tasks.Load(id); ok {
-		cancelFn.(context.CancelFunc)()
-		s.tasks.Delete(id)
+	return s.StopTaskWithCause(id, ErrTaskStopped)
+}
+
+// StopTaskWithCause is like StopTask but cancels the task context with
+// cause, which the task function can read with context.Cause. The task
+// error, as seen by hooks, events and WaitTask, wraps cause. A nil cause
+// defaults to ErrTaskStopped.
+func (s *TaskManager) StopTaskWithCause(id string, cause error) bool {
+	return s.stop(context.Background(), id, cause)
+}
+
+// StopTaskContext is like StopTask, ctx names the caller in the audit trail,
+// see WithCaller.
+func (s *TaskManager) StopTaskContext(ctx context.Context, id string) bool {
+	return s.stop(ctx, id, ErrTaskStopped)
+}
+
+func (s *TaskManager) stop(ctx context.Context, id string, cause error) bool {
+	if cause == nil {
+		cause = ErrTaskStopped
+	}
+	if v, ok := s.tasks.LoadAndDelete(id); ok {
+		t := v.(*task)
+		s.auditTask(ctx, AuditStop, t)
+		t.cancel(cause)
 		return true
 	}
 	return false
 }
 
-func (s *TaskManager) GracefulShutdown(wait bool, timeout time.Duration) {
-	// Cancel all tasks
+// StopTaskAndWait stops the task and waits up to timeout for its function to
+// return. It returns ErrTaskNotFound if no such task is running and
+// ErrStopTimeout if the task is still running once timeout has elapsed.
+func (s *TaskManager) StopTaskAndWait(id string, timeout time.Duration) error {
+	v, ok := s.tasks.LoadAndDelete(id)
+	if !ok {
+		return ErrTaskNotFound
+	}
+	t := v.(*task)
+	s.auditTask(context.Background(), AuditStop, t)
+	t.cancel(ErrTaskStopped)
+
+	timer := s.clock.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-t.done:
+		return nil
+	case <-timer.C():
+		return ErrStopTimeout
+	}
+}
+
+// WaitTask blocks until the task with the given id returns and yields the
+// task function's error. The bool reports whether such a task was running.
+// If ctx is done first, ctx.Err() is returned instead.
+func (s *TaskManager) WaitTask(ctx context.Context, id string) (error, bool) {
+	v, ok := s.tasks.Load(id)
+	if !ok {
+		return nil, false
+	}
+	t := v.(*task)
+
+	select {
+	case <-t.done:
+		return t.result(), true
+	case <-ctx.Done():
+		return ctx.Err(), true
+	}
+}
+
+// StopTasksByTag stops every running task labeled with tag and returns how
+// many were stopped.
+func (s *TaskManager) StopTasksByTag(tag string) int {
+	stopped := 0
 	s.tasks.Range(func(key, value interface{}) bool {
-		value.(context.CancelFunc)()
+		t := value.(*task)
+		if t.hasTag(tag) && s.tasks.CompareAndDelete(key, t) {
+			s.auditTask(context.Background(), AuditStop, t)
+			t.cancel(ErrTaskStopped)
+			stopped++
+		}
 		return true
 	})
+	return stopped
+}
 
-	if wait {
-		done := make(chan struct{})
-		go func() {
-			s.wg.Wait()
-			close(done)
-		}()
+// StopTasksMatching stops every running task whose ID matches pattern and
+// returns how many were stopped. A pattern without any of the *, ? or [
+// metacharacters matches IDs starting with it, otherwise it is a glob in the
+// syntax of path.Match, e.g. "sync-*". A malformed pattern matches nothing.
+func (s *TaskManager) StopTasksMatching(pattern string) int {
+	stopped := 0
+	s.tasks.Range(func(key, value interface{}) bool {
+		t := value.(*task)
+		if matchID(pattern, t.id) && s.tasks.CompareAndDelete(key, t) {
+			s.auditTask(context.Background(), AuditStop, t)
+			t.cancel(ErrTaskStopped)
+			stopped++
+		}
+		return true
+	})
+	return stopped
+}
 
+func matchID(pattern, id string) bool {
+	if !strings.ContainsAny(pattern, "*?[") {
+		return strings.HasPrefix(id, pattern)
+	}
+	ok, _ := path.Match(pattern, id)
+	return ok
+}
+
+// ListTasks returns a snapshot of the running tasks in the order they were
+// started.
+func (s *TaskManager) ListTasks() []TaskInfo {
+	return s.listTasks(func(t *task) bool { return true })
+}
+
+// PendingTasks returns the tasks waiting for a slot because of
+// WithMaxConcurrent, in the order they will start. Use StopTask to remove a
+// task from the queue.
+func (s *TaskManager) PendingTasks() []TaskInfo {
+	if s.limiter == nil {
+		return []TaskInfo{}
+	}
+	now := s.clock.Now()
+	tasks := s.limiter.queued()
+	infos := make([]TaskInfo, 0, len(tasks))
+	for _, t := range tasks {
+		infos = append(infos, t.info(now))
+	}
+	return infos
+}
+
+// ListTasksByTag is like ListTasks but only returns tasks labeled with tag.
+func (s *TaskManager) ListTasksByTag(tag string) []TaskInfo {
+	return s.listTasks(func(t *task) bool { return t.hasTag(tag) })
+}
+
+func (s *TaskManager) listTasks(match func(t *task) bool) []TaskInfo {
+	tasks := []*task{}
+	s.tasks.Range(func(key, value interface{}) bool {
+		if t := value.(*task); match(t) {
+			tasks = append(tasks, t)
+		}
+		return true
+	})
+	slices.SortFunc(tasks, func(a, b *task) int {
+		if c := a.createdAt.Compare(b.createdAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.id, b.id)
+	})
+
+	s.refreshUsage()
+	now := s.clock.Now()
+	infos := make([]TaskInfo, 0, len(tasks))
+	for _, t := range tasks {
+		info := t.info(now)
+		info.Health = t.checkHealth(context.Background())
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// Quiesce makes the manager reject new tasks with ErrShuttingDown while the
+// running ones continue, e.g. to stop the intake of a service before its
+// GracefulShutdown during a deployment. It cannot be undone.
+func (s *TaskManager) Quiesce() {
+	if !s.quiesced.Swap(true) {
+		s.logger.Printf("Task manager quiesced, rejecting new tasks")
+	}
+}
+
+// Quiesced reports whether Quiesce was called.
+func (s *TaskManager) Quiesced() bool {
+	return s.quiesced.Load()
+}
+
+// GracefulShutdown cancels every task but the detached ones and, if wait is
+// set, waits up to timeout for all of them to return. The outcome is only
+// logged, use GracefulShutdownContext to act on it. Without wait, the tasks
+// still running are reported as abandoned in LastShutdownReport.
+func (s *TaskManager) GracefulShutdown(wait bool, timeout time.Duration) {
+	if !wait {
+		began := s.clock.Now()
+		s.record(context.Background(), AuditRecord{Action: AuditShutdown})
+		tasks := s.cancelAll()
+		s.logger.Printf("Graceful shutdown triggered without waiting")
+		s.recordShutdown(began, tasks, nil, StatusAbandoned)
+		s.observers.notify(func(o TaskObserver) { o.ShutdownEnded(nil) })
+		return
+	}
+
+	ctx, cancel := s.timeoutContext(context.Background(), timeout)
+	defer cancel()
+	_ = s.GracefulShutdownContext(ctx)
+}
+
+// Shutdown is GracefulShutdownContext. Its signature matches the Shutdown
+// method of http.Server and similar components, so that the manager is shut
+// down along with them under the same deadline, e.g. the one of a Kubernetes
+// preStop hook.
+func (s *TaskManager) Shutdown(ctx context.Context) error {
+	return s.GracefulShutdownContext(ctx)
+}
+
+// GracefulShutdownContext cancels every task but the detached ones and waits
+// for all of them to return until ctx is done. It then returns a
+// *ShutdownError listing the tasks still running. The outcome of every task is
+// kept in LastShutdownReport.
+func (s *TaskManager) GracefulShutdownContext(ctx context.Context) (err error) {
+	began := s.clock.Now()
+	s.record(ctx, AuditRecord{Action: AuditShutdown})
+	tasks := s.cancelAll()
+	defer func() {
+		s.recordShutdown(began, tasks, err, StatusRunning)
+		s.observers.notify(func(o TaskObserver) { o.ShutdownEnded(err) })
+	}()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.logger.Printf("All tasks completed gracefully")
+		return nil
+	case <-ctx.Done():
+	}
+
+	now := s.clock.Now()
+	infos := []TaskInfo{}
+	for _, t := range tasks {
 		select {
-		case <-done:
-			log.Println("All tasks completed gracefully")
-		case <-time.After(timeout):
-			log.Println("Graceful shutdown timed out")
+		case <-t.done:
+		default:
+			infos = append(infos, t.info(now))
 		}
-	} else {
-		log.Println("Graceful shutdown triggered without waiting")
 	}
+	slices.SortFunc(infos, func(a, b TaskInfo) int { return strings.Compare(a.ID, b.ID) })
+
+	remaining := make([]string, len(infos))
+	running := make([]string, len(infos))
+	for i, info := range infos {
+		remaining[i] = info.ID
+		running[i] = fmt.Sprintf("%s (%v)", info.ID, info.Running)
+	}
+	s.logger.Printf("Graceful shutdown timed out, tasks still running: %s", strings.Join(running, ", "))
+	if s.escalate != nil {
+		for _, info := range infos {
+			s.escalate(info, infos)
+		}
+	}
+	return &ShutdownError{Remaining: remaining, Err: context.Cause(ctx)}
+}
+
+// cancelAll cancels every task but the detached ones and returns them all.
+func (s *TaskManager) cancelAll() []*task {
+	s.shuttingDown.Store(true)
+	s.observers.notify(func(o TaskObserver) { o.ShutdownBegan() })
+
+	tasks := []*task{}
+	s.tasks.Range(func(key, value interface{}) bool {
+		t := value.(*task)
+		if !t.detached {
+			t.cancel(ErrShutdown)
+		}
+		tasks = append(tasks, t)
+		return true
+	})
+	return tasks
 }
@@ -3,6 +3,8 @@ package taskmanager
 import (
 	"context"
 	"errors"
+	"net/http"
+	"slices"
 	"testing"
 	"time"
 )
@@ -12,7 +14,7 @@ func TestStartTask_NewTaskAdded(t *testing.T) {
 	ctx := context.Background()
 
 	started := make(chan struct{})
-	err := tm.StartTask(ctx, "task", func(ctx context.Context) error {
+	_, err := tm.StartTask(ctx, "task", func(ctx context.Context) error {
 		close(started)
 		<-ctx.Done()
 		return nil
@@ -37,7 +39,7 @@ func TestStartTask_ReplacesOldTask(t *testing.T) {
 	ctx := context.Background()
 
 	oldCanceled := make(chan struct{})
-	_ = tm.StartTask(ctx, "task", func(ctx context.Context) error {
+	_, _ = tm.StartTask(ctx, "task", func(ctx context.Context) error {
 		<-ctx.Done()
 		close(oldCanceled)
 		return nil
@@ -45,7 +47,7 @@ func TestStartTask_ReplacesOldTask(t *testing.T) {
 
 	// start new task with same ID
 	newStarted := make(chan struct{})
-	_ = tm.StartTask(ctx, "task", func(ctx context.Context) error {
+	_, _ = tm.StartTask(ctx, "task", func(ctx context.Context) error {
 		close(newStarted)
 		<-ctx.Done()
 		return nil
@@ -69,7 +71,7 @@ func TestStartTask_RemovesTaskAfterFinish(t *testing.T) {
 	ctx := context.Background()
 
 	done := make(chan struct{})
-	_ = tm.StartTask(ctx, "task", func(ctx context.Context) error {
+	_, _ = tm.StartTask(ctx, "task", func(ctx context.Context) error {
 		close(done)
 		return nil
 	})
@@ -87,18 +89,18 @@ func TestStartTask_LogCancelAndError(t *testing.T) {
 	ctx := context.Background()
 
 	canceledDone := make(chan struct{})
-	_ = tm.StartTask(ctx, "cancel", func(ctx context.Context) error {
+	_, _ = tm.StartTask(ctx, "cancel", func(ctx context.Context) error {
 		<-ctx.Done()
 		close(canceledDone)
 		return context.Canceled
 	})
-	_ = tm.StartTask(ctx, "cancel", func(ctx context.Context) error {
+	_, _ = tm.StartTask(ctx, "cancel", func(ctx context.Context) error {
 		return nil
 	})
 	<-canceledDone
 
 	errorDone := make(chan struct{})
-	_ = tm.StartTask(ctx, "error", func(ctx context.Context) error {
+	_, _ = tm.StartTask(ctx, "error", func(ctx context.Context) error {
 		close(errorDone)
 		return errors.New("boom")
 	})
@@ -111,7 +113,7 @@ func TestStartTask_ContextAlreadyCanceled(t *testing.T) {
 	cancel()
 
 	started := make(chan struct{})
-	err := tm.StartTask(ctx, "should_not_start", func(ctx context.Context) error {
+	_, err := tm.StartTask(ctx, "should_not_start", func(ctx context.Context) error {
 		close(started)
 		return nil
 	})
@@ -136,7 +138,7 @@ func TestStartTask_InvalidID(t *testing.T) {
 	tm := NewTaskManager()
 	ctx := context.Background()
 
-	err := tm.StartTask(ctx, "", func(ctx context.Context) error { return nil })
+	_, err := tm.StartTask(ctx, "", func(ctx context.Context) error { return nil })
 	if err == nil {
 		t.Fatal("Expected error for empty task ID, got nil")
 	}
@@ -146,7 +148,7 @@ func TestStartTask_NilFunction(t *testing.T) {
 	tm := NewTaskManager()
 	ctx := context.Background()
 
-	err := tm.StartTask(ctx, "task", nil)
+	_, err := tm.StartTask(ctx, "task", nil)
 	if err == nil {
 		t.Fatal("Expected error for nil task function, got nil")
 	}
@@ -161,14 +163,14 @@ func TestStartTask_TwoTasksRunningIndependently(t *testing.T) {
 	done1 := make(chan struct{})
 	done2 := make(chan struct{})
 
-	_ = tm.StartTask(ctx, "task1", func(ctx context.Context) error {
+	_, _ = tm.StartTask(ctx, "task1", func(ctx context.Context) error {
 		close(started1)
 		<-ctx.Done()
 		close(done1)
 		return nil
 	})
 
-	_ = tm.StartTask(ctx, "task2", func(ctx context.Context) error {
+	_, _ = tm.StartTask(ctx, "task2", func(ctx context.Context) error {
 		close(started2)
 		<-ctx.Done()
 		close(done2)
@@ -190,8 +192,8 @@ func TestStartTask_TwoTasksRunningIndependently(t *testing.T) {
 		t.Error("Expected both task1 and task2 to be running")
 	}
 
-	if cancelFn, ok := tm.tasks.Load("task1"); ok {
-		cancelFn.(context.CancelFunc)()
+	if t, ok := tm.tasks.Load("task1"); ok {
+		t.(*task).cancel(nil)
 	}
 
 	select {
@@ -213,7 +215,7 @@ func TestStopTask_ExistingTask(t *testing.T) {
 	ctx := context.Background()
 
 	stoppedCh := make(chan struct{})
-	_ = tm.StartTask(ctx, "task1", func(ctx context.Context) error {
+	_, _ = tm.StartTask(ctx, "task1", func(ctx context.Context) error {
 		<-ctx.Done()
 		close(stoppedCh)
 		return nil
@@ -252,7 +254,7 @@ func TestStopTask_StopTwice(t *testing.T) {
 	tm := NewTaskManager()
 	ctx := context.Background()
 
-	_ = tm.StartTask(ctx, "task1", func(ctx context.Context) error {
+	_, _ = tm.StartTask(ctx, "task1", func(ctx context.Context) error {
 		<-ctx.Done()
 		return nil
 	})
@@ -275,13 +277,13 @@ func TestStopTask_DoesNotAffectOtherTasks(t *testing.T) {
 	stopped1 := make(chan struct{})
 	stopped2 := make(chan struct{})
 
-	_ = tm.StartTask(ctx, "task1", func(ctx context.Context) error {
+	_, _ = tm.StartTask(ctx, "task1", func(ctx context.Context) error {
 		<-ctx.Done()
 		close(stopped1)
 		return nil
 	})
 
-	_ = tm.StartTask(ctx, "task2", func(ctx context.Context) error {
+	_, _ = tm.StartTask(ctx, "task2", func(ctx context.Context) error {
 		<-ctx.Done()
 		close(stopped2)
 		return nil
@@ -322,7 +324,7 @@ func TestGracefulShutdown_WaitTrue(t *testing.T) {
 	ctx := context.Background()
 
 	taskDone := make(chan struct{})
-	_ = tm.StartTask(ctx, "long_task", func(ctx context.Context) error {
+	_, _ = tm.StartTask(ctx, "long_task", func(ctx context.Context) error {
 		<-ctx.Done()
 		time.Sleep(50 * time.Millisecond) // simulate cleanup
 		close(taskDone)
@@ -347,12 +349,94 @@ func TestGracefulShutdown_WaitTrue(t *testing.T) {
 	}
 }
 
+func TestGracefulShutdownContext_ReportsRemaining(t *testing.T) {
+	tm := NewTaskManager()
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	_, _ = tm.StartTask(ctx, "quick", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	for _, id := range []string{"stubborn2", "stubborn1"} {
+		_, _ = tm.StartTask(ctx, id, func(ctx context.Context) error {
+			<-release
+			return nil
+		})
+	}
+	defer close(release)
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	err := tm.GracefulShutdownContext(shutdownCtx)
+
+	var shutdownErr *ShutdownError
+	if !errors.As(err, &shutdownErr) {
+		t.Fatalf("Expected a *ShutdownError, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the error to wrap the context error, got %v", err)
+	}
+	if !slices.Equal(shutdownErr.Remaining, []string{"stubborn1", "stubborn2"}) {
+		t.Errorf("Expected stubborn1 and stubborn2 to remain, got %v", shutdownErr.Remaining)
+	}
+}
+
+func TestGracefulShutdownContext_Escalation(t *testing.T) {
+	var escalated []string
+	var remaining []TaskInfo
+	tm := NewTaskManager(WithShutdownEscalation(func(info TaskInfo, all []TaskInfo) {
+		escalated = append(escalated, info.ID)
+		remaining = all
+	}))
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	defer close(release)
+	for _, id := range []string{"stubborn2", "stubborn1"} {
+		_, _ = tm.StartTask(ctx, id, func(ctx context.Context) error {
+			<-release
+			return nil
+		})
+	}
+	_, _ = tm.StartTask(ctx, "quick", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	time.Sleep(20 * time.Millisecond)
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_ = tm.GracefulShutdownContext(shutdownCtx)
+
+	if !slices.Equal(escalated, []string{"stubborn1", "stubborn2"}) {
+		t.Errorf("Expected the escalation for stubborn1 and stubborn2, got %v", escalated)
+	}
+	if len(remaining) != 2 || remaining[0].Running < 20*time.Millisecond {
+		t.Errorf("Expected the remaining tasks with their run duration, got %+v", remaining)
+	}
+}
+
+func TestGracefulShutdownContext_AllDone(t *testing.T) {
+	tm := NewTaskManager()
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := tm.GracefulShutdownContext(ctx); err != nil {
+		t.Errorf("Expected nil once every task returned, got %v", err)
+	}
+}
+
 func TestGracefulShutdown_WaitFalse(t *testing.T) {
 	tm := NewTaskManager()
 	ctx := context.Background()
 
 	taskDone := make(chan struct{})
-	_ = tm.StartTask(ctx, "long_task", func(ctx context.Context) error {
+	_, _ = tm.StartTask(ctx, "long_task", func(ctx context.Context) error {
 		<-ctx.Done()
 		time.Sleep(50 * time.Millisecond) // simulate cleanup
 		close(taskDone)
@@ -361,6 +445,9 @@ func TestGracefulShutdown_WaitFalse(t *testing.T) {
 
 	time.Sleep(20 * time.Millisecond) // ensure task started
 
+	observer := &recordingObserver{}
+	tm.AddObserver(observer)
+
 	start := time.Now()
 	tm.GracefulShutdown(false, 500*time.Millisecond)
 	elapsed := time.Since(start)
@@ -368,6 +455,16 @@ func TestGracefulShutdown_WaitFalse(t *testing.T) {
 	if elapsed >= 50*time.Millisecond {
 		t.Error("GracefulShutdown with wait=false should return immediately")
 	}
+	if calls := observer.recorded(); !slices.Equal(calls, []string{"shutdown began", "shutdown ended <nil>"}) {
+		t.Errorf("Expected the shutdown to begin and end, got %v", calls)
+	}
+	report := tm.LastShutdownReport()
+	if report == nil || report.Err != nil || len(report.Tasks) != 1 {
+		t.Fatalf("Expected a report of the shutdown, got %+v", report)
+	}
+	if r := report.Tasks[0]; r.ID != "long_task" || !r.MissedDeadline || r.Status != StatusAbandoned {
+		t.Errorf("Expected the running task to be abandoned, got %+v", r)
+	}
 
 	// Task should still finish eventually
 	select {
@@ -382,7 +479,7 @@ func TestGracefulShutdown_Timeout(t *testing.T) {
 	ctx := context.Background()
 
 	// timeout task
-	_ = tm.StartTask(ctx, "stuck_task", func(ctx context.Context) error {
+	_, _ = tm.StartTask(ctx, "stuck_task", func(ctx context.Context) error {
 		<-ctx.Done()
 		time.Sleep(200 * time.Millisecond) // simulate very long cleanup
 		return nil
@@ -400,3 +497,537 @@ func TestGracefulShutdown_Timeout(t *testing.T) {
 
 	time.Sleep(200 * time.Millisecond)
 }
+
+func TestListTasks_ReturnsRunningTasks(t *testing.T) {
+	tm := NewTaskManager()
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+	defer close(release)
+
+	_, _ = tm.StartTask(context.Background(), "task1", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	_, _ = tm.StartTask(ctx, "task2", func(ctx context.Context) error {
+		<-release // keep running after the parent is canceled
+		return nil
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	infos := tm.ListTasks()
+	if len(infos) != 2 {
+		t.Fatalf("Expected 2 tasks, got %d", len(infos))
+	}
+	if infos[0].ID != "task1" || infos[1].ID != "task2" {
+		t.Errorf("Expected tasks ordered by start time, got %s, %s", infos[0].ID, infos[1].ID)
+	}
+	for _, info := range infos {
+		if info.StartedAt.IsZero() || info.Running <= 0 {
+			t.Errorf("Expected start time and running duration for %s, got %v / %v", info.ID, info.StartedAt, info.Running)
+		}
+		if info.ParentErr != nil {
+			t.Errorf("Expected active parent context for %s, got %v", info.ID, info.ParentErr)
+		}
+	}
+
+	cancel()
+	if err := tm.ListTasks()[1].ParentErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected canceled parent context for task2, got %v", err)
+	}
+}
+
+func TestListTasks_Empty(t *testing.T) {
+	tm := NewTaskManager()
+
+	if infos := tm.ListTasks(); len(infos) != 0 {
+		t.Errorf("Expected no tasks, got %d", len(infos))
+	}
+}
+
+func TestStopTasksByTag_StopsOnlyTaggedTasks(t *testing.T) {
+	tm := NewTaskManager()
+	ctx := context.Background()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}
+	_, _ = tm.StartTask(ctx, "sync1", block, WithTags("sync"))
+	_, _ = tm.StartTask(ctx, "sync2", block, WithTags("sync", "nightly"))
+	_, _ = tm.StartTask(ctx, "report1", block, WithTags("report"))
+
+	if got := len(tm.ListTasksByTag("sync")); got != 2 {
+		t.Fatalf("Expected 2 tasks tagged sync, got %d", got)
+	}
+
+	if stopped := tm.StopTasksByTag("sync"); stopped != 2 {
+		t.Errorf("Expected 2 tasks stopped, got %d", stopped)
+	}
+	if tm.HasTask("sync1") || tm.HasTask("sync2") {
+		t.Error("Expected sync tasks to be removed after StopTasksByTag")
+	}
+	if !tm.HasTask("report1") {
+		t.Error("Expected report1 to keep running")
+	}
+	if stopped := tm.StopTasksByTag("sync"); stopped != 0 {
+		t.Errorf("Expected nothing left to stop, got %d", stopped)
+	}
+}
+
+func TestStopTaskWithCause(t *testing.T) {
+	tm := NewTaskManager()
+	maintenance := errors.New("maintenance window")
+
+	causes := make(chan error, 1)
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return ctx.Err()
+	})
+	hookErrs := make(chan error, 1)
+	tm.OnCancel(func(id string, duration time.Duration, err error) {
+		hookErrs <- err
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	if !tm.StopTaskWithCause("task", maintenance) {
+		t.Fatal("Expected StopTaskWithCause to find the task")
+	}
+	if cause := <-causes; cause != maintenance {
+		t.Errorf("Expected the task to see its cause, got %v", cause)
+	}
+	if hookErr := <-hookErrs; !errors.Is(hookErr, maintenance) || !errors.Is(hookErr, context.Canceled) {
+		t.Errorf("Expected the hook error to wrap the cause and context.Canceled, got %v", hookErr)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if info, _ := tm.Status("task"); info.Status != StatusCanceled {
+		t.Errorf("Expected status canceled, got %s", info.Status)
+	}
+	if tm.StopTaskWithCause("task", maintenance) {
+		t.Error("Expected false for a task no longer running")
+	}
+}
+
+func TestStopTask_Causes(t *testing.T) {
+	tm := NewTaskManager()
+
+	causes := make(chan error, 3)
+	fn := func(ctx context.Context) error {
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return ctx.Err()
+	}
+
+	_, _ = tm.StartTask(context.Background(), "stopped", fn)
+	tm.StopTask("stopped")
+	if cause := <-causes; !errors.Is(cause, ErrTaskStopped) {
+		t.Errorf("Expected ErrTaskStopped, got %v", cause)
+	}
+
+	_, _ = tm.StartTask(context.Background(), "replaced", fn)
+	_, _ = tm.StartTask(context.Background(), "replaced", fn)
+	if cause := <-causes; !errors.Is(cause, ErrTaskReplaced) {
+		t.Errorf("Expected ErrTaskReplaced, got %v", cause)
+	}
+
+	tm.GracefulShutdown(true, 500*time.Millisecond)
+	if cause := <-causes; !errors.Is(cause, ErrShutdown) {
+		t.Errorf("Expected ErrShutdown, got %v", cause)
+	}
+}
+
+func TestStopTasksMatching(t *testing.T) {
+	tm := NewTaskManager()
+	ctx := context.Background()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}
+	for _, id := range []string{"sync-orders", "sync-users", "report-daily", "resync"} {
+		_, _ = tm.StartTask(ctx, id, block)
+	}
+
+	if stopped := tm.StopTasksMatching("sync-*"); stopped != 2 {
+		t.Errorf("Expected 2 tasks stopped by glob, got %d", stopped)
+	}
+	if tm.HasTask("sync-orders") || tm.HasTask("sync-users") || !tm.HasTask("resync") {
+		t.Error("Expected only the sync- tasks to be stopped")
+	}
+	if stopped := tm.StopTasksMatching("report"); stopped != 1 || tm.HasTask("report-daily") {
+		t.Errorf("Expected the prefix to stop report-daily, stopped %d", stopped)
+	}
+	if stopped := tm.StopTasksMatching("[re"); stopped != 0 {
+		t.Errorf("Expected a malformed pattern to match nothing, got %d", stopped)
+	}
+	if stopped := tm.StopTasksMatching("re?ync"); stopped != 1 {
+		t.Errorf("Expected ? to match a single character, got %d", stopped)
+	}
+}
+
+func TestListTasksByTag_IncludesTags(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	_, _ = tm.StartTask(context.Background(), "task1", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}, WithTags("a", "b", "a"))
+
+	infos := tm.ListTasksByTag("b")
+	if len(infos) != 1 {
+		t.Fatalf("Expected 1 task tagged b, got %d", len(infos))
+	}
+	if len(infos[0].Tags) != 2 || infos[0].Tags[0] != "a" || infos[0].Tags[1] != "b" {
+		t.Errorf("Expected tags [a b], got %v", infos[0].Tags)
+	}
+	if got := len(tm.ListTasksByTag("missing")); got != 0 {
+		t.Errorf("Expected no tasks for unknown tag, got %d", got)
+	}
+}
+
+func TestWaitTask_ReturnsTaskError(t *testing.T) {
+	tm := NewTaskManager()
+	ctx := context.Background()
+
+	boom := errors.New("boom")
+	release := make(chan struct{})
+	_, _ = tm.StartTask(ctx, "task", func(ctx context.Context) error {
+		<-release
+		return boom
+	})
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+	}()
+
+	err, ok := tm.WaitTask(ctx, "task")
+	if !ok {
+		t.Fatal("Expected WaitTask to find the running task")
+	}
+	if !errors.Is(err, boom) {
+		t.Errorf("Expected task error, got %v", err)
+	}
+}
+
+func TestWaitTask_NonExistingTask(t *testing.T) {
+	tm := NewTaskManager()
+
+	err, ok := tm.WaitTask(context.Background(), "does_not_exist")
+	if ok || err != nil {
+		t.Errorf("Expected (nil, false) for non-existent task, got (%v, %v)", err, ok)
+	}
+}
+
+func TestWaitTask_ContextDone(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err, ok := tm.WaitTask(ctx, "task")
+	if !ok {
+		t.Fatal("Expected WaitTask to find the running task")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestStopTaskAndWait_WaitsForCleanup(t *testing.T) {
+	tm := NewTaskManager()
+
+	cleaned := make(chan struct{})
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(30 * time.Millisecond) // simulate cleanup
+		close(cleaned)
+		return nil
+	})
+
+	if err := tm.StopTaskAndWait("task", 500*time.Millisecond); err != nil {
+		t.Fatalf("Expected task to stop in time, got %v", err)
+	}
+
+	select {
+	case <-cleaned:
+	default:
+		t.Error("StopTaskAndWait returned before task finished cleanup")
+	}
+}
+
+func TestStopTaskAndWait_Timeout(t *testing.T) {
+	tm := NewTaskManager()
+
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(200 * time.Millisecond) // simulate very long cleanup
+		return nil
+	})
+
+	err := tm.StopTaskAndWait("task", 20*time.Millisecond)
+	if !errors.Is(err, ErrStopTimeout) {
+		t.Errorf("Expected ErrStopTimeout, got %v", err)
+	}
+	if tm.HasTask("task") {
+		t.Error("Expected task to be removed even when stop timed out")
+	}
+
+	time.Sleep(200 * time.Millisecond)
+}
+
+func TestStopTaskAndWait_NonExistingTask(t *testing.T) {
+	tm := NewTaskManager()
+
+	err := tm.StopTaskAndWait("does_not_exist", 20*time.Millisecond)
+	if !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestStartTask_RecoversPanic(t *testing.T) {
+	type panicCall struct {
+		id    string
+		v     any
+		stack []byte
+	}
+	handled := make(chan panicCall, 1)
+	tm := NewTaskManager(WithPanicHandler(func(id string, v any, stack []byte) {
+		handled <- panicCall{id, v, stack}
+	}))
+
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		panic("boom")
+	})
+
+	err, ok := tm.WaitTask(context.Background(), "task")
+	if !ok {
+		t.Fatal("Expected WaitTask to find the running task")
+	}
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || panicErr.Value != "boom" || len(panicErr.Stack) == 0 {
+		t.Fatalf("Expected *PanicError with value and stack, got %v", err)
+	}
+
+	select {
+	case call := <-handled:
+		if call.id != "task" || call.v != "boom" || len(call.stack) == 0 {
+			t.Errorf("Unexpected panic handler call: %s %v", call.id, call.v)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Panic handler was not called")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if tm.HasTask("task") {
+		t.Error("Expected panicked task to be removed")
+	}
+}
+
+func TestStartTaskAt_RunsAtScheduledTime(t *testing.T) {
+	tm := NewTaskManager()
+	ctx := context.Background()
+
+	at := time.Now().Add(50 * time.Millisecond)
+	ran := make(chan time.Time, 1)
+	err := tm.StartTaskAt(ctx, "task", func(ctx context.Context) error {
+		ran <- time.Now()
+		return nil
+	}, at)
+	if err != nil {
+		t.Fatalf("Unexpected error scheduling task: %v", err)
+	}
+
+	if !tm.HasTask("task") {
+		t.Fatal("Expected scheduled task to be registered before it fires")
+	}
+	infos := tm.ListTasks()
+	if len(infos) != 1 || !infos[0].ScheduledAt.Equal(at) || !infos[0].StartedAt.IsZero() {
+		t.Errorf("Expected pending task scheduled at %v, got %+v", at, infos)
+	}
+
+	select {
+	case ranAt := <-ran:
+		if ranAt.Before(at) {
+			t.Errorf("Task ran at %v, before its scheduled time %v", ranAt, at)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Fatal("Scheduled task did not run")
+	}
+}
+
+func TestStartTaskAt_CanceledBeforeFiring(t *testing.T) {
+	tm := NewTaskManager()
+	ctx := context.Background()
+
+	ran := make(chan struct{})
+	_ = tm.StartTaskAt(ctx, "task", func(ctx context.Context) error {
+		close(ran)
+		return nil
+	}, time.Now().Add(50*time.Millisecond))
+
+	if err := tm.StopTaskAndWait("task", 500*time.Millisecond); err != nil {
+		t.Fatalf("Expected scheduled task to stop, got %v", err)
+	}
+
+	select {
+	case <-ran:
+		t.Error("Task function should not run after being canceled")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestGracefulShutdown_Detached(t *testing.T) {
+	tm := NewTaskManager()
+
+	flushed := make(chan struct{})
+	h, _ := tm.StartTask(context.Background(), "flush", func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(30 * time.Millisecond):
+			close(flushed)
+			return nil
+		}
+	}, WithDetached())
+	_, _ = tm.StartTask(context.Background(), "worker", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := tm.GracefulShutdownContext(ctx); err != nil {
+		t.Fatalf("Unexpected shutdown error: %v", err)
+	}
+
+	select {
+	case <-flushed:
+	default:
+		t.Fatal("Expected the shutdown to wait for the detached task to finish")
+	}
+	if info := h.Info(); info.Status != StatusCompleted || !info.Detached {
+		t.Errorf("Expected the detached task to complete, got %+v", info)
+	}
+	if info, _ := tm.Status("worker"); info.Status != StatusCanceled {
+		t.Errorf("Expected the other task to be canceled, got %s", info.Status)
+	}
+}
+
+func TestLastShutdownReport(t *testing.T) {
+	tm := NewTaskManager()
+	if tm.LastShutdownReport() != nil {
+		t.Error("Expected no report before a shutdown")
+	}
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	defer close(release)
+	_, _ = tm.StartTask(ctx, "stubborn", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	_, _ = tm.StartTask(ctx, "slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond)
+		return errors.New("flush failed")
+	})
+	_, _ = tm.StartTask(ctx, "quick", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	time.Sleep(10 * time.Millisecond)
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	err := tm.GracefulShutdownContext(shutdownCtx)
+
+	report := tm.LastShutdownReport()
+	if report == nil || report.Err != err || !report.Ended.After(report.Began) {
+		t.Fatalf("Expected a report of the shutdown, got %+v", report)
+	}
+	ids := []string{}
+	for _, r := range report.Tasks {
+		ids = append(ids, r.ID)
+	}
+	if !slices.Equal(ids, []string{"quick", "slow", "stubborn"}) {
+		t.Fatalf("Expected the tasks in the order they returned, got %v", ids)
+	}
+	quick, slow, stubborn := report.Tasks[0], report.Tasks[1], report.Tasks[2]
+	if quick.MissedDeadline || quick.Status != StatusCanceled {
+		t.Errorf("Expected quick to be canceled in time, got %+v", quick)
+	}
+	if slow.MissedDeadline || slow.Status != StatusFailed || slow.Duration < 20*time.Millisecond {
+		t.Errorf("Expected slow to fail in time after its cleanup, got %+v", slow)
+	}
+	if !stubborn.MissedDeadline || stubborn.Status != StatusRunning || stubborn.Duration < 100*time.Millisecond {
+		t.Errorf("Expected stubborn to miss the deadline, got %+v", stubborn)
+	}
+}
+
+func TestQuiesce(t *testing.T) {
+	tm := NewTaskManager()
+	ctx := context.Background()
+
+	started := make(chan struct{})
+	running, _ := tm.StartTask(ctx, "running", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-started
+
+	if code := doAdmin(t, tm.AdminHandler(), http.MethodPost, "/quiesce", nil); code != http.StatusOK {
+		t.Fatalf("Expected 200 from /quiesce, got %d", code)
+	}
+	if !tm.Quiesced() {
+		t.Error("Expected the manager to be quiesced")
+	}
+	if _, err := tm.StartTask(ctx, "new", func(ctx context.Context) error { return nil }); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Expected ErrShuttingDown, got %v", err)
+	}
+	if info := running.Info(); info.Status != StatusRunning {
+		t.Errorf("Expected the running task to continue, got %s", info.Status)
+	}
+
+	if err := tm.GracefulShutdownContext(ctx); err != nil {
+		t.Errorf("Unexpected shutdown error: %v", err)
+	}
+}
+
+func TestShutdown_Context(t *testing.T) {
+	tm := NewTaskManager()
+
+	release := make(chan struct{})
+	_, _ = tm.StartTask(context.Background(), "stubborn", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var shutdownErr *ShutdownError
+	if err := tm.Shutdown(ctx); !errors.As(err, &shutdownErr) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected a ShutdownError caused by the canceled context, got %v", err)
+	}
+	if len(shutdownErr.Remaining) != 1 || shutdownErr.Remaining[0] != "stubborn" {
+		t.Errorf("Expected [stubborn] still running, got %v", shutdownErr.Remaining)
+	}
+
+	close(release)
+	ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := tm.Shutdown(ctx); err != nil {
+		t.Errorf("Expected the shutdown to complete, got %v", err)
+	}
+}
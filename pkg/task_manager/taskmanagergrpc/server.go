@@ -0,0 +1,129 @@
+// Package taskmanagergrpc serves a TaskManager over gRPC, see
+// taskmanagerpb.TaskManagerService.
+package taskmanagergrpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	taskmanager "github.com/joripage/go_util/pkg/task_manager"
+	"github.com/joripage/go_util/pkg/task_manager/taskmanagerpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const defaultShutdownTimeout = 30 * time.Second
+
+type Server struct {
+	taskmanagerpb.UnimplementedTaskManagerServiceServer
+	tm *taskmanager.TaskManager
+}
+
+func NewServer(tm *taskmanager.TaskManager) *Server {
+	return &Server{tm: tm}
+}
+
+// Register registers a Server for tm on s.
+func Register(s grpc.ServiceRegistrar, tm *taskmanager.TaskManager) {
+	taskmanagerpb.RegisterTaskManagerServiceServer(s, NewServer(tm))
+}
+
+func (s *Server) ListTasks(ctx context.Context, req *taskmanagerpb.ListTasksRequest) (*taskmanagerpb.ListTasksResponse, error) {
+	var infos []taskmanager.TaskInfo
+	if req.GetTag() != "" {
+		infos = s.tm.ListTasksByTag(req.GetTag())
+	} else {
+		infos = s.tm.ListTasks()
+	}
+
+	resp := &taskmanagerpb.ListTasksResponse{
+		Tasks: make([]*taskmanagerpb.Task, 0, len(infos)),
+	}
+	for _, info := range infos {
+		resp.Tasks = append(resp.Tasks, toProto(info))
+	}
+	return resp, nil
+}
+
+func (s *Server) StopTask(ctx context.Context, req *taskmanagerpb.StopTaskRequest) (*taskmanagerpb.StopTaskResponse, error) {
+	if !s.tm.StopTaskContext(ctx, req.GetId()) {
+		return nil, status.Error(codes.NotFound, taskmanager.ErrTaskNotFound.Error())
+	}
+	return &taskmanagerpb.StopTaskResponse{}, nil
+}
+
+func (s *Server) StartRegisteredTask(ctx context.Context, req *taskmanagerpb.StartRegisteredTaskRequest) (*taskmanagerpb.StartRegisteredTaskResponse, error) {
+	// the task outlives the call, keep the values (e.g. the trace) but not the
+	// cancellation of the request context
+	err := s.tm.StartRegistered(context.WithoutCancel(ctx), req.GetName(), req.GetParams())
+	switch {
+	case errors.Is(err, taskmanager.ErrTaskNotRegistered):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, taskmanager.ErrInvalidTaskID):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &taskmanagerpb.StartRegisteredTaskResponse{Id: req.GetName()}, nil
+}
+
+func (s *Server) Shutdown(ctx context.Context, req *taskmanagerpb.ShutdownRequest) (*taskmanagerpb.ShutdownResponse, error) {
+	timeout := defaultShutdownTimeout
+	if req.GetTimeout() != nil {
+		if err := req.GetTimeout().CheckValid(); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		timeout = req.GetTimeout().AsDuration()
+	}
+	// the shutdown must not be cut short by the caller going away
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	resp := &taskmanagerpb.ShutdownResponse{}
+	var shutdownErr *taskmanager.ShutdownError
+	if errors.As(s.tm.GracefulShutdownContext(ctx), &shutdownErr) {
+		resp.Remaining = shutdownErr.Remaining
+	}
+	return resp, nil
+}
+
+var statuses = map[taskmanager.TaskStatus]taskmanagerpb.TaskStatus{
+	taskmanager.StatusPending:   taskmanagerpb.TaskStatus_TASK_STATUS_PENDING,
+	taskmanager.StatusRunning:   taskmanagerpb.TaskStatus_TASK_STATUS_RUNNING,
+	taskmanager.StatusCompleted: taskmanagerpb.TaskStatus_TASK_STATUS_COMPLETED,
+	taskmanager.StatusFailed:    taskmanagerpb.TaskStatus_TASK_STATUS_FAILED,
+	taskmanager.StatusCanceled:  taskmanagerpb.TaskStatus_TASK_STATUS_CANCELED,
+	taskmanager.StatusTimedOut:  taskmanagerpb.TaskStatus_TASK_STATUS_TIMED_OUT,
+	taskmanager.StatusAbandoned: taskmanagerpb.TaskStatus_TASK_STATUS_ABANDONED,
+}
+
+func toProto(info taskmanager.TaskInfo) *taskmanagerpb.Task {
+	task := &taskmanagerpb.Task{
+		Id:          info.ID,
+		Status:      statuses[info.Status],
+		CreatedAt:   timestamp(info.CreatedAt),
+		ScheduledAt: timestamp(info.ScheduledAt),
+		StartedAt:   timestamp(info.StartedAt),
+		FinishedAt:  timestamp(info.FinishedAt),
+		Running:     durationpb.New(info.Running),
+		Tags:        info.Tags,
+		Priority:    int32(info.Priority),
+		Metadata:    info.Metadata,
+		RunId:       info.RunID,
+		ParentId:    info.ParentID,
+	}
+	if info.Err != nil {
+		task.Error = info.Err.Error()
+	}
+	return task
+}
+
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,93 @@
+package taskmanagergrpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	taskmanager "github.com/joripage/go_util/pkg/task_manager"
+	"github.com/joripage/go_util/pkg/task_manager/taskmanagerpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func newClient(t *testing.T, tm *taskmanager.TaskManager) taskmanagerpb.TaskManagerServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	Register(srv, tm)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error dialing: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return taskmanagerpb.NewTaskManagerServiceClient(conn)
+}
+
+func TestServer_StartListStop(t *testing.T) {
+	tm := taskmanager.NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+	client := newClient(t, tm)
+	ctx := context.Background()
+
+	_ = tm.Register("sync", func(ctx context.Context, params map[string]string) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	resp, err := client.StartRegisteredTask(ctx, &taskmanagerpb.StartRegisteredTaskRequest{Name: "sync"})
+	if err != nil || resp.GetId() != "sync" {
+		t.Fatalf("Expected task sync to start, got %v, %v", resp, err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	list, err := client.ListTasks(ctx, &taskmanagerpb.ListTasksRequest{})
+	if err != nil {
+		t.Fatalf("Unexpected error listing tasks: %v", err)
+	}
+	if len(list.GetTasks()) != 1 || list.GetTasks()[0].GetId() != "sync" || list.GetTasks()[0].GetStatus() != taskmanagerpb.TaskStatus_TASK_STATUS_RUNNING {
+		t.Fatalf("Expected running task sync, got %v", list.GetTasks())
+	}
+
+	if _, err := client.StopTask(ctx, &taskmanagerpb.StopTaskRequest{Id: "sync"}); err != nil {
+		t.Fatalf("Unexpected error stopping task: %v", err)
+	}
+	if _, err := client.StopTask(ctx, &taskmanagerpb.StopTaskRequest{Id: "sync"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound stopping twice, got %v", err)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	tm := taskmanager.NewTaskManager()
+	client := newClient(t, tm)
+	ctx := context.Background()
+
+	_, err := client.StartRegisteredTask(ctx, &taskmanagerpb.StartRegisteredTaskRequest{Name: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound for unregistered task, got %v", err)
+	}
+
+	_, err = client.Shutdown(ctx, &taskmanagerpb.ShutdownRequest{Timeout: &durationpb.Duration{Seconds: 1, Nanos: -1}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an invalid timeout, got %v", err)
+	}
+	resp, err := client.Shutdown(ctx, &taskmanagerpb.ShutdownRequest{Timeout: durationpb.New(100 * time.Millisecond)})
+	if err != nil {
+		t.Errorf("Unexpected error from shutdown: %v", err)
+	} else if len(resp.GetRemaining()) != 0 {
+		t.Errorf("Expected no remaining task, got %v", resp.GetRemaining())
+	}
+}
@@ -0,0 +1,5 @@
+// Package taskmanagerpb holds the protobuf and gRPC definitions of the task
+// manager control service.
+package taskmanagerpb
+
+//go:generate buf generate
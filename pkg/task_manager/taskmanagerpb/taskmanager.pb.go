@@ -0,0 +1,726 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: taskmanager.proto
+
+package taskmanagerpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type TaskStatus int32
+
+const (
+	TaskStatus_TASK_STATUS_UNSPECIFIED TaskStatus = 0
+	TaskStatus_TASK_STATUS_PENDING     TaskStatus = 1
+	TaskStatus_TASK_STATUS_RUNNING     TaskStatus = 2
+	TaskStatus_TASK_STATUS_COMPLETED   TaskStatus = 3
+	TaskStatus_TASK_STATUS_FAILED      TaskStatus = 4
+	TaskStatus_TASK_STATUS_CANCELED    TaskStatus = 5
+	TaskStatus_TASK_STATUS_TIMED_OUT   TaskStatus = 6
+	TaskStatus_TASK_STATUS_ABANDONED   TaskStatus = 7
+)
+
+// Enum value maps for TaskStatus.
+var (
+	TaskStatus_name = map[int32]string{
+		0: "TASK_STATUS_UNSPECIFIED",
+		1: "TASK_STATUS_PENDING",
+		2: "TASK_STATUS_RUNNING",
+		3: "TASK_STATUS_COMPLETED",
+		4: "TASK_STATUS_FAILED",
+		5: "TASK_STATUS_CANCELED",
+		6: "TASK_STATUS_TIMED_OUT",
+		7: "TASK_STATUS_ABANDONED",
+	}
+	TaskStatus_value = map[string]int32{
+		"TASK_STATUS_UNSPECIFIED": 0,
+		"TASK_STATUS_PENDING":     1,
+		"TASK_STATUS_RUNNING":     2,
+		"TASK_STATUS_COMPLETED":   3,
+		"TASK_STATUS_FAILED":      4,
+		"TASK_STATUS_CANCELED":    5,
+		"TASK_STATUS_TIMED_OUT":   6,
+		"TASK_STATUS_ABANDONED":   7,
+	}
+)
+
+func (x TaskStatus) Enum() *TaskStatus {
+	p := new(TaskStatus)
+	*p = x
+	return p
+}
+
+func (x TaskStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TaskStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_taskmanager_proto_enumTypes[0].Descriptor()
+}
+
+func (TaskStatus) Type() protoreflect.EnumType {
+	return &file_taskmanager_proto_enumTypes[0]
+}
+
+func (x TaskStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TaskStatus.Descriptor instead.
+func (TaskStatus) EnumDescriptor() ([]byte, []int) {
+	return file_taskmanager_proto_rawDescGZIP(), []int{0}
+}
+
+type Task struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Status        TaskStatus             `protobuf:"varint,2,opt,name=status,proto3,enum=taskmanager.v1.TaskStatus" json:"status,omitempty"`
+	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	ScheduledAt   *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=scheduled_at,json=scheduledAt,proto3" json:"scheduled_at,omitempty"`
+	StartedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	FinishedAt    *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"`
+	Running       *durationpb.Duration   `protobuf:"bytes,8,opt,name=running,proto3" json:"running,omitempty"`
+	Tags          []string               `protobuf:"bytes,9,rep,name=tags,proto3" json:"tags,omitempty"`
+	Priority      int32                  `protobuf:"varint,10,opt,name=priority,proto3" json:"priority,omitempty"`
+	Metadata      map[string]string      `protobuf:"bytes,11,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	RunId         uint64                 `protobuf:"varint,12,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	ParentId      string                 `protobuf:"bytes,13,opt,name=parent_id,json=parentId,proto3" json:"parent_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Task) Reset() {
+	*x = Task{}
+	mi := &file_taskmanager_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Task) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Task) ProtoMessage() {}
+
+func (x *Task) ProtoReflect() protoreflect.Message {
+	mi := &file_taskmanager_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Task.ProtoReflect.Descriptor instead.
+func (*Task) Descriptor() ([]byte, []int) {
+	return file_taskmanager_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Task) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Task) GetStatus() TaskStatus {
+	if x != nil {
+		return x.Status
+	}
+	return TaskStatus_TASK_STATUS_UNSPECIFIED
+}
+
+func (x *Task) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Task) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Task) GetScheduledAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ScheduledAt
+	}
+	return nil
+}
+
+func (x *Task) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *Task) GetFinishedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return nil
+}
+
+func (x *Task) GetRunning() *durationpb.Duration {
+	if x != nil {
+		return x.Running
+	}
+	return nil
+}
+
+func (x *Task) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Task) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+func (x *Task) GetMetadata() map[string]string {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *Task) GetRunId() uint64 {
+	if x != nil {
+		return x.RunId
+	}
+	return 0
+}
+
+func (x *Task) GetParentId() string {
+	if x != nil {
+		return x.ParentId
+	}
+	return ""
+}
+
+type ListTasksRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// tag only lists tasks labeled with it when set.
+	Tag           string `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTasksRequest) Reset() {
+	*x = ListTasksRequest{}
+	mi := &file_taskmanager_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTasksRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTasksRequest) ProtoMessage() {}
+
+func (x *ListTasksRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_taskmanager_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTasksRequest.ProtoReflect.Descriptor instead.
+func (*ListTasksRequest) Descriptor() ([]byte, []int) {
+	return file_taskmanager_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ListTasksRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+type ListTasksResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tasks         []*Task                `protobuf:"bytes,1,rep,name=tasks,proto3" json:"tasks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTasksResponse) Reset() {
+	*x = ListTasksResponse{}
+	mi := &file_taskmanager_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTasksResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTasksResponse) ProtoMessage() {}
+
+func (x *ListTasksResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_taskmanager_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTasksResponse.ProtoReflect.Descriptor instead.
+func (*ListTasksResponse) Descriptor() ([]byte, []int) {
+	return file_taskmanager_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListTasksResponse) GetTasks() []*Task {
+	if x != nil {
+		return x.Tasks
+	}
+	return nil
+}
+
+type StopTaskRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopTaskRequest) Reset() {
+	*x = StopTaskRequest{}
+	mi := &file_taskmanager_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopTaskRequest) ProtoMessage() {}
+
+func (x *StopTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_taskmanager_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopTaskRequest.ProtoReflect.Descriptor instead.
+func (*StopTaskRequest) Descriptor() ([]byte, []int) {
+	return file_taskmanager_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *StopTaskRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type StopTaskResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StopTaskResponse) Reset() {
+	*x = StopTaskResponse{}
+	mi := &file_taskmanager_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StopTaskResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StopTaskResponse) ProtoMessage() {}
+
+func (x *StopTaskResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_taskmanager_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StopTaskResponse.ProtoReflect.Descriptor instead.
+func (*StopTaskResponse) Descriptor() ([]byte, []int) {
+	return file_taskmanager_proto_rawDescGZIP(), []int{4}
+}
+
+type StartRegisteredTaskRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Params        map[string]string      `protobuf:"bytes,2,rep,name=params,proto3" json:"params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartRegisteredTaskRequest) Reset() {
+	*x = StartRegisteredTaskRequest{}
+	mi := &file_taskmanager_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartRegisteredTaskRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartRegisteredTaskRequest) ProtoMessage() {}
+
+func (x *StartRegisteredTaskRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_taskmanager_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartRegisteredTaskRequest.ProtoReflect.Descriptor instead.
+func (*StartRegisteredTaskRequest) Descriptor() ([]byte, []int) {
+	return file_taskmanager_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *StartRegisteredTaskRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *StartRegisteredTaskRequest) GetParams() map[string]string {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+type StartRegisteredTaskResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// id of the started task, the registered name.
+	Id            string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartRegisteredTaskResponse) Reset() {
+	*x = StartRegisteredTaskResponse{}
+	mi := &file_taskmanager_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartRegisteredTaskResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartRegisteredTaskResponse) ProtoMessage() {}
+
+func (x *StartRegisteredTaskResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_taskmanager_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartRegisteredTaskResponse.ProtoReflect.Descriptor instead.
+func (*StartRegisteredTaskResponse) Descriptor() ([]byte, []int) {
+	return file_taskmanager_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *StartRegisteredTaskResponse) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type ShutdownRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// timeout to wait for the tasks, 30s when unset.
+	Timeout       *durationpb.Duration `protobuf:"bytes,1,opt,name=timeout,proto3" json:"timeout,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShutdownRequest) Reset() {
+	*x = ShutdownRequest{}
+	mi := &file_taskmanager_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShutdownRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShutdownRequest) ProtoMessage() {}
+
+func (x *ShutdownRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_taskmanager_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShutdownRequest.ProtoReflect.Descriptor instead.
+func (*ShutdownRequest) Descriptor() ([]byte, []int) {
+	return file_taskmanager_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ShutdownRequest) GetTimeout() *durationpb.Duration {
+	if x != nil {
+		return x.Timeout
+	}
+	return nil
+}
+
+type ShutdownResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// ids of the tasks still running once the timeout elapsed.
+	Remaining     []string `protobuf:"bytes,1,rep,name=remaining,proto3" json:"remaining,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ShutdownResponse) Reset() {
+	*x = ShutdownResponse{}
+	mi := &file_taskmanager_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ShutdownResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ShutdownResponse) ProtoMessage() {}
+
+func (x *ShutdownResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_taskmanager_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ShutdownResponse.ProtoReflect.Descriptor instead.
+func (*ShutdownResponse) Descriptor() ([]byte, []int) {
+	return file_taskmanager_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ShutdownResponse) GetRemaining() []string {
+	if x != nil {
+		return x.Remaining
+	}
+	return nil
+}
+
+var File_taskmanager_proto protoreflect.FileDescriptor
+
+const file_taskmanager_proto_rawDesc = "" +
+	"\n" +
+	"\x11taskmanager.proto\x12\x0etaskmanager.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xe8\x04\n" +
+	"\x04Task\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x122\n" +
+	"\x06status\x18\x02 \x01(\x0e2\x1a.taskmanager.v1.TaskStatusR\x06status\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x129\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12=\n" +
+	"\fscheduled_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\vscheduledAt\x129\n" +
+	"\n" +
+	"started_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12;\n" +
+	"\vfinished_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"finishedAt\x123\n" +
+	"\arunning\x18\b \x01(\v2\x19.google.protobuf.DurationR\arunning\x12\x12\n" +
+	"\x04tags\x18\t \x03(\tR\x04tags\x12\x1a\n" +
+	"\bpriority\x18\n" +
+	" \x01(\x05R\bpriority\x12>\n" +
+	"\bmetadata\x18\v \x03(\v2\".taskmanager.v1.Task.MetadataEntryR\bmetadata\x12\x15\n" +
+	"\x06run_id\x18\f \x01(\x04R\x05runId\x12\x1b\n" +
+	"\tparent_id\x18\r \x01(\tR\bparentId\x1a;\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"$\n" +
+	"\x10ListTasksRequest\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\"?\n" +
+	"\x11ListTasksResponse\x12*\n" +
+	"\x05tasks\x18\x01 \x03(\v2\x14.taskmanager.v1.TaskR\x05tasks\"!\n" +
+	"\x0fStopTaskRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x12\n" +
+	"\x10StopTaskResponse\"\xbb\x01\n" +
+	"\x1aStartRegisteredTaskRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12N\n" +
+	"\x06params\x18\x02 \x03(\v26.taskmanager.v1.StartRegisteredTaskRequest.ParamsEntryR\x06params\x1a9\n" +
+	"\vParamsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"-\n" +
+	"\x1bStartRegisteredTaskResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"F\n" +
+	"\x0fShutdownRequest\x123\n" +
+	"\atimeout\x18\x01 \x01(\v2\x19.google.protobuf.DurationR\atimeout\"0\n" +
+	"\x10ShutdownResponse\x12\x1c\n" +
+	"\tremaining\x18\x01 \x03(\tR\tremaining*\xde\x01\n" +
+	"\n" +
+	"TaskStatus\x12\x1b\n" +
+	"\x17TASK_STATUS_UNSPECIFIED\x10\x00\x12\x17\n" +
+	"\x13TASK_STATUS_PENDING\x10\x01\x12\x17\n" +
+	"\x13TASK_STATUS_RUNNING\x10\x02\x12\x19\n" +
+	"\x15TASK_STATUS_COMPLETED\x10\x03\x12\x16\n" +
+	"\x12TASK_STATUS_FAILED\x10\x04\x12\x18\n" +
+	"\x14TASK_STATUS_CANCELED\x10\x05\x12\x19\n" +
+	"\x15TASK_STATUS_TIMED_OUT\x10\x06\x12\x19\n" +
+	"\x15TASK_STATUS_ABANDONED\x10\a2\xf4\x02\n" +
+	"\x12TaskManagerService\x12P\n" +
+	"\tListTasks\x12 .taskmanager.v1.ListTasksRequest\x1a!.taskmanager.v1.ListTasksResponse\x12M\n" +
+	"\bStopTask\x12\x1f.taskmanager.v1.StopTaskRequest\x1a .taskmanager.v1.StopTaskResponse\x12n\n" +
+	"\x13StartRegisteredTask\x12*.taskmanager.v1.StartRegisteredTaskRequest\x1a+.taskmanager.v1.StartRegisteredTaskResponse\x12M\n" +
+	"\bShutdown\x12\x1f.taskmanager.v1.ShutdownRequest\x1a .taskmanager.v1.ShutdownResponseB<Z:github.com/joripage/go_util/pkg/task_manager/taskmanagerpbb\x06proto3"
+
+var (
+	file_taskmanager_proto_rawDescOnce sync.Once
+	file_taskmanager_proto_rawDescData []byte
+)
+
+func file_taskmanager_proto_rawDescGZIP() []byte {
+	file_taskmanager_proto_rawDescOnce.Do(func() {
+		file_taskmanager_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_taskmanager_proto_rawDesc), len(file_taskmanager_proto_rawDesc)))
+	})
+	return file_taskmanager_proto_rawDescData
+}
+
+var file_taskmanager_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_taskmanager_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_taskmanager_proto_goTypes = []any{
+	(TaskStatus)(0),                     // 0: taskmanager.v1.TaskStatus
+	(*Task)(nil),                        // 1: taskmanager.v1.Task
+	(*ListTasksRequest)(nil),            // 2: taskmanager.v1.ListTasksRequest
+	(*ListTasksResponse)(nil),           // 3: taskmanager.v1.ListTasksResponse
+	(*StopTaskRequest)(nil),             // 4: taskmanager.v1.StopTaskRequest
+	(*StopTaskResponse)(nil),            // 5: taskmanager.v1.StopTaskResponse
+	(*StartRegisteredTaskRequest)(nil),  // 6: taskmanager.v1.StartRegisteredTaskRequest
+	(*StartRegisteredTaskResponse)(nil), // 7: taskmanager.v1.StartRegisteredTaskResponse
+	(*ShutdownRequest)(nil),             // 8: taskmanager.v1.ShutdownRequest
+	(*ShutdownResponse)(nil),            // 9: taskmanager.v1.ShutdownResponse
+	nil,                                 // 10: taskmanager.v1.Task.MetadataEntry
+	nil,                                 // 11: taskmanager.v1.StartRegisteredTaskRequest.ParamsEntry
+	(*timestamppb.Timestamp)(nil),       // 12: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),         // 13: google.protobuf.Duration
+}
+var file_taskmanager_proto_depIdxs = []int32{
+	0,  // 0: taskmanager.v1.Task.status:type_name -> taskmanager.v1.TaskStatus
+	12, // 1: taskmanager.v1.Task.created_at:type_name -> google.protobuf.Timestamp
+	12, // 2: taskmanager.v1.Task.scheduled_at:type_name -> google.protobuf.Timestamp
+	12, // 3: taskmanager.v1.Task.started_at:type_name -> google.protobuf.Timestamp
+	12, // 4: taskmanager.v1.Task.finished_at:type_name -> google.protobuf.Timestamp
+	13, // 5: taskmanager.v1.Task.running:type_name -> google.protobuf.Duration
+	10, // 6: taskmanager.v1.Task.metadata:type_name -> taskmanager.v1.Task.MetadataEntry
+	1,  // 7: taskmanager.v1.ListTasksResponse.tasks:type_name -> taskmanager.v1.Task
+	11, // 8: taskmanager.v1.StartRegisteredTaskRequest.params:type_name -> taskmanager.v1.StartRegisteredTaskRequest.ParamsEntry
+	13, // 9: taskmanager.v1.ShutdownRequest.timeout:type_name -> google.protobuf.Duration
+	2,  // 10: taskmanager.v1.TaskManagerService.ListTasks:input_type -> taskmanager.v1.ListTasksRequest
+	4,  // 11: taskmanager.v1.TaskManagerService.StopTask:input_type -> taskmanager.v1.StopTaskRequest
+	6,  // 12: taskmanager.v1.TaskManagerService.StartRegisteredTask:input_type -> taskmanager.v1.StartRegisteredTaskRequest
+	8,  // 13: taskmanager.v1.TaskManagerService.Shutdown:input_type -> taskmanager.v1.ShutdownRequest
+	3,  // 14: taskmanager.v1.TaskManagerService.ListTasks:output_type -> taskmanager.v1.ListTasksResponse
+	5,  // 15: taskmanager.v1.TaskManagerService.StopTask:output_type -> taskmanager.v1.StopTaskResponse
+	7,  // 16: taskmanager.v1.TaskManagerService.StartRegisteredTask:output_type -> taskmanager.v1.StartRegisteredTaskResponse
+	9,  // 17: taskmanager.v1.TaskManagerService.Shutdown:output_type -> taskmanager.v1.ShutdownResponse
+	14, // [14:18] is the sub-list for method output_type
+	10, // [10:14] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_taskmanager_proto_init() }
+func file_taskmanager_proto_init() {
+	if File_taskmanager_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_taskmanager_proto_rawDesc), len(file_taskmanager_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_taskmanager_proto_goTypes,
+		DependencyIndexes: file_taskmanager_proto_depIdxs,
+		EnumInfos:         file_taskmanager_proto_enumTypes,
+		MessageInfos:      file_taskmanager_proto_msgTypes,
+	}.Build()
+	File_taskmanager_proto = out.File
+	file_taskmanager_proto_goTypes = nil
+	file_taskmanager_proto_depIdxs = nil
+}
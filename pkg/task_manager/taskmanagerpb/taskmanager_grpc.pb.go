@@ -0,0 +1,249 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: taskmanager.proto
+
+package taskmanagerpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	TaskManagerService_ListTasks_FullMethodName           = "/taskmanager.v1.TaskManagerService/ListTasks"
+	TaskManagerService_StopTask_FullMethodName            = "/taskmanager.v1.TaskManagerService/StopTask"
+	TaskManagerService_StartRegisteredTask_FullMethodName = "/taskmanager.v1.TaskManagerService/StartRegisteredTask"
+	TaskManagerService_Shutdown_FullMethodName            = "/taskmanager.v1.TaskManagerService/Shutdown"
+)
+
+// TaskManagerServiceClient is the client API for TaskManagerService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// TaskManagerService controls the background tasks of a process remotely.
+// Authentication and authorization are left to interceptors.
+type TaskManagerServiceClient interface {
+	// ListTasks returns the running and pending tasks.
+	ListTasks(ctx context.Context, in *ListTasksRequest, opts ...grpc.CallOption) (*ListTasksResponse, error)
+	// StopTask stops a task, NOT_FOUND if it is not running.
+	StopTask(ctx context.Context, in *StopTaskRequest, opts ...grpc.CallOption) (*StopTaskResponse, error)
+	// StartRegisteredTask starts a task function registered by name.
+	StartRegisteredTask(ctx context.Context, in *StartRegisteredTaskRequest, opts ...grpc.CallOption) (*StartRegisteredTaskResponse, error)
+	// Shutdown stops every task and waits for them up to a timeout.
+	Shutdown(ctx context.Context, in *ShutdownRequest, opts ...grpc.CallOption) (*ShutdownResponse, error)
+}
+
+type taskManagerServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTaskManagerServiceClient(cc grpc.ClientConnInterface) TaskManagerServiceClient {
+	return &taskManagerServiceClient{cc}
+}
+
+func (c *taskManagerServiceClient) ListTasks(ctx context.Context, in *ListTasksRequest, opts ...grpc.CallOption) (*ListTasksResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListTasksResponse)
+	err := c.cc.Invoke(ctx, TaskManagerService_ListTasks_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskManagerServiceClient) StopTask(ctx context.Context, in *StopTaskRequest, opts ...grpc.CallOption) (*StopTaskResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StopTaskResponse)
+	err := c.cc.Invoke(ctx, TaskManagerService_StopTask_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskManagerServiceClient) StartRegisteredTask(ctx context.Context, in *StartRegisteredTaskRequest, opts ...grpc.CallOption) (*StartRegisteredTaskResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StartRegisteredTaskResponse)
+	err := c.cc.Invoke(ctx, TaskManagerService_StartRegisteredTask_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *taskManagerServiceClient) Shutdown(ctx context.Context, in *ShutdownRequest, opts ...grpc.CallOption) (*ShutdownResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ShutdownResponse)
+	err := c.cc.Invoke(ctx, TaskManagerService_Shutdown_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TaskManagerServiceServer is the server API for TaskManagerService service.
+// All implementations must embed UnimplementedTaskManagerServiceServer
+// for forward compatibility.
+//
+// TaskManagerService controls the background tasks of a process remotely.
+// Authentication and authorization are left to interceptors.
+type TaskManagerServiceServer interface {
+	// ListTasks returns the running and pending tasks.
+	ListTasks(context.Context, *ListTasksRequest) (*ListTasksResponse, error)
+	// StopTask stops a task, NOT_FOUND if it is not running.
+	StopTask(context.Context, *StopTaskRequest) (*StopTaskResponse, error)
+	// StartRegisteredTask starts a task function registered by name.
+	StartRegisteredTask(context.Context, *StartRegisteredTaskRequest) (*StartRegisteredTaskResponse, error)
+	// Shutdown stops every task and waits for them up to a timeout.
+	Shutdown(context.Context, *ShutdownRequest) (*ShutdownResponse, error)
+	mustEmbedUnimplementedTaskManagerServiceServer()
+}
+
+// UnimplementedTaskManagerServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTaskManagerServiceServer struct{}
+
+func (UnimplementedTaskManagerServiceServer) ListTasks(context.Context, *ListTasksRequest) (*ListTasksResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListTasks not implemented")
+}
+func (UnimplementedTaskManagerServiceServer) StopTask(context.Context, *StopTaskRequest) (*StopTaskResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method StopTask not implemented")
+}
+func (UnimplementedTaskManagerServiceServer) StartRegisteredTask(context.Context, *StartRegisteredTaskRequest) (*StartRegisteredTaskResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method StartRegisteredTask not implemented")
+}
+func (UnimplementedTaskManagerServiceServer) Shutdown(context.Context, *ShutdownRequest) (*ShutdownResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Shutdown not implemented")
+}
+func (UnimplementedTaskManagerServiceServer) mustEmbedUnimplementedTaskManagerServiceServer() {}
+func (UnimplementedTaskManagerServiceServer) testEmbeddedByValue()                            {}
+
+// UnsafeTaskManagerServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TaskManagerServiceServer will
+// result in compilation errors.
+type UnsafeTaskManagerServiceServer interface {
+	mustEmbedUnimplementedTaskManagerServiceServer()
+}
+
+func RegisterTaskManagerServiceServer(s grpc.ServiceRegistrar, srv TaskManagerServiceServer) {
+	// If the following call panics, it indicates UnimplementedTaskManagerServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&TaskManagerService_ServiceDesc, srv)
+}
+
+func _TaskManagerService_ListTasks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTasksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskManagerServiceServer).ListTasks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskManagerService_ListTasks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskManagerServiceServer).ListTasks(ctx, req.(*ListTasksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskManagerService_StopTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StopTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskManagerServiceServer).StopTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskManagerService_StopTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskManagerServiceServer).StopTask(ctx, req.(*StopTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskManagerService_StartRegisteredTask_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartRegisteredTaskRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskManagerServiceServer).StartRegisteredTask(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskManagerService_StartRegisteredTask_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskManagerServiceServer).StartRegisteredTask(ctx, req.(*StartRegisteredTaskRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TaskManagerService_Shutdown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShutdownRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TaskManagerServiceServer).Shutdown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TaskManagerService_Shutdown_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TaskManagerServiceServer).Shutdown(ctx, req.(*ShutdownRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TaskManagerService_ServiceDesc is the grpc.ServiceDesc for TaskManagerService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TaskManagerService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "taskmanager.v1.TaskManagerService",
+	HandlerType: (*TaskManagerServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListTasks",
+			Handler:    _TaskManagerService_ListTasks_Handler,
+		},
+		{
+			MethodName: "StopTask",
+			Handler:    _TaskManagerService_StopTask_Handler,
+		},
+		{
+			MethodName: "StartRegisteredTask",
+			Handler:    _TaskManagerService_StartRegisteredTask_Handler,
+		},
+		{
+			MethodName: "Shutdown",
+			Handler:    _TaskManagerService_Shutdown_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "taskmanager.proto",
+}
@@ -0,0 +1,49 @@
+package taskmanager
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/joripage/go_util/pkg/task_manager"
+
+// WithTracerProvider opens a span from tp for every task run. The span is the
+// root of its own trace and links to the span found in the context the task
+// was started with, since the task usually outlives the caller.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(s *TaskManager) {
+		s.tracer = tp.Tracer(tracerName)
+	}
+}
+
+func (s *TaskManager) startSpan(ctx context.Context, t *task) (context.Context, trace.Span) {
+	opts := []trace.SpanStartOption{
+		trace.WithNewRoot(),
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			attribute.String("task.id", t.id),
+			attribute.StringSlice("task.tags", t.tags),
+		),
+	}
+	if link := trace.LinkFromContext(t.parent); link.SpanContext.IsValid() {
+		opts = append(opts, trace.WithLinks(link))
+	}
+	return s.tracer.Start(ctx, "task.run", opts...)
+}
+
+func endSpan(span trace.Span, err error) {
+	status := statusOf(err)
+	span.SetAttributes(attribute.String("task.status", status.String()))
+
+	switch status {
+	case StatusCompleted:
+		span.SetStatus(codes.Ok, "")
+	case StatusFailed, StatusTimedOut:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,62 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWithTracerProvider_SpanPerTask(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tm := NewTaskManager(WithTracerProvider(tp))
+
+	callerCtx, caller := tp.Tracer("test").Start(context.Background(), "request")
+	boom := errors.New("boom")
+
+	var taskSpanCtx context.Context
+	_, _ = tm.StartTask(callerCtx, "task", func(ctx context.Context) error {
+		taskSpanCtx = ctx
+		return boom
+	}, WithTags("sync"))
+	_, _ = tm.WaitTask(context.Background(), "task")
+	caller.End()
+
+	var span sdktrace.ReadOnlySpan
+	for _, s := range recorder.Ended() {
+		if s.Name() == "task.run" {
+			span = s
+		}
+	}
+	if span == nil {
+		t.Fatal("Expected a task.run span")
+	}
+
+	if span.Parent().IsValid() {
+		t.Error("Expected task span to be a new root")
+	}
+	if links := span.Links(); len(links) != 1 || links[0].SpanContext.SpanID() != caller.SpanContext().SpanID() {
+		t.Errorf("Expected a link to the caller span, got %+v", links)
+	}
+	if span.Status().Code != codes.Error || span.Status().Description != "boom" {
+		t.Errorf("Expected error status, got %+v", span.Status())
+	}
+
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs["task.id"].AsString() != "task" || attrs["task.status"].AsString() != "failed" {
+		t.Errorf("Unexpected span attributes: %v", span.Attributes())
+	}
+
+	if got := trace.SpanContextFromContext(taskSpanCtx).SpanID(); got != span.SpanContext().SpanID() {
+		t.Errorf("Expected task context to carry the task span, got %s", got)
+	}
+}
@@ -0,0 +1,120 @@
+package taskmanager
+
+import (
+	"context"
+	"maps"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// Usage is the approximate resource usage of a task, see WithUsageTracking
+// and ReportGauge.
+type Usage struct {
+	// CPUTime is the user CPU time of the process while the task was the only
+	// one running.
+	CPUTime time.Duration
+	// Allocated is the heap memory allocated by the process while the task
+	// was the only one running.
+	Allocated uint64
+	// Gauges are the values reported by the task through ReportGauge.
+	Gauges map[string]float64
+}
+
+const (
+	metricCPU    = "/cpu/classes/user:cpu-seconds"
+	metricAllocs = "/gc/heap/allocs:bytes"
+)
+
+// WithUsageTracking attributes the CPU time and heap allocations of the
+// process to the running task whenever it is the only one, reported in
+// TaskInfo.Usage. Go doesn't account resources per goroutine, so the usage of
+// overlapping tasks is not attributed; it identifies the heavy jobs of
+// services mostly running one at a time. The runtime metrics are read when a
+// task starts or returns and when tasks are listed.
+func WithUsageTracking() Option {
+	return func(s *TaskManager) {
+		s.usage = &usageTracker{
+			running: map[*task]struct{}{},
+			samples: []metrics.Sample{{Name: metricCPU}, {Name: metricAllocs}},
+		}
+	}
+}
+
+// ReportGauge sets the gauge name of the task owning ctx to value, e.g. the
+// size of its in-memory buffer, reported in TaskInfo.Usage.Gauges. It is a
+// no-op outside a managed task.
+func ReportGauge(ctx context.Context, name string, value float64) {
+	t, ok := taskFromContext(ctx)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.usage.Gauges == nil {
+		t.usage.Gauges = map[string]float64{}
+	}
+	t.usage.Gauges[name] = value
+}
+
+type usageTracker struct {
+	mu      sync.Mutex
+	running map[*task]struct{}
+	samples []metrics.Sample
+	cpu     float64
+	allocs  uint64
+}
+
+// sample attributes the usage since the previous sample to the only running
+// task, if there is one, then applies change to the running tasks.
+func (u *usageTracker) sample(change func(running map[*task]struct{})) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	metrics.Read(u.samples)
+	var cpu float64
+	var allocs uint64
+	if u.samples[0].Value.Kind() == metrics.KindFloat64 {
+		cpu = u.samples[0].Value.Float64()
+	}
+	if u.samples[1].Value.Kind() == metrics.KindUint64 {
+		allocs = u.samples[1].Value.Uint64()
+	}
+	if len(u.running) == 1 {
+		for t := range u.running {
+			t.addUsage(time.Duration((cpu-u.cpu)*float64(time.Second)), allocs-u.allocs)
+		}
+	}
+	u.cpu, u.allocs = cpu, allocs
+
+	if change != nil {
+		change(u.running)
+	}
+}
+
+func (u *usageTracker) started(t *task) {
+	u.sample(func(running map[*task]struct{}) { running[t] = struct{}{} })
+}
+
+func (u *usageTracker) finished(t *task) {
+	u.sample(func(running map[*task]struct{}) { delete(running, t) })
+}
+
+// refreshUsage brings the usage of a task running alone up to date.
+func (s *TaskManager) refreshUsage() {
+	if s.usage != nil {
+		s.usage.sample(nil)
+	}
+}
+
+func (t *task) addUsage(cpu time.Duration, allocated uint64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.usage.CPUTime += max(cpu, 0)
+	t.usage.Allocated += allocated
+}
+
+func (u Usage) clone() Usage {
+	u.Gauges = maps.Clone(u.Gauges)
+	return u
+}
@@ -0,0 +1,62 @@
+package taskmanager
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+var usageSink []byte
+
+func TestWithUsageTracking(t *testing.T) {
+	tm := NewTaskManager(WithUsageTracking())
+
+	h, _ := tm.StartTask(context.Background(), "heavy", func(ctx context.Context) error {
+		for end := time.Now().Add(50 * time.Millisecond); time.Now().Before(end); {
+			usageSink = make([]byte, 1<<20)
+		}
+		ReportGauge(ctx, "buffer", 42)
+		return nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_ = h.Wait(ctx)
+
+	usage := h.Info().Usage
+	if usage.CPUTime <= 0 {
+		t.Errorf("Expected CPU time attributed to the task, got %v", usage.CPUTime)
+	}
+	if usage.Allocated < 1<<20 {
+		t.Errorf("Expected the allocations attributed to the task, got %d bytes", usage.Allocated)
+	}
+	if usage.Gauges["buffer"] != 42 {
+		t.Errorf("Expected the reported gauge, got %v", usage.Gauges)
+	}
+}
+
+func TestWithUsageTracking_Overlapping(t *testing.T) {
+	tm := NewTaskManager(WithUsageTracking())
+
+	release := make(chan struct{})
+	first, _ := tm.StartTask(context.Background(), "first", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	second, _ := tm.StartTask(context.Background(), "second", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	time.Sleep(10 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		usageSink = make([]byte, 1<<20)
+	}
+	close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	_ = first.Wait(ctx)
+	_ = second.Wait(ctx)
+	if a, b := first.Info().Usage.Allocated, second.Info().Usage.Allocated; a >= 10<<20 || b >= 10<<20 {
+		t.Errorf("Expected no attribution while both tasks ran, got %d and %d bytes", a, b)
+	}
+}
@@ -0,0 +1,102 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// WaitAll blocks until all the tasks ids have returned and yields the errors
+// of the failed ones. An id that is not running refers to its last finished
+// run. It returns ErrTaskNotFound, without waiting, if an id is unknown, and
+// ctx.Err() if ctx is done first.
+func (s *TaskManager) WaitAll(ctx context.Context, ids ...string) error {
+	tasks, err := s.lookupAll(ids)
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for _, t := range tasks {
+		select {
+		case <-t.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if err := t.result(); err != nil {
+			errs = append(errs, fmt.Errorf("task %s: %w", t.id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WaitAny blocks until one of the tasks ids has returned and yields its ID
+// and error. Like WaitAll, it uses the last finished run of an id that is not
+// running, so it returns at once if one of them has already finished.
+func (s *TaskManager) WaitAny(ctx context.Context, ids ...string) (string, error) {
+	if len(ids) == 0 {
+		return "", ErrTaskNotFound
+	}
+	tasks, err := s.lookupAll(ids)
+	if err != nil {
+		return "", err
+	}
+
+	for _, t := range tasks {
+		select {
+		case <-t.done:
+			return t.id, t.result()
+		default:
+		}
+	}
+
+	first := make(chan *task, len(tasks))
+	stop := make(chan struct{})
+	defer close(stop)
+	for _, t := range tasks {
+		go func() {
+			select {
+			case <-t.done:
+				first <- t
+			case <-stop:
+			}
+		}()
+	}
+
+	select {
+	case t := <-first:
+		return t.id, t.result()
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (s *TaskManager) lookupAll(ids []string) ([]*task, error) {
+	tasks := make([]*task, 0, len(ids))
+	for _, id := range ids {
+		t, ok := s.lookup(id)
+		if !ok {
+			return nil, fmt.Errorf("task %s: %w", id, ErrTaskNotFound)
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, nil
+}
+
+// lookup returns the running task id, or its last finished run.
+func (s *TaskManager) lookup(id string) (*task, bool) {
+	if v, ok := s.tasks.Load(id); ok {
+		return v.(*task), true
+	}
+	if info, ok := s.finished.get(id); ok {
+		return finishedTask(info), true
+	}
+	return nil, false
+}
+
+// finishedTask stands for the finished run info, to be waited for.
+func finishedTask(info TaskInfo) *task {
+	t := &task{id: info.ID, err: info.Err, done: make(chan struct{})}
+	close(t.done)
+	return t
+}
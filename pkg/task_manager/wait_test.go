@@ -0,0 +1,77 @@
+package taskmanager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitAll(t *testing.T) {
+	tm := NewTaskManager()
+	ctx := context.Background()
+
+	errBoom := errors.New("boom")
+	_, _ = tm.StartTask(ctx, "a", func(ctx context.Context) error { return nil })
+	_, _ = tm.StartTask(ctx, "b", func(ctx context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return errBoom
+	})
+	_, _ = tm.StartTask(ctx, "c", func(ctx context.Context) error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+
+	waitCtx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+	if err := tm.WaitAll(waitCtx, "a", "b", "c"); !errors.Is(err, errBoom) {
+		t.Errorf("Expected the error of b, got %v", err)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if info, _ := tm.Status(id); info.FinishedAt.IsZero() {
+			t.Errorf("Expected %s to have finished", id)
+		}
+	}
+
+	if err := tm.WaitAll(waitCtx, "a", "missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestWaitAll_Context(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+
+	_, _ = tm.StartTask(context.Background(), "task", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := tm.WaitAll(ctx, "task"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the context error, got %v", err)
+	}
+}
+
+func TestWaitAny(t *testing.T) {
+	tm := NewTaskManager()
+	defer tm.GracefulShutdown(true, 500*time.Millisecond)
+	ctx := context.Background()
+
+	errBoom := errors.New("boom")
+	_, _ = tm.StartTask(ctx, "slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	_, _ = tm.StartTask(ctx, "fast", func(ctx context.Context) error {
+		time.Sleep(10 * time.Millisecond)
+		return errBoom
+	})
+
+	waitCtx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+	id, err := tm.WaitAny(waitCtx, "slow", "fast")
+	if id != "fast" || !errors.Is(err, errBoom) {
+		t.Errorf("Expected fast to finish first with its error, got %q %v", id, err)
+	}
+}
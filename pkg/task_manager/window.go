@@ -0,0 +1,86 @@
+package taskmanager
+
+import (
+	"time"
+)
+
+// Window is a period during which the scheduled runs of a recurring task are
+// suppressed, see WithExclusionWindow. It returns the end of the window if t
+// falls inside it, and the zero time otherwise.
+type Window func(t time.Time) time.Time
+
+// WindowAction decides what happens to a run due inside an exclusion window.
+type WindowAction int
+
+const (
+	// WindowSkip drops the run.
+	WindowSkip WindowAction = iota
+	// WindowDefer runs it once the window ends. The runs due inside the same
+	// window are merged into one.
+	WindowDefer
+)
+
+type exclusion struct {
+	window Window
+	action WindowAction
+}
+
+// WithExclusionWindow suppresses the runs of a recurring task due inside w,
+// e.g. DailyWindow(0, 2*time.Hour, time.UTC) for "not between 00:00 and
+// 02:00 UTC". A suppressed run is logged and reported as an EventSuppressed.
+// The option may be given several times.
+func WithExclusionWindow(w Window, action WindowAction) TaskOption {
+	return func(cfg *taskConfig) {
+		if w != nil {
+			cfg.exclusions = append(cfg.exclusions, exclusion{window: w, action: action})
+		}
+	}
+}
+
+// DailyWindow returns a Window covering every day from the offset from to the
+// offset to since midnight in loc. A window with from after to spans
+// midnight, e.g. DailyWindow(22*time.Hour, 2*time.Hour, loc).
+func DailyWindow(from, to time.Duration, loc *time.Location) Window {
+	return func(t time.Time) time.Time {
+		t = t.In(loc)
+		midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		offset := t.Sub(midnight)
+		switch {
+		case from < to && offset >= from && offset < to:
+			return midnight.Add(to)
+		case from > to && offset >= from:
+			return midnight.AddDate(0, 0, 1).Add(to)
+		case from > to && offset < to:
+			return midnight.Add(to)
+		}
+		return time.Time{}
+	}
+}
+
+// CalendarWindow returns a Window covering the whole days, in loc, for which
+// excluded reports true, e.g. public holidays.
+func CalendarWindow(excluded func(day time.Time) bool, loc *time.Location) Window {
+	return func(t time.Time) time.Time {
+		t = t.In(loc)
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		if !excluded(day) {
+			return time.Time{}
+		}
+		// give up after a year of excluded days
+		for i := 0; i < 366 && excluded(day); i++ {
+			day = day.AddDate(0, 0, 1)
+		}
+		return day
+	}
+}
+
+// excludedUntil returns the end of the exclusion window t falls into and
+// whether the run due at t is deferred, or the zero time if t is not excluded.
+func excludedUntil(exclusions []exclusion, t time.Time) (time.Time, bool) {
+	for _, e := range exclusions {
+		if end := e.window(t); !end.IsZero() {
+			return end, e.action == WindowDefer
+		}
+	}
+	return time.Time{}, false
+}
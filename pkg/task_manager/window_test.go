@@ -0,0 +1,116 @@
+package taskmanager
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDailyWindow(t *testing.T) {
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(h, m int) time.Time { return day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute) }
+
+	tests := []struct {
+		name     string
+		from, to time.Duration
+		t        time.Time
+		want     time.Time
+	}{
+		{"inside", 0, 2 * time.Hour, at(1, 0), at(2, 0)},
+		{"start", 0, 2 * time.Hour, at(0, 0), at(2, 0)},
+		{"end", 0, 2 * time.Hour, at(2, 0), time.Time{}},
+		{"outside", 0, 2 * time.Hour, at(12, 0), time.Time{}},
+		{"before midnight", 22 * time.Hour, 2 * time.Hour, at(23, 0), at(26, 0)},
+		{"after midnight", 22 * time.Hour, 2 * time.Hour, at(1, 0), at(2, 0)},
+		{"outside overnight", 22 * time.Hour, 2 * time.Hour, at(12, 0), time.Time{}},
+	}
+	for _, tt := range tests {
+		if got := DailyWindow(tt.from, tt.to, time.UTC)(tt.t); !got.Equal(tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestCalendarWindow(t *testing.T) {
+	weekend := CalendarWindow(func(day time.Time) bool {
+		return day.Weekday() == time.Saturday || day.Weekday() == time.Sunday
+	}, time.UTC)
+
+	saturday := time.Date(2024, 1, 6, 15, 0, 0, 0, time.UTC)
+	if got, want := weekend(saturday), time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Expected the window to end on Monday %v, got %v", want, got)
+	}
+	if got := weekend(saturday.AddDate(0, 0, 2)); !got.IsZero() {
+		t.Errorf("Expected Monday not to be excluded, got %v", got)
+	}
+}
+
+// runWindowed runs a task every hour from 23:00 with runs excluded between
+// 00:30 and 02:30, and returns the offsets of its first three runs and the
+// number of suppressed runs meanwhile.
+func runWindowed(t *testing.T, action WindowAction) ([]time.Duration, int) {
+	t.Helper()
+	start := time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	tm := NewTaskManager(WithClock(clock))
+	events, unsubscribe := tm.Subscribe()
+	defer unsubscribe()
+
+	runs := make(chan time.Time, 1)
+	err := tm.StartRecurringTask(context.Background(), "task", func(ctx context.Context) error {
+		runs <- clock.Now()
+		return nil
+	}, Every(time.Hour), WithExclusionWindow(DailyWindow(30*time.Minute, 150*time.Minute, time.UTC), action))
+	if err != nil {
+		t.Fatalf("Unexpected error starting recurring task: %v", err)
+	}
+
+	var offsets []time.Duration
+	for len(offsets) < 3 {
+		clock.BlockUntil(1)
+		clock.Advance(10 * time.Minute)
+		select {
+		case at := <-runs:
+			offsets = append(offsets, at.Sub(start))
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	if err := tm.StopTaskAndWait("task", 500*time.Millisecond); err != nil {
+		t.Fatalf("Expected the task to stop, got %v", err)
+	}
+
+	suppressed := 0
+	for {
+		select {
+		case e := <-events:
+			if e.Type == EventSuppressed {
+				suppressed++
+			}
+		default:
+			return offsets, suppressed
+		}
+	}
+}
+
+func TestWithExclusionWindow_Skip(t *testing.T) {
+	got, suppressed := runWindowed(t, WindowSkip)
+	want := []time.Duration{time.Hour, 4 * time.Hour, 5 * time.Hour}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected runs at %v, got %v", want, got)
+	}
+	if suppressed != 2 {
+		t.Errorf("Expected 2 suppressed runs, got %d", suppressed)
+	}
+}
+
+func TestWithExclusionWindow_Defer(t *testing.T) {
+	got, suppressed := runWindowed(t, WindowDefer)
+	want := []time.Duration{time.Hour, 3*time.Hour + 30*time.Minute, 4*time.Hour + 30*time.Minute}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected runs at %v, got %v", want, got)
+	}
+	if suppressed != 1 {
+		t.Errorf("Expected 1 suppressed run, got %d", suppressed)
+	}
+}